@@ -0,0 +1,266 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/veraison/eat"
+)
+
+// ComidBuilder provides the same fluent interface as the Comid setters, but
+// instead of returning nil when a step fails, it records the reason and
+// carries on.  The accumulated errors are returned by Err and Build, so that a
+// chain of calls never needs to be checked for nil half-way through.
+type ComidBuilder struct {
+	comid *Comid
+	errs  []error
+}
+
+// NewComidBuilder instantiates a builder for an empty Comid
+func NewComidBuilder() *ComidBuilder {
+	return &ComidBuilder{comid: NewComid()}
+}
+
+func (o *ComidBuilder) check(step string, err error) *ComidBuilder {
+	if err != nil {
+		o.errs = append(o.errs, fmt.Errorf("%s: %w", step, err))
+	}
+	return o
+}
+
+// SetLanguage is the builder equivalent of Comid.SetLanguage
+func (o *ComidBuilder) SetLanguage(language string) *ComidBuilder {
+	return o.check("SetLanguage", o.comid.setLanguage(language))
+}
+
+// SetTagIdentity is the builder equivalent of Comid.SetTagIdentity
+func (o *ComidBuilder) SetTagIdentity(tagID interface{}, tagIDVersion uint) *ComidBuilder {
+	return o.check("SetTagIdentity", o.comid.setTagIdentity(tagID, tagIDVersion))
+}
+
+// AddEntity is the builder equivalent of Comid.AddEntity
+func (o *ComidBuilder) AddEntity(name string, regID *string, roles ...Role) *ComidBuilder {
+	return o.check("AddEntity", o.comid.addEntity(name, regID, roles...))
+}
+
+// AddLinkedTag is the builder equivalent of Comid.AddLinkedTag
+func (o *ComidBuilder) AddLinkedTag(tagID interface{}, rel Rel) *ComidBuilder {
+	return o.check("AddLinkedTag", o.comid.addLinkedTag(tagID, rel))
+}
+
+// AddReferenceValue is the builder equivalent of Comid.AddReferenceValue
+func (o *ComidBuilder) AddReferenceValue(val ValueTriple) *ComidBuilder {
+	return o.check("AddReferenceValue", o.comid.addReferenceValue(val))
+}
+
+// AddEndorsedValue is the builder equivalent of Comid.AddEndorsedValue
+func (o *ComidBuilder) AddEndorsedValue(val ValueTriple) *ComidBuilder {
+	return o.check("AddEndorsedValue", o.comid.addEndorsedValue(val))
+}
+
+// AddAttestVerifKey is the builder equivalent of Comid.AddAttestVerifKey
+func (o *ComidBuilder) AddAttestVerifKey(val KeyTriple) *ComidBuilder {
+	return o.check("AddAttestVerifKey", o.comid.addAttestVerifKey(val))
+}
+
+// AddDevIdentityKey is the builder equivalent of Comid.AddDevIdentityKey
+func (o *ComidBuilder) AddDevIdentityKey(val KeyTriple) *ComidBuilder {
+	return o.check("AddDevIdentityKey", o.comid.addDevIdentityKey(val))
+}
+
+// AddReferenceMeasurements adds a reference value for the supplied environment
+// made of the measurements produced by the supplied builders.  Errors recorded
+// by any of the measurement builders are added to those of the target.
+func (o *ComidBuilder) AddReferenceMeasurements(
+	env Environment, ms ...*MeasurementBuilder,
+) *ComidBuilder {
+	vt, err := newValueTriple(env, ms)
+	if err != nil {
+		return o.check("AddReferenceMeasurements", err)
+	}
+	return o.AddReferenceValue(*vt)
+}
+
+// AddEndorsedMeasurements adds an endorsed value for the supplied environment
+// made of the measurements produced by the supplied builders.  Errors recorded
+// by any of the measurement builders are added to those of the target.
+func (o *ComidBuilder) AddEndorsedMeasurements(
+	env Environment, ms ...*MeasurementBuilder,
+) *ComidBuilder {
+	vt, err := newValueTriple(env, ms)
+	if err != nil {
+		return o.check("AddEndorsedMeasurements", err)
+	}
+	return o.AddEndorsedValue(*vt)
+}
+
+func newValueTriple(env Environment, ms []*MeasurementBuilder) (*ValueTriple, error) {
+	var errs []error
+
+	vt := ValueTriple{Environment: env, Measurements: *NewMeasurements()}
+
+	for i, mb := range ms {
+		if mb == nil {
+			errs = append(errs, fmt.Errorf("measurement at index %d: nil builder", i))
+			continue
+		}
+
+		if len(mb.errs) != 0 {
+			for _, err := range mb.errs {
+				errs = append(errs, fmt.Errorf("measurement at index %d: %w", i, err))
+			}
+			continue
+		}
+
+		m, err := mb.Build()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("measurement at index %d: %w", i, err))
+			continue
+		}
+		vt.Measurements.Add(m)
+	}
+
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return &vt, nil
+}
+
+// Err returns the errors recorded so far, or nil if there are none
+func (o *ComidBuilder) Err() error {
+	return errors.Join(o.errs...)
+}
+
+// Build returns the assembled Comid after checking its validity.  If any of
+// the preceding steps failed, the recorded errors are returned instead.
+func (o *ComidBuilder) Build() (*Comid, error) {
+	if err := o.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := o.comid.Valid(); err != nil {
+		return nil, err
+	}
+
+	return o.comid, nil
+}
+
+// MeasurementBuilder provides the same fluent interface as the Measurement
+// setters, recording errors rather than returning nil.  See ComidBuilder.
+type MeasurementBuilder struct {
+	m    *Measurement
+	errs []error
+}
+
+// NewMeasurementBuilder instantiates a builder for a measurement with the
+// supplied key value and type (see NewMeasurement).  A failure to create the
+// key is recorded like any other error.
+func NewMeasurementBuilder(val any, typ string) *MeasurementBuilder {
+	m, err := NewMeasurement(val, typ)
+
+	o := &MeasurementBuilder{m: m}
+	if m == nil {
+		o.m = &Measurement{}
+	}
+
+	return o.check("NewMeasurement", err)
+}
+
+func (o *MeasurementBuilder) check(step string, err error) *MeasurementBuilder {
+	if err != nil {
+		o.errs = append(o.errs, fmt.Errorf("%s: %w", step, err))
+	}
+	return o
+}
+
+// SetVersion is the builder equivalent of Measurement.SetVersion
+func (o *MeasurementBuilder) SetVersion(ver string, scheme int64) *MeasurementBuilder {
+	return o.check("SetVersion", o.m.setVersion(ver, scheme))
+}
+
+// SetRawValueBytes is the builder equivalent of Measurement.SetRawValueBytes
+func (o *MeasurementBuilder) SetRawValueBytes(rawValue, rawValueMask []byte) *MeasurementBuilder {
+	o.m.SetRawValueBytes(rawValue, rawValueMask)
+	return o
+}
+
+// SetSVN is the builder equivalent of Measurement.SetSVN
+func (o *MeasurementBuilder) SetSVN(svn uint64) *MeasurementBuilder {
+	o.m.SetSVN(svn)
+	return o
+}
+
+// SetMinSVN is the builder equivalent of Measurement.SetMinSVN
+func (o *MeasurementBuilder) SetMinSVN(svn uint64) *MeasurementBuilder {
+	o.m.SetMinSVN(svn)
+	return o
+}
+
+// AddDigest is the builder equivalent of Measurement.AddDigest
+func (o *MeasurementBuilder) AddDigest(algID uint64, digest []byte) *MeasurementBuilder {
+	return o.check("AddDigest", o.m.addDigest(algID, digest))
+}
+
+// SetFlagsTrue is the builder equivalent of Measurement.SetFlagsTrue
+func (o *MeasurementBuilder) SetFlagsTrue(flags ...Flag) *MeasurementBuilder {
+	o.m.SetFlagsTrue(flags...)
+	return o
+}
+
+// SetFlagsFalse is the builder equivalent of Measurement.SetFlagsFalse
+func (o *MeasurementBuilder) SetFlagsFalse(flags ...Flag) *MeasurementBuilder {
+	o.m.SetFlagsFalse(flags...)
+	return o
+}
+
+// SetIPaddr is the builder equivalent of Measurement.SetIPaddr
+func (o *MeasurementBuilder) SetIPaddr(a net.IP) *MeasurementBuilder {
+	o.m.SetIPaddr(a)
+	return o
+}
+
+// SetMACaddr is the builder equivalent of Measurement.SetMACaddr
+func (o *MeasurementBuilder) SetMACaddr(a MACaddr) *MeasurementBuilder {
+	o.m.SetMACaddr(a)
+	return o
+}
+
+// SetSerialNumber is the builder equivalent of Measurement.SetSerialNumber
+func (o *MeasurementBuilder) SetSerialNumber(sn string) *MeasurementBuilder {
+	o.m.SetSerialNumber(sn)
+	return o
+}
+
+// SetUEID is the builder equivalent of Measurement.SetUEID
+func (o *MeasurementBuilder) SetUEID(ueid eat.UEID) *MeasurementBuilder {
+	return o.check("SetUEID", o.m.setUEID(ueid))
+}
+
+// SetUUID is the builder equivalent of Measurement.SetUUID
+func (o *MeasurementBuilder) SetUUID(u UUID) *MeasurementBuilder {
+	return o.check("SetUUID", o.m.setUUID(u))
+}
+
+// Err returns the errors recorded so far, or nil if there are none
+func (o *MeasurementBuilder) Err() error {
+	return errors.Join(o.errs...)
+}
+
+// Build returns the assembled Measurement after checking its validity.  If any
+// of the preceding steps failed, the recorded errors are returned instead.
+func (o *MeasurementBuilder) Build() (*Measurement, error) {
+	if err := o.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := o.m.Valid(); err != nil {
+		return nil, err
+	}
+
+	return o.m, nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/swid"
+)
+
+func TestNewTagID(t *testing.T) {
+	id, err := NewTagID(TestTagID)
+	require.NoError(t, err)
+	assert.Equal(t, TestTagID, id.String())
+
+	id, err = NewTagID(uuid.UUID(TestUUID))
+	require.NoError(t, err)
+	assert.Equal(t, TestUUIDString, id.String())
+
+	_, err = NewTagID("")
+	assert.EqualError(t, err, "empty tag-id")
+
+	_, err = NewTagID([]byte{0x01, 0x02})
+	assert.EqualError(t, err, "invalid UUID tag-id: invalid UUID (got 2 bytes)")
+
+	_, err = NewTagID(7)
+	assert.EqualError(t, err, "unsupported tag-id type int")
+}
+
+func TestComidBuilder_ok(t *testing.T) {
+	regID := TestRegID
+
+	c, err := NewComidBuilder().
+		SetLanguage("en-GB").
+		SetTagIdentity(TestTagID, 1).
+		AddEntity("ACME Ltd.", &regID, RoleCreator, RoleTagCreator).
+		AddLinkedTag(TestUUIDString, RelSupplements).
+		AddReferenceMeasurements(
+			Environment{Instance: MustNewUUIDInstance(TestUUID)},
+			NewMeasurementBuilder(uint64(1), UintType).
+				SetVersion("1.2.3", swid.VersionSchemeSemVer).
+				AddDigest(swid.Sha256_32, []byte{0xaa, 0xbb, 0xcc, 0xdd}),
+		).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "en-GB", *c.Language)
+	assert.Equal(t, TestTagID, c.TagIdentity.TagID.String())
+	assert.Len(t, c.Entities.Values, 1)
+	assert.Len(t, *c.LinkedTags, 1)
+	require.Len(t, c.Triples.ReferenceValues.Values, 1)
+
+	m := c.Triples.ReferenceValues.Values[0].Measurements.Values[0]
+	assert.Equal(t, "1.2.3", m.Val.Ver.Version)
+	assert.Len(t, *m.Val.Digests, 1)
+}
+
+func TestComidBuilder_errors(t *testing.T) {
+	badRegID := "not/absolute"
+
+	b := NewComidBuilder().
+		SetLanguage("").
+		SetTagIdentity(7, 0).
+		AddEntity("ACME Ltd.", &badRegID, RoleCreator).
+		AddLinkedTag(TestTagID, RelReplaces)
+
+	err := b.Err()
+	assert.EqualError(t, err, `SetLanguage: empty language
+SetTagIdentity: unsupported tag-id type int
+AddEntity: expecting an absolute URI: "not/absolute" is not an absolute URI`)
+
+	c, buildErr := b.Build()
+	assert.Nil(t, c)
+	assert.Equal(t, err, buildErr)
+}
+
+func TestComidBuilder_invalid(t *testing.T) {
+	b := NewComidBuilder().SetTagIdentity(TestTagID, 0)
+	require.NoError(t, b.Err())
+
+	_, err := b.Build()
+	assert.EqualError(t, err, "triples validation failed: triples struct must not be empty")
+}
+
+func TestComidBuilder_measurement_errors(t *testing.T) {
+	env := Environment{Instance: MustNewUUIDInstance(TestUUID)}
+
+	b := NewComidBuilder().
+		SetTagIdentity(TestTagID, 0).
+		AddEndorsedMeasurements(env,
+			NewMeasurementBuilder(uint64(1), UintType).SetSVN(2),
+			NewMeasurementBuilder(uint64(2), "no-such-type").SetSVN(2),
+			NewMeasurementBuilder(uint64(3), UintType).
+				SetVersion("1.0", 99).
+				AddDigest(swid.Sha256, []byte{0x00}),
+		)
+
+	assert.EqualError(t, b.Err(), `AddEndorsedMeasurements: measurement at index 1: NewMeasurement: unknown Mkey type: no-such-type
+measurement at index 2: SetVersion: invalid version scheme: unknown version scheme 99
+measurement at index 2: AddDigest: invalid digest: length mismatch for hash algorithm sha-256: want 32 bytes, got 1`)
+}
+
+func TestComidBuilder_nil_measurement_builder(t *testing.T) {
+	env := Environment{Instance: MustNewUUIDInstance(TestUUID)}
+
+	b := NewComidBuilder().
+		SetTagIdentity(TestTagID, 0).
+		AddReferenceMeasurements(env,
+			NewMeasurementBuilder(uint64(1), UintType).SetSVN(2),
+			nil,
+		)
+
+	assert.EqualError(t, b.Err(), "AddReferenceMeasurements: measurement at index 1: nil builder")
+}
+
+func TestComidBuilder_key_triple_errors(t *testing.T) {
+	b := NewComidBuilder().SetTagIdentity(TestTagID, 0)
+
+	// extensions that cannot be registered with a KeyTriple
+	b.comid.Triples.keyTripleExts = extensions.Map{ExtComid: &struct{}{}}
+
+	key := KeyTriple{
+		Environment: Environment{Instance: MustNewUUIDInstance(TestUUID)},
+		VerifKeys:   *NewCryptoKeys().Add(MustNewPKIXBase64Key(TestECPubKey)),
+	}
+
+	b.AddAttestVerifKey(key).AddDevIdentityKey(key)
+
+	assert.EqualError(t, b.Err(), `AddAttestVerifKey: unexpected extension point: "Comid"
+AddDevIdentityKey: unexpected extension point: "Comid"`)
+	assert.Nil(t, b.comid.Triples.AttestVerifKeys)
+	assert.Nil(t, b.comid.Triples.DevIdentityKeys)
+}
+
+func TestMeasurementBuilder(t *testing.T) {
+	m, err := NewMeasurementBuilder(TestUUID, UUIDType).
+		SetSVN(7).
+		SetSerialNumber("C02X70VHJHD5").
+		SetUEID(TestUEID).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, "C02X70VHJHD5", *m.Val.SerialNumber)
+
+	b := NewMeasurementBuilder(TestUUID, UUIDType).SetUEID(nil).SetUUID(UUID{})
+	assert.ErrorContains(t, b.Err(), "SetUEID: invalid UEID:")
+	assert.ErrorContains(t, b.Err(), "SetUUID: invalid UUID:")
+
+	_, err = NewMeasurementBuilder(TestUUID, UUIDType).Build()
+	assert.EqualError(t, err, "no measurement value set")
+}
@@ -6,8 +6,10 @@ package comid
 import (
 	"fmt"
 	"reflect"
+	"sync/atomic"
 
 	cbor "github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/encoding"
 )
 
 var (
@@ -36,6 +38,10 @@ var (
 		601: TaggedPSARefValID{},
 		602: TaggedCCAPlatformConfigID(""),
 	}
+
+	// comidTagsGeneration is incremented each time comidTagsMap changes, so
+	// that type registries know when to rebuild their encoding modes
+	comidTagsGeneration atomic.Uint64
 )
 
 func comidTags() cbor.TagSet {
@@ -56,19 +62,27 @@ func comidTags() cbor.TagSet {
 }
 
 func initCBOREncMode() (en cbor.EncMode, err error) {
+	return newCBOREncMode(comidTags())
+}
+
+func initCBORDecMode() (dm cbor.DecMode, err error) {
+	return newCBORDecMode(comidTags())
+}
+
+func newCBOREncMode(tags cbor.TagSet) (en cbor.EncMode, err error) {
 	encOpt := cbor.EncOptions{
 		Sort:        cbor.SortCoreDeterministic,
 		IndefLength: cbor.IndefLengthForbidden,
 		TimeTag:     cbor.EncTagRequired,
 	}
-	return encOpt.EncModeWithTags(comidTags())
+	return encOpt.EncModeWithTags(tags)
 }
 
-func initCBORDecMode() (dm cbor.DecMode, err error) {
+func newCBORDecMode(tags cbor.TagSet) (dm cbor.DecMode, err error) {
 	decOpt := cbor.DecOptions{
 		IndefLength: cbor.IndefLengthForbidden,
 	}
-	return decOpt.DecModeWithTags(comidTags())
+	return encoding.NewDecMode(decOpt, tags)
 }
 
 func registerCOMIDTag(tag uint64, t interface{}) error {
@@ -77,6 +91,7 @@ func registerCOMIDTag(tag uint64, t interface{}) error {
 	}
 
 	comidTagsMap[tag] = t
+	comidTagsGeneration.Add(1)
 
 	var err error
 
@@ -6,6 +6,10 @@ package comid
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
 )
 
 // Class represents the class of the (target / attesting) environment.  The only
@@ -13,11 +17,32 @@ import (
 // information about the specific brand & product as well as its topological
 // coordinates within the wider device can be recorded.
 type Class struct {
-	ClassID *ClassID `cbor:"0,keyasint,omitempty" json:"id,omitempty"`
-	Vendor  *string  `cbor:"1,keyasint,omitempty" json:"vendor,omitempty"`
-	Model   *string  `cbor:"2,keyasint,omitempty" json:"model,omitempty"`
-	Layer   *uint64  `cbor:"3,keyasint,omitempty" json:"layer,omitempty"`
-	Index   *uint64  `cbor:"4,keyasint,omitempty" json:"index,omitempty"`
+	ClassID *ClassID `cbor:"0,keyasint,omitempty" json:"id,omitempty" cddl:"comid.class-id"`
+	Vendor  *string  `cbor:"1,keyasint,omitempty" json:"vendor,omitempty" cddl:"comid.vendor"`
+	Model   *string  `cbor:"2,keyasint,omitempty" json:"model,omitempty" cddl:"comid.model"`
+	Layer   *uint64  `cbor:"3,keyasint,omitempty" json:"layer,omitempty" cddl:"comid.layer"`
+	Index   *uint64  `cbor:"4,keyasint,omitempty" json:"index,omitempty" cddl:"comid.index"`
+
+	Extensions
+}
+
+// RegisterExtensions registers a struct as a collections of extensions
+func (o *Class) RegisterExtensions(exts extensions.Map) error {
+	for p, v := range exts {
+		switch p {
+		case ExtClass:
+			o.Extensions.Register(v)
+		default:
+			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
+		}
+	}
+
+	return nil
+}
+
+// GetExtensions returns previously registered extension
+func (o *Class) GetExtensions() extensions.IMapValue {
+	return o.Extensions.IMapValue
 }
 
 // NewClassUUID instantiates a new Class object with the specified UUID as
@@ -122,14 +147,68 @@ func (o *Class) SetIndex(index uint64) *Class {
 	return o
 }
 
+// IsEmpty returns true if neither the base fields nor the extensions of the
+// target Class are set
+// nolint:gocritic
+func (o Class) IsEmpty() bool {
+	if (o.ClassID != nil && o.ClassID.IsSet()) ||
+		o.Vendor != nil || o.Model != nil || o.Layer != nil || o.Index != nil {
+		return false
+	}
+
+	return o.Extensions.IsEmpty()
+}
+
 // Valid checks the non-empty<> constraint on the map
+// nolint:gocritic
 func (o Class) Valid() error {
-	// check non-empty<{ ... }>
-	if (o.ClassID == nil || !o.ClassID.IsSet()) &&
-		o.Vendor == nil && o.Model == nil && o.Layer == nil && o.Index == nil {
-		return fmt.Errorf("class must not be empty")
+	return validation.FirstError(o.validate)
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *Class) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *Class) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtClass, o); err != nil {
+		return err
 	}
-	return nil
+
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o Class) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o Class) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *Class) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *Class) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtClass, o); err != nil {
+		return err
+	}
+
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o Class) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
 }
 
 // ToCBOR serializes the target Class to CBOR (if the Class is "valid")
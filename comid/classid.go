@@ -23,7 +23,13 @@ type ClassID struct {
 
 // NewClassID creates a new ClassID of the specified type using the specified value.
 func NewClassID(val any, typ string) (*ClassID, error) {
-	factory, ok := classIDValueRegister[typ]
+	return newClassID(nil, val, typ)
+}
+
+// newClassID is like NewClassID, except that the type choices of the supplied
+// registry (if any) are available in addition to the base ones
+func newClassID(r *TypeRegistry, val any, typ string) (*ClassID, error) {
+	factory, ok := classIDFactory(r, typ)
 	if !ok {
 		return nil, fmt.Errorf("unknown class id type: %s", typ)
 	}
@@ -65,14 +71,36 @@ func (o ClassID) IsSet() bool {
 
 // MarshalCBOR serializes the target ClassID to CBOR
 func (o ClassID) MarshalCBOR() ([]byte, error) {
-	return em.Marshal(o.Value)
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith is like MarshalCBOR, except that the CBOR tags of the
+// TypeRegistry in scope of enc (if any) are available
+func (o ClassID) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	mode, err := encModeOf(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return mode.Marshal(o.Value)
 }
 
 // UnmarshalCBOR deserializes the supplied CBOR buffer into the target ClassID.
 // It is undefined behavior to try and inspect the target ClassID in case this
 // method returns an error.
 func (o *ClassID) UnmarshalCBOR(data []byte) error {
-	return dm.Unmarshal(data, &o.Value)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith is like UnmarshalCBOR, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *ClassID) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	mode, err := decModeOf(dec)
+	if err != nil {
+		return err
+	}
+
+	return mode.Unmarshal(data, &o.Value)
 }
 
 // UnmarshalJSON deserializes the supplied JSON object into the target ClassID
@@ -95,15 +123,22 @@ func (o *ClassID) UnmarshalCBOR(data []byte) error {
 //		int: an integer value, e.g. 7
 //	 bytes: a variable length opaque bytes, example {0x07, 0x12, 0x34}
 
-//nolint:dupl
 func (o *ClassID) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith is like UnmarshalJSON, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+//
+//nolint:dupl
+func (o *ClassID) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
 	var tnv encoding.TypeAndValue
 
 	if err := json.Unmarshal(data, &tnv); err != nil {
 		return fmt.Errorf("class id decoding failure: %w", err)
 	}
 
-	decoded, err := NewClassID(nil, tnv.Type)
+	decoded, err := newClassID(typeRegistryOf(dec), nil, tnv.Type)
 	if err != nil {
 		return err
 	}
@@ -8,21 +8,29 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/google/uuid"
 	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
 	"github.com/veraison/swid"
 )
 
 // Comid is the top-level representation of a Concise Module IDentifier with
 // CBOR and JSON serialization.
 type Comid struct {
-	Language    *string     `cbor:"0,keyasint,omitempty" json:"lang,omitempty"`
-	TagIdentity TagIdentity `cbor:"1,keyasint" json:"tag-identity"`
-	Entities    *Entities   `cbor:"2,keyasint,omitempty" json:"entities,omitempty"`
-	LinkedTags  *LinkedTags `cbor:"3,keyasint,omitempty" json:"linked-tags,omitempty"`
-	Triples     Triples     `cbor:"4,keyasint" json:"triples"`
+	Language    *string     `cbor:"0,keyasint,omitempty" json:"lang,omitempty" cddl:"comid.language"`
+	TagIdentity TagIdentity `cbor:"1,keyasint" json:"tag-identity" cddl:"comid.tag-identity"`
+	Entities    *Entities   `cbor:"2,keyasint,omitempty" json:"entities,omitempty" cddl:"comid.entity"`
+	LinkedTags  *LinkedTags `cbor:"3,keyasint,omitempty" json:"linked-tags,omitempty" cddl:"comid.linked-tags"`
+	Triples     Triples     `cbor:"4,keyasint" json:"triples" cddl:"comid.triples"`
 
 	Extensions
+
+	types *TypeRegistry
+
+	// linkedTagExts holds the extensions registered for the LinkedTags
+	// entries (see extensions.WithElementExtensions)
+	linkedTagExts extensions.Map
 }
 
 // NewComid instantiates an empty Comid
@@ -47,6 +55,16 @@ func (o *Comid) RegisterExtensions(exts extensions.Map) error {
 			if err := o.Entities.RegisterExtensions(entMap); err != nil {
 				return err
 			}
+		case ExtTagIdentity:
+			tagIDMap := extensions.NewMap().Add(ExtTagIdentity, v)
+			if err := o.TagIdentity.RegisterExtensions(tagIDMap); err != nil {
+				return err
+			}
+		case ExtLinkedTag:
+			ltMap := extensions.NewMap().Add(ExtLinkedTag, v)
+			if err := o.registerLinkedTagExtensions(ltMap); err != nil {
+				return err
+			}
 		default:
 			triplesExts.Add(p, v)
 		}
@@ -55,6 +73,42 @@ func (o *Comid) RegisterExtensions(exts extensions.Map) error {
 	return o.Triples.RegisterExtensions(triplesExts)
 }
 
+func (o *Comid) registerLinkedTagExtensions(exts extensions.Map) error {
+	// validate the extensions by registering them with a new LinkedTag
+	if err := new(LinkedTag).RegisterExtensions(exts); err != nil {
+		return err
+	}
+
+	o.linkedTagExts = exts
+
+	if o.LinkedTags == nil {
+		return nil
+	}
+
+	for i := range *o.LinkedTags {
+		if err := extensions.RegisterIfUnset(&(*o.LinkedTags)[i], exts); err != nil {
+			return fmt.Errorf("linked tag at index %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// SetTypeRegistry sets the TypeRegistry whose type choices are used, in
+// addition to the base ones, when serializing and deserializing the target
+// Comid
+func (o *Comid) SetTypeRegistry(r *TypeRegistry) *Comid {
+	if o != nil {
+		o.types = r
+	}
+	return o
+}
+
+// GetTypeRegistry returns the TypeRegistry set on the target Comid, if any
+func (o Comid) GetTypeRegistry() *TypeRegistry {
+	return o.types
+}
+
 // GetExtensions returns previously registered extension
 func (o *Comid) GetExtensions() extensions.IMapValue {
 	return o.Extensions.IMapValue
@@ -64,14 +118,21 @@ func (o *Comid) GetExtensions() extensions.IMapValue {
 // language tag.  See also: BCP 47 and the IANA Language subtag registry.
 func (o *Comid) SetLanguage(language string) *Comid {
 	if o != nil {
-		if language == "" {
+		if o.setLanguage(language) != nil {
 			return nil
 		}
-		o.Language = &language
 	}
 	return o
 }
 
+func (o *Comid) setLanguage(language string) error {
+	if language == "" {
+		return fmt.Errorf("empty language")
+	}
+	o.Language = &language
+	return nil
+}
+
 // SetTagIdentity sets the identifier of the target Comid to the supplied tagID,
 // which MUST be of type string or [16]byte.  A tagIDVersion must also be
 // supplied to disambiguate between different revisions of the same tag
@@ -80,16 +141,49 @@ func (o *Comid) SetLanguage(language string) *Comid {
 // existing tagIDVersion's associated with that tagID.
 func (o *Comid) SetTagIdentity(tagID interface{}, tagIDVersion uint) *Comid {
 	if o != nil {
-		id := swid.NewTagID(tagID)
-		if id == nil {
+		if o.setTagIdentity(tagID, tagIDVersion) != nil {
 			return nil
 		}
-		o.TagIdentity.TagID = *id
-		o.TagIdentity.TagVersion = tagIDVersion
 	}
 	return o
 }
 
+func (o *Comid) setTagIdentity(tagID interface{}, tagIDVersion uint) error {
+	id, err := NewTagID(tagID)
+	if err != nil {
+		return err
+	}
+	o.TagIdentity.TagID = *id
+	o.TagIdentity.TagVersion = tagIDVersion
+	return nil
+}
+
+// NewTagID returns a swid.TagID for the supplied value, which must be a
+// non-empty string, a UUID in string or binary (16 bytes) form, or a
+// uuid.UUID.  Unlike swid.NewTagID, the reason for a failure is reported.
+func NewTagID(v interface{}) (*swid.TagID, error) {
+	switch t := v.(type) {
+	case string:
+		if t == "" {
+			return nil, fmt.Errorf("empty tag-id")
+		}
+	case []byte:
+		if _, err := swid.NewTagIDFromUUIDBytes(t); err != nil {
+			return nil, fmt.Errorf("invalid UUID tag-id: %w", err)
+		}
+	case uuid.UUID:
+	default:
+		return nil, fmt.Errorf("unsupported tag-id type %T", v)
+	}
+
+	id := swid.NewTagID(v)
+	if id == nil {
+		return nil, fmt.Errorf("invalid tag-id %v", v)
+	}
+
+	return id, nil
+}
+
 func IsAbsoluteURI(s string) error {
 	var (
 		u   *url.URL
@@ -129,31 +223,44 @@ func String2URI(s *string) (*TaggedURI, error) {
 // and RoleMaintainer.
 func (o *Comid) AddEntity(name string, regID *string, roles ...Role) *Comid {
 	if o != nil {
-		var rs Roles
-		if rs.Add(roles...) == nil {
+		if o.addEntity(name, regID, roles...) != nil {
 			return nil
 		}
+	}
+	return o
+}
 
-		uri, err := String2URI(regID)
-		if err != nil {
-			return nil
-		}
+func (o *Comid) addEntity(name string, regID *string, roles ...Role) error {
+	var rs Roles
+	if rs.Add(roles...) == nil {
+		return fmt.Errorf("invalid roles")
+	}
 
-		e := Entity{
-			Name:  MustNewStringEntityName(name),
-			RegID: uri,
-			Roles: rs,
-		}
+	uri, err := String2URI(regID)
+	if err != nil {
+		return err
+	}
 
-		if o.Entities == nil {
-			o.Entities = new(Entities)
-		}
+	en, err := NewStringEntityName(name)
+	if err != nil {
+		return err
+	}
 
-		if o.Entities.Add(&e) == nil {
-			return nil
-		}
+	e := Entity{
+		Name:  en,
+		RegID: uri,
+		Roles: rs,
 	}
-	return o
+
+	if o.Entities == nil {
+		o.Entities = new(Entities)
+	}
+
+	if o.Entities.Add(&e) == nil {
+		return fmt.Errorf("could not add entity")
+	}
+
+	return nil
 }
 
 // AddLinkedTag adds a link relationship of type rel between the target Comid
@@ -161,110 +268,118 @@ func (o *Comid) AddEntity(name string, regID *string, roles ...Role) *Comid {
 // RelSupplements or RelReplaces.
 func (o *Comid) AddLinkedTag(tagID interface{}, rel Rel) *Comid {
 	if o != nil {
-		id := swid.NewTagID(tagID)
-		if id == nil {
+		if o.addLinkedTag(tagID, rel) != nil {
 			return nil
 		}
+	}
+	return o
+}
 
-		lt := LinkedTag{
-			LinkedTagID: *id,
-			Rel:         rel,
-		}
+func (o *Comid) addLinkedTag(tagID interface{}, rel Rel) error {
+	id, err := NewTagID(tagID)
+	if err != nil {
+		return err
+	}
 
-		if o.LinkedTags == nil {
-			o.LinkedTags = new(LinkedTags)
-		}
+	lt := LinkedTag{
+		LinkedTagID: *id,
+		Rel:         rel,
+	}
 
-		if o.LinkedTags.AddLinkedTag(lt) == nil {
-			return nil
-		}
+	if err := extensions.RegisterIfUnset(&lt, o.linkedTagExts); err != nil {
+		return err
 	}
-	return o
+
+	if o.LinkedTags == nil {
+		o.LinkedTags = NewLinkedTags()
+	}
+
+	if o.LinkedTags.AddLinkedTag(lt) == nil {
+		return fmt.Errorf("could not add linked tag")
+	}
+
+	return nil
 }
 
 // AddReferenceValue adds the supplied reference value to the
 // reference-triples list of the target Comid.
 func (o *Comid) AddReferenceValue(val ValueTriple) *Comid {
 	if o != nil {
-		if o.Triples.ReferenceValues == nil {
-			o.Triples.ReferenceValues = NewValueTriples()
-		}
-
-		if o.Triples.AddReferenceValue(val) == nil {
+		if o.addReferenceValue(val) != nil {
 			return nil
 		}
 	}
 	return o
 }
 
+func (o *Comid) addReferenceValue(val ValueTriple) error {
+	if o.Triples.ReferenceValues == nil {
+		o.Triples.ReferenceValues = NewValueTriples()
+	}
+
+	if o.Triples.AddReferenceValue(val) == nil {
+		return fmt.Errorf("could not add reference value")
+	}
+
+	return nil
+}
+
 // AddEndorsedValue adds the supplied endorsed value to the
 // endorsed-triples list of the target Comid.
 func (o *Comid) AddEndorsedValue(val ValueTriple) *Comid {
 	if o != nil {
-		if o.Triples.EndorsedValues == nil {
-			o.Triples.EndorsedValues = NewValueTriples()
-		}
-
-		if o.Triples.AddEndorsedValue(val) == nil {
+		if o.addEndorsedValue(val) != nil {
 			return nil
 		}
 	}
 	return o
 }
 
+func (o *Comid) addEndorsedValue(val ValueTriple) error {
+	if o.Triples.EndorsedValues == nil {
+		o.Triples.EndorsedValues = NewValueTriples()
+	}
+
+	if o.Triples.AddEndorsedValue(val) == nil {
+		return fmt.Errorf("could not add endorsed value")
+	}
+
+	return nil
+}
+
 // AddAttestVerifKey adds the supplied verification key to the
 // attest-key-triples list of the target Comid.
 func (o *Comid) AddAttestVerifKey(val KeyTriple) *Comid {
 	if o != nil {
-		if o.Triples.AttestVerifKeys == nil {
-			o.Triples.AttestVerifKeys = NewKeyTriples()
-		}
-
-		if o.Triples.AddAttestVerifKey(val) == nil {
+		if o.addAttestVerifKey(val) != nil {
 			return nil
 		}
 	}
 	return o
 }
 
+func (o *Comid) addAttestVerifKey(val KeyTriple) error {
+	return o.Triples.addAttestVerifKey(val)
+}
+
 // AddDevIdentityKey adds the supplied identity key to the
 // identity-triples list of the target Comid.
 func (o *Comid) AddDevIdentityKey(val KeyTriple) *Comid {
 	if o != nil {
-		if o.Triples.DevIdentityKeys == nil {
-			o.Triples.DevIdentityKeys = NewKeyTriples()
-		}
-
-		if o.Triples.AddDevIdentityKey(val) == nil {
+		if o.addDevIdentityKey(val) != nil {
 			return nil
 		}
 	}
 	return o
 }
 
+func (o *Comid) addDevIdentityKey(val KeyTriple) error {
+	return o.Triples.addDevIdentityKey(val)
+}
+
 // nolint:gocritic
 func (o Comid) Valid() error {
-	if err := o.TagIdentity.Valid(); err != nil {
-		return fmt.Errorf("tag-identity validation failed: %w", err)
-	}
-
-	if o.Entities != nil {
-		if err := o.Entities.Valid(); err != nil {
-			return fmt.Errorf("entities validation failed: %w", err)
-		}
-	}
-
-	if o.LinkedTags != nil {
-		if err := o.LinkedTags.Valid(); err != nil {
-			return fmt.Errorf("linked-tags validation failed: %w", err)
-		}
-	}
-
-	if err := o.Triples.Valid(); err != nil {
-		return fmt.Errorf("triples validation failed: %w", err)
-	}
-
-	return o.Extensions.validComid(&o)
+	return validation.FirstError(o.validate)
 }
 
 // ToCBOR serializes the target Comid to CBOR
@@ -284,12 +399,48 @@ func (o Comid) ToCBOR() ([]byte, error) {
 		o.Entities = nil
 	}
 
-	return encoding.SerializeStructToCBOR(em, &o)
+	return encoding.SerializeStructToCBOR(o.types.ScopeEncoder(encoding.NewEncoder(em)), &o)
 }
 
-// FromCBOR deserializes a CBOR-encoded CoMID into the target Comid
-func (o *Comid) FromCBOR(data []byte) error {
-	return encoding.PopulateStructFromCBOR(dm, data, o)
+// FromCBOR deserializes a CBOR-encoded CoMID into the target Comid.
+// Optional DecodeOptions may be specified, e.g. to decode in strict mode.
+func (o *Comid) FromCBOR(data []byte, opts ...encoding.DecodeOptions) error {
+	dec := o.newDecoder().WithOptions(opts...)
+
+	if err := encoding.CheckCBORInput(dec, data, o); err != nil {
+		return err
+	}
+
+	return encoding.PopulateStructFromCBOR(dec, data, o)
+}
+
+// newDecoder returns a Decoder for the target Comid, with its type registry
+// and the extensions of its LinkedTags entries in scope
+func (o *Comid) newDecoder() *encoding.Decoder {
+	dec := o.types.ScopeDecoder(encoding.NewDecoder(dm))
+
+	return extensions.WithElementExtensions(dec, ExtLinkedTag, o.linkedTagExts)
+}
+
+// Canonicalize re-encodes the CBOR-encoded CoMID in data as per the core
+// deterministic encoding requirements of RFC 8949 (see
+// encoding.CanonicalizeCBOR). The CoMID is not decoded, so entries unknown to
+// this package (e.g. those of unregistered extensions) are preserved.
+func Canonicalize(data []byte) ([]byte, error) {
+	return encoding.CanonicalizeCBOR(data, Comid{})
+}
+
+// ToEDN serializes the target Comid to CBOR, and renders it in Extended
+// Diagnostic Notation, with map keys annotated with the names of the
+// corresponding fields in the CoRIM specification.
+// nolint:gocritic
+func (o Comid) ToEDN() (string, error) {
+	data, err := o.ToCBOR()
+	if err != nil {
+		return "", err
+	}
+
+	return encoding.FormatEDN(data, o)
 }
 
 // ToJSON serializes the target Comid to JSON
@@ -312,9 +463,16 @@ func (o Comid) ToJSON() ([]byte, error) {
 	return encoding.SerializeStructToJSON(&o)
 }
 
-// FromJSON deserializes a JSON-encoded CoMID into the target Comid
-func (o *Comid) FromJSON(data []byte) error {
-	return encoding.PopulateStructFromJSON(data, o)
+// FromJSON deserializes a JSON-encoded CoMID into the target Comid.
+// Optional DecodeOptions may be specified, e.g. to decode in strict mode.
+func (o *Comid) FromJSON(data []byte, opts ...encoding.DecodeOptions) error {
+	dec := o.newDecoder().WithOptions(opts...)
+
+	if err := encoding.CheckJSONLimits(data, dec.Limits()); err != nil {
+		return err
+	}
+
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
 }
 
 // nolint:gocritic
@@ -3,10 +3,17 @@
 package comid
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
 	"github.com/veraison/swid"
 )
@@ -94,3 +101,285 @@ func Test_String2URI_nok(t *testing.T) {
 	_, err := String2URI(&s)
 	assert.EqualError(t, err, `expecting an absolute URI: "@@@" is not an absolute URI`)
 }
+
+func Test_Comid_preserve_unknown_fields_CBOR(t *testing.T) {
+	var top map[int]cbor.RawMessage
+	require.NoError(t, dm.Unmarshal(testComid1, &top))
+
+	top[99] = cbor.RawMessage{0x66, 'v', 'e', 'n', 'd', 'o', 'r'} // "vendor"
+
+	data, err := em.Marshal(top)
+	require.NoError(t, err)
+
+	// without opting in, the unknown entry is dropped
+	c := NewComid()
+	require.NoError(t, c.FromCBOR(data))
+
+	out, err := c.ToCBOR()
+	require.NoError(t, err)
+	assert.Equal(t, testComid1, out)
+
+	c = NewComid()
+	err = c.FromCBOR(data, encoding.DecodeOptions{PreserveUnknownFields: true})
+	require.NoError(t, err)
+
+	out, err = c.ToCBOR()
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+}
+
+func Test_Comid_preserve_unknown_fields_JSON(t *testing.T) {
+	c := NewComid()
+	require.NoError(t, c.FromCBOR(testComid1))
+
+	base, err := c.ToJSON()
+	require.NoError(t, err)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(base, &doc))
+
+	doc["x-vendor"] = "acme"
+	mval := doc["triples"].(map[string]any)["reference-values"].([]any)[0].(map[string]any)["measurements"].([]any)[0].(map[string]any)["value"].(map[string]any)
+	mval["x-calibration"] = []any{1.0, 2.0}
+
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	c = NewComid()
+	err = c.FromJSON(data, encoding.DecodeOptions{PreserveUnknownFields: true})
+	require.NoError(t, err)
+
+	out, err := c.ToJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(out))
+}
+
+func Test_Comid_strict_decoding(t *testing.T) {
+	c := NewComid()
+	require.NoError(t, c.FromCBOR(testComid1))
+
+	// inject an unknown entry into the first reference measurement's mval
+	mval := &c.Triples.ReferenceValues.Values[0].Measurements.Values[0].Val
+	mval.SetUnknownFields(&encoding.UnknownFields{
+		CBOR: []encoding.UnknownCBORField{{Index: 99, Key: 99, Value: cbor.RawMessage{0xf5}}},
+	})
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	// lax decoding ignores the entry
+	require.NoError(t, NewComid().FromCBOR(data))
+
+	err = NewComid().FromCBOR(data, encoding.DecodeOptions{Strict: true})
+	assert.EqualError(t, err,
+		"unknown field: triples.reference-values[0].measurements[0].value key 99")
+	assert.ErrorIs(t, err, encoding.ErrUnknownField)
+
+	// the unmodified CoMID decodes fine in strict mode
+	require.NoError(t, NewComid().FromCBOR(testComid1, encoding.DecodeOptions{Strict: true}))
+}
+
+func Test_Comid_strict_decoding_JSON(t *testing.T) {
+	c := NewComid()
+	require.NoError(t, c.FromCBOR(testComid1))
+
+	base, err := c.ToJSON()
+	require.NoError(t, err)
+
+	require.NoError(t, NewComid().FromJSON(base, encoding.DecodeOptions{Strict: true}))
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(base, &doc))
+
+	doc["x-vendor"] = "acme"
+
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	err = NewComid().FromJSON(data, encoding.DecodeOptions{Strict: true})
+	assert.EqualError(t, err, `unknown field: key "x-vendor"`)
+
+	delete(doc, "x-vendor")
+	rv := doc["triples"].(map[string]any)["reference-values"].([]any)[0].(map[string]any)
+	rv["measurements"].([]any)[0].(map[string]any)["x-calibration"] = 1
+
+	data, err = json.Marshal(doc)
+	require.NoError(t, err)
+
+	err = NewComid().FromJSON(data, encoding.DecodeOptions{Strict: true})
+	assert.EqualError(t, err,
+		`unknown field: triples.reference-values[0].measurements[0] key "x-calibration"`)
+}
+
+func Test_Comid_decode_limits(t *testing.T) {
+	c := NewComid()
+	require.NoError(t, c.FromCBOR(testComid1))
+
+	measurements := &c.Triples.ReferenceValues.Values[0].Measurements
+	measurements.Add(MustNewUintMeasurement(uint64(1)).SetSVN(1))
+	measurements.Add(MustNewUintMeasurement(uint64(2)).SetSVN(2))
+
+	numMeasurements := len(measurements.Values)
+
+	cborData, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	limits := encoding.DefaultDecodeLimits
+	limits.MaxMeasurements = numMeasurements
+
+	require.NoError(t, NewComid().FromCBOR(cborData, encoding.DecodeOptions{Limits: &limits}))
+
+	limits.MaxMeasurements = numMeasurements - 1
+
+	err = NewComid().FromCBOR(cborData, encoding.DecodeOptions{Limits: &limits})
+	assert.ErrorIs(t, err, encoding.ErrLimitExceeded)
+
+	var le *encoding.LimitError
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxMeasurements", le.Limit)
+	assert.Equal(t, numMeasurements, le.Value)
+
+	jsonData, err := c.ToJSON()
+	require.NoError(t, err)
+
+	err = NewComid().FromJSON(jsonData, encoding.DecodeOptions{Limits: &limits})
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxMeasurements", le.Limit)
+
+	limits = encoding.DecodeLimits{MaxInputSize: len(testComid1) - 1}
+
+	err = NewComid().FromCBOR(testComid1, encoding.DecodeOptions{Limits: &limits})
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxInputSize", le.Limit)
+
+	limits = encoding.DecodeLimits{MaxNestingDepth: 4}
+
+	err = NewComid().FromCBOR(testComid1, encoding.DecodeOptions{Limits: &limits})
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxNestingDepth", le.Limit)
+}
+
+func Test_Comid_decode_limits_nesting(t *testing.T) {
+	var top map[int]cbor.RawMessage
+	require.NoError(t, dm.Unmarshal(testComid1, &top))
+
+	// an unknown entry nested deeper than the defaults allow
+	const depth = 40
+
+	nested := make(cbor.RawMessage, depth+1)
+	for i := 0; i < depth; i++ {
+		nested[i] = 0x81 // array(1)
+	}
+
+	top[99] = nested
+
+	data, err := em.Marshal(top)
+	require.NoError(t, err)
+
+	err = NewComid().FromCBOR(data)
+
+	var le *encoding.LimitError
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxNestingDepth", le.Limit)
+	assert.Equal(t, encoding.DefaultDecodeLimits.MaxNestingDepth, le.Max)
+
+	// values decoded directly are bounded by the same limits
+	assert.ErrorContains(t, dm.Unmarshal(data, NewComid()), "exceeded max nested level 32")
+
+	// raising the limit beyond the defaults of the CBOR library
+	limits := encoding.DefaultDecodeLimits
+	limits.MaxNestingDepth = 2 * depth
+
+	c := NewComid()
+	err = c.FromCBOR(data, encoding.DecodeOptions{
+		Limits:                &limits,
+		PreserveUnknownFields: true,
+	})
+	require.NoError(t, err)
+
+	out, err := c.ToCBOR()
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+}
+
+func Test_Comid_deterministic_decoding(t *testing.T) {
+	require.NoError(t, NewComid().FromCBOR(testComid1, encoding.DecodeOptions{Deterministic: true}))
+
+	c := NewComid()
+	require.NoError(t, c.FromCBOR(testComid1))
+
+	// an unknown entry emitted ahead of the known ones breaks key ordering
+	mval := &c.Triples.ReferenceValues.Values[0].Measurements.Values[0].Val
+	mval.SetUnknownFields(&encoding.UnknownFields{
+		CBOR: []encoding.UnknownCBORField{{Index: 0, Key: 99, Value: cbor.RawMessage{0xf5}}},
+	})
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	require.NoError(t, NewComid().FromCBOR(data))
+
+	err = NewComid().FromCBOR(data, encoding.DecodeOptions{Deterministic: true})
+	assert.EqualError(t, err,
+		"non-canonical encoding at triples.reference-values[0].measurements[0].value: map key 0 out of order")
+	assert.ErrorIs(t, err, encoding.ErrNonCanonical)
+
+	canonical, err := Canonicalize(data)
+	require.NoError(t, err)
+	assert.Len(t, canonical, len(data))
+
+	// the unknown entry survives canonicalization
+	out := NewComid()
+	require.NoError(t, out.FromCBOR(canonical, encoding.DecodeOptions{
+		Deterministic:         true,
+		PreserveUnknownFields: true,
+	}))
+
+	unknown := out.Triples.ReferenceValues.Values[0].Measurements.Values[0].Val.GetUnknownFields()
+	require.NotNil(t, unknown)
+	require.Len(t, unknown.CBOR, 1)
+	assert.Equal(t, 99, unknown.CBOR[0].Key)
+	assert.Equal(t, cbor.RawMessage{0xf5}, unknown.CBOR[0].Value)
+}
+
+func Test_Comid_testcases_EDN(t *testing.T) {
+	sources, err := filepath.Glob("testcases/src/*.diag")
+	require.NoError(t, err)
+	require.NotEmpty(t, sources)
+
+	for _, src := range sources {
+		name := strings.TrimSuffix(filepath.Base(src), ".diag")
+
+		t.Run(name, func(t *testing.T) {
+			edn, err := os.ReadFile(src)
+			require.NoError(t, err)
+
+			expected, err := os.ReadFile(filepath.Join("testcases", name+".cbor"))
+			require.NoError(t, err)
+
+			actual, err := encoding.ParseEDN(string(edn))
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func Test_Comid_ToEDN(t *testing.T) {
+	c := NewComid()
+	require.NoError(t, c.FromCBOR(testComid1))
+
+	edn, err := c.ToEDN()
+	require.NoError(t, err)
+
+	assert.Contains(t, edn, `/ comid.tag-identity / 1 : {
+    / comid.tag-id / 0 : h'3f06af63a93c11e4979700505690773f'
+  }`)
+	assert.Contains(t, edn, `/ comid.version-scheme / 1 : 16384`)
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	out, err := encoding.ParseEDN(edn)
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+}
@@ -60,7 +60,7 @@ type CryptoKey struct {
 // specified crypto key type. For PKIX types, k must be a string. For COSE_Key,
 // k must be a []byte. For thumbprint types, k must be a swid.HashEntry.
 func NewCryptoKey(k any, typ string) (*CryptoKey, error) {
-	factory, ok := cryptoKeyValueRegister[typ]
+	factory, ok := cryptoKeyFactory(nil, typ)
 	if !ok {
 		return nil, fmt.Errorf("unexpected CryptoKey type: %s", typ)
 	}
@@ -121,6 +121,12 @@ func (o CryptoKey) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON populates the CryptoKey from the JSON representation inside
 // the provided []byte.
 func (o *CryptoKey) UnmarshalJSON(b []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), b)
+}
+
+// UnmarshalJSONWith is like UnmarshalJSON, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *CryptoKey) UnmarshalJSONWith(dec *encoding.Decoder, b []byte) error {
 	var value encoding.TypeAndValue
 
 	if err := json.Unmarshal(b, &value); err != nil {
@@ -131,7 +137,7 @@ func (o *CryptoKey) UnmarshalJSON(b []byte) error {
 		return errors.New("key type not set")
 	}
 
-	factory, ok := cryptoKeyValueRegister[value.Type]
+	factory, ok := cryptoKeyFactory(typeRegistryOf(dec), value.Type)
 	if !ok {
 		return fmt.Errorf("unexpected ICryptoKeyValue type: %q", value.Type)
 	}
@@ -154,13 +160,35 @@ func (o *CryptoKey) UnmarshalJSON(b []byte) error {
 // MarshalCBOR returns a []byte containing the CBOR representation of the
 // CryptoKey.
 func (o CryptoKey) MarshalCBOR() ([]byte, error) {
-	return em.Marshal(o.Value)
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith is like MarshalCBOR, except that the CBOR tags of the
+// TypeRegistry in scope of enc (if any) are available
+func (o CryptoKey) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	mode, err := encModeOf(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return mode.Marshal(o.Value)
 }
 
 // UnmarshalCBOR populates the CryptoKey from the CBOR representation inside
 // the provided []byte.
 func (o *CryptoKey) UnmarshalCBOR(b []byte) error {
-	return dm.Unmarshal(b, &o.Value)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), b)
+}
+
+// UnmarshalCBORWith is like UnmarshalCBOR, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *CryptoKey) UnmarshalCBORWith(dec *encoding.Decoder, b []byte) error {
+	mode, err := decModeOf(dec)
+	if err != nil {
+		return err
+	}
+
+	return mode.Unmarshal(b, &o.Value)
 }
 
 // ICryptoKeyValue is the interface implemented by the concrete CryptoKey value
@@ -11,13 +11,14 @@ import (
 
 	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
 )
 
 // Entity stores an entity-map capable of CBOR and JSON serializations.
 type Entity struct {
-	Name  *EntityName `cbor:"0,keyasint" json:"name"`
-	RegID *TaggedURI  `cbor:"1,keyasint,omitempty" json:"regid,omitempty"`
-	Roles Roles       `cbor:"2,keyasint" json:"roles"`
+	Name  *EntityName `cbor:"0,keyasint" json:"name" cddl:"comid.entity-name"`
+	RegID *TaggedURI  `cbor:"1,keyasint,omitempty" json:"regid,omitempty" cddl:"comid.reg-id"`
+	Roles Roles       `cbor:"2,keyasint" json:"roles" cddl:"comid.role"`
 
 	Extensions
 }
@@ -44,26 +45,40 @@ func (o *Entity) GetExtensions() extensions.IMapValue {
 // SetName is used to set the Name field of Entity using supplied name
 func (o *Entity) SetName(name string) *Entity {
 	if o != nil {
-		if name == "" {
+		if o.setName(name) != nil {
 			return nil
 		}
-		o.Name = MustNewStringEntityName(name)
 	}
 	return o
 }
 
+func (o *Entity) setName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty entity-name")
+	}
+	o.Name = MustNewStringEntityName(name)
+	return nil
+}
+
 // SetRegID is used to set the RegID field of Entity using supplied uri
 func (o *Entity) SetRegID(uri string) *Entity {
 	if o != nil {
-		if uri == "" {
+		if o.setRegID(uri) != nil {
 			return nil
 		}
-		taggedURI := TaggedURI(uri)
-		o.RegID = &taggedURI
 	}
 	return o
 }
 
+func (o *Entity) setRegID(uri string) error {
+	if uri == "" {
+		return fmt.Errorf("empty reg-id")
+	}
+	taggedURI := TaggedURI(uri)
+	o.RegID = &taggedURI
+	return nil
+}
+
 // SetRoles appends the supplied roles to the target entity.
 func (o *Entity) SetRoles(roles ...Role) *Entity {
 	if o != nil {
@@ -74,38 +89,37 @@ func (o *Entity) SetRoles(roles ...Role) *Entity {
 
 // Valid checks for validity of the fields within each Entity
 func (o Entity) Valid() error {
-	if o.Name == nil {
-		return fmt.Errorf("invalid entity: empty entity-name")
-	}
-
-	if err := o.Name.Valid(); err != nil {
-		return fmt.Errorf("invalid entity: %w", err)
-	}
-
-	if o.RegID != nil && o.RegID.Empty() {
-		return fmt.Errorf("invalid entity: empty reg-id")
-	}
-
-	if err := o.Roles.Valid(); err != nil {
-		return fmt.Errorf("invalid entity: %w", err)
-	}
-
-	return o.Extensions.validEntity(&o)
+	return validation.FirstError(o.validate)
 }
 
 // UnmarshalCBOR deserializes from CBOR
 func (o *Entity) UnmarshalCBOR(data []byte) error {
-	return encoding.PopulateStructFromCBOR(dm, data, o)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *Entity) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
 }
 
 // MarshalCBOR serializes to CBOR
 func (o Entity) MarshalCBOR() ([]byte, error) {
-	return encoding.SerializeStructToCBOR(em, o)
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+func (o Entity) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
 }
 
 // UnmarshalJSON deserializes from JSON
 func (o *Entity) UnmarshalJSON(data []byte) error {
-	return encoding.PopulateStructFromJSON(data, o)
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *Entity) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
 }
 
 // MarshalJSON serializes to JSON
@@ -133,7 +147,13 @@ type EntityName struct {
 // NewEntityName creates a new EntityName of the specified type using the
 // provided value.
 func NewEntityName(val any, typ string) (*EntityName, error) {
-	factory, ok := entityNameValueRegister[typ]
+	return newEntityName(nil, val, typ)
+}
+
+// newEntityName is like NewEntityName, except that the type choices of the
+// supplied registry (if any) are available in addition to the base ones
+func newEntityName(r *TypeRegistry, val any, typ string) (*EntityName, error) {
+	factory, ok := entityNameFactory(r, typ)
 	if !ok {
 		return nil, fmt.Errorf("unexpected entity name type: %s", typ)
 	}
@@ -165,14 +185,31 @@ func (o EntityName) Valid() error {
 }
 
 func (o EntityName) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith is like MarshalCBOR, except that the CBOR tags of the
+// TypeRegistry in scope of enc (if any) are available
+func (o EntityName) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
 	if err := o.Valid(); err != nil {
 		return nil, err
 	}
 
-	return em.Marshal(o.Value)
+	mode, err := encModeOf(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return mode.Marshal(o.Value)
 }
 
 func (o *EntityName) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith is like UnmarshalCBOR, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *EntityName) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
 	if len(data) == 0 {
 		return errors.New("empty")
 	}
@@ -191,7 +228,12 @@ func (o *EntityName) UnmarshalCBOR(data []byte) error {
 		return nil
 	}
 
-	return dm.Unmarshal(data, &o.Value)
+	mode, err := decModeOf(dec)
+	if err != nil {
+		return err
+	}
+
+	return mode.Unmarshal(data, &o.Value)
 }
 
 func (o EntityName) MarshalJSON() ([]byte, error) {
@@ -207,6 +249,12 @@ func (o EntityName) MarshalJSON() ([]byte, error) {
 }
 
 func (o *EntityName) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith is like UnmarshalJSON, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *EntityName) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
 	var text string
 	if err := json.Unmarshal(data, &text); err == nil {
 		*o = *MustNewStringEntityName(text)
@@ -219,7 +267,7 @@ func (o *EntityName) UnmarshalJSON(data []byte) error {
 		return fmt.Errorf("entity name decoding failure: %w", err)
 	}
 
-	decoded, err := NewEntityName(nil, tnv.Type)
+	decoded, err := newEntityName(typeRegistryOf(dec), nil, tnv.Type)
 	if err != nil {
 		return err
 	}
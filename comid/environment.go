@@ -6,43 +6,125 @@ package comid
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
 )
 
 // Environment stores the identifying information about a target or attesting
 // environment at the class, instance and group scope.  The Environment type
 // has JSON and CBOR serializations.
 type Environment struct {
-	Class    *Class    `cbor:"0,keyasint,omitempty" json:"class,omitempty"`
-	Instance *Instance `cbor:"1,keyasint,omitempty" json:"instance,omitempty"`
-	Group    *Group    `cbor:"2,keyasint,omitempty" json:"group,omitempty"`
+	Class    *Class    `cbor:"0,keyasint,omitempty" json:"class,omitempty" cddl:"comid.class"`
+	Instance *Instance `cbor:"1,keyasint,omitempty" json:"instance,omitempty" cddl:"comid.instance"`
+	Group    *Group    `cbor:"2,keyasint,omitempty" json:"group,omitempty" cddl:"comid.group"`
+
+	Extensions
+
+	// classExts holds the extensions registered for the Class, so that they
+	// can be registered with it once it is set (see SetClass) or decoded
+	classExts extensions.Map
 }
 
-// Valid checks the validity (according to the spec) of the target Environment
-func (o Environment) Valid() error {
-	// non-empty<>
-	if o.Class == nil && o.Instance == nil && o.Group == nil {
-		return fmt.Errorf("environment must not be empty")
+// RegisterExtensions registers a struct as a collections of extensions. The
+// extensions for ExtClass are registered with the Class if it is set;
+// otherwise, they are registered with it when it is set using SetClass, or
+// when it is decoded.
+func (o *Environment) RegisterExtensions(exts extensions.Map) error {
+	for p, v := range exts {
+		switch p {
+		case ExtEnvironment:
+			o.Extensions.Register(v)
+		case ExtClass:
+			o.classExts = extensions.NewMap().Add(ExtClass, v)
+
+			if o.Class != nil {
+				o.Class.Extensions.Register(v)
+			}
+		default:
+			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
+		}
 	}
 
-	if o.Class != nil {
-		if err := o.Class.Valid(); err != nil {
-			return fmt.Errorf("class validation failed: %w", err)
-		}
+	return nil
+}
+
+// GetExtensions returns previously registered extension. The extensions
+// registered for ExtEnvironment take precedence; if there are none, those
+// registered for ExtClass are returned.
+func (o *Environment) GetExtensions() extensions.IMapValue {
+	if o.Extensions.IMapValue != nil {
+		return o.Extensions.IMapValue
 	}
 
-	if o.Instance != nil {
-		if err := o.Instance.Valid(); err != nil {
-			return fmt.Errorf("instance validation failed: %w", err)
-		}
+	if o.Class != nil && o.Class.GetExtensions() != nil {
+		return o.Class.GetExtensions()
 	}
 
-	if o.Group != nil {
-		if err := o.Group.Valid(); err != nil {
-			return fmt.Errorf("group validation failed: %w", err)
+	return o.classExts[ExtClass]
+}
+
+// SetClass sets the Class of the target Environment, registering with it the
+// extensions previously registered for ExtClass, unless it has extensions of
+// its own
+func (o *Environment) SetClass(class *Class) *Environment {
+	if o != nil {
+		if class != nil && extensions.RegisterIfUnset(class, o.classExts) != nil {
+			return nil
 		}
+
+		o.Class = class
 	}
+	return o
+}
 
-	return nil
+// Valid checks the validity (according to the spec) of the target Environment
+// nolint:gocritic
+func (o Environment) Valid() error {
+	return validation.FirstError(o.validate)
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *Environment) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *Environment) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	dec = extensions.WithElementExtensions(dec.WithDecMode(dm), ExtClass, o.classExts)
+
+	return encoding.PopulateStructFromCBOR(dec, data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o Environment) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o Environment) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *Environment) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *Environment) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	dec = extensions.WithElementExtensions(dec, ExtClass, o.classExts)
+
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o Environment) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
 }
 
 // ToCBOR serializes the target Environment to CBOR (if the Environment is "valid")
@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/extensions"
 )
 
 func TestEnvironment_Valid_empty(t *testing.T) {
@@ -338,3 +339,42 @@ func TestEnviroment_JSON(t *testing.T) {
 	err = outEnv.FromJSON([]byte(`{"class": 7}`))
 	assert.EqualError(t, err, "json: cannot unmarshal number into Go struct field Environment.class of type comid.Class")
 }
+
+func TestEnvironment_RegisterExtensions_class_lazily(t *testing.T) {
+	env := Environment{Instance: MustNewUEIDInstance(TestUEID)}
+	require.NoError(t, env.RegisterExtensions(
+		extensions.NewMap().Add(ExtClass, &testClassExtensions{})))
+
+	// no Class is created to hold the extensions
+	assert.Nil(t, env.Class)
+	assert.NoError(t, env.Valid())
+	assert.IsType(t, &testClassExtensions{}, env.GetExtensions())
+
+	require.NotNil(t, env.SetClass(NewClassUUID(TestUUID)))
+	assert.IsType(t, &testClassExtensions{}, env.Class.GetExtensions())
+	assert.EqualError(t, env.Valid(), "class validation failed: missing revision")
+
+	revision := uint64(3)
+	require.NoError(t, env.Class.Set("revision", &revision))
+	require.NoError(t, env.Valid())
+
+	data, err := env.MarshalCBOR()
+	require.NoError(t, err)
+
+	out := Environment{}
+	require.NoError(t, out.RegisterExtensions(
+		extensions.NewMap().Add(ExtClass, &testClassExtensions{})))
+	require.NoError(t, out.UnmarshalCBOR(data))
+	assert.Equal(t, uint64(3), out.Class.MustGetUint64("revision"))
+}
+
+func TestEnvironment_GetExtensions_precedence(t *testing.T) {
+	envExts := &testEnvironmentExtensions{}
+
+	env := Environment{Class: NewClassUUID(TestUUID)}
+	require.NoError(t, env.RegisterExtensions(extensions.NewMap().
+		Add(ExtClass, &testClassExtensions{}).
+		Add(ExtEnvironment, envExts)))
+
+	assert.Equal(t, envExts, env.GetExtensions())
+}
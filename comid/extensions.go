@@ -16,6 +16,11 @@ const (
 	ExtEndorsedValueFlags  extensions.Point = "EndorsedValueFlags"
 	ExtMval                extensions.Point = "Mval"
 	ExtFlags               extensions.Point = "Flags"
+	ExtEnvironment         extensions.Point = "Environment"
+	ExtClass               extensions.Point = "Class"
+	ExtKeyTriple           extensions.Point = "KeyTriple"
+	ExtLinkedTag           extensions.Point = "LinkedTag"
+	ExtTagIdentity         extensions.Point = "TagIdentity"
 )
 
 type IComidConstrainer interface {
@@ -38,6 +43,26 @@ type IFlagsMapConstrainer interface {
 	ConstrainFlagsMap(*FlagsMap) error
 }
 
+type IEnvironmentConstrainer interface {
+	ConstrainEnvironment(*Environment) error
+}
+
+type IClassConstrainer interface {
+	ConstrainClass(*Class) error
+}
+
+type IKeyTripleConstrainer interface {
+	ConstrainKeyTriple(*KeyTriple) error
+}
+
+type ILinkedTagConstrainer interface {
+	ConstrainLinkedTag(*LinkedTag) error
+}
+
+type ITagIdentityConstrainer interface {
+	ConstrainTagIdentity(*TagIdentity) error
+}
+
 type IFlagSetter interface {
 	AnySet() bool
 	SetTrue(Flag)
@@ -55,10 +80,12 @@ func (o *Extensions) validComid(comid *Comid) error {
 		return nil
 	}
 
-	ev, ok := o.IMapValue.(IComidConstrainer)
-	if ok {
-		if err := ev.ConstrainComid(comid); err != nil {
-			return err
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IComidConstrainer)
+		if ok {
+			if err := ev.ConstrainComid(comid); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -70,10 +97,12 @@ func (o *Extensions) validTriples(triples *Triples) error {
 		return nil
 	}
 
-	ev, ok := o.IMapValue.(ITriplesConstrainer)
-	if ok {
-		if err := ev.ValidTriples(triples); err != nil {
-			return err
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(ITriplesConstrainer)
+		if ok {
+			if err := ev.ValidTriples(triples); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -85,10 +114,12 @@ func (o *Extensions) validMval(triples *Mval) error {
 		return nil
 	}
 
-	ev, ok := o.IMapValue.(IMvalConstrainer)
-	if ok {
-		if err := ev.ConstrainMval(triples); err != nil {
-			return err
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IMvalConstrainer)
+		if ok {
+			if err := ev.ConstrainMval(triples); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -100,10 +131,12 @@ func (o *Extensions) validEntity(triples *Entity) error {
 		return nil
 	}
 
-	ev, ok := o.IMapValue.(IEntityConstrainer)
-	if ok {
-		if err := ev.ConstrainEntity(triples); err != nil {
-			return err
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IEntityConstrainer)
+		if ok {
+			if err := ev.ConstrainEntity(triples); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -115,10 +148,97 @@ func (o *Extensions) validFlagsMap(triples *FlagsMap) error {
 		return nil
 	}
 
-	ev, ok := o.IMapValue.(IFlagsMapConstrainer)
-	if ok {
-		if err := ev.ConstrainFlagsMap(triples); err != nil {
-			return err
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IFlagsMapConstrainer)
+		if ok {
+			if err := ev.ConstrainFlagsMap(triples); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *Extensions) validEnvironment(v *Environment) error {
+	if !o.HaveExtensions() {
+		return nil
+	}
+
+	for _, ext := range o.ExtensionValues() {
+		ev, ok := ext.(IEnvironmentConstrainer)
+		if ok {
+			if err := ev.ConstrainEnvironment(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *Extensions) validClass(v *Class) error {
+	if !o.HaveExtensions() {
+		return nil
+	}
+
+	for _, ext := range o.ExtensionValues() {
+		ev, ok := ext.(IClassConstrainer)
+		if ok {
+			if err := ev.ConstrainClass(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *Extensions) validKeyTriple(v *KeyTriple) error {
+	if !o.HaveExtensions() {
+		return nil
+	}
+
+	for _, ext := range o.ExtensionValues() {
+		ev, ok := ext.(IKeyTripleConstrainer)
+		if ok {
+			if err := ev.ConstrainKeyTriple(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *Extensions) validLinkedTag(v *LinkedTag) error {
+	if !o.HaveExtensions() {
+		return nil
+	}
+
+	for _, ext := range o.ExtensionValues() {
+		ev, ok := ext.(ILinkedTagConstrainer)
+		if ok {
+			if err := ev.ConstrainLinkedTag(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *Extensions) validTagIdentity(v *TagIdentity) error {
+	if !o.HaveExtensions() {
+		return nil
+	}
+
+	for _, ext := range o.ExtensionValues() {
+		ev, ok := ext.(ITagIdentityConstrainer)
+		if ok {
+			if err := ev.ConstrainTagIdentity(v); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -130,9 +250,11 @@ func (o *Extensions) setTrue(flag Flag) {
 		return
 	}
 
-	ev, ok := o.IMapValue.(IFlagSetter)
-	if ok {
-		ev.SetTrue(flag)
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IFlagSetter)
+		if ok {
+			ev.SetTrue(flag)
+		}
 	}
 }
 
@@ -141,9 +263,11 @@ func (o *Extensions) setFalse(flag Flag) {
 		return
 	}
 
-	ev, ok := o.IMapValue.(IFlagSetter)
-	if ok {
-		ev.SetFalse(flag)
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IFlagSetter)
+		if ok {
+			ev.SetFalse(flag)
+		}
 	}
 }
 
@@ -152,9 +276,11 @@ func (o *Extensions) clear(flag Flag) {
 		return
 	}
 
-	ev, ok := o.IMapValue.(IFlagSetter)
-	if ok {
-		ev.Clear(flag)
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IFlagSetter)
+		if ok {
+			ev.Clear(flag)
+		}
 	}
 }
 
@@ -163,9 +289,13 @@ func (o *Extensions) get(flag Flag) *bool {
 		return nil
 	}
 
-	ev, ok := o.IMapValue.(IFlagSetter)
-	if ok {
-		return ev.Get(flag)
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IFlagSetter)
+		if ok {
+			if ret := ev.Get(flag); ret != nil {
+				return ret
+			}
+		}
 	}
 
 	return nil
@@ -176,9 +306,11 @@ func (o *Extensions) anySet() bool {
 		return false
 	}
 
-	ev, ok := o.IMapValue.(IFlagSetter)
-	if ok {
-		return ev.AnySet()
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IFlagSetter)
+		if ok && ev.AnySet() {
+			return true
+		}
 	}
 
 	return false
@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/extensions"
 )
 
 var FlagTestFlag = Flag(-1)
@@ -35,6 +37,26 @@ func (o *TestExtension) ConstrainEntity(_ *Entity) error {
 	return errors.New("invalid")
 }
 
+func (o *TestExtension) ConstrainEnvironment(_ *Environment) error {
+	return errors.New("invalid")
+}
+
+func (o *TestExtension) ConstrainClass(_ *Class) error {
+	return errors.New("invalid")
+}
+
+func (o *TestExtension) ConstrainKeyTriple(_ *KeyTriple) error {
+	return errors.New("invalid")
+}
+
+func (o *TestExtension) ConstrainLinkedTag(_ *LinkedTag) error {
+	return errors.New("invalid")
+}
+
+func (o *TestExtension) ConstrainTagIdentity(_ *TagIdentity) error {
+	return errors.New("invalid")
+}
+
 func (o *TestExtension) SetTrue(flag Flag) {
 	if flag == FlagTestFlag {
 		o.TestFlag = &True
@@ -83,6 +105,21 @@ func Test_Extensions(t *testing.T) {
 	err = exts.validFlagsMap(nil)
 	assert.EqualError(t, err, "invalid")
 
+	err = exts.validEnvironment(nil)
+	assert.EqualError(t, err, "invalid")
+
+	err = exts.validClass(nil)
+	assert.EqualError(t, err, "invalid")
+
+	err = exts.validKeyTriple(nil)
+	assert.EqualError(t, err, "invalid")
+
+	err = exts.validLinkedTag(nil)
+	assert.EqualError(t, err, "invalid")
+
+	err = exts.validTagIdentity(nil)
+	assert.EqualError(t, err, "invalid")
+
 	assert.False(t, exts.anySet())
 
 	exts.setTrue(FlagTestFlag)
@@ -94,3 +131,265 @@ func Test_Extensions(t *testing.T) {
 	assert.Nil(t, exts.get(FlagTestFlag))
 	assert.False(t, exts.anySet())
 }
+
+type baseMvalExtensions struct {
+	Timestamp *int `cbor:"-1,keyasint,omitempty" json:"timestamp,omitempty"`
+}
+
+func (o *baseMvalExtensions) ConstrainMval(_ *Mval) error {
+	if o.Timestamp == nil {
+		return errors.New("missing timestamp")
+	}
+	return nil
+}
+
+type vendorMvalExtensions struct {
+	Vendor *string `cbor:"-2,keyasint,omitempty" json:"vendor,omitempty"`
+}
+
+func (o *vendorMvalExtensions) ConstrainMval(_ *Mval) error {
+	if o.Vendor == nil {
+		return errors.New("missing vendor")
+	}
+	return nil
+}
+
+func Test_Extensions_composite_Mval(t *testing.T) {
+	extMap := extensions.NewMap().Add(ExtMval, &baseMvalExtensions{})
+	require.NoError(t, extMap.Layer(ExtMval, &vendorMvalExtensions{}))
+
+	mval := Mval{}
+	require.NoError(t, mval.RegisterExtensions(extMap))
+	mval.SVN = MustNewSVN(uint64(1), ExactValueType)
+
+	// both constrainers are invoked
+	assert.EqualError(t, mval.Valid(), "missing timestamp")
+	ts := 1720782190
+	require.NoError(t, mval.Extensions.Set("timestamp", &ts))
+	assert.EqualError(t, mval.Valid(), "missing vendor")
+	vendorName := "ACME"
+	require.NoError(t, mval.Extensions.Set("vendor", &vendorName))
+	require.NoError(t, mval.Valid())
+
+	data, err := mval.MarshalCBOR()
+	require.NoError(t, err)
+
+	out := Mval{}
+	require.NoError(t, out.RegisterExtensions(extMap))
+	require.NoError(t, out.UnmarshalCBOR(data))
+
+	vendor, ok := extensions.Find[*vendorMvalExtensions](out.GetExtensions())
+	require.True(t, ok)
+	assert.Equal(t, "ACME", *vendor.Vendor)
+
+	base, ok := extensions.Find[*baseMvalExtensions](out.GetExtensions())
+	require.True(t, ok)
+	assert.Equal(t, 1720782190, *base.Timestamp)
+
+	data, err = mval.MarshalJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"timestamp":1720782190`)
+	assert.Contains(t, string(data), `"vendor":"ACME"`)
+
+	out = Mval{}
+	require.NoError(t, out.RegisterExtensions(extMap))
+	require.NoError(t, out.UnmarshalJSON(data))
+	assert.Equal(t, "ACME", out.Extensions.MustGetString("vendor"))
+	assert.Equal(t, 1720782190, out.Extensions.MustGetInt("timestamp"))
+}
+
+type testEnvironmentExtensions struct {
+	Location *string `cbor:"-1,keyasint,omitempty" json:"location,omitempty"`
+}
+
+type testClassExtensions struct {
+	Revision *uint64 `cbor:"-1,keyasint,omitempty" json:"revision,omitempty"`
+}
+
+func (o *testClassExtensions) ConstrainClass(_ *Class) error {
+	if o.Revision == nil {
+		return errors.New("missing revision")
+	}
+	return nil
+}
+
+type testKeyTripleExtensions struct {
+	Purpose *string `cbor:"-1,keyasint,omitempty" json:"purpose,omitempty"`
+}
+
+type testLinkedTagExtensions struct {
+	Reason *string `cbor:"-1,keyasint,omitempty" json:"reason,omitempty"`
+}
+
+type testTagIdentityExtensions struct {
+	Build *uint64 `cbor:"-1,keyasint,omitempty" json:"build,omitempty"`
+}
+
+func (o *testTagIdentityExtensions) ConstrainTagIdentity(_ *TagIdentity) error {
+	if o.Build == nil {
+		return errors.New("missing build")
+	}
+	return nil
+}
+
+func newTestMapPointsExtensions() extensions.Map {
+	return extensions.NewMap().
+		Add(ExtEnvironment, &testEnvironmentExtensions{}).
+		Add(ExtClass, &testClassExtensions{}).
+		Add(ExtKeyTriple, &testKeyTripleExtensions{}).
+		Add(ExtLinkedTag, &testLinkedTagExtensions{}).
+		Add(ExtTagIdentity, &testTagIdentityExtensions{})
+}
+
+func Test_Extensions_map_points(t *testing.T) {
+	c := NewComid()
+	require.NoError(t, c.RegisterExtensions(newTestMapPointsExtensions()))
+
+	c.SetTagIdentity("ext-points", 0).
+		AddLinkedTag("other-tag", RelSupplements).
+		AddReferenceValue(ValueTriple{
+			Environment: Environment{
+				Class: NewClassUUID(TestUUID),
+			},
+			Measurements: *NewMeasurements().Add(
+				MustNewUintMeasurement(uint64(1)).SetSVN(2),
+			),
+		}).
+		AddAttestVerifKey(KeyTriple{
+			Environment: Environment{
+				Instance: MustNewUEIDInstance(TestUEID),
+			},
+			VerifKeys: *NewCryptoKeys().Add(MustNewPKIXBase64Key(TestECPubKey)),
+		})
+	require.NotNil(t, c)
+
+	assert.EqualError(t, c.Valid(), "tag-identity validation failed: missing build")
+	build := uint64(7)
+	require.NoError(t, c.TagIdentity.Set("build", &build))
+
+	refVal := &c.Triples.ReferenceValues.Values[0]
+	assert.EqualError(t, c.Valid(),
+		"triples validation failed: reference values: error at index 0: "+
+			"environment validation failed: class validation failed: missing revision")
+	revision, location := uint64(3), "rack 4"
+	require.NoError(t, refVal.Environment.Class.Set("revision", &revision))
+	require.NoError(t, refVal.Environment.Set("location", &location))
+
+	keyTriple := &(*c.Triples.AttestVerifKeys)[0]
+	purpose := "attestation"
+	require.NoError(t, keyTriple.Conditions.Set("purpose", &purpose))
+	// the class extensions are registered lazily, so no Class is created
+	// for an Environment without class information
+	assert.Nil(t, keyTriple.Environment.Class)
+
+	reason := "patch"
+	require.NoError(t, (*c.LinkedTags)[0].Set("reason", &reason))
+
+	require.NoError(t, c.Valid())
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	out := NewComid()
+	require.NoError(t, out.RegisterExtensions(newTestMapPointsExtensions()))
+	require.NoError(t, out.FromCBOR(data))
+	require.NoError(t, out.Valid())
+
+	assert.Equal(t, uint64(7), out.TagIdentity.MustGetUint64("build"))
+	assert.Equal(t, "patch", (*out.LinkedTags)[0].MustGetString("reason"))
+
+	outEnv := out.Triples.ReferenceValues.Values[0].Environment
+	assert.Equal(t, "rack 4", outEnv.MustGetString("location"))
+	assert.Equal(t, uint64(3), outEnv.Class.MustGetUint64("revision"))
+
+	outKeyTriple := (*out.Triples.AttestVerifKeys)[0]
+	assert.Equal(t, "attestation", outKeyTriple.Conditions.MustGetString("purpose"))
+	assert.Nil(t, outKeyTriple.Environment.Class)
+
+	jsonData, err := c.ToJSON()
+	require.NoError(t, err)
+
+	out = NewComid()
+	require.NoError(t, out.RegisterExtensions(newTestMapPointsExtensions()))
+	require.NoError(t, out.FromJSON(jsonData))
+
+	assert.Equal(t, uint64(7), out.TagIdentity.MustGetUint64("build"))
+	assert.Equal(t, "patch", (*out.LinkedTags)[0].MustGetString("reason"))
+	assert.Equal(t, "attestation",
+		(*out.Triples.AttestVerifKeys)[0].Conditions.MustGetString("purpose"))
+	assert.Equal(t, uint64(3),
+		out.Triples.ReferenceValues.Values[0].Environment.Class.MustGetUint64("revision"))
+
+	// without registered extensions, extension fields are ignored
+	out = NewComid()
+	require.NoError(t, out.FromCBOR(data))
+	assert.NoError(t, out.Valid())
+	assert.Nil(t, out.TagIdentity.GetExtensions())
+}
+
+func Test_KeyTriple_CBOR_without_extensions(t *testing.T) {
+	kt := KeyTriple{
+		Environment: Environment{Instance: MustNewUEIDInstance(TestUEID)},
+		VerifKeys:   *NewCryptoKeys().Add(MustNewPKIXBase64Key(TestECPubKey)),
+	}
+	require.NoError(t, kt.RegisterExtensions(
+		extensions.NewMap().Add(ExtKeyTriple, &testKeyTripleExtensions{})))
+
+	data, err := em.Marshal(kt)
+	require.NoError(t, err)
+	// no extension fields set: two-element array
+	assert.Equal(t, byte(0x82), data[0])
+
+	var out KeyTriple
+	require.NoError(t, dm.Unmarshal(data, &out))
+	require.Len(t, out.VerifKeys, 1)
+	assert.Equal(t, kt.VerifKeys[0].String(), out.VerifKeys[0].String())
+
+	err = dm.Unmarshal([]byte{0x81, 0xa0}, &out)
+	assert.EqualError(t, err, "expected 2 or 3 array elements, found 1")
+}
+
+type testTextKeyMvalExtensions struct {
+	Vendor   *string `cbor:"vendor,omitempty" json:"vendor,omitempty"`
+	Priority *int    `cbor:"-70000,keyasint,omitempty" json:"priority,omitempty"`
+}
+
+func Test_Extensions_Mval_text_and_negative_keys(t *testing.T) {
+	newMval := func() *Mval {
+		mval := &Mval{}
+		require.NoError(t, mval.RegisterExtensions(
+			extensions.NewMap().Add(ExtMval, &testTextKeyMvalExtensions{}),
+		))
+		return mval
+	}
+
+	mval := newMval()
+	svn := MustNewTaggedSVN(uint64(7))
+	mval.SVN = svn
+
+	vendor := "acme"
+	priority := 3
+	require.NoError(t, mval.Set("vendor", &vendor))
+	require.NoError(t, mval.Set("priority", &priority))
+
+	data, err := mval.MarshalCBOR()
+	require.NoError(t, err)
+
+	var native map[any]any
+	require.NoError(t, dm.Unmarshal(data, &native))
+	assert.Equal(t, "acme", native["vendor"])
+	assert.Equal(t, uint64(3), native[int64(-70000)])
+
+	out := newMval()
+	require.NoError(t, out.UnmarshalCBOR(data))
+	assert.Equal(t, "acme", out.MustGetString("vendor"))
+	assert.Equal(t, 3, out.MustGetInt("priority"))
+
+	jsonData, err := mval.MarshalJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(jsonData), `"vendor":"acme"`)
+
+	out = newMval()
+	require.NoError(t, out.UnmarshalJSON(jsonData))
+	assert.Equal(t, 3, out.MustGetInt("priority"))
+}
@@ -34,31 +34,31 @@ const (
 type FlagsMap struct {
 	// IsConfigured indicates whether the measured environment is fully
 	// configured for normal operation.
-	IsConfigured *bool `cbor:"0,keyasint,omitempty" json:"is-configured,omitempty"`
+	IsConfigured *bool `cbor:"0,keyasint,omitempty" json:"is-configured,omitempty" cddl:"comid.is-configured"`
 	// IsSecure indicates whether the measured environment's configurable
 	// security settings are fully enabled.
-	IsSecure *bool `cbor:"1,keyasint,omitempty" json:"is-secure,omitempty"`
+	IsSecure *bool `cbor:"1,keyasint,omitempty" json:"is-secure,omitempty" cddl:"comid.is-secure"`
 	// IsRecovery indicates whether the measured environment is in recovery
 	// mode.
-	IsRecovery *bool `cbor:"2,keyasint,omitempty" json:"is-recovery,omitempty"`
+	IsRecovery *bool `cbor:"2,keyasint,omitempty" json:"is-recovery,omitempty" cddl:"comid.is-recovery"`
 	// IsDebug indicates whether the measured environment is in a debug
 	// enabled mode.
-	IsDebug *bool `cbor:"3,keyasint,omitempty" json:"is-debug,omitempty"`
+	IsDebug *bool `cbor:"3,keyasint,omitempty" json:"is-debug,omitempty" cddl:"comid.is-debug"`
 	// IsReplayProtected indicates whether the measured environment is
 	// protected from replay by a previous image that differs from the
 	// current image.
-	IsReplayProtected *bool `cbor:"4,keyasint,omitempty" json:"is-replay-protected,omitempty"`
+	IsReplayProtected *bool `cbor:"4,keyasint,omitempty" json:"is-replay-protected,omitempty" cddl:"comid.is-replay-protected"`
 	// IsIntegrityProtected indicates whether the measured environment is
 	// protected from unauthorized update.
-	IsIntegrityProtected *bool `cbor:"5,keyasint,omitempty" json:"is-integrity-protected,omitempty"`
+	IsIntegrityProtected *bool `cbor:"5,keyasint,omitempty" json:"is-integrity-protected,omitempty" cddl:"comid.is-integrity-protected"`
 	// IsRuntimeMeasured indicates whether the measured environment is
 	// measured after being loaded into memory.
-	IsRuntimeMeasured *bool `cbor:"6,keyasint,omitempty" json:"is-runtime-meas,omitempty"`
+	IsRuntimeMeasured *bool `cbor:"6,keyasint,omitempty" json:"is-runtime-meas,omitempty" cddl:"comid.is-runtime-meas"`
 	// IsImmutable indicates whether the measured environment is immutable.
-	IsImmutable *bool `cbor:"7,keyasint,omitempty" json:"is-immutable,omitempty"`
+	IsImmutable *bool `cbor:"7,keyasint,omitempty" json:"is-immutable,omitempty" cddl:"comid.is-immutable"`
 	// IsTcb indicates whether the measured environment is a trusted
 	// computing base.
-	IsTcb *bool `cbor:"8,keyasint,omitempty" json:"is-tcb,omitempty"`
+	IsTcb *bool `cbor:"8,keyasint,omitempty" json:"is-tcb,omitempty" cddl:"comid.is-tcb"`
 
 	Extensions
 }
@@ -200,18 +200,34 @@ func (o *FlagsMap) GetExtensions() extensions.IMapValue {
 
 // UnmarshalCBOR deserializes from CBOR
 func (o *FlagsMap) UnmarshalCBOR(data []byte) error {
-	return encoding.PopulateStructFromCBOR(dm, data, o)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *FlagsMap) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
 }
 
 // MarshalCBOR serializes to CBOR
 // nolint:gocritic
 func (o FlagsMap) MarshalCBOR() ([]byte, error) {
-	return encoding.SerializeStructToCBOR(em, o)
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o FlagsMap) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
 }
 
 // UnmarshalJSON deserializes from JSON
 func (o *FlagsMap) UnmarshalJSON(data []byte) error {
-	return encoding.PopulateStructFromJSON(data, o)
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *FlagsMap) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
 }
 
 // MarshalJSON serializes to JSON
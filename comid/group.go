@@ -19,7 +19,13 @@ type Group struct {
 
 // NewGroup instantiates an empty group
 func NewGroup(val any, typ string) (*Group, error) {
-	factory, ok := groupValueRegister[typ]
+	return newGroup(nil, val, typ)
+}
+
+// newGroup is like NewGroup, except that the type choices of the supplied
+// registry (if any) are available in addition to the base ones
+func newGroup(r *TypeRegistry, val any, typ string) (*Group, error) {
+	factory, ok := groupFactory(r, typ)
 	if !ok {
 		return nil, fmt.Errorf("unknown group type: %s", typ)
 	}
@@ -61,12 +67,34 @@ func (o Group) Bytes() []byte {
 
 // MarshalCBOR serializes the target group to CBOR
 func (o Group) MarshalCBOR() ([]byte, error) {
-	return em.Marshal(o.Value)
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith is like MarshalCBOR, except that the CBOR tags of the
+// TypeRegistry in scope of enc (if any) are available
+func (o Group) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	mode, err := encModeOf(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return mode.Marshal(o.Value)
 }
 
 // UnmarshalCBOR deserializes the supplied CBOR into the target group
 func (o *Group) UnmarshalCBOR(data []byte) error {
-	return dm.Unmarshal(data, &o.Value)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith is like UnmarshalCBOR, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *Group) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	mode, err := decModeOf(dec)
+	if err != nil {
+		return err
+	}
+
+	return mode.Unmarshal(data, &o.Value)
 }
 
 // UnmarshalJSON deserializes the supplied JSON type/value object into the Group
@@ -85,15 +113,22 @@ func (o *Group) UnmarshalCBOR(data []byte) error {
 //	  "value": "MTIzNDU2Nzg5"
 //	}
 
-//nolint:dupl
 func (o *Group) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith is like UnmarshalJSON, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+//
+//nolint:dupl
+func (o *Group) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
 	var tnv encoding.TypeAndValue
 
 	if err := json.Unmarshal(data, &tnv); err != nil {
 		return fmt.Errorf("group decoding failure: %w", err)
 	}
 
-	decoded, err := NewGroup(nil, tnv.Type)
+	decoded, err := newGroup(typeRegistryOf(dec), nil, tnv.Type)
 	if err != nil {
 		return err
 	}
@@ -20,7 +20,13 @@ type Instance struct {
 // NewInstance creates a new instance with the value of the specified type
 // populated using the provided value.
 func NewInstance(val any, typ string) (*Instance, error) {
-	factory, ok := instanceValueRegister[typ]
+	return newInstance(nil, val, typ)
+}
+
+// newInstance is like NewInstance, except that the type choices of the supplied
+// registry (if any) are available in addition to the base ones
+func newInstance(r *TypeRegistry, val any, typ string) (*Instance, error) {
+	factory, ok := instanceFactory(r, typ)
 	if !ok {
 		return nil, fmt.Errorf("unknown instance type: %s", typ)
 	}
@@ -59,11 +65,33 @@ func (o Instance) Bytes() []byte {
 
 // MarshalCBOR serializes the target instance to CBOR
 func (o Instance) MarshalCBOR() ([]byte, error) {
-	return em.Marshal(o.Value)
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith is like MarshalCBOR, except that the CBOR tags of the
+// TypeRegistry in scope of enc (if any) are available
+func (o Instance) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	mode, err := encModeOf(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return mode.Marshal(o.Value)
 }
 
 func (o *Instance) UnmarshalCBOR(data []byte) error {
-	return dm.Unmarshal(data, &o.Value)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith is like UnmarshalCBOR, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *Instance) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	mode, err := decModeOf(dec)
+	if err != nil {
+		return err
+	}
+
+	return mode.Unmarshal(data, &o.Value)
 }
 
 // UnmarshalJSON deserializes the supplied JSON object into the target Instance
@@ -83,15 +111,22 @@ func (o *Instance) UnmarshalCBOR(data []byte) error {
 //	uuid: standard UUID string representation, e.g. "550e8400-e29b-41d4-a716-446655440000"
 //	bytes: a variable-length opaque byte string, example {0x07, 0x12, 0x34}
 
-//nolint:dupl
 func (o *Instance) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith is like UnmarshalJSON, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+//
+//nolint:dupl
+func (o *Instance) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
 	var tnv encoding.TypeAndValue
 
 	if err := json.Unmarshal(data, &tnv); err != nil {
 		return fmt.Errorf("instance decoding failure: %w", err)
 	}
 
-	decoded, err := NewInstance(nil, tnv.Type)
+	decoded, err := newInstance(typeRegistryOf(dec), nil, tnv.Type)
 	if err != nil {
 		return err
 	}
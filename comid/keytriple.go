@@ -3,29 +3,285 @@
 
 package comid
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+
+	cbor "github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
+)
 
 // KeyTriple stores a cryptographic key triple record (identity-triple-record
 // or attest-key-triple-record) with CBOR and JSON serializations.  Note that
 // the CBOR serialization packs the structure into an array.  Instead, when
 // serializing to JSON, the structure is converted into an object.
+//
+// The optional conditions map is carried as the third element of the array.
 type KeyTriple struct {
-	_           struct{}    `cbor:",toarray"`
-	Environment Environment `json:"environment"`
-	VerifKeys   CryptoKeys  `json:"verification-keys"`
+	_           struct{}             `cbor:",toarray"`
+	Environment Environment          `json:"environment"`
+	VerifKeys   CryptoKeys           `json:"verification-keys"`
+	Conditions  *KeyTripleConditions `json:"conditions,omitempty"`
 }
 
-func (o KeyTriple) Valid() error {
-	if err := o.Environment.Valid(); err != nil {
-		return fmt.Errorf("environment validation failed: %w", err)
+// KeyTripleConditions stores the conditions under which the keys of a
+// KeyTriple apply. This map is also where the KeyTriple extensions (see
+// ExtKeyTriple) are carried, and so extension fields must not use the keys
+// defined below.
+type KeyTripleConditions struct {
+	Mkey         *Mkey       `cbor:"0,keyasint,omitempty" json:"mkey,omitempty"`
+	AuthorizedBy *CryptoKeys `cbor:"1,keyasint,omitempty" json:"authorized-by,omitempty"`
+
+	Extensions
+}
+
+// RegisterExtensions registers a struct as a collections of extensions
+func (o *KeyTripleConditions) RegisterExtensions(exts extensions.Map) error {
+	for p, v := range exts {
+		switch p {
+		case ExtKeyTriple:
+			o.Extensions.Register(v)
+		default:
+			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
+		}
 	}
 
-	if err := o.VerifKeys.Valid(); err != nil {
-		return fmt.Errorf("verification keys validation failed: %w", err)
+	return nil
+}
+
+// GetExtensions returns previously registered extension
+func (o *KeyTripleConditions) GetExtensions() extensions.IMapValue {
+	return o.Extensions.IMapValue
+}
+
+// IsEmpty returns true if none of the conditions (including the extension
+// fields) are set
+func (o *KeyTripleConditions) IsEmpty() bool {
+	return o.Mkey == nil && o.AuthorizedBy == nil && o.Extensions.IsEmpty()
+}
+
+// Valid returns an error if the KeyTripleConditions is invalid.
+// nolint:gocritic
+func (o KeyTripleConditions) Valid() error {
+	if o.IsEmpty() {
+		return errors.New("conditions must not be empty")
 	}
+
+	if o.Mkey != nil {
+		if err := o.Mkey.Valid(); err != nil {
+			return fmt.Errorf("mkey: %w", err)
+		}
+	}
+
+	if o.AuthorizedBy != nil {
+		if err := o.AuthorizedBy.Valid(); err != nil {
+			return fmt.Errorf("authorized-by: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// UnmarshalCBOR deserializes from CBOR
+func (o *KeyTripleConditions) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *KeyTripleConditions) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o KeyTripleConditions) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o KeyTripleConditions) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *KeyTripleConditions) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *KeyTripleConditions) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o KeyTripleConditions) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
+}
+
+// RegisterExtensions registers a struct as a collections of extensions
+func (o *KeyTriple) RegisterExtensions(exts extensions.Map) error {
+	envExts := extensions.NewMap()
+
+	for p, v := range exts {
+		switch p {
+		case ExtKeyTriple:
+			if o.Conditions == nil {
+				o.Conditions = &KeyTripleConditions{}
+			}
+
+			o.Conditions.Extensions.Register(v)
+		case ExtEnvironment, ExtClass:
+			envExts.Add(p, v)
+		default:
+			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
+		}
+	}
+
+	return o.Environment.RegisterExtensions(envExts)
+}
+
+// GetExtensions returns previously registered extension. The extensions
+// registered for ExtKeyTriple take precedence; if there are none, those of
+// the Environment are returned (see Environment.GetExtensions).
+func (o *KeyTriple) GetExtensions() extensions.IMapValue {
+	if o.Conditions != nil && o.Conditions.IMapValue != nil {
+		return o.Conditions.IMapValue
+	}
+
+	return o.Environment.GetExtensions()
+}
+
+// conditions returns the Conditions of the target KeyTriple, or nil if they
+// are empty (e.g. if extensions have been registered, but none of their
+// fields have been set)
+// nolint:gocritic
+func (o KeyTriple) conditions() *KeyTripleConditions {
+	if o.Conditions == nil || o.Conditions.IsEmpty() {
+		return nil
+	}
+
+	return o.Conditions
+}
+
+// nolint:gocritic
+func (o KeyTriple) Valid() error {
+	return validation.FirstError(o.validate)
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *KeyTriple) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *KeyTriple) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtKeyTriple, o); err != nil {
+		return err
+	}
+
+	var (
+		elts    []cbor.RawMessage
+		unknown encoding.UnknownFieldCollector
+	)
+
+	dec = dec.WithDecMode(dm)
+
+	if err := dec.Unmarshal(data, &elts); err != nil {
+		return err
+	}
+
+	if len(elts) != 2 && len(elts) != 3 {
+		return fmt.Errorf("expected 2 or 3 array elements, found %d", len(elts))
+	}
+
+	if err := dec.Unmarshal(elts[0], &o.Environment); err != nil {
+		if !unknown.Collect(err, "environment") {
+			return fmt.Errorf("environment: %w", err)
+		}
+	}
+
+	if err := dec.Unmarshal(elts[1], &o.VerifKeys); err != nil {
+		if !unknown.Collect(err, "verification-keys") {
+			return fmt.Errorf("verification keys: %w", err)
+		}
+	}
+
+	if len(elts) == 3 {
+		if o.Conditions == nil {
+			o.Conditions = &KeyTripleConditions{}
+		}
+
+		if err := dec.Unmarshal(elts[2], o.Conditions); err != nil {
+			if !unknown.Collect(err, "conditions") {
+				return fmt.Errorf("conditions: %w", err)
+			}
+		}
+	}
+
+	return unknown.Err()
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o KeyTriple) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o KeyTriple) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	enc = enc.WithEncMode(em)
+
+	env, err := enc.Marshal(o.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("environment: %w", err)
+	}
+
+	keys, err := enc.Marshal(o.VerifKeys)
+	if err != nil {
+		return nil, fmt.Errorf("verification keys: %w", err)
+	}
+
+	elts := []cbor.RawMessage{env, keys}
+
+	if c := o.conditions(); c != nil {
+		data, err := enc.Marshal(c)
+		if err != nil {
+			return nil, fmt.Errorf("conditions: %w", err)
+		}
+
+		elts = append(elts, data)
+	}
+
+	return enc.Marshal(elts)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *KeyTriple) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *KeyTriple) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtKeyTriple, o); err != nil {
+		return err
+	}
+
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o KeyTriple) MarshalJSON() ([]byte, error) {
+	o.Conditions = o.conditions()
+
+	return encoding.SerializeStructToJSON(o)
+}
+
 type KeyTriples []KeyTriple
 
 func NewKeyTriples() *KeyTriples {
@@ -4,9 +4,12 @@
 package comid
 
 import (
+	"encoding/json"
 	"testing"
 
+	cbor "github.com/fxamacker/cbor/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVerificationKeys_Valid_empty(t *testing.T) {
@@ -39,3 +42,53 @@ func TestVerificationKeys_Valid_empty(t *testing.T) {
 		assert.EqualError(t, err, tv.testerr)
 	}
 }
+
+func TestKeyTriple_conditions(t *testing.T) {
+	kt := KeyTriple{
+		Environment: Environment{Instance: MustNewUEIDInstance(TestUEID)},
+		VerifKeys:   CryptoKeys{MustNewPKIXBase64Key(TestECPubKey)},
+	}
+
+	data, err := kt.MarshalCBOR()
+	require.NoError(t, err)
+
+	var elems []cbor.RawMessage
+	require.NoError(t, cbor.Unmarshal(data, &elems))
+	assert.Len(t, elems, 2)
+
+	kt.Conditions = &KeyTripleConditions{
+		Mkey:         MustNewMkey(uint64(7), UintType),
+		AuthorizedBy: &CryptoKeys{MustNewPKIXBase64Key(TestECPubKey)},
+	}
+	require.NoError(t, kt.Valid())
+
+	data, err = kt.MarshalCBOR()
+	require.NoError(t, err)
+
+	require.NoError(t, cbor.Unmarshal(data, &elems))
+	require.Len(t, elems, 3)
+
+	var conds map[int]cbor.RawMessage
+	require.NoError(t, cbor.Unmarshal(elems[2], &conds))
+	assert.Contains(t, conds, 0)
+	assert.Contains(t, conds, 1)
+
+	var actual KeyTriple
+	require.NoError(t, actual.UnmarshalCBOR(data))
+	require.NotNil(t, actual.Conditions)
+	assert.Equal(t, kt.Conditions.Mkey, actual.Conditions.Mkey)
+	require.NotNil(t, actual.Conditions.AuthorizedBy)
+	assert.Equal(t, TestECPubKey, (*actual.Conditions.AuthorizedBy)[0].String())
+
+	jsonData, err := json.Marshal(kt)
+	require.NoError(t, err)
+
+	var fromJSON KeyTriple
+	require.NoError(t, json.Unmarshal(jsonData, &fromJSON))
+	require.NotNil(t, fromJSON.Conditions)
+	assert.Equal(t, kt.Conditions.Mkey, fromJSON.Conditions.Mkey)
+
+	kt.Conditions = &KeyTripleConditions{AuthorizedBy: &CryptoKeys{}}
+	assert.EqualError(t, kt.Valid(),
+		"conditions validation failed: authorized-by: no keys to validate")
+}
@@ -6,6 +6,9 @@ package comid
 import (
 	"fmt"
 
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
 	"github.com/veraison/swid"
 )
 
@@ -14,8 +17,10 @@ import (
 // be viewed as a statement of the form: "$link_context $link_relation_type
 // $link_target".
 type LinkedTag struct {
-	LinkedTagID swid.TagID `cbor:"0,keyasint" json:"target"`
-	Rel         Rel        `cbor:"1,keyasint" json:"rel"`
+	LinkedTagID swid.TagID `cbor:"0,keyasint" json:"target" cddl:"comid.linked-tag-id"`
+	Rel         Rel        `cbor:"1,keyasint" json:"rel" cddl:"comid.tag-rel"`
+
+	Extensions
 }
 
 func NewLinkedTag() *LinkedTag {
@@ -25,6 +30,25 @@ func NewLinkedTag() *LinkedTag {
 	}
 }
 
+// RegisterExtensions registers a struct as a collections of extensions
+func (o *LinkedTag) RegisterExtensions(exts extensions.Map) error {
+	for p, v := range exts {
+		switch p {
+		case ExtLinkedTag:
+			o.Extensions.Register(v)
+		default:
+			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
+		}
+	}
+
+	return nil
+}
+
+// GetExtensions returns previously registered extension
+func (o *LinkedTag) GetExtensions() extensions.IMapValue {
+	return o.Extensions.IMapValue
+}
+
 func (o *LinkedTag) SetLinkedTag(t swid.TagID) *LinkedTag {
 	if o != nil {
 		o.LinkedTagID = t
@@ -39,16 +63,55 @@ func (o *LinkedTag) SetRel(r Rel) *LinkedTag {
 	return o
 }
 
+// nolint:gocritic
 func (o LinkedTag) Valid() error {
-	if o.LinkedTagID == (swid.TagID{}) {
-		return fmt.Errorf("tag-id must be set in linked-tag")
+	return validation.FirstError(o.validate)
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *LinkedTag) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *LinkedTag) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtLinkedTag, o); err != nil {
+		return err
 	}
 
-	if err := o.Rel.Valid(); err != nil {
-		return fmt.Errorf("rel validation failed: %w", err)
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o LinkedTag) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o LinkedTag) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *LinkedTag) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *LinkedTag) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtLinkedTag, o); err != nil {
+		return err
 	}
 
-	return nil
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o LinkedTag) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
 }
 
 // LinkedTags is an array of LinkedTag
@@ -67,11 +130,5 @@ func (o *LinkedTags) AddLinkedTag(lt LinkedTag) *LinkedTags {
 }
 
 func (o LinkedTags) Valid() error {
-	for i, l := range o {
-		if err := l.Valid(); err != nil {
-			return fmt.Errorf("invalid linked-tag entry at index %d: %w", i, err)
-		}
-	}
-
-	return nil
+	return validation.FirstError(o.validate)
 }
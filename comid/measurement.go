@@ -12,6 +12,7 @@ import (
 
 	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
 	"github.com/veraison/eat"
 	"github.com/veraison/swid"
 )
@@ -27,7 +28,13 @@ type Mkey struct {
 
 // NewMkey creates a new Mkey of the specfied type using the provided value.
 func NewMkey(val any, typ string) (*Mkey, error) {
-	factory, ok := mkeyValueRegister[typ]
+	return newMkey(nil, val, typ)
+}
+
+// newMkey is like NewMkey, except that the type choices of the supplied
+// registry (if any) are available in addition to the base ones
+func newMkey(r *TypeRegistry, val any, typ string) (*Mkey, error) {
+	factory, ok := mkeyFactory(r, typ)
 	if !ok {
 		return nil, fmt.Errorf("unexpected measurement key type: %q", typ)
 	}
@@ -127,13 +134,19 @@ func (o Mkey) GetKeyUint() (uint64, error) {
 //	uuid: standard UUID string representation, e.g. "550e8400-e29b-41d4-a716-446655440000"
 //	psa.refval-id: JSON representation of the PSA refval-id
 func (o *Mkey) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith is like UnmarshalJSON, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *Mkey) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
 	var tnv encoding.TypeAndValue
 
 	if err := json.Unmarshal(data, &tnv); err != nil {
 		return err
 	}
 
-	decoded, err := NewMkey(nil, tnv.Type)
+	decoded, err := newMkey(typeRegistryOf(dec), nil, tnv.Type)
 	if err != nil {
 		return err
 	}
@@ -168,24 +181,46 @@ func (o Mkey) MarshalJSON() ([]byte, error) {
 
 // MarshalCBOR serializes the taret mkey into  CBOR-encoded bytes.
 func (o Mkey) MarshalCBOR() ([]byte, error) {
-	return em.Marshal(o.Value)
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith is like MarshalCBOR, except that the CBOR tags of the
+// TypeRegistry in scope of enc (if any) are available
+func (o Mkey) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	mode, err := encModeOf(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return mode.Marshal(o.Value)
 }
 
 // UnmarshalCBOR deserializes the Mkey from the provided CBOR bytes.
 func (o *Mkey) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith is like UnmarshalCBOR, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *Mkey) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
 	if len(data) == 0 {
 		return errors.New("empty input")
 	}
 
+	mode, err := decModeOf(dec)
+	if err != nil {
+		return err
+	}
+
 	majorType := (data[0] & 0xe0) >> 5
 	if majorType == 6 { // tag
-		return dm.Unmarshal(data, &o.Value)
+		return mode.Unmarshal(data, &o.Value)
 	}
 
 	// untagged value must be a uint
 
 	var val UintMkey
-	if err := dm.Unmarshal(data, &val); err != nil {
+	if err := mode.Unmarshal(data, &val); err != nil {
 		return err
 	}
 
@@ -343,18 +378,18 @@ func RegisterMkeyType(tag uint64, factory IMkeyFactory) error {
 
 // Mval stores a measurement-values-map with JSON and CBOR serializations.
 type Mval struct {
-	Ver                *Version            `cbor:"0,keyasint,omitempty" json:"version,omitempty"`
-	SVN                *SVN                `cbor:"1,keyasint,omitempty" json:"svn,omitempty"`
-	Digests            *Digests            `cbor:"2,keyasint,omitempty" json:"digests,omitempty"`
-	Flags              *FlagsMap           `cbor:"3,keyasint,omitempty" json:"flags,omitempty"`
-	RawValue           *RawValue           `cbor:"4,keyasint,omitempty" json:"raw-value,omitempty"`
-	RawValueMask       *[]byte             `cbor:"5,keyasint,omitempty" json:"raw-value-mask,omitempty"`
-	MACAddr            *MACaddr            `cbor:"6,keyasint,omitempty" json:"mac-addr,omitempty"`
-	IPAddr             *net.IP             `cbor:"7,keyasint,omitempty" json:"ip-addr,omitempty"`
-	SerialNumber       *string             `cbor:"8,keyasint,omitempty" json:"serial-number,omitempty"`
-	UEID               *eat.UEID           `cbor:"9,keyasint,omitempty" json:"ueid,omitempty"`
-	UUID               *UUID               `cbor:"10,keyasint,omitempty" json:"uuid,omitempty"`
-	IntegrityRegisters *IntegrityRegisters `cbor:"14,keyasint,omitempty" json:"integrity-registers,omitempty"`
+	Ver                *Version            `cbor:"0,keyasint,omitempty" json:"version,omitempty" cddl:"comid.ver"`
+	SVN                *SVN                `cbor:"1,keyasint,omitempty" json:"svn,omitempty" cddl:"comid.svn"`
+	Digests            *Digests            `cbor:"2,keyasint,omitempty" json:"digests,omitempty" cddl:"comid.digests"`
+	Flags              *FlagsMap           `cbor:"3,keyasint,omitempty" json:"flags,omitempty" cddl:"comid.flags"`
+	RawValue           *RawValue           `cbor:"4,keyasint,omitempty" json:"raw-value,omitempty" cddl:"comid.raw-value"`
+	RawValueMask       *[]byte             `cbor:"5,keyasint,omitempty" json:"raw-value-mask,omitempty" cddl:"comid.raw-value-mask"`
+	MACAddr            *MACaddr            `cbor:"6,keyasint,omitempty" json:"mac-addr,omitempty" cddl:"comid.mac-addr"`
+	IPAddr             *net.IP             `cbor:"7,keyasint,omitempty" json:"ip-addr,omitempty" cddl:"comid.ip-addr"`
+	SerialNumber       *string             `cbor:"8,keyasint,omitempty" json:"serial-number,omitempty" cddl:"comid.serial-number"`
+	UEID               *eat.UEID           `cbor:"9,keyasint,omitempty" json:"ueid,omitempty" cddl:"comid.ueid"`
+	UUID               *UUID               `cbor:"10,keyasint,omitempty" json:"uuid,omitempty" cddl:"comid.uuid"`
+	IntegrityRegisters *IntegrityRegisters `cbor:"14,keyasint,omitempty" json:"integrity-registers,omitempty" cddl:"comid.integrity-registers"`
 	Extensions
 }
 
@@ -385,12 +420,23 @@ func (o *Mval) GetExtensions() extensions.IMapValue {
 
 // UnmarshalCBOR deserializes from CBOR
 func (o *Mval) UnmarshalCBOR(data []byte) error {
-	return encoding.PopulateStructFromCBOR(dm, data, o)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *Mval) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
 }
 
 // MarshalCBOR serializes to CBOR
 // nolint:gocritic
 func (o Mval) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o Mval) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
 	// If extensions have been registered, the collection will exist, but
 	// might be empty. If that is the case, set it to nil to avoid
 	// marshaling an empty list (and let the marshaller omit the claim
@@ -401,12 +447,17 @@ func (o Mval) MarshalCBOR() ([]byte, error) {
 		o.Flags = nil
 	}
 
-	return encoding.SerializeStructToCBOR(em, o)
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
 }
 
 // UnmarshalJSON deserializes from JSON
 func (o *Mval) UnmarshalJSON(data []byte) error {
-	return encoding.PopulateStructFromJSON(data, o)
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *Mval) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
 }
 
 // MarshalJSON serializes to JSON
@@ -428,8 +479,12 @@ func (o Mval) MarshalJSON() ([]byte, error) {
 // Valid returns an error if none of the measurement values are set and the Extensions are empty.
 // nolint:gocritic
 func (o Mval) Valid() error {
-	// Check if no measurement values are set
-	if o.Ver == nil &&
+	return validation.FirstError(o.validate)
+}
+
+// nolint:gocritic
+func (o Mval) isEmpty() bool {
+	return o.Ver == nil &&
 		o.SVN == nil &&
 		o.Digests == nil &&
 		o.Flags == nil &&
@@ -441,58 +496,31 @@ func (o Mval) Valid() error {
 		o.UEID == nil &&
 		o.UUID == nil &&
 		o.IntegrityRegisters == nil &&
-		o.Extensions.IsEmpty() {
-		return fmt.Errorf("no measurement value set")
-	}
-
-	// Validate Version
-	if o.Ver != nil {
-		if err := o.Ver.Valid(); err != nil {
-			return err
-		}
-	}
+		o.Extensions.IsEmpty()
+}
 
-	// Validate Digests
-	if o.Digests != nil {
-		if err := o.Digests.Valid(); err != nil {
-			return err
-		}
+func validMACAddr(mac MACaddr) error {
+	// MAC address must be either 6 or 8 bytes
+	if macLen := len(mac); macLen != 6 && macLen != 8 {
+		return fmt.Errorf("invalid MAC address length: expected 6 or 8 bytes, got %d", macLen)
 	}
 
-	// Validate Flags
-	if o.Flags != nil {
-		if err := o.Flags.Valid(); err != nil {
-			return err
-		}
-	}
+	return nil
+}
 
-	// Validate MAC Address
-	if o.MACAddr != nil {
-		macLen := len(*o.MACAddr)
-		if macLen != 6 && macLen != 8 { // MAC address must be either 6 or 8 bytes
-			return fmt.Errorf("invalid MAC address length: expected 6 or 8 bytes, got %d", macLen)
-		}
+func validIPAddr(ip net.IP) error {
+	// Must be valid IPv4 or IPv6 (i.e., .To4() != nil or .To16() != nil)
+	if ip.To4() == nil && ip.To16() == nil {
+		return fmt.Errorf("invalid IP address: %s", ip.String())
 	}
 
-	// Validate IP Address
-	if o.IPAddr != nil {
-		ip := *o.IPAddr
-		// Must be valid IPv4 or IPv6 (i.e., .To4() != nil or .To16() != nil)
-		if ip.To4() == nil && ip.To16() == nil {
-			return fmt.Errorf("invalid IP address: %s", ip.String())
-		}
-	}
-
-	// raw value and raw-value-mask have no specific semantics here
-
-	// Validate extensions (custom logic implemented in validMval())
-	return o.Extensions.validMval(&o)
+	return nil
 }
 
 // Version stores a version-map with JSON and CBOR serializations.
 type Version struct {
-	Version string             `cbor:"0,keyasint" json:"value"`
-	Scheme  swid.VersionScheme `cbor:"1,keyasint" json:"scheme"`
+	Version string             `cbor:"0,keyasint" json:"value" cddl:"comid.version"`
+	Scheme  swid.VersionScheme `cbor:"1,keyasint" json:"scheme" cddl:"comid.version-scheme"`
 }
 
 func NewVersion() *Version {
@@ -524,13 +552,13 @@ func (o Version) Valid() error {
 
 // Measurement stores a measurement-map with CBOR and JSON serializations.
 type Measurement struct {
-	Key          *Mkey      `cbor:"0,keyasint,omitempty" json:"key,omitempty"`
-	Val          Mval       `cbor:"1,keyasint" json:"value"`
-	AuthorizedBy *CryptoKey `cbor:"2,keyasint,omitempty" json:"authorized-by,omitempty"`
+	Key          *Mkey      `cbor:"0,keyasint,omitempty" json:"key,omitempty" cddl:"comid.mkey"`
+	Val          Mval       `cbor:"1,keyasint" json:"value" cddl:"comid.mval"`
+	AuthorizedBy *CryptoKey `cbor:"2,keyasint,omitempty" json:"authorized-by,omitempty" cddl:"comid.authorized-by"`
 }
 
 func NewMeasurement(val any, typ string) (*Measurement, error) {
-	keyFactory, ok := mkeyValueRegister[typ]
+	keyFactory, ok := mkeyFactory(nil, typ)
 	if !ok {
 		return nil, fmt.Errorf("unknown Mkey type: %s", typ)
 	}
@@ -630,6 +658,44 @@ func NewOIDMeasurement(key any) (*Measurement, error) {
 	return NewMeasurement(key, OIDType)
 }
 
+// UnmarshalCBOR deserializes from CBOR
+func (o *Measurement) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *Measurement) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o Measurement) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o Measurement) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *Measurement) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *Measurement) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o Measurement) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
+}
+
 func (o *Measurement) RegisterExtensions(exts extensions.Map) error {
 	return o.Val.RegisterExtensions(exts)
 }
@@ -641,16 +707,23 @@ func (o Measurement) GetExtensions() extensions.IMapValue {
 
 func (o *Measurement) SetVersion(ver string, scheme int64) *Measurement {
 	if o != nil {
-		v := NewVersion().SetVersion(ver).SetScheme(scheme)
-		if v == nil {
+		if o.setVersion(ver, scheme) != nil {
 			return nil
 		}
-
-		o.Val.Ver = v
 	}
 	return o
 }
 
+func (o *Measurement) setVersion(ver string, scheme int64) error {
+	v := NewVersion().SetVersion(ver)
+	if err := v.Scheme.SetCode(scheme); err != nil {
+		return fmt.Errorf("invalid version scheme: %w", err)
+	}
+
+	o.Val.Ver = v
+	return nil
+}
+
 // SetRawValueBytes sets the supplied raw-value and its mask in the
 // measurement-values-map of the target measurement
 func (o *Measurement) SetRawValueBytes(rawValue, rawValueMask []byte) *Measurement {
@@ -682,19 +755,30 @@ func (o *Measurement) SetMinSVN(svn uint64) *Measurement {
 // the target measurement
 func (o *Measurement) AddDigest(algID uint64, digest []byte) *Measurement {
 	if o != nil {
-		ds := o.Val.Digests
-		if ds == nil {
-			ds = NewDigests()
-		}
-		if ds.AddDigest(algID, digest) == nil {
+		if o.addDigest(algID, digest) != nil {
 			return nil
 		}
-		o.Val.Digests = ds
 	}
 
 	return o
 }
 
+func (o *Measurement) addDigest(algID uint64, digest []byte) error {
+	var he swid.HashEntry
+	if err := he.Set(algID, digest); err != nil {
+		return fmt.Errorf("invalid digest: %w", err)
+	}
+
+	ds := o.Val.Digests
+	if ds == nil {
+		ds = NewDigests()
+	}
+	*ds = append(*ds, he)
+	o.Val.Digests = ds
+
+	return nil
+}
+
 // SetFlagsTrue sets the supplied operational flags to true in the
 // measurement-values-map of the target measurement
 func (o *Measurement) SetFlagsTrue(flags ...Flag) *Measurement {
@@ -770,35 +854,43 @@ func (o *Measurement) SetSerialNumber(sn string) *Measurement {
 // of the target measurement
 func (o *Measurement) SetUEID(ueid eat.UEID) *Measurement {
 	if o != nil {
-		if ueid.Validate() != nil {
+		if o.setUEID(ueid) != nil {
 			return nil
 		}
-		o.Val.UEID = &ueid
 	}
 	return o
 }
 
+func (o *Measurement) setUEID(ueid eat.UEID) error {
+	if err := ueid.Validate(); err != nil {
+		return fmt.Errorf("invalid UEID: %w", err)
+	}
+	o.Val.UEID = &ueid
+	return nil
+}
+
 // SetUUID sets the supplied uuid in the measurement-values-map
 // of the target measurement
 func (o *Measurement) SetUUID(u UUID) *Measurement {
 	if o != nil {
-		if u.Valid() != nil {
+		if o.setUUID(u) != nil {
 			return nil
 		}
-		o.Val.UUID = &u
 	}
 	return o
 }
 
-// nolint:gocritic
-func (o Measurement) Valid() error {
-	if o.Key != nil && o.Key.IsSet() {
-		if err := o.Key.Valid(); err != nil {
-			return err
-		}
+func (o *Measurement) setUUID(u UUID) error {
+	if err := u.Valid(); err != nil {
+		return fmt.Errorf("invalid UUID: %w", err)
 	}
+	o.Val.UUID = &u
+	return nil
+}
 
-	return o.Val.Valid()
+// nolint:gocritic
+func (o Measurement) Valid() error {
+	return validation.FirstError(o.validate)
 }
 
 // Measurements is a container for Measurement instances and their extensions.
@@ -834,13 +926,34 @@ func (o Measurements) MarshalCBOR() ([]byte, error) {
 	return (extensions.Collection[Measurement, *Measurement])(o).MarshalCBOR()
 }
 
+func (o Measurements) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return (extensions.Collection[Measurement, *Measurement])(o).MarshalCBORWith(enc)
+}
+
 func (o *Measurements) UnmarshalCBOR(data []byte) error {
-	return (*extensions.Collection[Measurement, *Measurement])(o).UnmarshalCBOR(data)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+func (o *Measurements) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	if err := encoding.CheckCBORMeasurements(data, dec.Limits()); err != nil {
+		return err
+	}
+
+	return (*extensions.Collection[Measurement, *Measurement])(o).UnmarshalCBORWith(dec, data)
 }
+
 func (o Measurements) MarshalJSON() ([]byte, error) {
 	return (extensions.Collection[Measurement, *Measurement])(o).MarshalJSON()
 }
 
 func (o *Measurements) UnmarshalJSON(data []byte) error {
-	return (*extensions.Collection[Measurement, *Measurement])(o).UnmarshalJSON(data)
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+func (o *Measurements) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	if err := encoding.CheckJSONMeasurements(data, dec.Limits()); err != nil {
+		return err
+	}
+
+	return (*extensions.Collection[Measurement, *Measurement])(o).UnmarshalJSONWith(dec, data)
 }
@@ -0,0 +1,469 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	cbor "github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/encoding"
+)
+
+// TypeRegistry is a set of type choice registrations (class IDs, instances,
+// groups, measurement keys, crypto keys, entity names and SVNs), along with
+// the CBOR tags they are associated with.
+//
+// The package-level RegisterXXXType functions add to the base set of type
+// choices, which is shared by the whole process. By contrast, the
+// registrations in a TypeRegistry only take effect for the CoMIDs it is set on
+// (see Comid.SetTypeRegistry), or more generally while it is in scope of an
+// encoding.Decoder or encoding.Encoder (see ScopeDecoder and ScopeEncoder).
+// This allows profiles that assign different meanings to the same CBOR tag to
+// coexist. Type choices that are not found in the registry are looked up in
+// the base set.
+//
+// A TypeRegistry is safe for concurrent use: types may be registered with it
+// while it is in use. By contrast, the base set must only be added to before
+// any CoMID is encoded or decoded (typically, from an init function).
+type TypeRegistry struct {
+	// mu guards all of the fields below
+	mu sync.RWMutex
+
+	classID    map[string]IClassIDFactory
+	instance   map[string]IInstanceFactory
+	group      map[string]IGroupFactory
+	mkey       map[string]IMkeyFactory
+	cryptoKey  map[string]ICryptoKeyFactory
+	entityName map[string]IEntityNameFactory
+	svn        map[string]ISVNFactory
+
+	tags map[uint64]interface{}
+
+	// encoding modes combining the base tags with the registry's tags, and
+	// the generation of the base tags they have been built with. They are
+	// built on first use.
+	em         cbor.EncMode
+	dm         cbor.DecMode
+	generation uint64
+}
+
+// NewTypeRegistry instantiates an empty TypeRegistry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		classID:    make(map[string]IClassIDFactory),
+		instance:   make(map[string]IInstanceFactory),
+		group:      make(map[string]IGroupFactory),
+		mkey:       make(map[string]IMkeyFactory),
+		cryptoKey:  make(map[string]ICryptoKeyFactory),
+		entityName: make(map[string]IEntityNameFactory),
+		svn:        make(map[string]ISVNFactory),
+		tags:       make(map[uint64]interface{}),
+	}
+}
+
+// RegisterClassIDType is like the package-level RegisterClassIDType, except
+// that the new type is only available while the target registry is in scope.
+func (o *TypeRegistry) RegisterClassIDType(tag uint64, factory IClassIDFactory) error {
+	nilVal, err := factory(nil)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	typ := nilVal.Type()
+	if _, exists := lookupFactory(o.classID, classIDValueRegister, typ); exists {
+		return fmt.Errorf("class ID type with name %q already exists", typ)
+	}
+
+	if err := o.registerTag(tag, nilVal.Value); err != nil {
+		return err
+	}
+
+	o.classID[typ] = factory
+
+	return nil
+}
+
+// RegisterInstanceType is like the package-level RegisterInstanceType, except
+// that the new type is only available while the target registry is in scope.
+func (o *TypeRegistry) RegisterInstanceType(tag uint64, factory IInstanceFactory) error {
+	nilVal, err := factory(nil)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	typ := nilVal.Type()
+	if _, exists := lookupFactory(o.instance, instanceValueRegister, typ); exists {
+		return fmt.Errorf("instance type with name %q already exists", typ)
+	}
+
+	if err := o.registerTag(tag, nilVal.Value); err != nil {
+		return err
+	}
+
+	o.instance[typ] = factory
+
+	return nil
+}
+
+// RegisterGroupType is like the package-level RegisterGroupType, except that
+// the new type is only available while the target registry is in scope.
+func (o *TypeRegistry) RegisterGroupType(tag uint64, factory IGroupFactory) error {
+	nilVal, err := factory(nil)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	typ := nilVal.Value.Type()
+	if _, exists := lookupFactory(o.group, groupValueRegister, typ); exists {
+		return fmt.Errorf("Group type with name %q already exists", typ)
+	}
+
+	if err := o.registerTag(tag, nilVal.Value); err != nil {
+		return err
+	}
+
+	o.group[typ] = factory
+
+	return nil
+}
+
+// RegisterMkeyType is like the package-level RegisterMkeyType, except that
+// the new type is only available while the target registry is in scope.
+func (o *TypeRegistry) RegisterMkeyType(tag uint64, factory IMkeyFactory) error {
+	nilVal, err := factory(nil)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	typ := nilVal.Value.Type()
+	if _, exists := lookupFactory(o.mkey, mkeyValueRegister, typ); exists {
+		return fmt.Errorf("measurement key type with name %q already exists", typ)
+	}
+
+	if err := o.registerTag(tag, nilVal.Value); err != nil {
+		return err
+	}
+
+	o.mkey[typ] = factory
+
+	return nil
+}
+
+// RegisterCryptoKeyType is like the package-level RegisterCryptoKeyType,
+// except that the new type is only available while the target registry is in
+// scope.
+func (o *TypeRegistry) RegisterCryptoKeyType(tag uint64, factory ICryptoKeyFactory) error {
+	nilVal, err := factory(nil)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	typ := nilVal.Type()
+	if _, exists := lookupFactory(o.cryptoKey, cryptoKeyValueRegister, typ); exists {
+		return fmt.Errorf("crypto key type with name %q already exists", typ)
+	}
+
+	if err := o.registerTag(tag, nilVal.Value); err != nil {
+		return err
+	}
+
+	o.cryptoKey[typ] = factory
+
+	return nil
+}
+
+// RegisterEntityNameType is like the package-level RegisterEntityNameType,
+// except that the new type is only available while the target registry is in
+// scope.
+func (o *TypeRegistry) RegisterEntityNameType(tag uint64, factory IEntityNameFactory) error {
+	nilVal, err := factory(nil)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	typ := nilVal.Value.Type()
+	if _, exists := lookupFactory(o.entityName, entityNameValueRegister, typ); exists {
+		return fmt.Errorf("entity name type with name %q already exists", typ)
+	}
+
+	if err := o.registerTag(tag, nilVal.Value); err != nil {
+		return err
+	}
+
+	o.entityName[typ] = factory
+
+	return nil
+}
+
+// RegisterSVNType is like the package-level RegisterSVNType, except that the
+// new type is only available while the target registry is in scope.
+func (o *TypeRegistry) RegisterSVNType(tag uint64, factory ISVNFactory) error {
+	nilVal, err := factory(nil)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	typ := nilVal.Value.Type()
+	if _, exists := lookupFactory(o.svn, svnValueRegister, typ); exists {
+		return fmt.Errorf("SVN type with name %q already exists", typ)
+	}
+
+	if err := o.registerTag(tag, nilVal.Value); err != nil {
+		return err
+	}
+
+	o.svn[typ] = factory
+
+	return nil
+}
+
+// Tags returns the CBOR tags registered with the target registry (not
+// including the base tags), mapped onto the Go types they are decoded into
+func (o *TypeRegistry) Tags() map[uint64]reflect.Type {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	ret := make(map[uint64]reflect.Type, len(o.tags))
+
+	for tag, typ := range o.tags {
+		ret[tag] = reflect.TypeOf(typ)
+	}
+
+	return ret
+}
+
+// TagSet returns a cbor.TagSet containing both the base CBOR tags and the
+// ones registered with the target registry
+func (o *TypeRegistry) TagSet() (cbor.TagSet, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return o.tagSet()
+}
+
+// tagSet is TagSet, with the lock of the target registry held by the caller
+func (o *TypeRegistry) tagSet() (cbor.TagSet, error) {
+	opts := cbor.TagOptions{
+		EncTag: cbor.EncTagRequired,
+		DecTag: cbor.DecTagRequired,
+	}
+
+	tags := comidTags()
+
+	for tag, typ := range o.tags {
+		if _, exists := comidTagsMap[tag]; exists {
+			return nil, fmt.Errorf("tag %d is already registered", tag)
+		}
+
+		if err := tags.Add(opts, reflect.TypeOf(typ), tag); err != nil {
+			return nil, fmt.Errorf("tag %d: %w", tag, err)
+		}
+	}
+
+	return tags, nil
+}
+
+func (o *TypeRegistry) registerTag(tag uint64, t interface{}) error {
+	if _, exists := comidTagsMap[tag]; exists {
+		return fmt.Errorf("tag %d is already registered", tag)
+	}
+
+	if _, exists := o.tags[tag]; exists {
+		return fmt.Errorf("tag %d is already registered", tag)
+	}
+
+	o.tags[tag] = t
+
+	// force the encoding modes to be rebuilt
+	o.em, o.dm = nil, nil
+
+	return nil
+}
+
+func (o *TypeRegistry) modes() (cbor.EncMode, cbor.DecMode, error) {
+	generation := comidTagsGeneration.Load()
+
+	o.mu.RLock()
+	en, de := o.em, o.dm
+	current := o.generation == generation
+	o.mu.RUnlock()
+
+	if en != nil && de != nil && current {
+		return en, de, nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	tags, err := o.tagSet()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	en, err = newCBOREncMode(tags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	de, err = newCBORDecMode(tags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	o.em, o.dm, o.generation = en, de, generation
+
+	return en, de, nil
+}
+
+// typeRegistryKey is the key of the TypeRegistry in scope of an
+// encoding.Decoder or encoding.Encoder
+type typeRegistryKey struct{}
+
+// ScopeDecoder returns a copy of dec with the target registry in scope, so
+// that the CoMID values it decodes can use the registry's type choices and
+// CBOR tags in addition to the base ones. If the registry is nil, dec is
+// returned as is.
+func (o *TypeRegistry) ScopeDecoder(dec *encoding.Decoder) *encoding.Decoder {
+	if o == nil {
+		return dec
+	}
+
+	return dec.WithValue(typeRegistryKey{}, o)
+}
+
+// ScopeEncoder is the encoding counterpart of ScopeDecoder
+func (o *TypeRegistry) ScopeEncoder(enc *encoding.Encoder) *encoding.Encoder {
+	if o == nil {
+		return enc
+	}
+
+	return enc.WithValue(typeRegistryKey{}, o)
+}
+
+// typeRegistryOf returns the TypeRegistry in scope of the supplied Decoder or
+// Encoder, or nil if there is none
+func typeRegistryOf(v interface{ Value(key any) any }) *TypeRegistry {
+	r, _ := v.Value(typeRegistryKey{}).(*TypeRegistry)
+	return r
+}
+
+// decModeOf returns the decoding mode to be used for the CoMID type choices
+// decoded by dec, i.e. that of the TypeRegistry in scope, or the base one,
+// applying the DecodeLimits of dec
+func decModeOf(dec *encoding.Decoder) (cbor.DecMode, error) {
+	r := typeRegistryOf(dec)
+	if r == nil {
+		return dec.WithDecMode(dm), nil
+	}
+
+	_, de, err := r.modes()
+	if err != nil {
+		return nil, fmt.Errorf("type registry: %w", err)
+	}
+
+	return dec.WithDecMode(de), nil
+}
+
+// encModeOf is the encoding counterpart of decModeOf
+func encModeOf(enc *encoding.Encoder) (cbor.EncMode, error) {
+	r := typeRegistryOf(enc)
+	if r == nil {
+		return em, nil
+	}
+
+	en, _, err := r.modes()
+	if err != nil {
+		return nil, fmt.Errorf("type registry: %w", err)
+	}
+
+	return en, nil
+}
+
+func lookupFactory[F any](scoped map[string]F, base map[string]F, typ string) (F, bool) {
+	if f, ok := scoped[typ]; ok {
+		return f, true
+	}
+
+	f, ok := base[typ]
+	return f, ok
+}
+
+// rlock read-locks the target registry (if not nil), and returns the function
+// unlocking it
+func (o *TypeRegistry) rlock() func() {
+	if o == nil {
+		return func() {}
+	}
+
+	o.mu.RLock()
+
+	return o.mu.RUnlock
+}
+
+// orEmpty returns the target registry, or an empty one if it is nil
+func (o *TypeRegistry) orEmpty() *TypeRegistry {
+	if o == nil {
+		return &TypeRegistry{}
+	}
+
+	return o
+}
+
+func classIDFactory(r *TypeRegistry, typ string) (IClassIDFactory, bool) {
+	defer r.rlock()()
+	return lookupFactory(r.orEmpty().classID, classIDValueRegister, typ)
+}
+
+func instanceFactory(r *TypeRegistry, typ string) (IInstanceFactory, bool) {
+	defer r.rlock()()
+	return lookupFactory(r.orEmpty().instance, instanceValueRegister, typ)
+}
+
+func groupFactory(r *TypeRegistry, typ string) (IGroupFactory, bool) {
+	defer r.rlock()()
+	return lookupFactory(r.orEmpty().group, groupValueRegister, typ)
+}
+
+func mkeyFactory(r *TypeRegistry, typ string) (IMkeyFactory, bool) {
+	defer r.rlock()()
+	return lookupFactory(r.orEmpty().mkey, mkeyValueRegister, typ)
+}
+
+func cryptoKeyFactory(r *TypeRegistry, typ string) (ICryptoKeyFactory, bool) {
+	defer r.rlock()()
+	return lookupFactory(r.orEmpty().cryptoKey, cryptoKeyValueRegister, typ)
+}
+
+func entityNameFactory(r *TypeRegistry, typ string) (IEntityNameFactory, bool) {
+	defer r.rlock()()
+	return lookupFactory(r.orEmpty().entityName, entityNameValueRegister, typ)
+}
+
+func svnFactory(r *TypeRegistry, typ string) (ISVNFactory, bool) {
+	defer r.rlock()()
+	return lookupFactory(r.orEmpty().svn, svnValueRegister, typ)
+}
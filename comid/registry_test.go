@@ -0,0 +1,324 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/encoding"
+)
+
+// both profile class ID types are associated with the same CBOR tag, but have
+// different meanings (and type names)
+const testScopedClassIDTag = 99990
+
+type profileAClassID string
+
+func newProfileAClassID(val any) (*ClassID, error) {
+	if val == nil {
+		v := profileAClassID("")
+		return &ClassID{&v}, nil
+	}
+
+	s, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type for profile A class ID: %T", val)
+	}
+
+	v := profileAClassID(s)
+	return &ClassID{&v}, nil
+}
+
+func (o profileAClassID) Bytes() []byte  { return []byte(o) }
+func (o profileAClassID) Type() string   { return "profile-a-class-id" }
+func (o profileAClassID) String() string { return string(o) }
+func (o profileAClassID) Valid() error {
+	if o == "" {
+		return errors.New("empty")
+	}
+	return nil
+}
+
+type profileBClassID uint64
+
+func newProfileBClassID(val any) (*ClassID, error) {
+	if val == nil {
+		v := profileBClassID(0)
+		return &ClassID{&v}, nil
+	}
+
+	u, ok := val.(uint64)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type for profile B class ID: %T", val)
+	}
+
+	v := profileBClassID(u)
+	return &ClassID{&v}, nil
+}
+
+func (o profileBClassID) Bytes() []byte  { return []byte(fmt.Sprint(uint64(o))) }
+func (o profileBClassID) Type() string   { return "profile-b-class-id" }
+func (o profileBClassID) String() string { return fmt.Sprint(uint64(o)) }
+func (o profileBClassID) Valid() error   { return nil }
+
+func profileRegistries(t *testing.T) (*TypeRegistry, *TypeRegistry) {
+	a := NewTypeRegistry()
+	require.NoError(t, a.RegisterClassIDType(testScopedClassIDTag, newProfileAClassID))
+
+	b := NewTypeRegistry()
+	require.NoError(t, b.RegisterClassIDType(testScopedClassIDTag, newProfileBClassID))
+
+	return a, b
+}
+
+func comidWithClassID(classID *ClassID) *Comid {
+	return NewComid().
+		SetTagIdentity("scoped", 0).
+		AddReferenceValue(ValueTriple{
+			Environment: Environment{
+				Class: &Class{ClassID: classID},
+			},
+			Measurements: *NewMeasurements().Add(
+				MustNewUintMeasurement(uint64(1)).SetSVN(2),
+			),
+		})
+}
+
+func TestTypeRegistry_same_tag_different_profiles(t *testing.T) {
+	a, b := profileRegistries(t)
+
+	classID, err := newClassID(a, "acme", "profile-a-class-id")
+	require.NoError(t, err)
+
+	data, err := a.ScopeEncoder(encoding.NewEncoder(em)).Marshal(classID)
+	require.NoError(t, err)
+
+	// 99990("acme")
+	assert.Equal(t, []byte{0xda, 0x00, 0x01, 0x86, 0x96, 0x64, 0x61, 0x63, 0x6d, 0x65}, data)
+
+	var decoded ClassID
+
+	err = a.ScopeDecoder(encoding.NewDecoder(dm)).Unmarshal(data, &decoded)
+	require.NoError(t, err)
+	assert.Equal(t, "profile-a-class-id", decoded.Type())
+	assert.Equal(t, "acme", decoded.String())
+
+	// the same tag means something else in profile B
+	var other ClassID
+	err = b.ScopeDecoder(encoding.NewDecoder(dm)).Unmarshal(data, &other)
+	assert.Error(t, err)
+
+	// ...and nothing at all outside of a profile
+	var unscoped ClassID
+	err = dm.Unmarshal(data, &unscoped)
+	assert.Error(t, err)
+
+	_, err = NewClassID("acme", "profile-a-class-id")
+	assert.EqualError(t, err, "unknown class id type: profile-a-class-id")
+}
+
+func TestTypeRegistry_falls_back_to_base(t *testing.T) {
+	a, _ := profileRegistries(t)
+
+	classID, err := newClassID(a, TestUUIDString, UUIDType)
+	require.NoError(t, err)
+
+	data, err := a.ScopeEncoder(encoding.NewEncoder(em)).Marshal(classID)
+	require.NoError(t, err)
+
+	var decoded ClassID
+	err = a.ScopeDecoder(encoding.NewDecoder(dm)).Unmarshal(data, &decoded)
+	require.NoError(t, err)
+	assert.Equal(t, UUIDType, decoded.Type())
+}
+
+func TestTypeRegistry_Comid_round_trip(t *testing.T) {
+	a, b := profileRegistries(t)
+
+	classID, err := newClassID(a, "acme", "profile-a-class-id")
+	require.NoError(t, err)
+
+	c := comidWithClassID(classID).SetTypeRegistry(a)
+	require.NotNil(t, c)
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	decoded := NewComid().SetTypeRegistry(a)
+	require.NoError(t, decoded.FromCBOR(data))
+
+	actual := decoded.Triples.ReferenceValues.Values[0].Environment.Class.ClassID
+	assert.Equal(t, "profile-a-class-id", actual.Type())
+	assert.Equal(t, "acme", actual.String())
+
+	assert.Error(t, NewComid().SetTypeRegistry(b).FromCBOR(data))
+	assert.Error(t, NewComid().FromCBOR(data))
+
+	// an unscoped Comid does not know the CBOR tag of a profile-specific type
+	unscoped, err := comidWithClassID(classID).ToCBOR()
+	require.NoError(t, err)
+	assert.NotEqual(t, data, unscoped)
+
+	jsonData, err := c.ToJSON()
+	require.NoError(t, err)
+
+	decoded = NewComid().SetTypeRegistry(a)
+	require.NoError(t, decoded.FromJSON(jsonData))
+	assert.Equal(t, "acme", decoded.Triples.ReferenceValues.Values[0].Environment.Class.ClassID.String())
+
+	err = NewComid().FromJSON(jsonData)
+	assert.ErrorContains(t, err, "unknown class id type: profile-a-class-id")
+}
+
+func TestTypeRegistry_concurrent_decodes(t *testing.T) {
+	a, _ := profileRegistries(t)
+
+	classID, err := newClassID(a, "acme", "profile-a-class-id")
+	require.NoError(t, err)
+
+	scoped, err := comidWithClassID(classID).SetTypeRegistry(a).ToCBOR()
+	require.NoError(t, err)
+
+	unscoped, err := comidWithClassID(MustNewUUIDClassID(TestUUID)).ToCBOR()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 20)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			errs <- NewComid().SetTypeRegistry(a).FromCBOR(scoped)
+		}()
+
+		go func() {
+			defer wg.Done()
+			errs <- NewComid().FromCBOR(unscoped)
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestTypeRegistry_concurrent_registration(t *testing.T) {
+	a, _ := profileRegistries(t)
+
+	classID, err := newClassID(a, "acme", "profile-a-class-id")
+	require.NoError(t, err)
+
+	scoped, err := comidWithClassID(classID).SetTypeRegistry(a).ToCBOR()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 11)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs <- a.RegisterClassIDType(testScopedClassIDTag+1, newProfileBClassID)
+	}()
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- NewComid().SetTypeRegistry(a).FromCBOR(scoped)
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	assert.Contains(t, a.Tags(), uint64(testScopedClassIDTag+1))
+}
+
+func TestTypeRegistry_nested_scopes(t *testing.T) {
+	a, b := profileRegistries(t)
+
+	in := []byte(`{"type":"profile-a-class-id","value":"acme"}`)
+
+	// the innermost registry wins
+	dec := a.ScopeDecoder(b.ScopeDecoder(encoding.NewDecoder(dm)))
+
+	var classID ClassID
+	require.NoError(t, dec.DecodeJSON(in, &classID))
+	assert.Equal(t, "acme", classID.String())
+
+	// scoping does not alter the decoder it was derived from
+	dec = b.ScopeDecoder(encoding.NewDecoder(dm))
+	_ = a.ScopeDecoder(dec)
+
+	err := dec.DecodeJSON(in, &classID)
+	assert.ErrorContains(t, err, "unknown class id type: profile-a-class-id")
+}
+
+func TestTypeRegistry_collisions(t *testing.T) {
+	r := NewTypeRegistry()
+
+	// clashes with a base tag
+	err := r.RegisterClassIDType(600, newProfileAClassID)
+	assert.EqualError(t, err, "tag 600 is already registered")
+
+	// clashes with a base type name
+	err = r.RegisterClassIDType(99991, NewUUIDClassID)
+	assert.EqualError(t, err, `class ID type with name "uuid" already exists`)
+
+	require.NoError(t, r.RegisterClassIDType(99991, newProfileAClassID))
+
+	err = r.RegisterClassIDType(99992, newProfileAClassID)
+	assert.EqualError(t, err, `class ID type with name "profile-a-class-id" already exists`)
+
+	err = r.RegisterClassIDType(99991, newProfileBClassID)
+	assert.EqualError(t, err, "tag 99991 is already registered")
+
+	tags := r.Tags()
+	assert.Len(t, tags, 1)
+	assert.Contains(t, tags, uint64(99991))
+
+	_, err = r.TagSet()
+	assert.NoError(t, err)
+}
+
+func TestTypeRegistry_Scope_nil(t *testing.T) {
+	var r *TypeRegistry
+
+	dec := encoding.NewDecoder(dm)
+	assert.Same(t, dec, r.ScopeDecoder(dec))
+
+	enc := encoding.NewEncoder(em)
+	assert.Same(t, enc, r.ScopeEncoder(enc))
+}
+
+func TestTypeRegistry_JSON_type_names(t *testing.T) {
+	a, b := profileRegistries(t)
+
+	in := []byte(`{"type":"profile-b-class-id","value":7}`)
+
+	var classID ClassID
+
+	err := a.ScopeDecoder(encoding.NewDecoder(dm)).DecodeJSON(in, &classID)
+	assert.ErrorContains(t, err, "unknown class id type: profile-b-class-id")
+
+	err = b.ScopeDecoder(encoding.NewDecoder(dm)).DecodeJSON(in, &classID)
+	assert.NoError(t, err)
+	assert.Equal(t, "7", classID.String())
+}
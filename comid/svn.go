@@ -22,7 +22,13 @@ type SVN struct {
 // the strings defined by the spec ("exact-value", "min-value"), or has been
 // registered with RegisterSVNType().
 func NewSVN(val any, typ string) (*SVN, error) {
-	factory, ok := svnValueRegister[typ]
+	return newSVN(nil, val, typ)
+}
+
+// newSVN is like NewSVN, except that the type choices of the supplied registry
+// (if any) are available in addition to the base ones
+func newSVN(r *TypeRegistry, val any, typ string) (*SVN, error) {
+	factory, ok := svnFactory(r, typ)
 	if !ok {
 		return nil, fmt.Errorf("unknown SVN type: %s", typ)
 	}
@@ -43,12 +49,34 @@ func MustNewSVN(val any, typ string) *SVN {
 
 // MarshalCBOR returns the CBOR encoding of the SVN.
 func (o SVN) MarshalCBOR() ([]byte, error) {
-	return em.Marshal(o.Value)
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith is like MarshalCBOR, except that the CBOR tags of the
+// TypeRegistry in scope of enc (if any) are available
+func (o SVN) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	mode, err := encModeOf(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return mode.Marshal(o.Value)
 }
 
 // UnmarshalCBOR populates the SVN form the provided CBOR bytes.
 func (o *SVN) UnmarshalCBOR(data []byte) error {
-	return dm.Unmarshal(data, &o.Value)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith is like UnmarshalCBOR, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *SVN) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	mode, err := decModeOf(dec)
+	if err != nil {
+		return err
+	}
+
+	return mode.Unmarshal(data, &o.Value)
 }
 
 // UnmarshalJSON deserializes the supplied JSON object into the target SVN
@@ -65,13 +93,19 @@ func (o *SVN) UnmarshalCBOR(data []byte) error {
 // class id value. The exact encoding is <SVN_TYPE> dependent. For both base
 // types, it is an integer (JSON number).
 func (o *SVN) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith is like UnmarshalJSON, except that the type choices of the
+// TypeRegistry in scope of dec (if any) are available
+func (o *SVN) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
 	var tnv encoding.TypeAndValue
 
 	if err := json.Unmarshal(data, &tnv); err != nil {
 		return fmt.Errorf("SVN decoding failure: %w", err)
 	}
 
-	decoded, err := NewSVN(nil, tnv.Type)
+	decoded, err := newSVN(typeRegistryOf(dec), nil, tnv.Type)
 	if err != nil {
 		return err
 	}
@@ -6,18 +6,77 @@ package comid
 import (
 	"fmt"
 
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
 	"github.com/veraison/swid"
 )
 
 type TagIdentity struct {
-	TagID      swid.TagID `cbor:"0,keyasint" json:"id"`
-	TagVersion uint       `cbor:"1,keyasint,omitempty" json:"version,omitempty"`
+	TagID      swid.TagID `cbor:"0,keyasint" json:"id" cddl:"comid.tag-id"`
+	TagVersion uint       `cbor:"1,keyasint,omitempty" json:"version,omitempty" cddl:"comid.tag-version"`
+
+	Extensions
 }
 
-func (o TagIdentity) Valid() error {
-	if o.TagID == (swid.TagID{}) {
-		return fmt.Errorf("empty tag-id")
+// RegisterExtensions registers a struct as a collections of extensions
+func (o *TagIdentity) RegisterExtensions(exts extensions.Map) error {
+	for p, v := range exts {
+		switch p {
+		case ExtTagIdentity:
+			o.Extensions.Register(v)
+		default:
+			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
+		}
 	}
 
 	return nil
 }
+
+// GetExtensions returns previously registered extension
+func (o *TagIdentity) GetExtensions() extensions.IMapValue {
+	return o.Extensions.IMapValue
+}
+
+// nolint:gocritic
+func (o TagIdentity) Valid() error {
+	return validation.FirstError(o.validate)
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *TagIdentity) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *TagIdentity) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o TagIdentity) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o TagIdentity) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *TagIdentity) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *TagIdentity) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o TagIdentity) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
+}
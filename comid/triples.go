@@ -8,21 +8,28 @@ import (
 
 	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
 )
 
 type Triples struct {
-	ReferenceValues *ValueTriples `cbor:"0,keyasint,omitempty" json:"reference-values,omitempty"`
-	EndorsedValues  *ValueTriples `cbor:"1,keyasint,omitempty" json:"endorsed-values,omitempty"`
-	DevIdentityKeys *KeyTriples   `cbor:"2,keyasint,omitempty" json:"dev-identity-keys,omitempty"`
-	AttestVerifKeys *KeyTriples   `cbor:"3,keyasint,omitempty" json:"attester-verification-keys,omitempty"`
+	ReferenceValues *ValueTriples `cbor:"0,keyasint,omitempty" json:"reference-values,omitempty" cddl:"comid.reference-triples"`
+	EndorsedValues  *ValueTriples `cbor:"1,keyasint,omitempty" json:"endorsed-values,omitempty" cddl:"comid.endorsed-triples"`
+	DevIdentityKeys *KeyTriples   `cbor:"2,keyasint,omitempty" json:"dev-identity-keys,omitempty" cddl:"comid.identity-triples"`
+	AttestVerifKeys *KeyTriples   `cbor:"3,keyasint,omitempty" json:"attester-verification-keys,omitempty" cddl:"comid.attest-key-triples"`
 
 	Extensions
+
+	// keyTripleExts holds the extensions registered for the KeyTriple
+	// entries of both key triple lists (see
+	// extensions.WithElementExtensions)
+	keyTripleExts extensions.Map
 }
 
 // RegisterExtensions registers a struct as a collections of extensions
 func (o *Triples) RegisterExtensions(exts extensions.Map) error {
 	refValExts := extensions.NewMap()
 	endValExts := extensions.NewMap()
+	keyExts := extensions.NewMap()
 
 	for p, v := range exts {
 		switch p {
@@ -36,6 +43,12 @@ func (o *Triples) RegisterExtensions(exts extensions.Map) error {
 			endValExts[ExtMval] = v
 		case ExtEndorsedValueFlags:
 			endValExts[ExtFlags] = v
+		case ExtEnvironment, ExtClass:
+			refValExts[p] = v
+			endValExts[p] = v
+			keyExts[p] = v
+		case ExtKeyTriple:
+			keyExts[p] = v
 		default:
 			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
 		}
@@ -56,7 +69,13 @@ func (o *Triples) RegisterExtensions(exts extensions.Map) error {
 			o.EndorsedValues = NewValueTriples()
 		}
 
-		if err := o.EndorsedValues.RegisterExtensions(refValExts); err != nil {
+		if err := o.EndorsedValues.RegisterExtensions(endValExts); err != nil {
+			return err
+		}
+	}
+
+	if len(keyExts) != 0 {
+		if err := o.registerKeyTripleExtensions(keyExts); err != nil {
 			return err
 		}
 	}
@@ -64,6 +83,29 @@ func (o *Triples) RegisterExtensions(exts extensions.Map) error {
 	return nil
 }
 
+func (o *Triples) registerKeyTripleExtensions(exts extensions.Map) error {
+	// validate the extensions by registering them with a new KeyTriple
+	if err := new(KeyTriple).RegisterExtensions(exts); err != nil {
+		return err
+	}
+
+	o.keyTripleExts = exts
+
+	for _, kts := range []*KeyTriples{o.AttestVerifKeys, o.DevIdentityKeys} {
+		if kts == nil {
+			continue
+		}
+
+		for i := range *kts {
+			if err := extensions.RegisterIfUnset(&(*kts)[i], exts); err != nil {
+				return fmt.Errorf("key triple at index %d: %w", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
 // GetExtensions returns pervisouosly registered extension
 func (o *Triples) GetExtensions() extensions.IMapValue {
 	return o.Extensions.IMapValue
@@ -71,11 +113,23 @@ func (o *Triples) GetExtensions() extensions.IMapValue {
 
 // UnmarshalCBOR deserializes from CBOR
 func (o *Triples) UnmarshalCBOR(data []byte) error {
-	return encoding.PopulateStructFromCBOR(dm, data, o)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *Triples) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	dec = extensions.WithElementExtensions(dec, ExtKeyTriple, o.keyTripleExts)
+
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
 }
 
 // MarshalCBOR serializes to CBOR
 func (o Triples) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+func (o Triples) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
 	// If extensions have been registered, the collection will exist, but
 	// might be empty. If that is the case, set it to nil to avoid
 	// marshaling an empty list (and let the marshaller omit the claim
@@ -90,12 +144,19 @@ func (o Triples) MarshalCBOR() ([]byte, error) {
 		o.EndorsedValues = nil
 	}
 
-	return encoding.SerializeStructToCBOR(em, o)
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
 }
 
 // UnmarshalJSON deserializes from JSON
 func (o *Triples) UnmarshalJSON(data []byte) error {
-	return encoding.PopulateStructFromJSON(data, o)
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *Triples) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	dec = extensions.WithElementExtensions(dec, ExtKeyTriple, o.keyTripleExts)
+
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
 }
 
 // MarshalJSON serializes to JSON
@@ -119,43 +180,7 @@ func (o Triples) MarshalJSON() ([]byte, error) {
 
 // Valid checks that the Triples is valid as per the specification
 func (o Triples) Valid() error {
-	// non-empty<>
-	if (o.ReferenceValues == nil || o.ReferenceValues.IsEmpty()) &&
-		(o.EndorsedValues == nil || o.EndorsedValues.IsEmpty()) &&
-		(o.AttestVerifKeys == nil || len(*o.AttestVerifKeys) == 0) &&
-		(o.DevIdentityKeys == nil || len(*o.DevIdentityKeys) == 0) {
-		return fmt.Errorf("triples struct must not be empty")
-	}
-
-	if o.ReferenceValues != nil {
-		if err := o.ReferenceValues.Valid(); err != nil {
-			return fmt.Errorf("reference values: %w", err)
-		}
-	}
-
-	if o.EndorsedValues != nil {
-		if err := o.EndorsedValues.Valid(); err != nil {
-			return fmt.Errorf("endorsed values: %w", err)
-		}
-	}
-
-	if o.AttestVerifKeys != nil {
-		for i, ak := range *o.AttestVerifKeys {
-			if err := ak.Valid(); err != nil {
-				return fmt.Errorf("attestation verification key at index %d: %w", i, err)
-			}
-		}
-	}
-
-	if o.DevIdentityKeys != nil {
-		for i, dk := range *o.DevIdentityKeys {
-			if err := dk.Valid(); err != nil {
-				return fmt.Errorf("device identity key at index %d: %w", i, err)
-			}
-		}
-	}
-
-	return o.Extensions.validTriples(&o)
+	return validation.FirstError(o.validate)
 }
 
 func (o *Triples) AddReferenceValue(val ValueTriple) *Triples {
@@ -184,16 +209,48 @@ func (o *Triples) AddEndorsedValue(val ValueTriple) *Triples {
 
 func (o *Triples) AddAttestVerifKey(val KeyTriple) *Triples {
 	if o != nil {
-		*o.AttestVerifKeys = append(*o.AttestVerifKeys, val)
+		if o.addAttestVerifKey(val) != nil {
+			return nil
+		}
 	}
 
 	return o
 }
 
+func (o *Triples) addAttestVerifKey(val KeyTriple) error {
+	if err := extensions.RegisterIfUnset(&val, o.keyTripleExts); err != nil {
+		return err
+	}
+
+	if o.AttestVerifKeys == nil {
+		o.AttestVerifKeys = NewKeyTriples()
+	}
+
+	*o.AttestVerifKeys = append(*o.AttestVerifKeys, val)
+
+	return nil
+}
+
 func (o *Triples) AddDevIdentityKey(val KeyTriple) *Triples {
 	if o != nil {
-		*o.DevIdentityKeys = append(*o.DevIdentityKeys, val)
+		if o.addDevIdentityKey(val) != nil {
+			return nil
+		}
 	}
 
 	return o
 }
+
+func (o *Triples) addDevIdentityKey(val KeyTriple) error {
+	if err := extensions.RegisterIfUnset(&val, o.keyTripleExts); err != nil {
+		return err
+	}
+
+	if o.DevIdentityKeys == nil {
+		o.DevIdentityKeys = NewKeyTriples()
+	}
+
+	*o.DevIdentityKeys = append(*o.DevIdentityKeys, val)
+
+	return nil
+}
@@ -84,3 +84,59 @@ func TestTriples_adders(t *testing.T) {
 	assert.Len(t, triples.ReferenceValues.Values, 1)
 	assert.Len(t, triples.EndorsedValues.Values, 1)
 }
+
+type testRefValMvalExtensions struct {
+	Origin *string `cbor:"-1,keyasint,omitempty" json:"origin,omitempty"`
+}
+
+type testEndValMvalExtensions struct {
+	Endorser *string `cbor:"-1,keyasint,omitempty" json:"endorser,omitempty"`
+}
+
+func TestTriples_extensions_endorsed_values(t *testing.T) {
+	triples := Triples{}
+
+	extMap := extensions.NewMap().
+		Add(ExtReferenceValue, &testRefValMvalExtensions{}).
+		Add(ExtEndorsedValue, &testEndValMvalExtensions{})
+
+	require.NoError(t, triples.RegisterExtensions(extMap))
+
+	newValueTriple := func() ValueTriple {
+		return ValueTriple{
+			Environment: Environment{Class: NewClassUUID(TestUUID)},
+			Measurements: *NewMeasurements().Add(
+				MustNewUintMeasurement(uint64(1)),
+			),
+		}
+	}
+
+	triples.AddReferenceValue(newValueTriple()).AddEndorsedValue(newValueTriple())
+
+	assert.IsType(t, &testRefValMvalExtensions{},
+		triples.ReferenceValues.Values[0].Measurements.Values[0].GetExtensions())
+	assert.IsType(t, &testEndValMvalExtensions{},
+		triples.EndorsedValues.Values[0].Measurements.Values[0].GetExtensions())
+
+	data := []byte(`{
+		"reference-values": [{
+			"environment": {"class": {"id": {"type": "uuid", "value": "31fb5abf-023e-4992-aa4e-95f9c1503bfa"}}},
+			"measurements": [{"value": {"raw-value": {"type": "bytes", "value": "AQ=="}, "origin": "vendor"}}]
+		}],
+		"endorsed-values": [{
+			"environment": {"class": {"id": {"type": "uuid", "value": "31fb5abf-023e-4992-aa4e-95f9c1503bfa"}}},
+			"measurements": [{"value": {"raw-value": {"type": "bytes", "value": "AQ=="}, "endorser": "acme"}}]
+		}]
+	}`)
+
+	out := Triples{}
+	require.NoError(t, out.RegisterExtensions(extensions.NewMap().
+		Add(ExtReferenceValue, &testRefValMvalExtensions{}).
+		Add(ExtEndorsedValue, &testEndValMvalExtensions{})))
+	require.NoError(t, out.UnmarshalJSON(data))
+
+	assert.Equal(t, "vendor",
+		out.ReferenceValues.Values[0].Measurements.Values[0].Val.MustGetString("origin"))
+	assert.Equal(t, "acme",
+		out.EndorsedValues.Values[0].Measurements.Values[0].Val.MustGetString("endorser"))
+}
@@ -0,0 +1,236 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package comid
+
+import (
+	"errors"
+
+	"github.com/veraison/corim/validation"
+	"github.com/veraison/swid"
+)
+
+// The validate methods below record every problem found in the target value
+// with the supplied Collector. They implement both ValidateAll, which reports
+// all of them, and the Valid methods, which report the first one. The messages
+// set via Collector.Wrap are those of the errors returned by Valid.
+
+// ValidateAll performs the same checks as Valid, but rather than stopping at
+// the first problem, it reports all of them, each with its location (as a
+// JSON pointer and as a sequence of CBOR keys), its kind and its severity.
+// The returned list is empty if the Comid is valid; use its Err method to
+// obtain an error value.
+// nolint:gocritic
+func (o Comid) ValidateAll() validation.Errors {
+	c := validation.NewCollector()
+	o.validate(c)
+
+	return c.Errors()
+}
+
+// nolint:gocritic
+func (o Comid) validate(c *validation.Collector) {
+	o.TagIdentity.validate(c.Field(o, "TagIdentity").Wrap("tag-identity validation failed"))
+
+	if o.Entities != nil {
+		entities := c.Field(o, "Entities").Wrap("entities validation failed")
+		for i, e := range o.Entities.Values {
+			e.validate(entities.Index(i).Wrap("error at index %d", i))
+		}
+	}
+
+	if o.LinkedTags != nil {
+		o.LinkedTags.validate(c.Field(o, "LinkedTags").Wrap("linked-tags validation failed"))
+	}
+
+	o.Triples.validate(c.Field(o, "Triples").Wrap("triples validation failed"))
+
+	c.CheckExtension(o.Extensions.validComid(&o))
+}
+
+// nolint:gocritic
+func (o TagIdentity) validate(c *validation.Collector) {
+	if o.TagID == (swid.TagID{}) {
+		c.Field(o, "TagID").Error(validation.ErrMissing, errors.New("empty tag-id"))
+	}
+
+	c.CheckExtension(o.Extensions.validTagIdentity(&o))
+}
+
+// nolint:gocritic
+func (o Entity) validate(c *validation.Collector) {
+	invalid := c.Wrap("invalid entity")
+
+	if o.Name == nil {
+		invalid.Field(o, "Name").Error(validation.ErrMissing, errors.New("empty entity-name"))
+	} else {
+		invalid.Field(o, "Name").Check(o.Name.Valid())
+	}
+
+	if o.RegID != nil && o.RegID.Empty() {
+		invalid.Field(o, "RegID").Error(validation.ErrEmpty, errors.New("empty reg-id"))
+	}
+
+	invalid.Field(o, "Roles").Check(o.Roles.Valid())
+
+	c.CheckExtension(o.Extensions.validEntity(&o))
+}
+
+func (o LinkedTags) validate(c *validation.Collector) {
+	for i, l := range o {
+		l.validate(c.Index(i).Wrap("invalid linked-tag entry at index %d", i))
+	}
+}
+
+// nolint:gocritic
+func (o LinkedTag) validate(c *validation.Collector) {
+	if o.LinkedTagID == (swid.TagID{}) {
+		c.Field(o, "LinkedTagID").Error(validation.ErrMissing,
+			errors.New("tag-id must be set in linked-tag"))
+	}
+
+	c.Field(o, "Rel").Wrap("rel validation failed").Check(o.Rel.Valid())
+
+	c.CheckExtension(o.Extensions.validLinkedTag(&o))
+}
+
+func (o Triples) validate(c *validation.Collector) {
+	// non-empty<>
+	if (o.ReferenceValues == nil || o.ReferenceValues.IsEmpty()) &&
+		(o.EndorsedValues == nil || o.EndorsedValues.IsEmpty()) &&
+		(o.AttestVerifKeys == nil || len(*o.AttestVerifKeys) == 0) &&
+		(o.DevIdentityKeys == nil || len(*o.DevIdentityKeys) == 0) {
+		c.Error(validation.ErrEmpty, errors.New("triples struct must not be empty"))
+	}
+
+	if o.ReferenceValues != nil {
+		o.ReferenceValues.validate(c.Field(o, "ReferenceValues").Wrap("reference values"))
+	}
+
+	if o.EndorsedValues != nil {
+		o.EndorsedValues.validate(c.Field(o, "EndorsedValues").Wrap("endorsed values"))
+	}
+
+	if o.AttestVerifKeys != nil {
+		keys := c.Field(o, "AttestVerifKeys")
+		for i, ak := range *o.AttestVerifKeys {
+			ak.validate(keys.Index(i).Wrap("attestation verification key at index %d", i))
+		}
+	}
+
+	if o.DevIdentityKeys != nil {
+		keys := c.Field(o, "DevIdentityKeys")
+		for i, dk := range *o.DevIdentityKeys {
+			dk.validate(keys.Index(i).Wrap("device identity key at index %d", i))
+		}
+	}
+
+	c.CheckExtension(o.Extensions.validTriples(&o))
+}
+
+func (o ValueTriples) validate(c *validation.Collector) {
+	for i, v := range o.Values {
+		v.validate(c.Index(i).Wrap("error at index %d", i))
+	}
+}
+
+func (o ValueTriple) validate(c *validation.Collector) {
+	o.Environment.validate(c.Field(o, "Environment").Wrap("environment validation failed"))
+
+	measurements := c.Field(o, "Measurements").Wrap("measurements validation failed")
+
+	if o.Measurements.IsEmpty() {
+		measurements.Error(validation.ErrEmpty, errors.New("no measurement entries"))
+		return
+	}
+
+	for i, m := range o.Measurements.Values {
+		m.validate(measurements.Index(i).Wrap("error at index %d", i))
+	}
+}
+
+// nolint:gocritic
+func (o KeyTriple) validate(c *validation.Collector) {
+	o.Environment.validate(c.Field(o, "Environment").Wrap("environment validation failed"))
+	c.Field(o, "VerifKeys").Wrap("verification keys validation failed").Check(o.VerifKeys.Valid())
+
+	if cond := o.conditions(); cond != nil {
+		c.Field(o, "Conditions").Wrap("conditions validation failed").Check(cond.Valid())
+	}
+
+	if o.Conditions != nil {
+		c.CheckExtension(o.Conditions.Extensions.validKeyTriple(&o))
+	}
+}
+
+// nolint:gocritic
+func (o Environment) validate(c *validation.Collector) {
+	// non-empty<>
+	if o.Class == nil && o.Instance == nil && o.Group == nil &&
+		o.Extensions.IsEmpty() {
+		c.Error(validation.ErrEmpty, errors.New("environment must not be empty"))
+	}
+
+	if o.Class != nil {
+		o.Class.validate(c.Field(o, "Class").Wrap("class validation failed"))
+	}
+
+	if o.Instance != nil {
+		c.Field(o, "Instance").Wrap("instance validation failed").Check(o.Instance.Valid())
+	}
+
+	if o.Group != nil {
+		c.Field(o, "Group").Wrap("group validation failed").Check(o.Group.Valid())
+	}
+
+	c.CheckExtension(o.Extensions.validEnvironment(&o))
+}
+
+// nolint:gocritic
+func (o Class) validate(c *validation.Collector) {
+	// check non-empty<{ ... }>
+	if o.IsEmpty() {
+		c.Error(validation.ErrEmpty, errors.New("class must not be empty"))
+		return
+	}
+
+	c.CheckExtension(o.Extensions.validClass(&o))
+}
+
+// nolint:gocritic
+func (o Measurement) validate(c *validation.Collector) {
+	if o.Key != nil && o.Key.IsSet() {
+		c.Field(o, "Key").Check(o.Key.Valid())
+	}
+
+	o.Val.validate(c.Field(o, "Val"))
+}
+
+// nolint:gocritic
+func (o Mval) validate(c *validation.Collector) {
+	if o.isEmpty() {
+		c.Error(validation.ErrEmpty, errors.New("no measurement value set"))
+		return
+	}
+
+	if o.Ver != nil {
+		c.Field(o, "Ver").Check(o.Ver.Valid())
+	}
+
+	if o.Digests != nil {
+		c.Field(o, "Digests").Check(o.Digests.Valid())
+	}
+
+	if o.Flags != nil {
+		c.Field(o, "Flags").Check(o.Flags.Valid())
+	}
+
+	if o.MACAddr != nil {
+		c.Field(o, "MACAddr").Check(validMACAddr(*o.MACAddr))
+	}
+
+	if o.IPAddr != nil {
+		c.Field(o, "IPAddr").Check(validIPAddr(*o.IPAddr))
+	}
+
+	c.CheckExtension(o.Extensions.validMval(&o))
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package comid
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/validation"
+	"github.com/veraison/swid"
+)
+
+func Test_Comid_ValidateAll_ok(t *testing.T) {
+	c := NewComid()
+	require.NoError(t, c.FromCBOR(testComid1))
+
+	errs := c.ValidateAll()
+	assert.Empty(t, errs)
+	assert.NoError(t, errs.Err())
+}
+
+func Test_Comid_ValidateAll(t *testing.T) {
+	c := NewComid()
+	require.NoError(t, c.FromCBOR(testComid1))
+
+	c.TagIdentity = TagIdentity{}
+	c.Entities.Values[0].Name = nil
+	emptyRegID := TaggedURI("")
+	c.Entities.Values[0].RegID = &emptyRegID
+
+	rv := &c.Triples.ReferenceValues.Values[0]
+	rv.Environment = Environment{}
+
+	mac := MACaddr{0x01, 0x02, 0x03}
+	rv.Measurements.Values[0].Val.MACAddr = &mac
+
+	c.Triples.ReferenceValues.Add(&ValueTriple{
+		Environment: Environment{Instance: MustNewUUIDInstance(TestUUID)},
+	})
+
+	// Valid only reports the first problem
+	assert.EqualError(t, c.Valid(), "tag-identity validation failed: empty tag-id")
+
+	errs := c.ValidateAll()
+	require.Len(t, errs, 6)
+
+	expected := []struct {
+		pointer string
+		keys    []any
+		code    string
+		msg     string
+	}{
+		{"/tag-identity/id", []any{1, 0}, "missing", "empty tag-id"},
+		{"/entities/0/name", []any{2, 0, 0}, "missing", "empty entity-name"},
+		{"/entities/0/regid", []any{2, 0, 1}, "empty", "empty reg-id"},
+		{"/triples/reference-values/0/environment", []any{4, 0, 0, 0}, "empty", "environment must not be empty"},
+		{
+			"/triples/reference-values/0/measurements/0/value/mac-addr", []any{4, 0, 0, 1, 0, 1, 6},
+			"invalid-value", "invalid MAC address length: expected 6 or 8 bytes, got 3",
+		},
+		{"/triples/reference-values/1/measurements", []any{4, 0, 1, 1}, "empty", "no measurement entries"},
+	}
+
+	for i, e := range expected {
+		assert.Equal(t, e.pointer, errs[i].Path.Pointer())
+		assert.Equal(t, e.keys, errs[i].Path.CBORKeys())
+		assert.Equal(t, e.code, errs[i].Code)
+		assert.Equal(t, validation.SeverityError, errs[i].Severity)
+		assert.EqualError(t, errs[i].Err, e.msg)
+	}
+
+	err := errs.Err()
+	assert.ErrorIs(t, err, validation.ErrMissing)
+	assert.ErrorIs(t, err, validation.ErrEmpty)
+	assert.ErrorIs(t, err, validation.ErrInvalidValue)
+	assert.NotErrorIs(t, err, validation.ErrExtension)
+
+	var verrs validation.Errors
+	require.True(t, errors.As(err, &verrs))
+	assert.Len(t, verrs, 6)
+
+	// Valid reports the first problem found by the same checks
+	c.TagIdentity = TagIdentity{TagID: *swid.NewTagID("test")}
+	assert.EqualError(t, c.Valid(),
+		"entities validation failed: error at index 0: invalid entity: empty entity-name")
+	assert.EqualError(t, c.Valid(), errs[1:].First().Error())
+}
+
+func Test_Comid_ValidateAll_empty_triples(t *testing.T) {
+	c := NewComid().SetTagIdentity("test", 0)
+	require.NotNil(t, c)
+
+	errs := c.ValidateAll()
+	require.Len(t, errs, 1)
+	assert.Equal(t, "/triples", errs[0].Path.Pointer())
+	assert.ErrorIs(t, errs[0], validation.ErrEmpty)
+	assert.EqualError(t, errs, "/triples: triples struct must not be empty")
+}
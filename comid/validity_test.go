@@ -0,0 +1,38 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidity_Valid(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	notBefore := notAfter.AddDate(-1, 0, 0)
+
+	assert.NoError(t, Validity{NotAfter: notAfter}.Valid())
+	assert.NoError(t, Validity{NotAfter: notAfter, NotBefore: &notBefore}.Valid())
+	assert.EqualError(t, Validity{}.Valid(), "missing not-after")
+	assert.ErrorContains(t, Validity{NotAfter: notBefore, NotBefore: &notAfter}.Valid(),
+		"invalid not-before / not-after: negative delta")
+
+	assert.NotNil(t, NewValidity().Set(notAfter, &notBefore))
+	assert.Nil(t, NewValidity().Set(notBefore, &notAfter))
+}
+
+func TestValidity_Contains(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	notBefore := notAfter.AddDate(-1, 0, 0)
+	v := Validity{NotAfter: notAfter, NotBefore: &notBefore}
+
+	assert.True(t, v.Contains(notBefore))
+	assert.True(t, v.Contains(notAfter))
+	assert.False(t, v.Contains(notBefore.Add(-time.Second)))
+	assert.False(t, v.Contains(notAfter.Add(time.Second)))
+
+	assert.True(t, Validity{NotAfter: notAfter}.Contains(time.Time{}))
+}
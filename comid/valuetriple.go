@@ -4,10 +4,12 @@
 package comid
 
 import (
-	"errors"
 	"fmt"
 
+	cbor "github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
 )
 
 // ValueTriple relates measurements to a target environment, essentially
@@ -22,27 +24,107 @@ type ValueTriple struct {
 }
 
 func (o *ValueTriple) RegisterExtensions(exts extensions.Map) error {
-	return o.Measurements.RegisterExtensions(exts)
+	envExts := extensions.NewMap()
+	measExts := extensions.NewMap()
+
+	for p, v := range exts {
+		switch p {
+		case ExtEnvironment, ExtClass:
+			envExts.Add(p, v)
+		default:
+			measExts.Add(p, v)
+		}
+	}
+
+	if err := o.Environment.RegisterExtensions(envExts); err != nil {
+		return err
+	}
+
+	if len(measExts) == 0 {
+		return nil
+	}
+
+	return o.Measurements.RegisterExtensions(measExts)
 }
 
+// GetExtensions returns previously registered extension. The extensions of
+// the Measurements take precedence; if there are none, those of the
+// Environment are returned (see Environment.GetExtensions).
 func (o *ValueTriple) GetExtensions() extensions.IMapValue {
-	return o.Measurements.GetExtensions()
+	if exts := o.Measurements.GetExtensions(); exts != nil {
+		return exts
+	}
+
+	return o.Environment.GetExtensions()
 }
 
-func (o ValueTriple) Valid() error {
-	if err := o.Environment.Valid(); err != nil {
-		return fmt.Errorf("environment validation failed: %w", err)
+// UnmarshalCBOR deserializes from CBOR
+func (o *ValueTriple) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *ValueTriple) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	var (
+		elts    []cbor.RawMessage
+		unknown encoding.UnknownFieldCollector
+	)
+
+	dec = dec.WithDecMode(dm)
+
+	if err := dec.Unmarshal(data, &elts); err != nil {
+		return err
 	}
 
-	if o.Measurements.IsEmpty() {
-		return errors.New("measurements validation failed: no measurement entries")
+	if len(elts) != 2 {
+		return fmt.Errorf("expected 2 array elements, found %d", len(elts))
 	}
 
-	if err := o.Measurements.Valid(); err != nil {
-		return fmt.Errorf("measurements validation failed: %w", err)
+	if err := dec.Unmarshal(elts[0], &o.Environment); err != nil {
+		if !unknown.Collect(err, "environment") {
+			return fmt.Errorf("environment: %w", err)
+		}
 	}
 
-	return nil
+	if err := dec.Unmarshal(elts[1], &o.Measurements); err != nil {
+		if !unknown.Collect(err, "measurements") {
+			return fmt.Errorf("measurements: %w", err)
+		}
+	}
+
+	return unknown.Err()
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o ValueTriple) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	enc = enc.WithEncMode(em)
+
+	env, err := enc.Marshal(o.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("environment: %w", err)
+	}
+
+	meas, err := enc.Marshal(o.Measurements)
+	if err != nil {
+		return nil, fmt.Errorf("measurements: %w", err)
+	}
+
+	return enc.Marshal([]cbor.RawMessage{env, meas})
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *ValueTriple) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *ValueTriple) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+func (o ValueTriple) Valid() error {
+	return validation.FirstError(o.validate)
 }
 
 // ValueTriples is a container for ValueTriple instances and their extensions.
@@ -62,7 +144,7 @@ func (o *ValueTriples) GetExtensions() extensions.IMapValue {
 }
 
 func (o ValueTriples) Valid() error {
-	return (extensions.Collection[ValueTriple, *ValueTriple])(o).Valid()
+	return validation.FirstError(o.validate)
 }
 
 func (o *ValueTriples) IsEmpty() bool {
@@ -78,10 +160,18 @@ func (o ValueTriples) MarshalCBOR() ([]byte, error) {
 	return (extensions.Collection[ValueTriple, *ValueTriple])(o).MarshalCBOR()
 }
 
+func (o ValueTriples) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return (extensions.Collection[ValueTriple, *ValueTriple])(o).MarshalCBORWith(enc)
+}
+
 func (o *ValueTriples) UnmarshalCBOR(data []byte) error {
 	return (*extensions.Collection[ValueTriple, *ValueTriple])(o).UnmarshalCBOR(data)
 }
 
+func (o *ValueTriples) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return (*extensions.Collection[ValueTriple, *ValueTriple])(o).UnmarshalCBORWith(dec, data)
+}
+
 func (o ValueTriples) MarshalJSON() ([]byte, error) {
 	return (extensions.Collection[ValueTriple, *ValueTriple])(o).MarshalJSON()
 }
@@ -89,3 +179,7 @@ func (o ValueTriples) MarshalJSON() ([]byte, error) {
 func (o *ValueTriples) UnmarshalJSON(data []byte) error {
 	return (*extensions.Collection[ValueTriple, *ValueTriple])(o).UnmarshalJSON(data)
 }
+
+func (o *ValueTriples) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return (*extensions.Collection[ValueTriple, *ValueTriple])(o).UnmarshalJSONWith(dec, data)
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package corim
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+)
+
+var benchCBORFixtures = []string{
+	"unsigned-good-corim.cbor",
+	"unsigned-example-corim.cbor",
+	"unsigned-corim-with-extensions.cbor",
+}
+
+func readBenchFixture(b *testing.B, name string) []byte {
+	data, err := os.ReadFile("testcases/" + name)
+	require.NoError(b, err)
+	return data
+}
+
+func BenchmarkUnsignedCorim_FromCBOR(b *testing.B) {
+	for _, name := range benchCBORFixtures {
+		data := readBenchFixture(b, name)
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var c UnsignedCorim
+				if err := c.FromCBOR(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnsignedCorim_Comids(b *testing.B) {
+	for _, name := range benchCBORFixtures {
+		data := readBenchFixture(b, name)
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var c UnsignedCorim
+				if err := c.FromCBOR(data); err != nil {
+					b.Fatal(err)
+				}
+
+				if _, err := c.Comids(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnsignedCorim_ToCBOR(b *testing.B) {
+	for _, name := range benchCBORFixtures {
+		var c UnsignedCorim
+		require.NoError(b, c.FromCBOR(readBenchFixture(b, name)))
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.ToCBOR(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnsignedCorim_FromJSON(b *testing.B) {
+	for _, name := range []string{"corim.json", "corim-ext.json"} {
+		data := readBenchFixture(b, name)
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var c UnsignedCorim
+				if err := c.FromJSON(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// newBenchComid returns a CBOR-encoded CoMID with the specified number of
+// reference measurements
+func newBenchComid(b *testing.B, numMeasurements int) []byte {
+	measurements := comid.NewMeasurements()
+	for i := 0; i < numMeasurements; i++ {
+		m := comid.MustNewUintMeasurement(uint64(i)).
+			SetSVN(uint64(i)).
+			AddDigest(1, make([]byte, 32))
+		measurements.Add(m)
+	}
+
+	c := comid.NewComid().
+		SetTagIdentity("bench", 0).
+		AddReferenceValue(comid.ValueTriple{
+			Environment: comid.Environment{
+				Class: comid.NewClassUUID(comid.TestUUID),
+			},
+			Measurements: *measurements,
+		})
+	require.NotNil(b, c)
+
+	data, err := c.ToCBOR()
+	require.NoError(b, err)
+
+	return data
+}
+
+func BenchmarkComid_FromCBOR_measurements(b *testing.B) {
+	for _, n := range []int{10, 1000} {
+		data := newBenchComid(b, n)
+
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c := comid.NewComid()
+				if err := c.FromCBOR(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
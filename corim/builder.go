@@ -0,0 +1,178 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/cotl"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/swid"
+)
+
+// UnsignedCorimBuilder provides the same fluent interface as the UnsignedCorim
+// setters, but instead of returning nil when a step fails, it records the
+// reason and carries on.  The accumulated errors are returned by Err and
+// Build.  See also comid.ComidBuilder and cots.ConciseTaStoreBuilder.
+type UnsignedCorimBuilder struct {
+	corim *UnsignedCorim
+	errs  []error
+}
+
+// NewUnsignedCorimBuilder instantiates a builder for an empty UnsignedCorim
+func NewUnsignedCorimBuilder() *UnsignedCorimBuilder {
+	return &UnsignedCorimBuilder{corim: NewUnsignedCorim()}
+}
+
+func (o *UnsignedCorimBuilder) check(step string, err error) *UnsignedCorimBuilder {
+	if err != nil {
+		o.errs = append(o.errs, fmt.Errorf("%s: %w", step, err))
+	}
+	return o
+}
+
+// SetID is the builder equivalent of UnsignedCorim.SetID
+func (o *UnsignedCorimBuilder) SetID(v interface{}) *UnsignedCorimBuilder {
+	return o.check("SetID", o.corim.setID(v))
+}
+
+// AddComid is the builder equivalent of UnsignedCorim.AddComid
+func (o *UnsignedCorimBuilder) AddComid(c *comid.Comid) *UnsignedCorimBuilder {
+	return o.check("AddComid", o.corim.addComid(c))
+}
+
+// AddComidBuilder adds the CoMID assembled by the supplied builder.  Errors
+// recorded by the CoMID builder are added to those of the target.
+func (o *UnsignedCorimBuilder) AddComidBuilder(b *comid.ComidBuilder) *UnsignedCorimBuilder {
+	c, err := b.Build()
+	if err != nil {
+		return o.check("AddComidBuilder", err)
+	}
+	return o.check("AddComidBuilder", o.corim.addComid(c))
+}
+
+// AddCots is the builder equivalent of UnsignedCorim.AddCots
+func (o *UnsignedCorimBuilder) AddCots(c *cots.ConciseTaStore) *UnsignedCorimBuilder {
+	return o.check("AddCots", o.corim.addCots(c))
+}
+
+// AddCotsBuilder adds the CoTS assembled by the supplied builder.  Errors
+// recorded by the CoTS builder are added to those of the target.
+func (o *UnsignedCorimBuilder) AddCotsBuilder(b *cots.ConciseTaStoreBuilder) *UnsignedCorimBuilder {
+	c, err := b.Build()
+	if err != nil {
+		return o.check("AddCotsBuilder", err)
+	}
+	return o.check("AddCotsBuilder", o.corim.addCots(c))
+}
+
+// AddCotl is the builder equivalent of UnsignedCorim.AddCotl
+func (o *UnsignedCorimBuilder) AddCotl(c *cotl.ConciseTagList) *UnsignedCorimBuilder {
+	return o.check("AddCotl", o.corim.addCotl(c))
+}
+
+// AddCoswid is the builder equivalent of UnsignedCorim.AddCoswid
+func (o *UnsignedCorimBuilder) AddCoswid(c *swid.SoftwareIdentity) *UnsignedCorimBuilder {
+	return o.check("AddCoswid", o.corim.addCoswid(c))
+}
+
+// AddDependentRim is the builder equivalent of UnsignedCorim.AddDependentRim
+func (o *UnsignedCorimBuilder) AddDependentRim(href string, thumbprint *swid.HashEntry) *UnsignedCorimBuilder {
+	return o.check("AddDependentRim", o.corim.addDependentRim(href, thumbprint))
+}
+
+// AddLocator is the builder equivalent of UnsignedCorim.AddLocator
+func (o *UnsignedCorimBuilder) AddLocator(l Locator) *UnsignedCorimBuilder {
+	return o.check("AddLocator", o.corim.addLocator(l))
+}
+
+// SetProfile is the builder equivalent of UnsignedCorim.SetProfile
+func (o *UnsignedCorimBuilder) SetProfile(urlOrOID string) *UnsignedCorimBuilder {
+	return o.check("SetProfile", o.corim.setProfile(urlOrOID))
+}
+
+// SetRimValidity is the builder equivalent of UnsignedCorim.SetRimValidity
+func (o *UnsignedCorimBuilder) SetRimValidity(notAfter time.Time, notBefore *time.Time) *UnsignedCorimBuilder {
+	return o.check("SetRimValidity", o.corim.setRimValidity(notAfter, notBefore))
+}
+
+// AddEntity is the builder equivalent of UnsignedCorim.AddEntity
+func (o *UnsignedCorimBuilder) AddEntity(name string, regID *string, roles ...Role) *UnsignedCorimBuilder {
+	return o.check("AddEntity", o.corim.addEntity(name, regID, roles...))
+}
+
+// Err returns the errors recorded so far, or nil if there are none
+func (o *UnsignedCorimBuilder) Err() error {
+	return errors.Join(o.errs...)
+}
+
+// Build returns the assembled UnsignedCorim after checking its validity.  If
+// any of the preceding steps failed, the recorded errors are returned instead.
+func (o *UnsignedCorimBuilder) Build() (*UnsignedCorim, error) {
+	if err := o.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := o.corim.Valid(); err != nil {
+		return nil, err
+	}
+
+	return o.corim, nil
+}
+
+// EntityBuilder provides the same fluent interface as the Entity setters,
+// recording errors rather than returning nil.
+type EntityBuilder struct {
+	entity *Entity
+	errs   []error
+}
+
+// NewEntityBuilder instantiates a builder for an empty Entity
+func NewEntityBuilder() *EntityBuilder {
+	return &EntityBuilder{entity: NewEntity()}
+}
+
+func (o *EntityBuilder) check(step string, err error) *EntityBuilder {
+	if err != nil {
+		o.errs = append(o.errs, fmt.Errorf("%s: %w", step, err))
+	}
+	return o
+}
+
+// SetName is the builder equivalent of Entity.SetName
+func (o *EntityBuilder) SetName(name any) *EntityBuilder {
+	return o.check("SetName", o.entity.setName(name))
+}
+
+// SetRegID is the builder equivalent of Entity.SetRegID
+func (o *EntityBuilder) SetRegID(uri string) *EntityBuilder {
+	return o.check("SetRegID", o.entity.setRegID(uri))
+}
+
+// SetRoles is the builder equivalent of Entity.SetRoles
+func (o *EntityBuilder) SetRoles(roles ...Role) *EntityBuilder {
+	return o.check("SetRoles", o.entity.setRoles(roles...))
+}
+
+// Err returns the errors recorded so far, or nil if there are none
+func (o *EntityBuilder) Err() error {
+	return errors.Join(o.errs...)
+}
+
+// Build returns the assembled Entity after checking its validity.  If any of
+// the preceding steps failed, the recorded errors are returned instead.
+func (o *EntityBuilder) Build() (*Entity, error) {
+	if err := o.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := o.entity.Valid(); err != nil {
+		return nil, err
+	}
+
+	return o.entity, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/extensions"
+)
+
+func testComidBuilder() *comid.ComidBuilder {
+	return comid.NewComidBuilder().
+		SetTagIdentity(comid.TestTagID, 0).
+		AddAttestVerifKey(
+			comid.KeyTriple{
+				Environment: comid.Environment{
+					Instance: comid.MustNewUUIDInstance(comid.TestUUID),
+				},
+				VerifKeys: *comid.NewCryptoKeys().
+					Add(
+						comid.MustNewPKIXBase64Key(comid.TestECPubKey),
+					),
+			},
+		)
+}
+
+func TestUnsignedCorimBuilder_ok(t *testing.T) {
+	regID := "https://acme.example"
+
+	u, err := NewUnsignedCorimBuilder().
+		SetID("test.corim").
+		AddDependentRim("http://endorser.example/addon.corim", nil).
+		SetProfile("https://arm.com/psa/iot/2.0.0").
+		AddComidBuilder(testComidBuilder()).
+		SetRimValidity(time.Now().Add(time.Hour), nil).
+		AddEntity("ACME Ltd.", &regID, RoleManifestCreator).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "test.corim", u.GetID())
+	assert.Len(t, u.Tags, 1)
+	assert.Len(t, u.Entities.Values, 1)
+	assert.NotNil(t, u.RimValidity)
+}
+
+func TestUnsignedCorimBuilder_errors(t *testing.T) {
+	regID := "acme.example"
+	notBefore := time.Now().Add(time.Hour)
+
+	b := NewUnsignedCorimBuilder().
+		SetID("").
+		SetProfile("").
+		AddComidBuilder(comid.NewComidBuilder().SetTagIdentity(7, 0)).
+		AddComid(comid.NewComid()).
+		SetRimValidity(time.Now(), &notBefore).
+		AddEntity("ACME Ltd.", &regID, RoleManifestCreator).
+		AddEntity("ACME Ltd.", nil, Role(99))
+
+	err := b.Err()
+	require.Error(t, err)
+
+	for _, msg := range []string{
+		"SetID: empty tag-id",
+		"SetProfile: ",
+		"AddComidBuilder: SetTagIdentity: unsupported tag-id type int",
+		"AddComid: invalid CoMID: tag-identity validation failed: empty tag-id",
+		"SetRimValidity: invalid not-before / not-after: negative delta",
+		`AddEntity: expecting an absolute URI: "acme.example" is not an absolute URI`,
+		"AddEntity: unknown role in [Role(99)]",
+	} {
+		assert.Contains(t, err.Error(), msg)
+	}
+
+	u, buildErr := b.Build()
+	assert.Nil(t, u)
+	assert.Equal(t, err, buildErr)
+}
+
+func TestUnsignedCorimBuilder_invalid(t *testing.T) {
+	b := NewUnsignedCorimBuilder().SetID("test.corim")
+	require.NoError(t, b.Err())
+
+	_, err := b.Build()
+	assert.EqualError(t, err, "tags validation failed: no tags")
+}
+
+func TestUnsignedCorimBuilder_locator_errors(t *testing.T) {
+	b := NewUnsignedCorimBuilder().SetID("test.corim")
+
+	// extensions that cannot be registered with a Locator
+	b.corim.locatorExts = extensions.Map{ExtUnsignedCorim: &struct{}{}}
+
+	b.AddDependentRim("https://example.com/rim", nil).
+		AddLocator(Locator{Href: comid.TaggedURI("https://example.com/other")})
+
+	assert.EqualError(t, b.Err(), `AddDependentRim: unexpected extension point: "UnsignedCorim"
+AddLocator: unexpected extension point: "UnsignedCorim"`)
+	assert.Nil(t, b.corim.DependentRims)
+}
+
+func TestEntityBuilder(t *testing.T) {
+	e, err := NewEntityBuilder().
+		SetName("ACME Ltd.").
+		SetRegID("https://acme.example").
+		SetRoles(RoleManifestCreator).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, "ACME Ltd.", e.Name.String())
+
+	_, err = NewEntityBuilder().
+		SetName(7).
+		SetRegID("").
+		Build()
+	assert.EqualError(t, err, `SetName: unexpected type for string entity name: int
+SetRegID: empty reg-id`)
+}
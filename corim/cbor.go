@@ -9,6 +9,7 @@ import (
 
 	cbor "github.com/fxamacker/cbor/v2"
 	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/encoding"
 )
 
 var (
@@ -17,9 +18,11 @@ var (
 )
 
 var (
-	UnsignedCorimTag = []byte{0xd9, 0x01, 0xf5} // 501()
-	CoswidTag        = []byte{0xd9, 0x01, 0xf9} // 505()
-	ComidTag         = []byte{0xd9, 0x01, 0xfa} // 506()
+	CorimTypeChoiceTag = []byte{0xd9, 0x01, 0xf4} // 500()
+	UnsignedCorimTag   = []byte{0xd9, 0x01, 0xf5} // 501()
+	SignedCorimTag     = []byte{0xd9, 0x01, 0xf6} // 502()
+	CoswidTag          = []byte{0xd9, 0x01, 0xf9} // 505()
+	ComidTag           = []byte{0xd9, 0x01, 0xfa} // 506()
 
 	corimTagsMap = map[uint64]interface{}{
 		32:  comid.TaggedURI(""),
@@ -57,7 +60,7 @@ func initCBORDecMode() (dm cbor.DecMode, err error) {
 		IndefLength: cbor.IndefLengthForbidden,
 		TimeTag:     cbor.DecTagRequired,
 	}
-	return decOpt.DecModeWithTags(corimTags())
+	return encoding.NewDecMode(decOpt, corimTags())
 }
 
 func registerCORIMTag(tag uint64, t interface{}) error {
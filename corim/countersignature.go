@@ -0,0 +1,384 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+
+	cbor "github.com/fxamacker/cbor/v2"
+	cose "github.com/veraison/go-cose"
+)
+
+// HeaderLabelCountersignature is the COSE header parameter that carries
+// COSE_Countersignature (RFC 9338)
+var HeaderLabelCountersignature = int64(11)
+
+// countersignatureContext is the context string used in the Countersign_structure
+// of a full countersignature over a COSE_Sign1 (RFC 9338, Section 3.3)
+const countersignatureContext = "CounterSignatureV2"
+
+// Countersignature is a COSE countersignature (RFC 9338) over the signature of
+// a signed-corim.  It is carried in the unprotected header of the COSE_Sign1
+// and can be added by a party other than the CoRIM signer (e.g., a compliance
+// service) without re-signing the payload.
+type Countersignature struct {
+	// Headers are the countersignature's protected and unprotected headers
+	Headers cose.Headers
+	// Signature is the countersignature value
+	Signature []byte
+}
+
+// Algorithm returns the algorithm of the countersignature, as found in its
+// protected header
+func (o Countersignature) Algorithm() (cose.Algorithm, error) {
+	return o.Headers.Protected.Algorithm()
+}
+
+// Countersign adds a countersignature, made with the supplied signer, to the
+// target SignedCorim and returns the re-serialized signed-corim.  The target
+// must have been either signed or decoded (with its payload) beforehand.
+// Optional protected header parameters for the countersignature (e.g., a key
+// identifier) can be supplied via headers.  Existing countersignatures are
+// preserved.
+func (o *SignedCorim) Countersign(signer cose.Signer, headers cose.ProtectedHeader) ([]byte, error) {
+	if o.message == nil {
+		return nil, errors.New("no Sign1 message found")
+	}
+
+	cs, err := countersignSign1(o.message, signer, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	o.countersignatures = append(o.countersignatures, *cs)
+
+	if err := setCountersignatures(&o.message.Headers, o.countersignatures); err != nil {
+		return nil, err
+	}
+
+	return o.marshal()
+}
+
+// Countersignatures returns the countersignatures found in the target
+// SignedCorim
+func (o SignedCorim) Countersignatures() []Countersignature {
+	return o.countersignatures
+}
+
+// VerifyCountersignature verifies the countersignature at the supplied index
+// using the supplied public key
+func (o *SignedCorim) VerifyCountersignature(index int, pk crypto.PublicKey) error {
+	if o.message == nil {
+		return errors.New("no Sign1 message found")
+	}
+
+	if index < 0 || index >= len(o.countersignatures) {
+		return fmt.Errorf("no countersignature at index %d", index)
+	}
+
+	return verifyCountersignature(o.message, o.countersignatures[index], pk)
+}
+
+// VerifyCountersignatures checks every countersignature of the target
+// SignedCorim against each of the supplied public keys and returns one
+// SignatureResult per countersignature.  A key verifies at most one
+// countersignature.  Note that the signature of the signed-corim itself is not
+// verified: use Verify for that.
+func (o *SignedCorim) VerifyCountersignatures(pks ...crypto.PublicKey) ([]SignatureResult, error) {
+	if o.message == nil {
+		return nil, errors.New("no Sign1 message found")
+	}
+
+	used := make([]bool, len(pks))
+	results := make([]SignatureResult, len(o.countersignatures))
+
+	for i, cs := range o.countersignatures {
+		res := SignatureResult{
+			Index:    i,
+			KeyIndex: -1,
+			Err:      errors.New("no matching verification key"),
+		}
+
+		for j, pk := range pks {
+			if used[j] {
+				continue
+			}
+
+			if err := verifyCountersignature(o.message, cs, pk); err != nil {
+				continue
+			}
+
+			used[j] = true
+			res.KeyIndex = j
+			res.Err = nil
+			break
+		}
+
+		results[i] = res
+	}
+
+	return results, nil
+}
+
+// AddCountersignature adds a countersignature, made with the supplied signer,
+// to the supplied CBOR-encoded COSE_Sign1 signed-corim without decoding the
+// embedded unsigned-corim.  The signed-corim tags (if any) are preserved.
+// Signed-corims with a detached payload must be decoded with
+// SignedCorim.FromCOSEWithPayload and countersigned using
+// SignedCorim.Countersign instead.
+func AddCountersignature(buf []byte, signer cose.Signer, headers cose.ProtectedHeader) ([]byte, error) {
+	stripped, tagged, err := stripSignedCorimTags(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
+	}
+
+	message := cose.NewSign1Message()
+	if err := message.UnmarshalCBOR(stripped); err != nil {
+		return nil, fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
+	}
+
+	if message.Payload == nil {
+		return nil, errors.New("detached payload: cannot countersign without the payload")
+	}
+
+	sigs, err := countersignaturesFromHeaders(message.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := countersignSign1(message, signer, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setCountersignatures(&message.Headers, append(sigs, *cs)); err != nil {
+		return nil, err
+	}
+
+	o := SignedCorim{message: message, tagged: tagged}
+
+	return o.marshal()
+}
+
+// countersignSign1 computes a full countersignature (RFC 9338) over the
+// supplied (signed) COSE_Sign1 message
+func countersignSign1(
+	message *cose.Sign1Message,
+	signer cose.Signer,
+	headers cose.ProtectedHeader,
+) (*Countersignature, error) {
+	if signer == nil {
+		return nil, errors.New("nil signer")
+	}
+
+	if len(message.Signature) == 0 {
+		return nil, errors.New("the signed-corim has no signature to countersign")
+	}
+
+	alg := signer.Algorithm()
+	if strings.Contains(alg.String(), "unknown algorithm value") {
+		return nil, errors.New("signer has no algorithm")
+	}
+
+	cs := Countersignature{
+		Headers: cose.Headers{
+			Protected:   cose.ProtectedHeader{},
+			Unprotected: cose.UnprotectedHeader{},
+		},
+	}
+
+	for k, v := range headers {
+		cs.Headers.Protected[k] = v
+	}
+	cs.Headers.Protected.SetAlgorithm(alg)
+
+	toBeSigned, err := countersignToBeSigned(message, &cs)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.Signature, err = signer.Sign(rand.Reader, toBeSigned)
+	if err != nil {
+		return nil, fmt.Errorf("COSE countersignature failed: %w", err)
+	}
+
+	return &cs, nil
+}
+
+func verifyCountersignature(message *cose.Sign1Message, cs Countersignature, pk crypto.PublicKey) error {
+	alg, err := cs.Algorithm()
+	if err != nil {
+		return fmt.Errorf("unable to get verification algorithm: %w", err)
+	}
+
+	verifier, err := cose.NewVerifier(alg, pk)
+	if err != nil {
+		return fmt.Errorf("unable to instantiate verifier: %w", err)
+	}
+
+	toBeSigned, err := countersignToBeSigned(message, &cs)
+	if err != nil {
+		return err
+	}
+
+	return verifier.Verify(toBeSigned, cs.Signature)
+}
+
+// countersignToBeSigned builds the Countersign_structure for a full
+// countersignature over a COSE_Sign1 (RFC 9338, Section 3.3):
+//
+//	[ "CounterSignatureV2", body_protected, sign_protected, external_aad,
+//	  payload, [ signature ] ]
+func countersignToBeSigned(message *cose.Sign1Message, cs *Countersignature) ([]byte, error) {
+	bodyProtected, err := message.Headers.MarshalProtected()
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode body protected header: %w", err)
+	}
+
+	signProtected, err := cs.Headers.MarshalProtected()
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode countersignature protected header: %w", err)
+	}
+	// make sure the exact bytes are used when the countersignature is
+	// serialized
+	cs.Headers.RawProtected = signProtected
+
+	payload := message.Payload
+	if payload == nil {
+		return nil, errors.New("missing payload")
+	}
+
+	return em.Marshal([]interface{}{
+		countersignatureContext,
+		cbor.RawMessage(bodyProtected),
+		cbor.RawMessage(signProtected),
+		NoExternalData,
+		payload,
+		[]interface{}{message.Signature},
+	})
+}
+
+// countersignature is the COSE_Countersignature array
+type countersignature struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   cbor.RawMessage
+	Unprotected cbor.RawMessage
+	Signature   []byte
+}
+
+// countersignaturesFromHeaders extracts the countersignatures (if any) from
+// the unprotected header of a COSE_Sign1 message.  Both the single
+// COSE_Countersignature and the array of COSE_Countersignature forms are
+// accepted.
+func countersignaturesFromHeaders(hdr cose.Headers) ([]Countersignature, error) {
+	var raw cbor.RawMessage
+
+	if len(hdr.RawUnprotected) > 0 {
+		var m map[interface{}]cbor.RawMessage
+		if err := dm.Unmarshal(hdr.RawUnprotected, &m); err != nil {
+			return nil, fmt.Errorf("decoding unprotected header: %w", err)
+		}
+
+		for k, v := range m {
+			if label, ok := k.(uint64); ok && int64(label) == HeaderLabelCountersignature {
+				raw = v
+			}
+		}
+	} else if v, ok := hdr.Unprotected[HeaderLabelCountersignature]; ok {
+		var err error
+		if raw, err = em.Marshal(v); err != nil {
+			return nil, fmt.Errorf("encoding countersignature: %w", err)
+		}
+	}
+
+	if raw == nil {
+		return nil, nil
+	}
+
+	var items []cbor.RawMessage
+	if err := dm.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("invalid countersignature: %w", err)
+	}
+
+	// a single COSE_Countersignature starts with its protected header (a bstr),
+	// whereas an array of them starts with an array
+	if len(items) > 0 && len(items[0]) > 0 && items[0][0]>>5 == 2 {
+		items = []cbor.RawMessage{raw}
+	}
+
+	ret := make([]Countersignature, 0, len(items))
+
+	for i, item := range items {
+		var cs countersignature
+		if err := dm.Unmarshal(item, &cs); err != nil {
+			return nil, fmt.Errorf("invalid countersignature at index %d: %w", i, err)
+		}
+
+		headers := cose.Headers{
+			RawProtected:   cs.Protected,
+			RawUnprotected: cs.Unprotected,
+		}
+
+		if err := headers.UnmarshalFromRaw(); err != nil {
+			return nil, fmt.Errorf("invalid countersignature at index %d: %w", i, err)
+		}
+
+		if len(cs.Signature) == 0 {
+			return nil, fmt.Errorf("invalid countersignature at index %d: empty signature", i)
+		}
+
+		ret = append(ret, Countersignature{Headers: headers, Signature: cs.Signature})
+	}
+
+	return ret, nil
+}
+
+// setCountersignatures stores the supplied countersignatures in the
+// unprotected header of a COSE_Sign1 message, using the single
+// COSE_Countersignature form if there is only one
+func setCountersignatures(hdr *cose.Headers, sigs []Countersignature) error {
+	items := make([]countersignature, 0, len(sigs))
+
+	for i, s := range sigs {
+		protected, err := s.Headers.MarshalProtected()
+		if err != nil {
+			return fmt.Errorf("countersignature at index %d: %w", i, err)
+		}
+
+		unprotected, err := s.Headers.MarshalUnprotected()
+		if err != nil {
+			return fmt.Errorf("countersignature at index %d: %w", i, err)
+		}
+
+		items = append(items, countersignature{
+			Protected:   protected,
+			Unprotected: unprotected,
+			Signature:   s.Signature,
+		})
+	}
+
+	var value interface{} = items
+	if len(items) == 1 {
+		value = items[0]
+	}
+
+	raw, err := em.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding countersignatures: %w", err)
+	}
+
+	if hdr.Unprotected == nil {
+		hdr.Unprotected = cose.UnprotectedHeader{}
+	}
+
+	hdr.Unprotected[HeaderLabelCountersignature] = cbor.RawMessage(raw)
+	// force re-encoding of the unprotected header
+	hdr.RawUnprotected = nil
+
+	return nil
+}
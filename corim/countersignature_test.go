@@ -0,0 +1,183 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+)
+
+func signedCorimForCountersigning(t *testing.T, opts SignOptions) (*SignedCorim, []byte) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	in := SignedCorim{
+		UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR),
+		Meta:          *metaGood(t),
+	}
+
+	data, err := in.SignWithOptions(signer, opts)
+	require.NoError(t, err)
+
+	return &in, data
+}
+
+func TestSignedCorim_Countersign_ok(t *testing.T) {
+	_, data := signedCorimForCountersigning(t, SignOptions{})
+
+	var in SignedCorim
+	require.NoError(t, in.FromCOSE(data))
+	assert.Empty(t, in.Countersignatures())
+
+	countersigner, err := NewSignerFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	countersigned, err := in.Countersign(
+		countersigner,
+		cose.ProtectedHeader{cose.HeaderLabelKeyID: []byte("compliance-key")},
+	)
+	require.NoError(t, err)
+
+	var out SignedCorim
+	require.NoError(t, out.FromCOSE(countersigned))
+
+	// the original signature is untouched
+	assert.NoError(t, out.Verify(publicKeyFromJWK(t, testES256Key)))
+
+	require.Len(t, out.Countersignatures(), 1)
+	cs := out.Countersignatures()[0]
+	alg, err := cs.Algorithm()
+	require.NoError(t, err)
+	assert.Equal(t, cose.AlgorithmEd25519, alg)
+	assert.Equal(t, []byte("compliance-key"), cs.Headers.Protected[cose.HeaderLabelKeyID])
+
+	assert.NoError(t, out.VerifyCountersignature(0, publicKeyFromJWK(t, testEdDSAKey)))
+	assert.Error(t, out.VerifyCountersignature(0, publicKeyFromJWK(t, testES256Key)))
+	assert.EqualError(t, out.VerifyCountersignature(1, publicKeyFromJWK(t, testEdDSAKey)),
+		"no countersignature at index 1")
+}
+
+func TestSignedCorim_Countersign_multiple(t *testing.T) {
+	_, data := signedCorimForCountersigning(t, SignOptions{Tagged: true})
+
+	first, err := NewSignerFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	second, err := NewSignerFromJWK(testES384Key)
+	require.NoError(t, err)
+
+	data, err = AddCountersignature(data, first, nil)
+	require.NoError(t, err)
+	assert.Equal(t, SignedCorimTag, data[:3])
+
+	data, err = AddCountersignature(data, second, nil)
+	require.NoError(t, err)
+
+	var out SignedCorim
+	require.NoError(t, out.FromCOSE(data))
+	assert.NoError(t, out.Verify(publicKeyFromJWK(t, testES256Key)))
+	require.Len(t, out.Countersignatures(), 2)
+
+	results, err := out.VerifyCountersignatures(
+		publicKeyFromJWK(t, testES384Key),
+		publicKeyFromJWK(t, testEdDSAKey),
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Verified())
+	assert.Equal(t, 1, results[0].KeyIndex)
+	assert.True(t, results[1].Verified())
+	assert.Equal(t, 0, results[1].KeyIndex)
+
+	// countersignatures survive a decode/encode round trip
+	third, err := NewSignerFromJWK(testPS256Key)
+	require.NoError(t, err)
+
+	data, err = out.Countersign(third, nil)
+	require.NoError(t, err)
+	assert.Equal(t, SignedCorimTag, data[:3])
+
+	var again SignedCorim
+	require.NoError(t, again.FromCOSE(data))
+	require.Len(t, again.Countersignatures(), 3)
+
+	results, err = again.VerifyCountersignatures(
+		publicKeyFromJWK(t, testEdDSAKey),
+		publicKeyFromJWK(t, testES384Key),
+		publicKeyFromJWK(t, testPS256Key),
+	)
+	require.NoError(t, err)
+	for _, r := range results {
+		assert.True(t, r.Verified(), "countersignature at index %d", r.Index)
+	}
+}
+
+func TestSignedCorim_Countersign_detached(t *testing.T) {
+	in, data := signedCorimForCountersigning(t, SignOptions{Detached: true})
+
+	countersigner, err := NewSignerFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	_, err = AddCountersignature(data, countersigner, nil)
+	assert.EqualError(t, err, "detached payload: cannot countersign without the payload")
+
+	payload, err := in.UnsignedCorim.ToCBOR()
+	require.NoError(t, err)
+
+	var decoded SignedCorim
+	require.NoError(t, decoded.FromCOSEWithPayload(data, payload))
+
+	countersigned, err := decoded.Countersign(countersigner, nil)
+	require.NoError(t, err)
+
+	var out SignedCorim
+	require.NoError(t, out.FromCOSEWithPayload(countersigned, payload))
+	assert.True(t, out.IsDetached())
+	assert.NoError(t, out.Verify(publicKeyFromJWK(t, testES256Key)))
+	assert.NoError(t, out.VerifyCountersignature(0, publicKeyFromJWK(t, testEdDSAKey)))
+}
+
+func TestSignedCorim_Countersign_tampered(t *testing.T) {
+	_, data := signedCorimForCountersigning(t, SignOptions{})
+
+	countersigner, err := NewSignerFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	data, err = AddCountersignature(data, countersigner, nil)
+	require.NoError(t, err)
+
+	var out SignedCorim
+	require.NoError(t, out.FromCOSE(data))
+
+	// tamper with the countersigned signature
+	out.message.Signature[0] ^= 0xff
+	assert.Error(t, out.VerifyCountersignature(0, publicKeyFromJWK(t, testEdDSAKey)))
+
+	results, err := out.VerifyCountersignatures(publicKeyFromJWK(t, testEdDSAKey))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Verified())
+	assert.EqualError(t, results[0].Err, "no matching verification key")
+}
+
+func TestSignedCorim_Countersign_fail(t *testing.T) {
+	var empty SignedCorim
+
+	_, err := empty.Countersign(nil, nil)
+	assert.EqualError(t, err, "no Sign1 message found")
+
+	_, err = empty.VerifyCountersignatures()
+	assert.EqualError(t, err, "no Sign1 message found")
+
+	in, _ := signedCorimForCountersigning(t, SignOptions{})
+
+	_, err = in.Countersign(nil, nil)
+	assert.EqualError(t, err, "nil signer")
+
+	_, err = AddCountersignature([]byte{0xf6}, nil, nil)
+	assert.ErrorContains(t, err, "failed CBOR decoding for COSE-Sign1 signed CoRIM")
+}
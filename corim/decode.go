@@ -0,0 +1,263 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/veraison/corim/encoding"
+)
+
+// CBOR tag numbers used by the corim type choices
+const (
+	CBORTagCorimTypeChoice uint64 = 500
+	CBORTagUnsignedCorim   uint64 = 501
+	CBORTagSignedCorim     uint64 = 502
+	CBORTagCOSESign1       uint64 = 18
+	CBORTagCOSESign        uint64 = 98
+)
+
+// Kind identifies the variant of a decoded CoRIM
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	// KindUnsigned is an unsigned-corim-map
+	KindUnsigned
+	// KindSigned is a COSE_Sign1 signed-corim
+	KindSigned
+	// KindMultiSigned is a COSE_Sign signed-corim
+	KindMultiSigned
+)
+
+func (o Kind) String() string {
+	switch o {
+	case KindUnsigned:
+		return "unsigned"
+	case KindSigned:
+		return "signed"
+	case KindMultiSigned:
+		return "multi-signed"
+	default:
+		return "unknown"
+	}
+}
+
+// Decoded is the result of Decode.  Depending on Kind, exactly one of
+// UnsignedCorim, SignedCorim or MultiSignedCorim is set.
+type Decoded struct {
+	Kind Kind
+	// Tagged is true if the input was wrapped in one of the corim type
+	// choice tags (#6.500, #6.501 or #6.502)
+	Tagged bool
+
+	UnsignedCorim    *UnsignedCorim
+	SignedCorim      *SignedCorim
+	MultiSignedCorim *MultiSignedCorim
+}
+
+// GetUnsignedCorim returns the unsigned-corim carried by the target Decoded,
+// regardless of whether it was signed or not
+func (o Decoded) GetUnsignedCorim() *UnsignedCorim {
+	switch o.Kind {
+	case KindUnsigned:
+		return o.UnsignedCorim
+	case KindSigned:
+		return &o.SignedCorim.UnsignedCorim
+	case KindMultiSigned:
+		return &o.MultiSignedCorim.UnsignedCorim
+	default:
+		return nil
+	}
+}
+
+// Decode is the single entry point for decoding CBOR-encoded CoRIMs of any
+// kind.  It detects whether the supplied data is a signed (COSE_Sign1 or
+// COSE_Sign) or an unsigned CoRIM, and whether it is wrapped in the corim type
+// choice tags (#6.500, #6.501, #6.502), then decodes it with the extensions of
+// the profile it declares (if registered).  Untagged COSE_Sign1 messages are
+// accepted too.
+func Decode(buf []byte) (*Decoded, error) {
+	var ret Decoded
+
+	tags, rest, err := peelTags(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(tags) > 0 && tags[0] == CBORTagCorimTypeChoice {
+		ret.Tagged = true
+		tags = tags[1:]
+	}
+
+	if len(rest) == 0 {
+		return nil, errors.New("empty input")
+	}
+
+	majorType := rest[0] >> 5
+
+	switch {
+	case len(tags) == 0 && majorType == 5: // untagged map
+		ret.Kind = KindUnsigned
+	case len(tags) == 1 && tags[0] == CBORTagUnsignedCorim && majorType == 5:
+		ret.Kind = KindUnsigned
+		ret.Tagged = true
+	case len(tags) == 0 && majorType == 4: // untagged COSE_Sign1
+		ret.Kind = KindSigned
+	case len(tags) == 1 && tags[0] == CBORTagCOSESign1:
+		ret.Kind = KindSigned
+	case len(tags) == 2 && tags[0] == CBORTagSignedCorim && tags[1] == CBORTagCOSESign1:
+		ret.Kind = KindSigned
+		ret.Tagged = true
+	case len(tags) == 1 && tags[0] == CBORTagSignedCorim && majorType == 4:
+		ret.Kind = KindSigned
+		ret.Tagged = true
+	case len(tags) == 1 && tags[0] == CBORTagCOSESign:
+		ret.Kind = KindMultiSigned
+	default:
+		return nil, fmt.Errorf("unrecognized CoRIM type: tags %v, CBOR major type %d", tags, majorType)
+	}
+
+	switch ret.Kind {
+	case KindUnsigned:
+		ret.UnsignedCorim, err = UnmarshalUnsignedCorimFromCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+	case KindSigned:
+		ret.SignedCorim, err = UnmarshalSignedCorimFromCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+	case KindMultiSigned:
+		ret.MultiSignedCorim, err = UnmarshalMultiSignedCorimFromCBOR(append([]byte{0xd8, 0x62}, rest...))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ret, nil
+}
+
+// Canonicalize re-encodes the CBOR-encoded unsigned CoRIM in buf, including
+// the tags it carries, as per the core deterministic encoding requirements of
+// RFC 8949 (see encoding.CanonicalizeCBOR).  The CoRIM is not decoded, so
+// entries unknown to this package are preserved.  Signed CoRIMs are rejected,
+// as re-encoding their payload would invalidate the signature.
+func Canonicalize(buf []byte) ([]byte, error) {
+	tags, rest, err := peelTags(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(tags) > 0 && tags[0] == CBORTagCorimTypeChoice {
+		tags = tags[1:]
+	}
+
+	if len(rest) == 0 {
+		return nil, errors.New("empty input")
+	}
+
+	isMap := rest[0]>>5 == 5
+
+	switch {
+	case len(tags) == 0 && isMap, len(tags) == 1 && tags[0] == CBORTagUnsignedCorim && isMap:
+		return encoding.CanonicalizeCBOR(buf, UnsignedCorim{})
+	case len(tags) == 0 && rest[0]>>5 == 4, len(tags) != 0 &&
+		(tags[0] == CBORTagSignedCorim || tags[0] == CBORTagCOSESign1 || tags[0] == CBORTagCOSESign):
+		return nil, errors.New("signed CoRIMs cannot be canonicalized without invalidating their signature")
+	default:
+		return nil, fmt.Errorf("unrecognized CoRIM type: tags %v, CBOR major type %d", tags, rest[0]>>5)
+	}
+}
+
+// stripSignedCorimTags removes the optional tagged-corim-type-choice (#6.500)
+// and tagged-signed-corim (#6.502) tags from the supplied signed-corim, and
+// makes sure the remaining COSE_Sign1 is tagged with #6.18.  It also reports
+// whether the tagged-signed-corim tag was found.
+func stripSignedCorimTags(buf []byte) ([]byte, bool, error) {
+	tags, rest, err := peelTags(buf)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tagged := false
+
+	for len(tags) > 0 &&
+		(tags[0] == CBORTagCorimTypeChoice || tags[0] == CBORTagSignedCorim) {
+		if tags[0] == CBORTagSignedCorim {
+			tagged = true
+		}
+		tags = tags[1:]
+	}
+
+	switch {
+	case len(tags) == 0, len(tags) == 1 && tags[0] == CBORTagCOSESign1:
+		return coseSign1Tagged(rest), tagged, nil
+	default:
+		// let the COSE decoder report the problem
+		return buf, tagged, nil
+	}
+}
+
+// coseSign1Tagged prepends the COSE_Sign1 tag (#6.18) to the supplied
+// untagged COSE_Sign1
+func coseSign1Tagged(buf []byte) []byte {
+	return append([]byte{0xd2}, buf...)
+}
+
+// peelTags returns the sequence of CBOR tag numbers found at the beginning of
+// the supplied buffer, and the remaining (untagged) data item.
+func peelTags(buf []byte) ([]uint64, []byte, error) {
+	var tags []uint64
+
+	rest := buf
+
+	for len(rest) > 0 && rest[0]>>5 == 6 {
+		info := rest[0] & 0x1f
+		rest = rest[1:]
+
+		var (
+			num uint64
+			n   int
+		)
+
+		switch {
+		case info < 24:
+			num = uint64(info)
+		case info == 24:
+			n = 1
+		case info == 25:
+			n = 2
+		case info == 26:
+			n = 4
+		case info == 27:
+			n = 8
+		default:
+			return nil, nil, fmt.Errorf("invalid CBOR tag additional information %d", info)
+		}
+
+		if len(rest) < n {
+			return nil, nil, errors.New("unexpected EOF")
+		}
+
+		switch n {
+		case 1:
+			num = uint64(rest[0])
+		case 2:
+			num = uint64(binary.BigEndian.Uint16(rest))
+		case 4:
+			num = uint64(binary.BigEndian.Uint32(rest))
+		case 8:
+			num = binary.BigEndian.Uint64(rest)
+		}
+
+		rest = rest[n:]
+		tags = append(tags, num)
+	}
+
+	return tags, rest, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/encoding"
+)
+
+func prefixed(prefix []byte, data ...[]byte) []byte {
+	ret := append([]byte{}, prefix...)
+	for _, d := range data {
+		ret = append(ret, d...)
+	}
+	return ret
+}
+
+func TestDecode_unsigned(t *testing.T) {
+	tvs := []struct {
+		desc   string
+		input  []byte
+		tagged bool
+	}{
+		{"untagged", testGoodUnsignedCorimCBOR, false},
+		{"tagged 501", prefixed(UnsignedCorimTag, testGoodUnsignedCorimCBOR), true},
+		{"tagged 500+501", prefixed(CorimTypeChoiceTag, UnsignedCorimTag, testGoodUnsignedCorimCBOR), true},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			out, err := Decode(tv.input)
+			require.NoError(t, err)
+			assert.Equal(t, KindUnsigned, out.Kind)
+			assert.Equal(t, tv.tagged, out.Tagged)
+			require.NotNil(t, out.UnsignedCorim)
+			assert.Equal(t, "test corim id", out.GetUnsignedCorim().ID.String())
+		})
+	}
+}
+
+func TestDecode_signed(t *testing.T) {
+	tvs := []struct {
+		desc   string
+		input  []byte
+		tagged bool
+	}{
+		{"COSE_Sign1 tagged 18", testGoodSignedCorimCBOR, false},
+		{"COSE_Sign1 untagged", testGoodSignedCorimCBOR[1:], false},
+		{"tagged 502+18", prefixed(SignedCorimTag, testGoodSignedCorimCBOR), true},
+		{"tagged 502, untagged COSE_Sign1", prefixed(SignedCorimTag, testGoodSignedCorimCBOR[1:]), true},
+		{"tagged 500+502+18", prefixed(CorimTypeChoiceTag, SignedCorimTag, testGoodSignedCorimCBOR), true},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			out, err := Decode(tv.input)
+			require.NoError(t, err)
+			assert.Equal(t, KindSigned, out.Kind)
+			assert.Equal(t, tv.tagged, out.Tagged)
+			require.NotNil(t, out.SignedCorim)
+			assert.Equal(t, "ACME Ltd signing key", out.SignedCorim.Meta.Signer.Name)
+			assert.NotNil(t, out.GetUnsignedCorim())
+		})
+	}
+}
+
+func TestDecode_multi_signed(t *testing.T) {
+	var in MultiSignedCorim
+	in.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	data, err := in.Sign(corimSignerFromJWK(t, testES256Key, "ACME Silicon"))
+	require.NoError(t, err)
+
+	out, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, KindMultiSigned, out.Kind)
+	assert.False(t, out.Tagged)
+	require.NotNil(t, out.MultiSignedCorim)
+	assert.NoError(t, out.MultiSignedCorim.Verify(publicKeyFromJWK(t, testES256Key)))
+	assert.Equal(t, in.UnsignedCorim.ID, out.GetUnsignedCorim().ID)
+}
+
+func TestDecode_fail(t *testing.T) {
+	_, err := Decode(nil)
+	assert.EqualError(t, err, "empty input")
+
+	_, err = Decode(CorimTypeChoiceTag)
+	assert.EqualError(t, err, "empty input")
+
+	_, err = Decode([]byte{0xf6})
+	assert.EqualError(t, err, "unrecognized CoRIM type: tags [], CBOR major type 7")
+
+	_, err = Decode(prefixed(ComidTag, []byte{0xa0}))
+	assert.EqualError(t, err, "unrecognized CoRIM type: tags [506], CBOR major type 5")
+
+	_, err = Decode([]byte{0xd9, 0x01})
+	assert.EqualError(t, err, "unexpected EOF")
+
+	_, err = Decode([]byte{0xa1, 0x00, 0x00})
+	assert.ErrorContains(t, err, "tag-id MUST be []byte or string")
+}
+
+func TestSignedCorim_Sign_tagged(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	in := SignedCorim{
+		UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR),
+		Meta:          *metaGood(t),
+	}
+
+	data, err := in.SignWithOptions(signer, SignOptions{Tagged: true, TaggedPayload: true})
+	require.NoError(t, err)
+	assert.Equal(t, SignedCorimTag, data[:3])
+	assert.Equal(t, byte(0xd2), data[3])
+
+	var out SignedCorim
+	require.NoError(t, out.FromCOSE(data))
+	assert.Equal(t, UnsignedCorimTag, out.message.Payload[:3])
+	assert.Equal(t, in.UnsignedCorim.ID, out.UnsignedCorim.ID)
+	assert.NoError(t, out.Verify(publicKeyFromJWK(t, testES256Key)))
+
+	unmarshaled, err := UnmarshalSignedCorimFromCBOR(data)
+	require.NoError(t, err)
+	assert.NoError(t, unmarshaled.Verify(publicKeyFromJWK(t, testES256Key)))
+
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, KindSigned, decoded.Kind)
+	assert.True(t, decoded.Tagged)
+}
+
+func TestUnsignedCorim_ToTaggedCBOR(t *testing.T) {
+	in := unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	data, err := in.ToTaggedCBOR()
+	require.NoError(t, err)
+	assert.Equal(t, prefixed(UnsignedCorimTag, testGoodUnsignedCorimCBOR), data)
+
+	var out UnsignedCorim
+	require.NoError(t, out.FromCBOR(data))
+	assert.Equal(t, in.ID, out.ID)
+}
+
+func TestCanonicalize(t *testing.T) {
+	// the entity map of the embedded CoMID has its keys out of order
+	err := NewUnsignedCorim().FromCBOR(testGoodUnsignedCorimCBOR,
+		encoding.DecodeOptions{Deterministic: true})
+	assert.EqualError(t, err,
+		"non-canonical encoding at tags[0].entities[0]: map key 0 out of order")
+	assert.ErrorIs(t, err, encoding.ErrNonCanonical)
+
+	canonical, err := Canonicalize(testGoodUnsignedCorimCBOR)
+	require.NoError(t, err)
+	assert.Len(t, canonical, len(testGoodUnsignedCorimCBOR))
+
+	out := NewUnsignedCorim()
+	require.NoError(t, out.FromCBOR(canonical, encoding.DecodeOptions{Deterministic: true}))
+
+	expected := NewUnsignedCorim()
+	require.NoError(t, expected.FromCBOR(testGoodUnsignedCorimCBOR))
+
+	expectedComids, err := expected.Comids()
+	require.NoError(t, err)
+
+	actualComids, err := out.Comids()
+	require.NoError(t, err)
+	assert.Equal(t, expectedComids, actualComids)
+
+	// canonicalization is idempotent, and keeps the optional tags
+	tagged := prefixed(CorimTypeChoiceTag, UnsignedCorimTag, canonical)
+
+	again, err := Canonicalize(tagged)
+	require.NoError(t, err)
+	assert.Equal(t, tagged, again)
+}
+
+func TestCanonicalize_fail(t *testing.T) {
+	_, err := Canonicalize(testGoodSignedCorimCBOR)
+	assert.EqualError(t, err,
+		"signed CoRIMs cannot be canonicalized without invalidating their signature")
+
+	_, err = Canonicalize(prefixed(SignedCorimTag, testGoodSignedCorimCBOR))
+	assert.EqualError(t, err,
+		"signed CoRIMs cannot be canonicalized without invalidating their signature")
+
+	_, err = Canonicalize([]byte{0x01})
+	assert.EqualError(t, err, "unrecognized CoRIM type: tags [], CBOR major type 0")
+
+	_, err = Canonicalize(prefixed(UnsignedCorimTag, []byte{0xa1, 0x00}))
+	assert.ErrorContains(t, err, "malformed CBOR")
+}
@@ -12,13 +12,14 @@ import (
 	"github.com/veraison/corim/comid"
 	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
+	"github.com/veraison/corim/validation"
 )
 
 // Entity stores an entity-map capable of CBOR and JSON serializations.
 type Entity struct {
-	Name  *EntityName      `cbor:"0,keyasint" json:"name"`
-	RegID *comid.TaggedURI `cbor:"1,keyasint,omitempty" json:"regid,omitempty"`
-	Roles Roles            `cbor:"2,keyasint" json:"roles"`
+	Name  *EntityName      `cbor:"0,keyasint" json:"name" cddl:"corim.entity-name"`
+	RegID *comid.TaggedURI `cbor:"1,keyasint,omitempty" json:"regid,omitempty" cddl:"corim.reg-id"`
+	Roles Roles            `cbor:"2,keyasint" json:"roles" cddl:"corim.role"`
 
 	Extensions
 }
@@ -48,67 +49,82 @@ func (o *Entity) GetExtensions() extensions.IMapValue {
 
 // SetName is used to set the EntityName field of Entity using supplied name
 func (o *Entity) SetName(name any) *Entity {
-
 	if o != nil {
-		if name == "" {
+		if o.setName(name) != nil {
 			return nil
 		}
-		o.Name = MustNewStringEntityName(name)
 	}
 	return o
 }
 
+func (o *Entity) setName(name any) error {
+	if name == "" {
+		return fmt.Errorf("empty entity-name")
+	}
+
+	n, err := NewStringEntityName(name)
+	if err != nil {
+		return err
+	}
+
+	o.Name = n
+	return nil
+}
+
 // SetRegID is used to set the RegID field of Entity using supplied uri
 func (o *Entity) SetRegID(uri string) *Entity {
 	if o != nil {
-		if uri == "" {
+		if o.setRegID(uri) != nil {
 			return nil
 		}
+	}
+	return o
+}
 
-		taggedURI, err := comid.String2URI(&uri)
-		if err != nil {
-			return nil
-		}
+func (o *Entity) setRegID(uri string) error {
+	if uri == "" {
+		return fmt.Errorf("empty reg-id")
+	}
 
-		o.RegID = taggedURI
+	taggedURI, err := comid.String2URI(&uri)
+	if err != nil {
+		return err
 	}
-	return o
+
+	o.RegID = taggedURI
+	return nil
 }
 
 // SetRoles appends the supplied roles to the target entity.
 func (o *Entity) SetRoles(roles ...Role) *Entity {
 	if o != nil {
-		if o.Roles.Add(roles...) == nil {
+		if o.setRoles(roles...) != nil {
 			return nil
 		}
 	}
 	return o
 }
 
-// Valid checks for validity of the fields within each Entity
-func (o Entity) Valid() error {
-	if o.Name == nil {
-		return fmt.Errorf("invalid entity: empty entity-name")
-	}
-
-	if err := o.Name.Valid(); err != nil {
-		return fmt.Errorf("invalid entity: %w", err)
-	}
-
-	if o.RegID != nil && o.RegID.Empty() {
-		return fmt.Errorf("invalid entity: empty reg-id")
-	}
-
-	if err := o.Roles.Valid(); err != nil {
-		return fmt.Errorf("invalid entity: %w", err)
+func (o *Entity) setRoles(roles ...Role) error {
+	if o.Roles.Add(roles...) == nil {
+		return fmt.Errorf("unknown role in %v", roles)
 	}
+	return nil
+}
 
-	return o.Extensions.validEntity(&o)
+// Valid checks for validity of the fields within each Entity
+func (o Entity) Valid() error {
+	return validation.FirstError(o.validate)
 }
 
 // UnmarshalCBOR deserializes from CBOR
 func (o *Entity) UnmarshalCBOR(data []byte) error {
-	return encoding.PopulateStructFromCBOR(dm, data, o)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *Entity) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
 }
 
 // MarshalCBOR serializes to CBOR
@@ -118,7 +134,12 @@ func (o Entity) MarshalCBOR() ([]byte, error) {
 
 // UnmarshalJSON deserializes from JSON
 func (o *Entity) UnmarshalJSON(data []byte) error {
-	return encoding.PopulateStructFromJSON(data, o)
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *Entity) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
 }
 
 // MarshalJSON serializes to JSON
@@ -163,6 +184,10 @@ func (o *Entities) UnmarshalCBOR(data []byte) error {
 	return (*extensions.Collection[Entity, *Entity])(o).UnmarshalCBOR(data)
 }
 
+func (o *Entities) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return (*extensions.Collection[Entity, *Entity])(o).UnmarshalCBORWith(dec, data)
+}
+
 func (o Entities) MarshalJSON() ([]byte, error) {
 	return (extensions.Collection[Entity, *Entity])(o).MarshalJSON()
 }
@@ -171,6 +196,10 @@ func (o *Entities) UnmarshalJSON(data []byte) error {
 	return (*extensions.Collection[Entity, *Entity])(o).UnmarshalJSON(data)
 }
 
+func (o *Entities) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return (*extensions.Collection[Entity, *Entity])(o).UnmarshalJSONWith(dec, data)
+}
+
 // EntityName encapsulates the name of the associated Entity. The CoRIM
 // specification only allows for text (string) name, but this may be extended
 // by other specifications.
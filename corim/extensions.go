@@ -10,6 +10,7 @@ const (
 	ExtUnsignedCorim extensions.Point = "UnsignedCorim"
 	ExtEntity        extensions.Point = "CorimEntity"
 	ExtSigner        extensions.Point = "Signer"
+	ExtLocator       extensions.Point = "Locator"
 )
 
 type IEntityConstrainer interface {
@@ -24,6 +25,10 @@ type ISignerConstrainer interface {
 	ConstrainSigner(*Signer) error
 }
 
+type ILocatorConstrainer interface {
+	ConstrainLocator(*Locator) error
+}
+
 type Extensions struct {
 	extensions.Extensions
 }
@@ -33,10 +38,12 @@ func (o *Extensions) validEntity(entity *Entity) error {
 		return nil
 	}
 
-	ev, ok := o.IMapValue.(IEntityConstrainer)
-	if ok {
-		if err := ev.ConstrainEntity(entity); err != nil {
-			return err
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IEntityConstrainer)
+		if ok {
+			if err := ev.ConstrainEntity(entity); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -48,10 +55,12 @@ func (o *Extensions) validCorim(c *UnsignedCorim) error {
 		return nil
 	}
 
-	ev, ok := o.IMapValue.(ICorimConstrainer)
-	if ok {
-		if err := ev.ConstrainCorim(c); err != nil {
-			return err
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(ICorimConstrainer)
+		if ok {
+			if err := ev.ConstrainCorim(c); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -63,10 +72,29 @@ func (o *Extensions) validSigner(signer *Signer) error {
 		return nil
 	}
 
-	ev, ok := o.IMapValue.(ISignerConstrainer)
-	if ok {
-		if err := ev.ConstrainSigner(signer); err != nil {
-			return err
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(ISignerConstrainer)
+		if ok {
+			if err := ev.ConstrainSigner(signer); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *Extensions) validLocator(locator *Locator) error {
+	if !o.HaveExtensions() {
+		return nil
+	}
+
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(ILocatorConstrainer)
+		if ok {
+			if err := ev.ConstrainLocator(locator); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -0,0 +1,120 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	_ "crypto/sha256" // register SHA-256 with crypto.Hash
+	_ "crypto/sha512" // register SHA-384 and SHA-512 with crypto.Hash
+	"errors"
+	"fmt"
+
+	cose "github.com/veraison/go-cose"
+)
+
+// COSE header parameters defined by draft-ietf-cose-hash-envelope
+var (
+	HeaderLabelPayloadHashAlg             = int64(258)
+	HeaderLabelPayloadPreimageContentType = int64(259)
+	HeaderLabelPayloadLocation            = int64(260)
+)
+
+// COSE algorithm identifiers for the hash functions that can be used with a
+// hash envelope (see the IANA "COSE Algorithms" registry)
+const (
+	AlgorithmSHA256 = cose.Algorithm(-16)
+	AlgorithmSHA384 = cose.Algorithm(-43)
+	AlgorithmSHA512 = cose.Algorithm(-44)
+)
+
+// HashEnvelope describes a COSE hash envelope, i.e., a COSE_Sign1 whose
+// payload is the hash of the unsigned-corim rather than the unsigned-corim
+// itself.
+type HashEnvelope struct {
+	// HashAlg is the COSE algorithm identifier of the hash function used to
+	// compute the payload
+	HashAlg cose.Algorithm
+	// Location is an optional hint at where the unsigned-corim can be
+	// retrieved from
+	Location string
+}
+
+// Valid checks that the hash algorithm of the target HashEnvelope is supported
+func (o HashEnvelope) Valid() error {
+	_, err := hashEnvelopeHash(o.HashAlg)
+	return err
+}
+
+// Digest computes the hash of the supplied unsigned-corim using the hash
+// algorithm of the target HashEnvelope
+func (o HashEnvelope) Digest(payload []byte) ([]byte, error) {
+	h, err := hashEnvelopeHash(o.HashAlg)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher := h.New()
+	hasher.Write(payload) // nolint:errcheck
+
+	return hasher.Sum(nil), nil
+}
+
+func (o HashEnvelope) setHeaders(hdr cose.ProtectedHeader) {
+	hdr[HeaderLabelPayloadHashAlg] = o.HashAlg
+	hdr[HeaderLabelPayloadPreimageContentType] = ContentType
+
+	if o.Location != "" {
+		hdr[HeaderLabelPayloadLocation] = o.Location
+	}
+}
+
+// hashEnvelopeFromHeaders returns the HashEnvelope described by the supplied
+// protected header, or nil if the header does not describe a hash envelope.
+func hashEnvelopeFromHeaders(hdr cose.ProtectedHeader) (*HashEnvelope, error) {
+	v, ok := hdr[HeaderLabelPayloadHashAlg]
+	if !ok {
+		return nil, nil
+	}
+
+	var alg cose.Algorithm
+
+	switch t := v.(type) {
+	case int64:
+		alg = cose.Algorithm(t)
+	case cose.Algorithm:
+		alg = t
+	default:
+		return nil, fmt.Errorf("expecting integer payload hash algorithm, got %T instead", v)
+	}
+
+	env := HashEnvelope{HashAlg: alg}
+	if err := env.Valid(); err != nil {
+		return nil, err
+	}
+
+	if v, ok := hdr[HeaderLabelPayloadLocation]; ok {
+		loc, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expecting string payload location, got %T instead", v)
+		}
+		env.Location = loc
+	}
+
+	return &env, nil
+}
+
+func hashEnvelopeHash(alg cose.Algorithm) (crypto.Hash, error) {
+	switch alg {
+	case AlgorithmSHA256:
+		return crypto.SHA256, nil
+	case AlgorithmSHA384:
+		return crypto.SHA384, nil
+	case AlgorithmSHA512:
+		return crypto.SHA512, nil
+	case 0:
+		return 0, errors.New("missing payload hash algorithm")
+	default:
+		return 0, fmt.Errorf("unsupported payload hash algorithm %d", alg)
+	}
+}
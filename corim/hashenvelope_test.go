@@ -0,0 +1,111 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto/sha512"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+)
+
+func TestHashEnvelope_Digest(t *testing.T) {
+	env := HashEnvelope{HashAlg: AlgorithmSHA384}
+	require.NoError(t, env.Valid())
+
+	digest, err := env.Digest([]byte("test"))
+	require.NoError(t, err)
+
+	expected := sha512.Sum384([]byte("test"))
+	assert.Equal(t, expected[:], digest)
+
+	_, err = HashEnvelope{}.Digest([]byte("test"))
+	assert.EqualError(t, err, "missing payload hash algorithm")
+
+	err = HashEnvelope{HashAlg: cose.AlgorithmES256}.Valid()
+	assert.EqualError(t, err, "unsupported payload hash algorithm -7")
+}
+
+func TestSignedCorim_SignVerify_hash_envelope_ok(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	for _, detached := range []bool{false, true} {
+		var signedCorimIn SignedCorim
+		signedCorimIn.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+		signedCorimIn.Meta = *metaGood(t)
+
+		env := HashEnvelope{
+			HashAlg:  AlgorithmSHA256,
+			Location: "https://example.com/corims/good-corim.cbor",
+		}
+
+		sig, err := signedCorimIn.SignWithOptions(signer, SignOptions{
+			Detached:     detached,
+			HashEnvelope: &env,
+		})
+		require.NoError(t, err)
+
+		payload, err := signedCorimIn.UnsignedCorim.ToCBOR()
+		require.NoError(t, err)
+
+		var signedCorimOut SignedCorim
+
+		err = signedCorimOut.FromCOSE(sig)
+		assert.EqualError(t, err, "hash envelope: unsigned CoRIM payload must be supplied")
+
+		err = signedCorimOut.FromCOSEWithPayload(sig, payload)
+		require.NoError(t, err)
+		assert.Equal(t, detached, signedCorimOut.IsDetached())
+		assert.Equal(t, &env, signedCorimOut.GetHashEnvelope())
+		assert.Equal(t, ContentType,
+			signedCorimOut.message.Headers.Protected[HeaderLabelPayloadPreimageContentType])
+		assert.NotContains(t, signedCorimOut.message.Headers.Protected, cose.HeaderLabelContentType)
+
+		assert.NoError(t, signedCorimOut.Verify(pk))
+
+		out, err := UnmarshalSignedCorimFromCBORWithPayload(sig, payload)
+		require.NoError(t, err)
+		assert.NoError(t, out.Verify(pk))
+
+		_, err = UnmarshalSignedCorimFromCBOR(sig)
+		assert.EqualError(t, err, "unsigned CoRIM payload must be supplied")
+	}
+}
+
+func TestSignedCorim_FromCOSEWithPayload_hash_envelope_mismatch(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	var signedCorimIn SignedCorim
+	signedCorimIn.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+	signedCorimIn.Meta = *metaGood(t)
+
+	sig, err := signedCorimIn.SignWithOptions(signer, SignOptions{
+		HashEnvelope: &HashEnvelope{HashAlg: AlgorithmSHA512},
+	})
+	require.NoError(t, err)
+
+	var signedCorimOut SignedCorim
+	err = signedCorimOut.FromCOSEWithPayload(sig, testGoodUnsignedCorimCBOR[1:])
+	assert.EqualError(t, err, "hash envelope: supplied payload does not match the signed hash")
+}
+
+func TestSignedCorim_SignWithOptions_bad_hash_envelope(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	var signedCorimIn SignedCorim
+	signedCorimIn.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	_, err = signedCorimIn.SignWithOptions(signer, SignOptions{
+		HashEnvelope: &HashEnvelope{HashAlg: cose.AlgorithmES256},
+	})
+	assert.EqualError(t, err, "invalid hash envelope: unsupported payload hash algorithm -7")
+}
@@ -0,0 +1,413 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	cbor "github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/swid"
+)
+
+// Thumbprint is a list of digests of the CoRIM referenced by a
+// corim-locator-map, computed with different algorithms, as allowed by more
+// recent versions of the CoRIM specification. It is serialized as a list of
+// digests, even if it has only one, but can be decoded from either a single
+// digest or a list of digests. Locator keeps track of which of the two forms
+// its thumbprint was decoded from (see Locator.Thumbprints).
+type Thumbprint []swid.HashEntry
+
+// NewLocator creates a corim-locator-map for the CoRIM found at href, whose
+// (signed or unsigned) CBOR encoding is rim.  One thumbprint digest is
+// computed for each of the supplied algorithms, using the IANA "Named
+// Information Hash Algorithm" registry identifiers.  If no algorithm is
+// supplied, sha-256 is used.
+func NewLocator(href string, rim []byte, algs ...uint64) (*Locator, error) {
+	if len(rim) == 0 {
+		return nil, errors.New("no CoRIM data")
+	}
+
+	if len(algs) == 0 {
+		algs = []uint64{swid.Sha256}
+	}
+
+	var tp Thumbprint
+
+	for _, alg := range algs {
+		digest, err := computeDigest(alg, rim)
+		if err != nil {
+			return nil, err
+		}
+
+		tp = append(tp, swid.HashEntry{
+			HashAlgID: alg,
+			HashValue: digest,
+		})
+	}
+
+	l := Locator{Href: comid.TaggedURI(href)}
+	l.SetDigests(tp)
+
+	if err := l.Valid(); err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// Digests returns the digests of the thumbprint, whichever form it is in
+// nolint:gocritic
+func (o Locator) Digests() Thumbprint {
+	if o.Thumbprint != nil {
+		return Thumbprint{*o.Thumbprint}
+	}
+
+	return o.Thumbprints
+}
+
+// SetDigests sets the thumbprint to the supplied digests, using the single
+// digest form (i.e. Thumbprint) if there is exactly one of them, and the
+// multi-digest form (i.e. Thumbprints) otherwise. This is meant for locally
+// built locators: decoded ones keep the form they were found in.
+func (o *Locator) SetDigests(tp Thumbprint) {
+	o.Thumbprint, o.Thumbprints = nil, nil
+
+	switch len(tp) {
+	case 0:
+	case 1:
+		o.Thumbprint = &tp[0]
+	default:
+		o.Thumbprints = tp
+	}
+}
+
+// locatorCodec is the serialization of a Locator, where both forms of the
+// thumbprint are carried by the same field
+type locatorCodec struct {
+	Href       comid.TaggedURI    `cbor:"0,keyasint" json:"href" cddl:"corim.href"`
+	Thumbprint *locatorThumbprint `cbor:"1,keyasint,omitempty" json:"thumbprint,omitempty" cddl:"corim.thumbprint"`
+
+	Extensions
+}
+
+// nolint:gocritic
+func (o Locator) toCodec() (*locatorCodec, error) {
+	c := locatorCodec{Href: o.Href, Extensions: o.Extensions}
+
+	switch {
+	case o.Thumbprint != nil && len(o.Thumbprints) != 0:
+		return nil, errors.New("both single and multi-digest thumbprints set")
+	case o.Thumbprint != nil:
+		c.Thumbprint = &locatorThumbprint{Single: o.Thumbprint}
+	case len(o.Thumbprints) != 0:
+		c.Thumbprint = &locatorThumbprint{List: o.Thumbprints}
+	}
+
+	return &c, nil
+}
+
+func (o *Locator) fromCodec(c *locatorCodec) {
+	o.Href = c.Href
+	o.Thumbprint, o.Thumbprints = nil, nil
+	o.Extensions = c.Extensions
+
+	if c.Thumbprint != nil {
+		o.Thumbprint, o.Thumbprints = c.Thumbprint.Single, c.Thumbprint.List
+	}
+}
+
+// locatorThumbprint is the serialization of the thumbprint of a Locator. It
+// records which of the two forms was decoded, so that the thumbprint is
+// re-encoded in that same form: exactly one of Single and List is set.
+type locatorThumbprint struct {
+	Single *swid.HashEntry
+	List   Thumbprint
+}
+
+func (o locatorThumbprint) MarshalCBOR() ([]byte, error) {
+	if o.Single != nil {
+		return em.Marshal(o.Single)
+	}
+
+	return em.Marshal([]swid.HashEntry(o.List))
+}
+
+func (o *locatorThumbprint) UnmarshalCBOR(data []byte) error {
+	var items []cbor.RawMessage
+
+	if err := dm.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		return errors.New("empty thumbprint")
+	}
+
+	*o = locatorThumbprint{}
+
+	// a single digest starts with its algorithm identifier (a uint), whereas
+	// an array of digests starts with an array
+	if len(items[0]) > 0 && items[0][0]>>5 == 0 {
+		o.Single = &swid.HashEntry{}
+		return dm.Unmarshal(data, o.Single)
+	}
+
+	return dm.Unmarshal(data, (*[]swid.HashEntry)(&o.List))
+}
+
+func (o locatorThumbprint) MarshalJSON() ([]byte, error) {
+	if o.Single != nil {
+		return json.Marshal(o.Single)
+	}
+
+	return json.Marshal([]swid.HashEntry(o.List))
+}
+
+func (o *locatorThumbprint) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+
+	*o = locatorThumbprint{}
+
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, (*[]swid.HashEntry)(&o.List)); err != nil {
+			return err
+		}
+
+		if len(o.List) == 0 {
+			return errors.New("empty thumbprint")
+		}
+
+		return nil
+	}
+
+	o.Single = &swid.HashEntry{}
+
+	return json.Unmarshal(trimmed, o.Single)
+}
+
+// digests returns the digests of the target thumbprint, whichever form it is
+// in
+func (o locatorThumbprint) digests() Thumbprint {
+	if o.Single != nil {
+		return Thumbprint{*o.Single}
+	}
+
+	return o.List
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *Locator) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *Locator) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtLocator, o); err != nil {
+		return err
+	}
+
+	c := locatorCodec{Extensions: o.Extensions}
+
+	if err := encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, &c); err != nil {
+		return err
+	}
+
+	o.fromCodec(&c)
+
+	return nil
+}
+
+// MarshalCBOR serializes to CBOR, omitting the thumbprint if empty
+// nolint:gocritic
+func (o Locator) MarshalCBOR() ([]byte, error) {
+	c, err := o.toCodec()
+	if err != nil {
+		return nil, err
+	}
+
+	return encoding.SerializeStructToCBOR(em, c)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *Locator) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *Locator) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtLocator, o); err != nil {
+		return err
+	}
+
+	c := locatorCodec{Extensions: o.Extensions}
+
+	if err := encoding.PopulateStructFromJSONWith(dec, data, &c); err != nil {
+		return err
+	}
+
+	o.fromCodec(&c)
+
+	return nil
+}
+
+// MarshalJSON serializes to JSON, omitting the thumbprint if empty
+// nolint:gocritic
+func (o Locator) MarshalJSON() ([]byte, error) {
+	c, err := o.toCodec()
+	if err != nil {
+		return nil, err
+	}
+
+	return encoding.SerializeStructToJSON(c)
+}
+
+// Valid checks that each digest in the thumbprint is well-formed
+func (o Thumbprint) Valid() error {
+	if len(o) == 0 {
+		return errors.New("no digests")
+	}
+
+	for i, d := range o {
+		if err := swid.ValidHashEntry(d.HashAlgID, d.HashValue); err != nil {
+			return fmt.Errorf("digest at index %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Verify checks that the supplied data matches the thumbprint.  Every digest
+// computed with a supported algorithm must match, and at least one of the
+// digests must use a supported algorithm.  Truncated digests (e.g.
+// sha-256-32) are too weak to be relied upon as the only integrity check, and
+// so are not accepted (see VerifyAllowingTruncated).
+func (o Thumbprint) Verify(data []byte) error {
+	return o.verify(data, false)
+}
+
+// VerifyAllowingTruncated is like Verify, except that truncated digests are
+// accepted
+func (o Thumbprint) VerifyAllowingTruncated(data []byte) error {
+	return o.verify(data, true)
+}
+
+func (o Thumbprint) verify(data []byte, allowTruncated bool) error {
+	if len(o) == 0 {
+		return errors.New("no digests")
+	}
+
+	var (
+		checked int
+		lastErr error
+	)
+
+	for _, d := range o {
+		if !allowTruncated && isTruncatedDigest(d.HashAlgID) {
+			lastErr = fmt.Errorf("truncated thumbprint digest (%s) not accepted", d.AlgIDToString())
+			continue
+		}
+
+		digest, err := computeDigest(d.HashAlgID, data)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if !bytes.Equal(digest, d.HashValue) {
+			return errors.New("thumbprint mismatch")
+		}
+
+		checked++
+	}
+
+	if checked == 0 {
+		return lastErr
+	}
+
+	return nil
+}
+
+// MarshalCBOR encodes the digests as an array of digests
+func (o Thumbprint) MarshalCBOR() ([]byte, error) {
+	return em.Marshal([]swid.HashEntry(o))
+}
+
+// UnmarshalCBOR decodes either a single digest or an array of digests
+func (o *Thumbprint) UnmarshalCBOR(data []byte) error {
+	var tp locatorThumbprint
+
+	if err := tp.UnmarshalCBOR(data); err != nil {
+		return err
+	}
+
+	*o = tp.digests()
+
+	return nil
+}
+
+// MarshalJSON encodes the digests as an array of strings
+func (o Thumbprint) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]swid.HashEntry(o))
+}
+
+// UnmarshalJSON decodes either a single digest or an array of digests
+func (o *Thumbprint) UnmarshalJSON(data []byte) error {
+	var tp locatorThumbprint
+
+	if err := tp.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	*o = tp.digests()
+
+	return nil
+}
+
+// isTruncatedDigest returns true if alg is one of the truncated SHA-256
+// algorithms of the IANA "Named Information Hash Algorithm" registry
+func isTruncatedDigest(alg uint64) bool {
+	return alg >= swid.Sha256_128 && alg <= swid.Sha256_32
+}
+
+// computeDigest computes the digest of the supplied data using the supplied
+// algorithm from the IANA "Named Information Hash Algorithm" registry
+func computeDigest(alg uint64, data []byte) ([]byte, error) {
+	var (
+		h      crypto.Hash
+		length int
+	)
+
+	switch alg {
+	case swid.Sha256:
+		h, length = crypto.SHA256, 32
+	case swid.Sha256_128:
+		h, length = crypto.SHA256, 16
+	case swid.Sha256_120:
+		h, length = crypto.SHA256, 15
+	case swid.Sha256_96:
+		h, length = crypto.SHA256, 12
+	case swid.Sha256_64:
+		h, length = crypto.SHA256, 8
+	case swid.Sha256_32:
+		h, length = crypto.SHA256, 4
+	case swid.Sha384:
+		h, length = crypto.SHA384, 48
+	case swid.Sha512:
+		h, length = crypto.SHA512, 64
+	default:
+		return nil, fmt.Errorf("unsupported thumbprint hash algorithm %d", alg)
+	}
+
+	hasher := h.New()
+	hasher.Write(data) // nolint:errcheck
+
+	return hasher.Sum(nil)[:length], nil
+}
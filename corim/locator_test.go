@@ -0,0 +1,289 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/swid"
+)
+
+func TestNewLocator_default_algorithm(t *testing.T) {
+	rim := []byte("a CoRIM")
+	digest := sha256.Sum256(rim)
+
+	l, err := NewLocator("https://example.com/rim.cbor", rim)
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://example.com/rim.cbor", string(l.Href))
+	assert.Equal(t, &swid.HashEntry{HashAlgID: swid.Sha256, HashValue: digest[:]}, l.Thumbprint)
+	assert.Nil(t, l.Thumbprints)
+	assert.NoError(t, l.Digests().Verify(rim))
+}
+
+func TestNewLocator_multiple_algorithms(t *testing.T) {
+	rim := []byte("a CoRIM")
+	d256 := sha256.Sum256(rim)
+	d384 := sha512.Sum384(rim)
+
+	l, err := NewLocator("https://example.com/rim.cbor", rim, swid.Sha256_128, swid.Sha384)
+	require.NoError(t, err)
+
+	expected := Thumbprint{
+		{HashAlgID: swid.Sha256_128, HashValue: d256[:16]},
+		{HashAlgID: swid.Sha384, HashValue: d384[:]},
+	}
+	assert.Nil(t, l.Thumbprint)
+	assert.Equal(t, expected, l.Thumbprints)
+	assert.Equal(t, expected, l.Digests())
+	assert.NoError(t, l.Digests().Verify(rim))
+	assert.EqualError(t, l.Digests().Verify([]byte("another CoRIM")), "thumbprint mismatch")
+}
+
+func TestNewLocator_NOK(t *testing.T) {
+	_, err := NewLocator("https://example.com/rim.cbor", nil)
+	assert.EqualError(t, err, "no CoRIM data")
+
+	_, err = NewLocator("https://example.com/rim.cbor", []byte("a CoRIM"), 42)
+	assert.EqualError(t, err, "unsupported thumbprint hash algorithm 42")
+
+	_, err = NewLocator("", []byte("a CoRIM"))
+	assert.EqualError(t, err, "empty href")
+}
+
+func TestThumbprint_Verify_unsupported(t *testing.T) {
+	rim := []byte("a CoRIM")
+	digest := sha256.Sum256(rim)
+
+	// unsupported algorithms are skipped if another digest can be checked
+	tp := Thumbprint{
+		{HashAlgID: swid.Sha3_256, HashValue: make([]byte, 32)},
+		{HashAlgID: swid.Sha256, HashValue: digest[:]},
+	}
+	assert.NoError(t, tp.Verify(rim))
+
+	tp = Thumbprint{{HashAlgID: swid.Sha3_256, HashValue: make([]byte, 32)}}
+	assert.EqualError(t, tp.Verify(rim), "unsupported thumbprint hash algorithm 10")
+
+	assert.EqualError(t, Thumbprint{}.Verify(rim), "no digests")
+}
+
+func TestThumbprint_Verify_truncated(t *testing.T) {
+	rim := []byte("a CoRIM")
+	digest := sha256.Sum256(rim)
+
+	tp := Thumbprint{{HashAlgID: swid.Sha256_32, HashValue: digest[:4]}}
+	assert.EqualError(t, tp.Verify(rim), "truncated thumbprint digest (sha-256-32) not accepted")
+	assert.NoError(t, tp.VerifyAllowingTruncated(rim))
+
+	// truncated digests are skipped if a full one can be checked
+	tp = append(tp, swid.HashEntry{HashAlgID: swid.Sha256, HashValue: digest[:]})
+	assert.NoError(t, tp.Verify(rim))
+}
+
+func TestThumbprint_Valid(t *testing.T) {
+	tp := Thumbprint{
+		{HashAlgID: swid.Sha256_32, HashValue: []byte{1, 2, 3, 4}},
+		{HashAlgID: swid.Sha256, HashValue: []byte{1, 2, 3, 4}},
+	}
+	assert.ErrorContains(t, tp.Valid(), "digest at index 1: ")
+
+	assert.EqualError(t, Thumbprint{}.Valid(), "no digests")
+}
+
+func TestLocator_CBOR_single_digest(t *testing.T) {
+	l := Locator{
+		Href:       "https://a.b",
+		Thumbprint: &swid.HashEntry{HashAlgID: swid.Sha256_32, HashValue: []byte{1, 2, 3, 4}},
+	}
+
+	data, err := em.Marshal(l)
+	require.NoError(t, err)
+
+	// { 0: 32("https://a.b"), 1: [ 6, h'01020304' ] }
+	expected := []byte{
+		0xa2, 0x00, 0xd8, 0x20, 0x6b, 0x68, 0x74, 0x74, 0x70, 0x73, 0x3a, 0x2f,
+		0x2f, 0x61, 0x2e, 0x62, 0x01, 0x82, 0x06, 0x44, 0x01, 0x02, 0x03, 0x04,
+	}
+	assert.Equal(t, expected, data)
+
+	var actual Locator
+	require.NoError(t, dm.Unmarshal(data, &actual))
+	assert.Equal(t, l, actual)
+}
+
+func TestLocator_CBOR_multiple_digests(t *testing.T) {
+	l := Locator{
+		Href: "https://a.b",
+		Thumbprints: Thumbprint{
+			{HashAlgID: swid.Sha256_32, HashValue: []byte{1, 2, 3, 4}},
+			{HashAlgID: swid.Sha256_64, HashValue: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		},
+	}
+
+	data, err := em.Marshal(l)
+	require.NoError(t, err)
+
+	// { 0: 32("https://a.b"), 1: [ [ 6, h'01020304' ], [ 5, h'0102030405060708' ] ] }
+	expected := []byte{
+		0xa2, 0x00, 0xd8, 0x20, 0x6b, 0x68, 0x74, 0x74, 0x70, 0x73, 0x3a, 0x2f,
+		0x2f, 0x61, 0x2e, 0x62, 0x01, 0x82, 0x82, 0x06, 0x44, 0x01, 0x02, 0x03,
+		0x04, 0x82, 0x05, 0x48, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+	}
+	assert.Equal(t, expected, data)
+
+	var actual Locator
+	require.NoError(t, dm.Unmarshal(data, &actual))
+	assert.Equal(t, l, actual)
+}
+
+func TestLocator_CBOR_single_digest_list(t *testing.T) {
+	// { 0: 32("https://a.b"), 1: [ [ 6, h'01020304' ] ] }
+	data := []byte{
+		0xa2, 0x00, 0xd8, 0x20, 0x6b, 0x68, 0x74, 0x74, 0x70, 0x73, 0x3a, 0x2f,
+		0x2f, 0x61, 0x2e, 0x62, 0x01, 0x81, 0x82, 0x06, 0x44, 0x01, 0x02, 0x03,
+		0x04,
+	}
+
+	var l Locator
+	require.NoError(t, dm.Unmarshal(data, &l))
+	assert.Nil(t, l.Thumbprint)
+	assert.Equal(t, Thumbprint{{HashAlgID: swid.Sha256_32, HashValue: []byte{1, 2, 3, 4}}}, l.Thumbprints)
+
+	// the list form is preserved
+	actual, err := em.Marshal(l)
+	require.NoError(t, err)
+	assert.Equal(t, data, actual)
+
+	jsonData, err := json.Marshal(l)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"href":"https://a.b","thumbprint":["sha-256-32;AQIDBA=="]}`, string(jsonData))
+
+	l = Locator{}
+	require.NoError(t, json.Unmarshal(jsonData, &l))
+	assert.Nil(t, l.Thumbprint)
+	assert.Len(t, l.Thumbprints, 1)
+}
+
+func TestLocator_CBOR_no_thumbprint(t *testing.T) {
+	l := Locator{Href: "https://a.b"}
+
+	data, err := em.Marshal(l)
+	require.NoError(t, err)
+
+	var actual Locator
+	require.NoError(t, dm.Unmarshal(data, &actual))
+	assert.Equal(t, l, actual)
+	assert.Nil(t, actual.Thumbprint)
+	assert.Nil(t, actual.Thumbprints)
+}
+
+func TestLocator_both_thumbprint_forms(t *testing.T) {
+	l := Locator{
+		Href:        "https://a.b",
+		Thumbprint:  &swid.HashEntry{HashAlgID: swid.Sha256_32, HashValue: []byte{1, 2, 3, 4}},
+		Thumbprints: Thumbprint{{HashAlgID: swid.Sha256_32, HashValue: []byte{1, 2, 3, 4}}},
+	}
+
+	assert.EqualError(t, l.Valid(), "both single and multi-digest thumbprints set")
+
+	_, err := em.Marshal(l)
+	assert.ErrorContains(t, err, "both single and multi-digest thumbprints set")
+}
+
+func TestThumbprint_UnmarshalCBOR_empty(t *testing.T) {
+	var tp Thumbprint
+	assert.EqualError(t, tp.UnmarshalCBOR([]byte{0x80}), "empty thumbprint")
+}
+
+func TestLocator_JSON(t *testing.T) {
+	single := `{"href":"https://a.b","thumbprint":"sha-256-32;AQIDBA=="}`
+	multi := `{"href":"https://a.b","thumbprint":["sha-256-32;AQIDBA==","sha-256-64;AQIDBAUGBwg="]}`
+
+	var l Locator
+	require.NoError(t, json.Unmarshal([]byte(single), &l))
+	require.NotNil(t, l.Thumbprint)
+	assert.Equal(t, swid.Sha256_32, l.Thumbprint.HashAlgID)
+
+	data, err := json.Marshal(l)
+	require.NoError(t, err)
+	assert.JSONEq(t, single, string(data))
+
+	l = Locator{}
+	require.NoError(t, json.Unmarshal([]byte(multi), &l))
+	assert.Nil(t, l.Thumbprint)
+	assert.Len(t, l.Thumbprints, 2)
+	assert.Equal(t, swid.Sha256_64, l.Thumbprints[1].HashAlgID)
+
+	data, err = json.Marshal(l)
+	require.NoError(t, err)
+	assert.JSONEq(t, multi, string(data))
+
+	assert.ErrorContains(t,
+		json.Unmarshal([]byte(`{"href":"https://a.b","thumbprint":[]}`), &l),
+		"empty thumbprint")
+}
+
+func TestUnsignedCorim_AddLocator(t *testing.T) {
+	l, err := NewLocator("https://example.com/rim.cbor", []byte("a CoRIM"), swid.Sha256, swid.Sha512)
+	require.NoError(t, err)
+
+	rim := NewUnsignedCorim().AddLocator(*l)
+	require.NotNil(t, rim)
+	require.NotNil(t, rim.DependentRims)
+	assert.Equal(t, []Locator{*l}, *rim.DependentRims)
+}
+
+type testLocatorExtensions struct {
+	Mirror *string `cbor:"-1,keyasint,omitempty" json:"mirror,omitempty"`
+}
+
+func (o *testLocatorExtensions) ConstrainLocator(_ *Locator) error {
+	if o.Mirror == nil {
+		return errors.New("missing mirror")
+	}
+	return nil
+}
+
+func TestUnsignedCorim_Locator_extensions(t *testing.T) {
+	exts := extensions.NewMap().Add(ExtLocator, &testLocatorExtensions{})
+
+	l, err := NewLocator("https://example.com/rim.cbor", []byte("a CoRIM"))
+	require.NoError(t, err)
+
+	rim := NewUnsignedCorim()
+	require.NoError(t, rim.RegisterExtensions(exts))
+	rim.SetID("locator-ext").AddLocator(*l)
+	require.NotNil(t, rim.AddComid(comidFromTemplate(t)))
+
+	assert.ErrorContains(t, rim.Valid(), "missing mirror")
+
+	mirror := "https://mirror.example.com/rim.cbor"
+	require.NoError(t, (*rim.DependentRims)[0].Set("mirror", &mirror))
+	require.NoError(t, rim.Valid())
+
+	data, err := rim.ToCBOR()
+	require.NoError(t, err)
+
+	out := NewUnsignedCorim()
+	require.NoError(t, out.RegisterExtensions(exts))
+	require.NoError(t, out.FromCBOR(data))
+	require.NoError(t, out.Valid())
+
+	actual := (*out.DependentRims)[0]
+	assert.Equal(t, mirror, actual.MustGetString("mirror"))
+	assert.Equal(t, l.Thumbprint, actual.Thumbprint)
+	assert.Nil(t, actual.Thumbprints)
+
+	jsonData, err := json.Marshal(actual)
+	require.NoError(t, err)
+	assert.Contains(t, string(jsonData), `"mirror":"https://mirror.example.com/rim.cbor"`)
+}
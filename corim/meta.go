@@ -16,8 +16,8 @@ import (
 // associated with the signed assertion.  A corim-meta-map is serialized to CBOR
 // and added to the protected header structure in the signed-corim as a byte string
 type Meta struct {
-	Signer   Signer    `cbor:"0,keyasint" json:"signer"`
-	Validity *Validity `cbor:"1,keyasint,omitempty" json:"validity,omitempty"`
+	Signer   Signer    `cbor:"0,keyasint" json:"signer" cddl:"corim.signer"`
+	Validity *Validity `cbor:"1,keyasint,omitempty" json:"validity,omitempty" cddl:"corim.signature-validity"`
 }
 
 func NewMeta() *Meta {
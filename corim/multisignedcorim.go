@@ -0,0 +1,347 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
+	cose "github.com/veraison/go-cose"
+)
+
+// CorimSigner associates a cose.Signer with the corim-meta that is carried in
+// the protected header of the signature it produces.  Headers can be used to
+// supply additional protected header parameters (e.g., a key identifier) for
+// that signature only.
+type CorimSigner struct {
+	Signer  cose.Signer
+	Meta    Meta
+	Headers cose.ProtectedHeader
+}
+
+// SignatureInfo describes one of the signatures found in a MultiSignedCorim
+type SignatureInfo struct {
+	// Meta is the corim-meta decoded from the signature's protected header
+	Meta Meta
+	// Headers are the signature's protected and unprotected headers
+	Headers cose.Headers
+}
+
+// SignatureResult is the outcome of verifying one of the signatures in a
+// MultiSignedCorim
+type SignatureResult struct {
+	// Index is the position of the signature in the COSE_Sign message
+	Index int
+	// Meta is the corim-meta associated with the signature
+	Meta Meta
+	// KeyIndex is the index of the public key that verified the signature,
+	// or -1 if none did
+	KeyIndex int
+	// Err is the verification error, or nil if the signature verified
+	Err error
+}
+
+// Verified returns true if the signature was successfully verified
+func (o SignatureResult) Verified() bool {
+	return o.Err == nil && o.KeyIndex >= 0
+}
+
+// MultiSignedCorim encodes a signed-corim message that carries multiple
+// signatures (i.e., a COSE_Sign wrapped CoRIM), for example one from the
+// silicon vendor and one from the OEM.  Each signature carries its own
+// corim-meta in its protected header.
+type MultiSignedCorim struct {
+	UnsignedCorim UnsignedCorim
+	Signatures    []SignatureInfo
+
+	signerExts extensions.Map
+	message    *cose.SignMessage
+}
+
+// NewMultiSignedCorim instantiates an empty MultiSignedCorim
+func NewMultiSignedCorim() *MultiSignedCorim {
+	return &MultiSignedCorim{}
+}
+
+// RegisterExtensions registers the supplied extensions.  Extensions at the
+// ExtSigner point are registered with the Meta of every signature decoded by
+// FromCOSE.
+func (o *MultiSignedCorim) RegisterExtensions(exts extensions.Map) error {
+	unsignedExts := extensions.NewMap()
+
+	for p, v := range exts {
+		switch p {
+		case ExtSigner:
+			var m Meta
+			signerExts := extensions.NewMap().Add(ExtSigner, v)
+			if err := m.RegisterExtensions(signerExts); err != nil {
+				return err
+			}
+			o.signerExts = signerExts
+		default:
+			unsignedExts.Add(p, v)
+		}
+	}
+
+	return o.UnsignedCorim.RegisterExtensions(unsignedExts)
+}
+
+// FromCOSE decodes and effects syntactic validation on the supplied COSE_Sign
+// signed-corim message, including the embedded unsigned-corim and the
+// corim-meta of each signature.  On success, the unsigned-corim-map is made
+// available via the UnsignedCorim field, while the decoded signatures are
+// made available via the Signatures field. Optional DecodeOptions may be
+// specified, e.g. to decode in strict mode, or with DecodeLimits other than
+// those set via encoding.SetDecodeLimits.
+func (o *MultiSignedCorim) FromCOSE(buf []byte, opts ...encoding.DecodeOptions) error {
+	o.message = cose.NewSignMessage()
+
+	limits := encoding.NewDecoder(dm).WithOptions(opts...).Limits()
+
+	if err := encoding.CheckCBORLimits(buf, limits); err != nil {
+		return fmt.Errorf("failed CBOR decoding for COSE-Sign signed CoRIM: %w", err)
+	}
+
+	if err := o.message.UnmarshalCBOR(buf); err != nil {
+		return fmt.Errorf("failed CBOR decoding for COSE-Sign signed CoRIM: %w", err)
+	}
+
+	if err := o.processHdrs(); err != nil {
+		return fmt.Errorf("processing COSE headers: %w", err)
+	}
+
+	if err := o.UnsignedCorim.FromCBOR(o.message.Payload, opts...); err != nil {
+		return fmt.Errorf("failed CBOR decoding of unsigned CoRIM: %w", err)
+	}
+
+	if err := o.UnsignedCorim.Valid(); err != nil {
+		return fmt.Errorf("failed validation of unsigned CoRIM: %w", err)
+	}
+
+	return nil
+}
+
+func (o *MultiSignedCorim) processHdrs() error {
+	hdr := o.message.Headers
+
+	if hdr.Protected == nil {
+		return errors.New("missing mandatory protected header")
+	}
+
+	v, ok := hdr.Protected[cose.HeaderLabelContentType]
+	if !ok {
+		return errors.New("missing mandatory content type")
+	}
+
+	if v != ContentType {
+		return fmt.Errorf("expecting content type %q, got %q instead", ContentType, v)
+	}
+
+	o.Signatures = make([]SignatureInfo, 0, len(o.message.Signatures))
+
+	for i, sig := range o.message.Signatures {
+		var meta Meta
+
+		if o.signerExts != nil {
+			if err := meta.RegisterExtensions(o.newSignerExts()); err != nil {
+				return err
+			}
+		}
+
+		if sig.Headers.Protected == nil {
+			return fmt.Errorf("signature at index %d: missing mandatory protected header", i)
+		}
+
+		if err := decodeMetaHeader(sig.Headers.Protected, &meta); err != nil {
+			return fmt.Errorf("signature at index %d: %w", i, err)
+		}
+
+		o.Signatures = append(o.Signatures, SignatureInfo{Meta: meta, Headers: sig.Headers})
+	}
+
+	return nil
+}
+
+func (o *MultiSignedCorim) newSignerExts() extensions.Map {
+	ret := extensions.NewMap()
+
+	for p, v := range o.signerExts {
+		ret.Add(p, extensions.Extensions{IMapValue: v}.New())
+	}
+
+	return ret
+}
+
+// Sign returns the serialized COSE_Sign signed-corim, with one signature for
+// each of the supplied signers.  The target MultiSignedCorim must have its
+// UnsignedCorim field correctly populated.
+func (o *MultiSignedCorim) Sign(signers ...CorimSigner) ([]byte, error) {
+	if len(signers) == 0 {
+		return nil, errors.New("no signers")
+	}
+
+	if err := o.UnsignedCorim.Valid(); err != nil {
+		return nil, fmt.Errorf("failed validation of unsigned CoRIM: %w", err)
+	}
+
+	o.message = cose.NewSignMessage()
+
+	var err error
+	o.message.Payload, err = o.UnsignedCorim.ToCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR encoding of unsigned CoRIM: %w", err)
+	}
+
+	o.message.Headers.Protected[cose.HeaderLabelContentType] = ContentType
+
+	coseSigners := make([]cose.Signer, 0, len(signers))
+	o.Signatures = make([]SignatureInfo, 0, len(signers))
+
+	for i, s := range signers {
+		if s.Signer == nil {
+			return nil, fmt.Errorf("signer at index %d: nil signer", i)
+		}
+
+		alg := s.Signer.Algorithm()
+		if strings.Contains(alg.String(), "unknown algorithm value") {
+			return nil, fmt.Errorf("signer at index %d: signer has no algorithm", i)
+		}
+
+		metaCBOR, err := s.Meta.ToCBOR()
+		if err != nil {
+			return nil, fmt.Errorf("signer at index %d: failed CBOR encoding of CoRIM Meta: %w", i, err)
+		}
+
+		sig := cose.NewSignature()
+		for k, v := range s.Headers {
+			sig.Headers.Protected[k] = v
+		}
+		sig.Headers.Protected.SetAlgorithm(alg)
+		sig.Headers.Protected[HeaderLabelCorimMeta] = metaCBOR
+
+		o.message.Signatures = append(o.message.Signatures, sig)
+		o.Signatures = append(o.Signatures, SignatureInfo{Meta: s.Meta, Headers: sig.Headers})
+		coseSigners = append(coseSigners, s.Signer)
+	}
+
+	if err := o.message.Sign(rand.Reader, NoExternalData, coseSigners...); err != nil {
+		return nil, fmt.Errorf("COSE Sign signature failed: %w", err)
+	}
+
+	wrap, err := o.message.MarshalCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("signed-corim marshaling failed: %w", err)
+	}
+
+	return wrap, nil
+}
+
+// VerifyEach checks every signature of the target MultiSignedCorim against
+// each of the supplied public keys and returns one SignatureResult per
+// signature.  A key verifies at most one signature.
+func (o *MultiSignedCorim) VerifyEach(pks ...crypto.PublicKey) ([]SignatureResult, error) {
+	if o.message == nil {
+		return nil, errors.New("no Sign message found")
+	}
+
+	bodyProtected, err := o.message.Headers.MarshalProtected()
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode body protected header: %w", err)
+	}
+
+	used := make([]bool, len(pks))
+	results := make([]SignatureResult, len(o.message.Signatures))
+
+	for i, sig := range o.message.Signatures {
+		res := SignatureResult{Index: i, KeyIndex: -1}
+		if i < len(o.Signatures) {
+			res.Meta = o.Signatures[i].Meta
+		}
+
+		alg, err := sig.Headers.Protected.Algorithm()
+		if err != nil {
+			res.Err = fmt.Errorf("unable to get verification algorithm: %w", err)
+			results[i] = res
+			continue
+		}
+
+		res.Err = errors.New("no matching verification key")
+
+		for j, pk := range pks {
+			if used[j] {
+				continue
+			}
+
+			verifier, err := cose.NewVerifier(alg, pk)
+			if err != nil {
+				continue
+			}
+
+			if err := sig.Verify(verifier, bodyProtected, o.message.Payload, NoExternalData); err != nil {
+				continue
+			}
+
+			used[j] = true
+			res.KeyIndex = j
+			res.Err = nil
+			break
+		}
+
+		results[i] = res
+	}
+
+	return results, nil
+}
+
+// Verify succeeds only if every signature of the target MultiSignedCorim
+// is verified by one of the supplied public keys
+func (o *MultiSignedCorim) Verify(pks ...crypto.PublicKey) error {
+	results, err := o.VerifyEach(pks...)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if !r.Verified() {
+			return fmt.Errorf("signature at index %d: %w", r.Index, r.Err)
+		}
+	}
+
+	return nil
+}
+
+// VerifyThreshold succeeds if at least threshold signatures of the target
+// MultiSignedCorim are verified by the supplied public keys.  The
+// per-signature results are returned in both cases.
+func (o *MultiSignedCorim) VerifyThreshold(threshold int, pks ...crypto.PublicKey) ([]SignatureResult, error) {
+	if threshold <= 0 {
+		return nil, fmt.Errorf("invalid threshold %d", threshold)
+	}
+
+	results, err := o.VerifyEach(pks...)
+	if err != nil {
+		return nil, err
+	}
+
+	verified := 0
+	for _, r := range results {
+		if r.Verified() {
+			verified++
+		}
+	}
+
+	if verified < threshold {
+		return results, fmt.Errorf(
+			"threshold not met: %d of %d signatures verified, %d required",
+			verified, len(results), threshold,
+		)
+	}
+
+	return results, nil
+}
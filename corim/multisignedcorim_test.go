@@ -0,0 +1,268 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/eat"
+	cose "github.com/veraison/go-cose"
+)
+
+func corimSignerFromJWK(t *testing.T, key []byte, name string) CorimSigner {
+	signer, err := NewSignerFromJWK(key)
+	require.NoError(t, err)
+
+	meta := NewMeta().SetSigner(name, nil)
+	require.NotNil(t, meta)
+
+	return CorimSigner{Signer: signer, Meta: *meta}
+}
+
+func publicKeyFromJWK(t *testing.T, key []byte) crypto.PublicKey {
+	pk, err := NewPublicKeyFromJWK(key)
+	require.NoError(t, err)
+	return pk
+}
+
+func TestMultiSignedCorim_SignVerify_ok(t *testing.T) {
+	vendor := corimSignerFromJWK(t, testES256Key, "ACME Silicon")
+	vendor.Headers = cose.ProtectedHeader{cose.HeaderLabelKeyID: []byte("vendor-key")}
+	oem := corimSignerFromJWK(t, testEdDSAKey, "ACME OEM")
+
+	var in MultiSignedCorim
+	in.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	data, err := in.Sign(vendor, oem)
+	require.NoError(t, err)
+	assert.Len(t, in.Signatures, 2)
+
+	var out MultiSignedCorim
+	err = out.FromCOSE(data)
+	require.NoError(t, err)
+
+	require.Len(t, out.Signatures, 2)
+	assert.Equal(t, "ACME Silicon", out.Signatures[0].Meta.Signer.Name)
+	assert.Equal(t, []byte("vendor-key"), out.Signatures[0].Headers.Protected[cose.HeaderLabelKeyID])
+	assert.Equal(t, "ACME OEM", out.Signatures[1].Meta.Signer.Name)
+	assert.Equal(t, in.UnsignedCorim.ID, out.UnsignedCorim.ID)
+
+	// keys can be supplied in any order
+	err = out.Verify(
+		publicKeyFromJWK(t, testEdDSAKey),
+		publicKeyFromJWK(t, testES256Key),
+	)
+	assert.NoError(t, err)
+
+	results, err := out.VerifyEach(
+		publicKeyFromJWK(t, testES256Key),
+		publicKeyFromJWK(t, testEdDSAKey),
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Verified())
+	assert.Equal(t, 0, results[0].KeyIndex)
+	assert.Equal(t, "ACME Silicon", results[0].Meta.Signer.Name)
+	assert.True(t, results[1].Verified())
+	assert.Equal(t, 1, results[1].KeyIndex)
+}
+
+func TestMultiSignedCorim_VerifyThreshold(t *testing.T) {
+	var in MultiSignedCorim
+	in.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	data, err := in.Sign(
+		corimSignerFromJWK(t, testES256Key, "ACME Silicon"),
+		corimSignerFromJWK(t, testPS256Key, "ACME OEM"),
+		corimSignerFromJWK(t, testEdDSAKey, "ACME Compliance"),
+	)
+	require.NoError(t, err)
+
+	var out MultiSignedCorim
+	require.NoError(t, out.FromCOSE(data))
+
+	results, err := out.VerifyThreshold(2,
+		publicKeyFromJWK(t, testES256Key),
+		publicKeyFromJWK(t, testEdDSAKey),
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.True(t, results[0].Verified())
+	assert.False(t, results[1].Verified())
+	assert.EqualError(t, results[1].Err, "no matching verification key")
+	assert.Equal(t, -1, results[1].KeyIndex)
+	assert.True(t, results[2].Verified())
+
+	_, err = out.VerifyThreshold(3,
+		publicKeyFromJWK(t, testES256Key),
+		publicKeyFromJWK(t, testEdDSAKey),
+	)
+	assert.EqualError(t, err, "threshold not met: 2 of 3 signatures verified, 3 required")
+
+	err = out.Verify(publicKeyFromJWK(t, testES256Key), publicKeyFromJWK(t, testEdDSAKey))
+	assert.EqualError(t, err, "signature at index 1: no matching verification key")
+
+	// the same key cannot be used to satisfy two signatures
+	_, err = out.VerifyThreshold(2, publicKeyFromJWK(t, testES256Key))
+	assert.EqualError(t, err, "threshold not met: 1 of 3 signatures verified, 2 required")
+
+	_, err = out.VerifyThreshold(0)
+	assert.EqualError(t, err, "invalid threshold 0")
+}
+
+func TestMultiSignedCorim_Verify_fail_tampered(t *testing.T) {
+	var in MultiSignedCorim
+	in.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	data, err := in.Sign(corimSignerFromJWK(t, testES256Key, "ACME Silicon"))
+	require.NoError(t, err)
+
+	// Flip the last byte in the signature field
+	data[len(data)-1] ^= 0xff
+
+	var out MultiSignedCorim
+	require.NoError(t, out.FromCOSE(data))
+
+	err = out.Verify(publicKeyFromJWK(t, testES256Key))
+	assert.EqualError(t, err, "signature at index 0: no matching verification key")
+}
+
+func TestMultiSignedCorim_Sign_fail(t *testing.T) {
+	var in MultiSignedCorim
+
+	_, err := in.Sign()
+	assert.EqualError(t, err, "no signers")
+
+	_, err = in.Sign(CorimSigner{})
+	assert.EqualError(t, err, "failed validation of unsigned CoRIM: empty id")
+
+	in.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	_, err = in.Sign(corimSignerFromJWK(t, testES256Key, "ACME Silicon"), CorimSigner{})
+	assert.EqualError(t, err, "signer at index 1: nil signer")
+}
+
+func TestMultiSignedCorim_FromCOSE_fail(t *testing.T) {
+	var out MultiSignedCorim
+
+	err := out.FromCOSE([]byte{0xf6})
+	assert.EqualError(t, err, "failed CBOR decoding for COSE-Sign signed CoRIM: cbor: invalid COSE_Sign_Tagged object")
+
+	// a COSE_Sign1 signed-corim is not a COSE_Sign signed-corim
+	err = out.FromCOSE(testGoodSignedCorimCBOR)
+	assert.EqualError(t, err, "failed CBOR decoding for COSE-Sign signed CoRIM: cbor: invalid COSE_Sign_Tagged object")
+
+	var empty MultiSignedCorim
+	_, err = empty.VerifyEach()
+	assert.EqualError(t, err, "no Sign message found")
+}
+
+func TestMultiSignedCorim_FromCOSE_fail_no_meta(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	payload, err := unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR).ToCBOR()
+	require.NoError(t, err)
+
+	msg := cose.NewSignMessage()
+	msg.Payload = payload
+	msg.Headers.Protected[cose.HeaderLabelContentType] = ContentType
+
+	sig := cose.NewSignature()
+	sig.Headers.Protected.SetAlgorithm(signer.Algorithm())
+	msg.Signatures = append(msg.Signatures, sig)
+
+	require.NoError(t, msg.Sign(rand.Reader, NoExternalData, signer))
+
+	data, err := msg.MarshalCBOR()
+	require.NoError(t, err)
+
+	var out MultiSignedCorim
+	err = out.FromCOSE(data)
+	assert.EqualError(t, err, "processing COSE headers: signature at index 0: missing mandatory corim.meta")
+}
+
+func TestMultiSignedCorim_extensions(t *testing.T) {
+	var in MultiSignedCorim
+	in.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	signer := corimSignerFromJWK(t, testES256Key, "ACME Silicon")
+	signer.Meta.Signer.Extensions.Register(&signerExtensions{Address: "Somewhere"})
+
+	data, err := in.Sign(signer)
+	require.NoError(t, err)
+
+	out := NewMultiSignedCorim()
+	err = out.RegisterExtensions(extensions.NewMap().Add(ExtSigner, &signerExtensions{}))
+	require.NoError(t, err)
+
+	require.NoError(t, out.FromCOSE(data))
+	require.Len(t, out.Signatures, 1)
+	assert.Equal(t, "Somewhere", out.Signatures[0].Meta.Signer.Extensions.MustGetString("address"))
+
+	badMap := extensions.NewMap().Add(extensions.Point("test"), &struct{}{})
+	err = out.RegisterExtensions(badMap)
+	assert.EqualError(t, err, `unexpected extension point: "test"`)
+}
+
+func TestUnmarshalMultiSignedCorimFromCBOR(t *testing.T) {
+	profileID, err := eat.NewProfile("http://example.com/multi-signed-profile")
+	require.NoError(t, err)
+
+	err = RegisterProfile(profileID, extensions.NewMap().Add(ExtSigner, &signerExtensions{}))
+	require.NoError(t, err)
+	defer UnregisterProfile(profileID)
+
+	var in MultiSignedCorim
+	in.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+	in.UnsignedCorim.Profile = profileID
+
+	signer := corimSignerFromJWK(t, testES256Key, "ACME Silicon")
+	signer.Meta.Signer.Extensions.Register(&signerExtensions{Address: "Somewhere"})
+
+	data, err := in.Sign(signer)
+	require.NoError(t, err)
+
+	out, err := UnmarshalMultiSignedCorimFromCBOR(data)
+	require.NoError(t, err)
+	assert.Equal(t, "Somewhere", out.Signatures[0].Meta.Signer.Extensions.MustGetString("address"))
+	assert.NoError(t, out.Verify(publicKeyFromJWK(t, testES256Key)))
+
+	_, err = UnmarshalMultiSignedCorimFromCBOR([]byte{0xf6})
+	assert.EqualError(t, err, "failed CBOR decoding for COSE-Sign signed CoRIM: cbor: invalid COSE_Sign_Tagged object")
+}
+
+func TestMultiSignedCorim_FromCOSE_limits(t *testing.T) {
+	var in MultiSignedCorim
+	in.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	data, err := in.Sign(corimSignerFromJWK(t, testES256Key, "ACME Silicon"))
+	require.NoError(t, err)
+
+	// COSE_Sign is a 4-element array
+	limits := encoding.DecodeLimits{MaxElements: 3}
+	opts := encoding.DecodeOptions{Limits: &limits}
+
+	var le *encoding.LimitError
+
+	err = new(MultiSignedCorim).FromCOSE(data, opts)
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxElements", le.Limit)
+
+	_, err = UnmarshalMultiSignedCorimFromCBOR(data, opts)
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxElements", le.Limit)
+
+	_, err = UnmarshalMultiSignedCorimFromCBOR(data, encoding.DecodeOptions{
+		Limits: &encoding.DefaultDecodeLimits,
+	})
+	assert.NoError(t, err)
+}
@@ -4,10 +4,16 @@ package corim
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
 	"github.com/veraison/eat"
 	"github.com/veraison/go-cose"
@@ -19,6 +25,7 @@ var SignedCorimMapExtensionPoints = []extensions.Point{
 	ExtSigner,
 	ExtUnsignedCorim,
 	ExtEntity,
+	ExtLocator,
 }
 
 // UnsignedCorimMapExtensionPoints is a list of extension.Point's valid for a
@@ -26,6 +33,7 @@ var SignedCorimMapExtensionPoints = []extensions.Point{
 var UnsignedCorimMapExtensionPoints = []extensions.Point{
 	ExtUnsignedCorim,
 	ExtEntity,
+	ExtLocator,
 }
 
 // ComidMapExtensionPoints is a list of extension.Point's valid for a comid.Comid.
@@ -37,6 +45,20 @@ var ComidMapExtensionPoints = []extensions.Point{
 	comid.ExtReferenceValueFlags,
 	comid.ExtEndorsedValue,
 	comid.ExtEndorsedValueFlags,
+	comid.ExtEnvironment,
+	comid.ExtClass,
+	comid.ExtKeyTriple,
+	comid.ExtLinkedTag,
+	comid.ExtTagIdentity,
+}
+
+// CotsMapExtensionPoints is a list of extension.Point's valid for a
+// cots.ConciseTaStore.
+var CotsMapExtensionPoints = []extensions.Point{
+	cots.ExtCots,
+	cots.ExtEnvironmentGroup,
+	cots.ExtPermClaims,
+	cots.ExtExclClaims,
 }
 
 // AllExtensionPoints is a list of all valid extension.Point's
@@ -45,24 +67,105 @@ var AllExtensionPoints = make(map[extensions.Point]bool) // populated inside ini
 // UnmarshalSignedCorimFromCBOR unmarshals a SignedCorim from provided
 // CBOR data. If there are extensions associated with the profile specified by
 // the data, they will be registered with the UnsignedCorim before it is
-// unmarshaled.
-func UnmarshalSignedCorimFromCBOR(buf []byte) (*SignedCorim, error) {
+// unmarshaled. Optional DecodeOptions may be specified, as for
+// SignedCorim.FromCOSE.
+func UnmarshalSignedCorimFromCBOR(
+	buf []byte,
+	opts ...encoding.DecodeOptions,
+) (*SignedCorim, error) {
+	return UnmarshalSignedCorimFromCBORWithPayload(buf, nil, opts...)
+}
+
+// UnmarshalSignedCorimFromCBORWithPayload is like UnmarshalSignedCorimFromCBOR,
+// except that the CBOR-encoded unsigned-corim is supplied separately. This is
+// needed for signed-corims with a detached payload, or using a hash envelope.
+// See also SignedCorim.FromCOSEWithPayload.
+func UnmarshalSignedCorimFromCBORWithPayload(
+	buf []byte,
+	payload []byte,
+	opts ...encoding.DecodeOptions,
+) (*SignedCorim, error) {
 	message := cose.NewSign1Message()
+	dec := encoding.NewDecoder(dm).WithOptions(opts...)
+
+	if err := encoding.CheckCBORLimits(buf, dec.Limits()); err != nil {
+		return nil, fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
+	}
+
+	buf, _, err := stripSignedCorimTags(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
+	}
 
 	if err := message.UnmarshalCBOR(buf); err != nil {
 		return nil, fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
 	}
 
+	// unless a hash envelope is used, the payload of the message is the
+	// unsigned-corim (or nil, if detached)
+	_, isHashEnvelope := message.Headers.Protected[HeaderLabelPayloadHashAlg]
+	if payload == nil && !isHashEnvelope {
+		payload = message.Payload
+	}
+
+	if payload == nil {
+		return nil, errors.New("unsigned CoRIM payload must be supplied")
+	}
+
+	if err := encoding.CheckCBORLimits(payload, dec.Limits()); err != nil {
+		return nil, err
+	}
+
 	profiled := struct {
 		Profile *eat.Profile `cbor:"3,keyasint,omitempty"`
 	}{}
 
-	if err := dm.Unmarshal(message.Payload, &profiled); err != nil {
+	if err := dec.Unmarshal(payload, &profiled); err != nil {
 		return nil, err
 	}
 
 	ret := GetSignedCorim(profiled.Profile)
-	if err := ret.FromCOSE(buf); err != nil {
+	if err := ret.FromCOSEWithPayload(buf, payload, opts...); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UnmarshalMultiSignedCorimFromCBOR unmarshals a MultiSignedCorim from
+// provided COSE_Sign CBOR data. If there are extensions associated with the
+// profile specified by the data, they will be registered with the
+// MultiSignedCorim before it is unmarshaled. Optional DecodeOptions may be
+// specified, as for MultiSignedCorim.FromCOSE.
+func UnmarshalMultiSignedCorimFromCBOR(
+	buf []byte,
+	opts ...encoding.DecodeOptions,
+) (*MultiSignedCorim, error) {
+	message := cose.NewSignMessage()
+	dec := encoding.NewDecoder(dm).WithOptions(opts...)
+
+	if err := encoding.CheckCBORLimits(buf, dec.Limits()); err != nil {
+		return nil, fmt.Errorf("failed CBOR decoding for COSE-Sign signed CoRIM: %w", err)
+	}
+
+	if err := message.UnmarshalCBOR(buf); err != nil {
+		return nil, fmt.Errorf("failed CBOR decoding for COSE-Sign signed CoRIM: %w", err)
+	}
+
+	profiled := struct {
+		Profile *eat.Profile `cbor:"3,keyasint,omitempty"`
+	}{}
+
+	if err := encoding.CheckCBORLimits(message.Payload, dec.Limits()); err != nil {
+		return nil, err
+	}
+
+	if err := dec.Unmarshal(message.Payload, &profiled); err != nil {
+		return nil, err
+	}
+
+	ret := GetMultiSignedCorim(profiled.Profile)
+	if err := ret.FromCOSE(buf, opts...); err != nil {
 		return nil, err
 	}
 
@@ -72,18 +175,28 @@ func UnmarshalSignedCorimFromCBOR(buf []byte) (*SignedCorim, error) {
 // UnmarshalUnsignedCorimFromCBOR unmarshals an UnsignedCorim from provided
 // CBOR data. If there are extensions associated with the profile specified by
 // the data, they will be registered with the UnsignedCorim before it is
-// unmarshaled.
-func UnmarshalUnsignedCorimFromCBOR(buf []byte) (*UnsignedCorim, error) {
+// unmarshaled. Optional DecodeOptions may be specified, as for
+// UnsignedCorim.FromCBOR.
+func UnmarshalUnsignedCorimFromCBOR(
+	buf []byte,
+	opts ...encoding.DecodeOptions,
+) (*UnsignedCorim, error) {
 	profiled := struct {
 		Profile *eat.Profile `cbor:"3,keyasint,omitempty"`
 	}{}
 
-	if err := dm.Unmarshal(buf, &profiled); err != nil {
+	dec := encoding.NewDecoder(dm).WithOptions(opts...)
+
+	if err := encoding.CheckCBORLimits(buf, dec.Limits()); err != nil {
+		return nil, err
+	}
+
+	if err := dec.Unmarshal(buf, &profiled); err != nil {
 		return nil, err
 	}
 
 	ret := GetUnsignedCorim(profiled.Profile)
-	if err := ret.FromCBOR(buf); err != nil {
+	if err := ret.FromCBOR(buf, opts...); err != nil {
 		return nil, err
 	}
 
@@ -93,18 +206,28 @@ func UnmarshalUnsignedCorimFromCBOR(buf []byte) (*UnsignedCorim, error) {
 // UnmarshalUnsignedCorimFromJSON unmarshals an UnsignedCorim from provided
 // JSON data. If there are extensions associated with the profile specified by
 // the data, they will be registered with the UnsignedCorim before it is
-// unmarshaled.
-func UnmarshalUnsignedCorimFromJSON(buf []byte) (*UnsignedCorim, error) {
+// unmarshaled. Optional DecodeOptions may be specified, as for
+// UnsignedCorim.FromJSON.
+func UnmarshalUnsignedCorimFromJSON(
+	buf []byte,
+	opts ...encoding.DecodeOptions,
+) (*UnsignedCorim, error) {
 	profiled := struct {
 		Profile *eat.Profile `json:"profile,omitempty"`
 	}{}
 
+	limits := encoding.NewDecoder(nil).WithOptions(opts...).Limits()
+
+	if err := encoding.CheckJSONLimits(buf, limits); err != nil {
+		return nil, err
+	}
+
 	if err := json.Unmarshal(buf, &profiled); err != nil {
 		return nil, err
 	}
 
 	ret := GetUnsignedCorim(profiled.Profile)
-	if err := ret.FromJSON(buf); err != nil {
+	if err := ret.FromJSON(buf, opts...); err != nil {
 		return nil, err
 	}
 
@@ -113,8 +236,13 @@ func UnmarshalUnsignedCorimFromJSON(buf []byte) (*UnsignedCorim, error) {
 
 // UnmarshalComidFromCBOR unmarshals a comid.Comid from provided CBOR data. If
 // there are extensions associated with the profile specified by the data, they
-// will be registered with the comid.Comid before it is unmarshaled.
-func UnmarshalComidFromCBOR(buf []byte, profileID *eat.Profile) (*comid.Comid, error) {
+// will be registered with the comid.Comid before it is unmarshaled. Optional
+// DecodeOptions may be specified, as for comid.Comid.FromCBOR.
+func UnmarshalComidFromCBOR(
+	buf []byte,
+	profileID *eat.Profile,
+	opts ...encoding.DecodeOptions,
+) (*comid.Comid, error) {
 	var ret *comid.Comid
 
 	profileManifest, ok := GetProfileManifest(profileID)
@@ -124,7 +252,34 @@ func UnmarshalComidFromCBOR(buf []byte, profileID *eat.Profile) (*comid.Comid, e
 		ret = comid.NewComid()
 	}
 
-	if err := ret.FromCBOR(buf); err != nil {
+	if err := ret.FromCBOR(buf, opts...); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UnmarshalCotsFromCBOR unmarshals a cots.ConciseTaStore from provided CBOR
+// data. If there are extensions associated with the profile specified by the
+// data, they will be registered with the cots.ConciseTaStore before it is
+// unmarshaled, and the profile's type choices (if any) are used in addition to
+// the base ones. Optional DecodeOptions may be specified, as for
+// cots.ConciseTaStore.FromCBOR.
+func UnmarshalCotsFromCBOR(
+	buf []byte,
+	profileID *eat.Profile,
+	opts ...encoding.DecodeOptions,
+) (*cots.ConciseTaStore, error) {
+	var ret *cots.ConciseTaStore
+
+	profileManifest, ok := GetProfileManifest(profileID)
+	if ok {
+		ret = profileManifest.GetCots()
+	} else {
+		ret = cots.NewConciseTaStore()
+	}
+
+	if err := ret.FromCBOR(buf, opts...); err != nil {
 		return nil, err
 	}
 
@@ -160,6 +315,20 @@ func GetSignedCorim(profileID *eat.Profile) *SignedCorim {
 	return ret
 }
 
+// GetMultiSignedCorim returns a pointer to a new MultiSignedCorim instance. If
+// there are extensions associated with the provided profileID, they will be
+// registered with the instance.
+func GetMultiSignedCorim(profileID *eat.Profile) *MultiSignedCorim {
+	profileManifest, ok := GetProfileManifest(profileID)
+	if !ok {
+		// unknown (or no) profile -- treat like an unprofiled CoRIM (see
+		// the comment in GetSignedCorim)
+		return NewMultiSignedCorim()
+	}
+
+	return profileManifest.GetMultiSignedCorim()
+}
+
 // GetUnsignedCorim returns a pointer to a new UnsignedCorim instance. If there
 // are extensions associated with the provided profileID, they will be
 // registered with the instance.
@@ -189,22 +358,36 @@ func GetUnsignedCorim(profileID *eat.Profile) *UnsignedCorim {
 	return ret
 }
 
-// ProfileManifest associates an EAT profile ID with a set of extensions. It allows
-// obtaining new CoRIM and CoMID structures that had associated extensions
-// registered.
+// ProfileManifest associates an EAT profile ID with a set of extensions and,
+// optionally, a set of profile-specific type choices. It allows obtaining new
+// CoRIM and CoMID structures that had associated extensions registered.
 type ProfileManifest struct {
 	ID            *eat.Profile
 	MapExtensions extensions.Map
+	// TypeRegistry holds the type choices (and their CBOR tags) that are
+	// specific to the profile. These are used in addition to the base ones
+	// when decoding CoMIDs (and CoTS) associated with the profile.
+	TypeRegistry *comid.TypeRegistry
 }
 
 // GetComid returns a pointer to a new comid.Comid that had the ProfileManifest's
-// extensions (if any) registered.
+// extensions (if any) registered, and that uses the ProfileManifest's type
+// registry (if any).
 func (o *ProfileManifest) GetComid() *comid.Comid {
-	ret := comid.NewComid()
+	ret := comid.NewComid().SetTypeRegistry(o.TypeRegistry)
 	o.registerExtensions(ret, ComidMapExtensionPoints)
 	return ret
 }
 
+// GetCots returns a pointer to a new cots.ConciseTaStore that had the
+// ProfileManifest's extensions (if any) registered, and that uses the
+// ProfileManifest's type registry (if any).
+func (o *ProfileManifest) GetCots() *cots.ConciseTaStore {
+	ret := cots.NewConciseTaStore().SetTypeRegistry(o.TypeRegistry)
+	o.registerExtensions(ret, CotsMapExtensionPoints)
+	return ret
+}
+
 // GetUnsignedCorim returns a pointer to a new UnsignedCorim that had the
 // ProfileManifest's extensions (if any) registered.
 func (o *ProfileManifest) GetUnsignedCorim() *UnsignedCorim {
@@ -223,6 +406,15 @@ func (o *ProfileManifest) GetSignedCorim() *SignedCorim {
 	return ret
 }
 
+// GetMultiSignedCorim returns a pointer to a new MultiSignedCorim that had the
+// ProfileManifest's extensions (if any) registered.
+func (o *ProfileManifest) GetMultiSignedCorim() *MultiSignedCorim {
+	ret := NewMultiSignedCorim()
+	ret.UnsignedCorim.Profile = o.ID
+	o.registerExtensions(ret, SignedCorimMapExtensionPoints)
+	return ret
+}
+
 func (o *ProfileManifest) registerExtensions(e iextensible, points []extensions.Point) {
 	exts := extensions.NewMap()
 	for _, p := range points {
@@ -243,15 +435,21 @@ func (o *ProfileManifest) registerExtensions(e iextensible, points []extensions.
 // the profile has already been registered, or if the extensions are invalid,
 // an error is returned.
 func RegisterProfile(id *eat.Profile, exts extensions.Map) error {
+	return RegisterProfileWithTypes(id, exts, nil)
+}
+
+// RegisterProfileWithTypes is like RegisterProfile, but also associates the
+// profile with a registry of profile-specific type choices. Unlike the ones
+// registered with comid.RegisterClassIDType and friends, these are only used
+// when processing CoMIDs associated with the profile, so that different
+// profiles may assign different meanings to the same CBOR tag. Types is
+// optional.
+func RegisterProfileWithTypes(id *eat.Profile, exts extensions.Map, types *comid.TypeRegistry) error {
 	strID, err := id.Get()
 	if err != nil {
 		return err
 	}
 
-	if _, ok := profilesRegister[strID]; ok {
-		return fmt.Errorf("profile with id %q already registered", strID)
-	}
-
 	for p, v := range exts {
 		if _, ok := AllExtensionPoints[p]; !ok {
 			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
@@ -262,7 +460,24 @@ func RegisterProfile(id *eat.Profile, exts extensions.Map) error {
 		}
 	}
 
-	profilesRegister[strID] = ProfileManifest{ID: id, MapExtensions: exts}
+	if types != nil {
+		if _, err := types.TagSet(); err != nil {
+			return fmt.Errorf("invalid type registry: %w", err)
+		}
+	}
+
+	profilesRegisterMu.Lock()
+	defer profilesRegisterMu.Unlock()
+
+	if _, ok := profilesRegister[strID]; ok {
+		return fmt.Errorf("profile with id %q already registered", strID)
+	}
+
+	profilesRegister[strID] = ProfileManifest{
+		ID:            id,
+		MapExtensions: exts,
+		TypeRegistry:  types,
+	}
 
 	return nil
 }
@@ -280,6 +495,9 @@ func UnregisterProfile(id *eat.Profile) bool {
 		return false
 	}
 
+	profilesRegisterMu.Lock()
+	defer profilesRegisterMu.Unlock()
+
 	if _, ok := profilesRegister[strID]; ok {
 		delete(profilesRegister, strID)
 		return true
@@ -301,15 +519,174 @@ func GetProfileManifest(id *eat.Profile) (ProfileManifest, bool) {
 		return ProfileManifest{}, false
 	}
 
+	profilesRegisterMu.RLock()
+	defer profilesRegisterMu.RUnlock()
+
 	prof, ok := profilesRegister[strID]
 	return prof, ok
 }
 
+// ListProfiles returns the IDs of the registered profiles, sorted by their
+// string representation.
+func ListProfiles() []*eat.Profile {
+	manifests := registeredProfiles()
+
+	ret := make([]*eat.Profile, 0, len(manifests))
+	for _, manifest := range manifests {
+		ret = append(ret, manifest.ID)
+	}
+
+	return ret
+}
+
+// registeredProfiles returns a snapshot of the registered profile manifests,
+// sorted by the string representation of their IDs
+func registeredProfiles() []ProfileManifest {
+	profilesRegisterMu.RLock()
+	defer profilesRegisterMu.RUnlock()
+
+	strIDs := make([]string, 0, len(profilesRegister))
+	for strID := range profilesRegister {
+		strIDs = append(strIDs, strID)
+	}
+
+	sort.Strings(strIDs)
+
+	ret := make([]ProfileManifest, 0, len(strIDs))
+	for _, strID := range strIDs {
+		ret = append(ret, profilesRegister[strID])
+	}
+
+	return ret
+}
+
+// ProfileDescription summarizes what a registered profile provides
+type ProfileDescription struct {
+	// ID is the string representation of the profile ID
+	ID string
+	// Extensions lists the extension structs registered by the profile,
+	// sorted by point. Structs composed at the same point (see
+	// extensions.Compose and extensions.Map.Layer) are listed separately,
+	// in the order in which they have been composed.
+	Extensions []ExtensionDescription
+	// Types maps the CBOR tags of the profile-specific type choices (if any)
+	// onto the Go types they are decoded into
+	Types map[uint64]reflect.Type
+}
+
+// ExtensionDescription describes the extensions registered at an
+// extensions.Point
+type ExtensionDescription struct {
+	Point extensions.Point
+	Type  reflect.Type
+}
+
+// String returns a human-readable multi-line representation of the target
+// ProfileDescription
+func (o ProfileDescription) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "profile %s\n", o.ID)
+
+	for _, e := range o.Extensions {
+		fmt.Fprintf(&b, "  extension %s: %s\n", e.Point, e.Type)
+	}
+
+	tags := make([]uint64, 0, len(o.Types))
+	for tag := range o.Types {
+		tags = append(tags, tag)
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+
+	for _, tag := range tags {
+		fmt.Fprintf(&b, "  type tag %d: %s\n", tag, o.Types[tag])
+	}
+
+	return b.String()
+}
+
+// DescribeProfile reports the extension points populated by the specified
+// profile, along with the Go types registered for each, and the
+// profile-specific type choices (if any). An error is returned if the profile
+// has not been registered.
+func DescribeProfile(id *eat.Profile) (*ProfileDescription, error) {
+	if id == nil {
+		return nil, errors.New("nil profile ID")
+	}
+
+	strID, err := id.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, ok := GetProfileManifest(id)
+	if !ok {
+		return nil, fmt.Errorf("profile with id %q not registered", strID)
+	}
+
+	ret := describeManifest(strID, manifest)
+
+	return &ret, nil
+}
+
+func describeManifest(strID string, manifest ProfileManifest) ProfileDescription {
+	ret := ProfileDescription{
+		ID:         strID,
+		Extensions: make([]ExtensionDescription, 0, len(manifest.MapExtensions)),
+		Types:      make(map[uint64]reflect.Type),
+	}
+
+	for p, v := range manifest.MapExtensions {
+		members := []extensions.IMapValue{v}
+		if c, ok := v.(*extensions.Composite); ok {
+			members = c.Values()
+		}
+
+		for _, m := range members {
+			ret.Extensions = append(ret.Extensions, ExtensionDescription{
+				Point: p,
+				Type:  reflect.TypeOf(m),
+			})
+		}
+	}
+
+	sort.SliceStable(ret.Extensions, func(i, j int) bool {
+		return ret.Extensions[i].Point < ret.Extensions[j].Point
+	})
+
+	if manifest.TypeRegistry != nil {
+		ret.Types = manifest.TypeRegistry.Tags()
+	}
+
+	return ret
+}
+
+// DescribeProfiles returns the description of every registered profile, in
+// the same order as ListProfiles. The descriptions are taken from a snapshot
+// of the registered profiles, so profiles (un)registered concurrently may or
+// may not be included.
+func DescribeProfiles() []ProfileDescription {
+	manifests := registeredProfiles()
+	ret := make([]ProfileDescription, 0, len(manifests))
+
+	for _, manifest := range manifests {
+		// registered profile IDs are known to be valid
+		strID, _ := manifest.ID.Get()
+		ret = append(ret, describeManifest(strID, manifest))
+	}
+
+	return ret
+}
+
 type iextensible interface {
 	RegisterExtensions(exts extensions.Map) error
 }
 
-var profilesRegister = make(map[string]ProfileManifest)
+var (
+	profilesRegister   = make(map[string]ProfileManifest)
+	profilesRegisterMu sync.RWMutex
+)
 
 func init() {
 	for _, p := range SignedCorimMapExtensionPoints {
@@ -323,4 +700,8 @@ func init() {
 	for _, p := range ComidMapExtensionPoints {
 		AllExtensionPoints[p] = true
 	}
+
+	for _, p := range CotsMapExtensionPoints {
+		AllExtensionPoints[p] = true
+	}
 }
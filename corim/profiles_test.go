@@ -3,11 +3,15 @@
 package corim
 
 import (
+	"fmt"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/cots"
 	"github.com/veraison/corim/extensions"
 	"github.com/veraison/eat"
 )
@@ -185,3 +189,316 @@ func TestProfileManifest_marshaling(t *testing.T) {
 
 	UnregisterProfile(profID)
 }
+
+type testProfileClassID string
+
+func newTestProfileClassID(val any) (*comid.ClassID, error) {
+	var v testProfileClassID
+
+	if val != nil {
+		s, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T", val)
+		}
+		v = testProfileClassID(s)
+	}
+
+	return &comid.ClassID{Value: &v}, nil
+}
+
+func (o testProfileClassID) Bytes() []byte  { return []byte(o) }
+func (o testProfileClassID) Type() string   { return "test-profile-class-id" }
+func (o testProfileClassID) String() string { return string(o) }
+func (o testProfileClassID) Valid() error   { return nil }
+
+type otherProfileClassID uint64
+
+func newOtherProfileClassID(val any) (*comid.ClassID, error) {
+	v := otherProfileClassID(0)
+	return &comid.ClassID{Value: &v}, nil
+}
+
+func (o otherProfileClassID) Bytes() []byte  { return nil }
+func (o otherProfileClassID) Type() string   { return "other-profile-class-id" }
+func (o otherProfileClassID) String() string { return fmt.Sprint(uint64(o)) }
+func (o otherProfileClassID) Valid() error   { return nil }
+
+func TestProfileManifest_type_registry(t *testing.T) {
+	// the two profiles assign different meanings to the same CBOR tag
+	const tag = 99980
+
+	typesA := comid.NewTypeRegistry()
+	require.NoError(t, typesA.RegisterClassIDType(tag, newTestProfileClassID))
+
+	typesB := comid.NewTypeRegistry()
+	require.NoError(t, typesB.RegisterClassIDType(tag, newOtherProfileClassID))
+
+	profileA, err := eat.NewProfile("http://example.com/profile-a")
+	require.NoError(t, err)
+	require.NoError(t, RegisterProfileWithTypes(profileA, extensions.NewMap(), typesA))
+	defer UnregisterProfile(profileA)
+
+	profileB, err := eat.NewProfile("http://example.com/profile-b")
+	require.NoError(t, err)
+	require.NoError(t, RegisterProfileWithTypes(profileB, extensions.NewMap(), typesB))
+	defer UnregisterProfile(profileB)
+
+	manifest, ok := GetProfileManifest(profileA)
+	require.True(t, ok)
+	assert.Same(t, typesA, manifest.TypeRegistry)
+
+	v := testProfileClassID("acme")
+
+	c := manifest.GetComid().
+		SetTagIdentity("scoped", 0).
+		AddReferenceValue(comid.ValueTriple{
+			Environment: comid.Environment{
+				Class: &comid.Class{ClassID: &comid.ClassID{Value: &v}},
+			},
+			Measurements: *comid.NewMeasurements().Add(
+				comid.MustNewUintMeasurement(uint64(1)).SetSVN(2),
+			),
+		})
+	require.NotNil(t, c)
+
+	rim := manifest.GetUnsignedCorim().SetID("scoped").AddComid(c)
+	require.NotNil(t, rim)
+
+	data, err := rim.ToCBOR()
+	require.NoError(t, err)
+
+	decoded, err := UnmarshalUnsignedCorimFromCBOR(data)
+	require.NoError(t, err)
+
+	comids, err := decoded.Comids()
+	require.NoError(t, err)
+	require.Len(t, comids, 1)
+
+	classID := comids[0].Triples.ReferenceValues.Values[0].Environment.Class.ClassID
+	assert.Equal(t, "test-profile-class-id", classID.Type())
+	assert.Equal(t, "acme", classID.String())
+
+	// the same CoMID cannot be decoded under the other profile, or without
+	// a profile
+	_, err = UnmarshalComidFromCBOR(decoded.Tags[0][3:], profileB)
+	assert.Error(t, err)
+
+	_, err = UnmarshalComidFromCBOR(decoded.Tags[0][3:], nil)
+	assert.Error(t, err)
+
+	// CoTS environments use the profile's type choices as well
+	store := manifest.GetCots()
+	require.NoError(t, store.FromJSON([]byte(cots.ConciseTaStoreTemplateSingleOrg)))
+	store.Environments[0].Environment.Class.ClassID = &comid.ClassID{Value: &v}
+
+	storeData, err := store.ToCBOR()
+	require.NoError(t, err)
+
+	decodedStore, err := UnmarshalCotsFromCBOR(storeData, profileA)
+	require.NoError(t, err)
+
+	classID = decodedStore.Environments[0].Environment.Class.ClassID
+	assert.Equal(t, "test-profile-class-id", classID.Type())
+	assert.Equal(t, "acme", classID.String())
+
+	_, err = UnmarshalCotsFromCBOR(storeData, profileB)
+	assert.Error(t, err)
+}
+
+type baseClassID struct{ otherProfileClassID }
+
+func newBaseClassID(val any) (*comid.ClassID, error) {
+	return &comid.ClassID{Value: &baseClassID{}}, nil
+}
+
+func (o baseClassID) Type() string { return "base-class-id" }
+
+func TestRegisterProfileWithTypes_bad_registry(t *testing.T) {
+	types := comid.NewTypeRegistry()
+	require.NoError(t, types.RegisterClassIDType(99981, newTestProfileClassID))
+
+	// a base tag registered after the registry's creation clashes with it
+	require.NoError(t, comid.RegisterClassIDType(99981, newBaseClassID))
+
+	profileID, err := eat.NewProfile("http://example.com/bad-types")
+	require.NoError(t, err)
+
+	err = RegisterProfileWithTypes(profileID, extensions.NewMap(), types)
+	assert.EqualError(t, err, "invalid type registry: tag 99981 is already registered")
+}
+
+func TestListProfiles(t *testing.T) {
+	p1, err := eat.NewProfile("http://example.com/list-b")
+	require.NoError(t, err)
+	p2, err := eat.NewProfile("http://example.com/list-a")
+	require.NoError(t, err)
+
+	require.NoError(t, RegisterProfile(p1, extensions.NewMap()))
+	require.NoError(t, RegisterProfile(p2, extensions.NewMap()))
+
+	var ids []string
+	for _, p := range ListProfiles() {
+		id, err := p.Get()
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	assert.Contains(t, ids, "http://example.com/list-a")
+	assert.Contains(t, ids, "http://example.com/list-b")
+	assert.IsIncreasing(t, ids)
+
+	assert.True(t, UnregisterProfile(p1))
+	assert.True(t, UnregisterProfile(p2))
+
+	for _, p := range ListProfiles() {
+		id, err := p.Get()
+		require.NoError(t, err)
+		assert.NotContains(t, []string{"http://example.com/list-a", "http://example.com/list-b"}, id)
+	}
+}
+
+func TestDescribeProfile(t *testing.T) {
+	profileID, err := eat.NewProfile("http://example.com/example-profile")
+	require.NoError(t, err)
+
+	desc, err := DescribeProfile(profileID)
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://example.com/example-profile", desc.ID)
+	assert.Equal(t, []ExtensionDescription{
+		{Point: comid.ExtComid, Type: reflect.TypeOf(&ComidExtensions{})},
+		{Point: comid.ExtEntity, Type: reflect.TypeOf(&EntityExtensions{})},
+		{Point: ExtEntity, Type: reflect.TypeOf(&EntityExtensions{})},
+		{Point: comid.ExtReferenceValue, Type: reflect.TypeOf(&RefValExtensions{})},
+	}, desc.Extensions)
+	assert.Empty(t, desc.Types)
+
+	assert.Contains(t, desc.String(), "profile http://example.com/example-profile\n")
+	assert.Contains(t, desc.String(), "  extension ReferenceValue: *corim.RefValExtensions\n")
+
+	unknown, err := eat.NewProfile("http://example.com/unknown")
+	require.NoError(t, err)
+
+	_, err = DescribeProfile(unknown)
+	assert.EqualError(t, err, `profile with id "http://example.com/unknown" not registered`)
+
+	_, err = DescribeProfile(nil)
+	assert.EqualError(t, err, "nil profile ID")
+}
+
+type testLayeredRefValExtensions struct {
+	Vendor *string `cbor:"-98,keyasint,omitempty" json:"vendor,omitempty"`
+}
+
+func TestDescribeProfile_composite(t *testing.T) {
+	exts := extensions.NewMap().Add(comid.ExtReferenceValue, &RefValExtensions{})
+	require.NoError(t, exts.Layer(comid.ExtReferenceValue, &testLayeredRefValExtensions{}))
+
+	profileID, err := eat.NewProfile("http://example.com/described-composite")
+	require.NoError(t, err)
+	require.NoError(t, RegisterProfile(profileID, exts))
+	defer UnregisterProfile(profileID)
+
+	desc, err := DescribeProfile(profileID)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ExtensionDescription{
+		{Point: comid.ExtReferenceValue, Type: reflect.TypeOf(&RefValExtensions{})},
+		{Point: comid.ExtReferenceValue, Type: reflect.TypeOf(&testLayeredRefValExtensions{})},
+	}, desc.Extensions)
+	assert.NotContains(t, desc.String(), "Composite")
+	assert.Contains(t, desc.String(), "  extension ReferenceValue: *corim.testLayeredRefValExtensions\n")
+}
+
+func TestDescribeProfile_types(t *testing.T) {
+	types := comid.NewTypeRegistry()
+	require.NoError(t, types.RegisterClassIDType(99982, newTestProfileClassID))
+
+	profileID, err := eat.NewProfile("http://example.com/described-types")
+	require.NoError(t, err)
+	require.NoError(t, RegisterProfileWithTypes(profileID, extensions.NewMap(), types))
+	defer UnregisterProfile(profileID)
+
+	desc, err := DescribeProfile(profileID)
+	require.NoError(t, err)
+
+	assert.Empty(t, desc.Extensions)
+	assert.Equal(t, map[uint64]reflect.Type{
+		99982: reflect.TypeOf(new(testProfileClassID)),
+	}, desc.Types)
+	assert.Contains(t, desc.String(), "  type tag 99982: *corim.testProfileClassID\n")
+
+	var found bool
+	for _, d := range DescribeProfiles() {
+		if d.ID == "http://example.com/described-types" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestDescribeProfiles_concurrent_unregister(t *testing.T) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		profileID, err := eat.NewProfile(fmt.Sprintf("http://example.com/transient/%d", i))
+		require.NoError(t, err)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				assert.NoError(t, RegisterProfile(profileID, extensions.NewMap()))
+				assert.True(t, UnregisterProfile(profileID))
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		assert.NotPanics(t, func() { DescribeProfiles() })
+	}
+
+	wg.Wait()
+}
+
+type testProfileCotsExtensions struct {
+	Expiry *int64 `cbor:"-1,keyasint,omitempty" json:"expiry,omitempty"`
+}
+
+func TestProfileManifest_cots_extensions(t *testing.T) {
+	profileID, err := eat.NewProfile("http://example.com/cots-profile")
+	require.NoError(t, err)
+
+	extMap := extensions.NewMap().Add(cots.ExtCots, &testProfileCotsExtensions{})
+	require.NoError(t, RegisterProfile(profileID, extMap))
+	defer UnregisterProfile(profileID)
+
+	manifest, ok := GetProfileManifest(profileID)
+	require.True(t, ok)
+
+	c := manifest.GetCots()
+	require.NoError(t, c.FromJSON([]byte(cots.ConciseTaStoreTemplateSingleOrg)))
+
+	expiry := int64(1735689600)
+	require.NoError(t, c.Set("expiry", &expiry))
+
+	rim := manifest.GetUnsignedCorim().SetID("cots with extensions").AddCots(c)
+	require.NotNil(t, rim)
+
+	data, err := rim.ToCBOR()
+	require.NoError(t, err)
+
+	decoded, err := UnmarshalUnsignedCorimFromCBOR(data)
+	require.NoError(t, err)
+
+	stores, err := decoded.Cots()
+	require.NoError(t, err)
+	require.Len(t, stores, 1)
+	assert.Equal(t, expiry, stores[0].MustGetInt64("expiry"))
+
+	// without the profile, the extension is not recognised
+	plain, err := UnmarshalCotsFromCBOR(decoded.Tags[0][3:], nil)
+	require.NoError(t, err)
+	assert.Nil(t, plain.GetExtensions())
+}
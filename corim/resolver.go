@@ -0,0 +1,415 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DefaultMaxResolveDepth is the maximum depth of the dependent RIMs
+	// graph used by ResolveDependentRims when none is specified
+	DefaultMaxResolveDepth = 8
+	// DefaultMaxRimSize is the maximum size of a dependent RIM fetched by
+	// HTTPResolver when none is specified
+	DefaultMaxRimSize = 16 * 1024 * 1024
+)
+
+// Resolver fetches the (CBOR-encoded, signed or unsigned) CoRIM that is
+// referenced by the href of a corim-locator-map
+type Resolver interface {
+	Resolve(ctx context.Context, href string) ([]byte, error)
+}
+
+// FileResolver resolves file:// URIs from the local filesystem
+type FileResolver struct{}
+
+// Resolve reads the file referenced by the supplied file:// URI
+func (o FileResolver) Resolve(ctx context.Context, href string) ([]byte, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "file" {
+		return nil, fmt.Errorf("expecting file URI, got %q", href)
+	}
+
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("unsupported file URI host %q", u.Host)
+	}
+
+	return os.ReadFile(filepath.FromSlash(u.Path))
+}
+
+// DirResolver resolves any href to the file in Dir that has the same name as
+// the last segment of the href path.  It is meant for the offline processing
+// of a set of CoRIMs that has been downloaded into a single directory.
+type DirResolver struct {
+	Dir string
+}
+
+// Resolve reads the file in the target's Dir that corresponds to the
+// supplied href
+func (o DirResolver) Resolve(ctx context.Context, href string) ([]byte, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+
+	p := u.Path
+	if p == "" {
+		p = u.Opaque
+	}
+
+	name := path.Base(p)
+	if name == "." || name == "/" || name == ".." {
+		return nil, fmt.Errorf("no file name in %q", href)
+	}
+
+	return os.ReadFile(filepath.Join(o.Dir, name))
+}
+
+// HTTPResolver resolves http:// and https:// URIs
+type HTTPResolver struct {
+	// Client is the HTTP client used to fetch the dependent RIMs.  If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+	// MaxSize is the maximum size of a fetched dependent RIM.  If zero,
+	// DefaultMaxRimSize is used.
+	MaxSize int64
+}
+
+// Resolve fetches the supplied href using an HTTP GET
+func (o HTTPResolver) Resolve(ctx context.Context, href string) ([]byte, error) {
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	maxSize := o.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxRimSize
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, href, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", ContentType)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status %q", res.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("response exceeds the maximum size of %d bytes", maxSize)
+	}
+
+	return data, nil
+}
+
+// SchemeResolver dispatches the resolution of an href to the Resolver
+// associated with its URI scheme
+type SchemeResolver map[string]Resolver
+
+// NewDefaultResolver returns a SchemeResolver for the file, http and https
+// schemes. Note that ResolveDependentRims restricts the schemes a dependent RIM
+// may use based on where the RIM referencing it was found (see
+// ResolveDependentRims), so that, for example, a remote RIM cannot cause local
+// files to be read.
+func NewDefaultResolver() SchemeResolver {
+	return SchemeResolver{
+		"file":  FileResolver{},
+		"http":  HTTPResolver{},
+		"https": HTTPResolver{},
+	}
+}
+
+// Resolve resolves the supplied href using the Resolver associated with its
+// scheme
+func (o SchemeResolver) Resolve(ctx context.Context, href string) ([]byte, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := o[strings.ToLower(u.Scheme)]
+	if !ok {
+		return nil, fmt.Errorf("no resolver for scheme %q", u.Scheme)
+	}
+
+	return r.Resolve(ctx, href)
+}
+
+// ResolveOptions control the behaviour of ResolveDependentRims
+type ResolveOptions struct {
+	// MaxDepth is the maximum depth of the dependent RIMs graph, the
+	// dependents of the root CoRIM being at depth 1.  If zero,
+	// DefaultMaxResolveDepth is used.
+	MaxDepth int
+	// AllowMissingThumbprint allows corim-locator-maps without a
+	// thumbprint, whose dependent RIMs are then accepted without any
+	// integrity check. By default, resolution fails on such locators.
+	AllowMissingThumbprint bool
+	// AllowTruncatedDigests allows the thumbprint of a dependent RIM to be
+	// checked using truncated digests (e.g. sha-256-32). By default, such
+	// digests are not accepted (see Thumbprint.Verify).
+	AllowTruncatedDigests bool
+}
+
+// ResolvedRim is a node in the graph of dependent RIMs
+type ResolvedRim struct {
+	// Locator is the corim-locator-map that references this RIM
+	Locator Locator
+	// Data is the CoRIM as fetched by the Resolver
+	Data []byte
+	// Corim is the decoded CoRIM
+	Corim *Decoded
+	// Dependents are the resolved dependent RIMs of this RIM.  RIMs that are
+	// referenced more than once in the graph share the same node.
+	Dependents []*ResolvedRim
+}
+
+// ResolveDependentRims fetches, using the supplied Resolver, the dependent
+// RIMs of the supplied unsigned-corim and, recursively, their own dependent
+// RIMs.  Each fetched RIM is checked against the thumbprint found in its
+// locator before being decoded; unless allowed by opts, resolution fails if
+// there is no thumbprint.  Resolution also fails if a dependency cycle is
+// found, or if the graph is deeper than the maximum depth.  Note that the
+// signatures of signed dependent RIMs are not verified.
+//
+// The dependents of a RIM that was fetched from a remote location may not be
+// file URIs, nor refer to loopback, private or link-local hosts (unless the
+// RIM was itself fetched from such a host), and those of a RIM fetched over
+// https must also use https.  Only the dependents of root, and of RIMs read
+// from files, may use any scheme supported by the Resolver.
+func ResolveDependentRims(
+	ctx context.Context,
+	root *UnsignedCorim,
+	r Resolver,
+	opts *ResolveOptions,
+) ([]*ResolvedRim, error) {
+	if root == nil {
+		return nil, errors.New("nil unsigned CoRIM")
+	}
+
+	if r == nil {
+		return nil, errors.New("nil resolver")
+	}
+
+	s := rimResolution{
+		resolver: r,
+		maxDepth: DefaultMaxResolveDepth,
+		resolved: make(map[string]*ResolvedRim),
+	}
+
+	if opts != nil {
+		if opts.MaxDepth > 0 {
+			s.maxDepth = opts.MaxDepth
+		}
+		s.allowMissingThumbprint = opts.AllowMissingThumbprint
+		s.allowTruncatedDigests = opts.AllowTruncatedDigests
+	}
+
+	return s.resolveAll(ctx, root.DependentRims, nil, nil)
+}
+
+type rimResolution struct {
+	resolver               Resolver
+	maxDepth               int
+	allowMissingThumbprint bool
+	allowTruncatedDigests  bool
+	// resolved RIMs, indexed by href
+	resolved map[string]*ResolvedRim
+}
+
+// resolveAll resolves the RIMs referenced by the supplied locators, found in
+// the RIM fetched from origin (nil for the root)
+func (o *rimResolution) resolveAll(
+	ctx context.Context,
+	locators *[]Locator,
+	chain []string,
+	origin *url.URL,
+) ([]*ResolvedRim, error) {
+	if locators == nil || len(*locators) == 0 {
+		return nil, nil
+	}
+
+	if len(chain) >= o.maxDepth {
+		return nil, fmt.Errorf(
+			"maximum dependent RIM depth (%d) exceeded at %s",
+			o.maxDepth, strings.Join(chain, " -> "),
+		)
+	}
+
+	ret := make([]*ResolvedRim, 0, len(*locators))
+
+	for _, l := range *locators {
+		rr, err := o.resolve(ctx, l, chain, origin)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, rr)
+	}
+
+	return ret, nil
+}
+
+func (o *rimResolution) resolve(
+	ctx context.Context,
+	l Locator,
+	chain []string,
+	origin *url.URL,
+) (*ResolvedRim, error) {
+	href := string(l.Href)
+
+	for _, h := range chain {
+		if h == href {
+			return nil, fmt.Errorf(
+				"dependent RIM cycle detected: %s -> %s",
+				strings.Join(chain, " -> "), href,
+			)
+		}
+	}
+
+	if err := l.Valid(); err != nil {
+		return nil, fmt.Errorf("dependent RIM %s: %w", href, err)
+	}
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, fmt.Errorf("dependent RIM %s: %w", href, err)
+	}
+
+	if err := checkOrigin(origin, u); err != nil {
+		return nil, fmt.Errorf("dependent RIM %s: %w", href, err)
+	}
+
+	if rr, ok := o.resolved[href]; ok {
+		// already resolved via another path: make sure that the thumbprint
+		// in this locator matches too
+		if err := o.checkThumbprint(l, rr.Data); err != nil {
+			return nil, fmt.Errorf("dependent RIM %s: %w", href, err)
+		}
+		return rr, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := o.resolver.Resolve(ctx, href)
+	if err != nil {
+		return nil, fmt.Errorf("resolving dependent RIM %s: %w", href, err)
+	}
+
+	if err := o.checkThumbprint(l, data); err != nil {
+		return nil, fmt.Errorf("dependent RIM %s: %w", href, err)
+	}
+
+	decoded, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding dependent RIM %s: %w", href, err)
+	}
+
+	rr := &ResolvedRim{
+		Locator: l,
+		Data:    data,
+		Corim:   decoded,
+	}
+
+	next := append(append([]string{}, chain...), href)
+
+	rr.Dependents, err = o.resolveAll(ctx, decoded.GetUnsignedCorim().DependentRims, next, u)
+	if err != nil {
+		return nil, err
+	}
+
+	o.resolved[href] = rr
+
+	return rr, nil
+}
+
+func (o *rimResolution) checkThumbprint(l Locator, data []byte) error {
+	tp := l.Digests()
+	if len(tp) == 0 {
+		if !o.allowMissingThumbprint {
+			return errors.New("missing thumbprint")
+		}
+		return nil
+	}
+
+	if o.allowTruncatedDigests {
+		return tp.VerifyAllowingTruncated(data)
+	}
+
+	return tp.Verify(data)
+}
+
+// checkOrigin checks that the RIM fetched from origin (nil for the root) may
+// reference a dependent RIM at target
+func checkOrigin(origin *url.URL, target *url.URL) error {
+	if origin == nil || isLocalScheme(origin.Scheme) {
+		return nil
+	}
+
+	if isLocalScheme(target.Scheme) {
+		return fmt.Errorf("%s URI referenced by a remote RIM", target.Scheme)
+	}
+
+	if strings.EqualFold(origin.Scheme, "https") && !strings.EqualFold(target.Scheme, "https") {
+		return fmt.Errorf("%s URI referenced by a RIM fetched over https", target.Scheme)
+	}
+
+	if isInternalHost(target) && !isInternalHost(origin) {
+		return fmt.Errorf("internal host %q referenced by a remote RIM", target.Hostname())
+	}
+
+	return nil
+}
+
+// isLocalScheme returns true for the URI schemes that refer to local
+// resources
+func isLocalScheme(scheme string) bool {
+	return scheme == "" || strings.EqualFold(scheme, "file")
+}
+
+// isInternalHost returns true if the host of the supplied URI is localhost, or
+// a loopback, private, link-local or unspecified IP address
+func isInternalHost(u *url.URL) bool {
+	host := u.Hostname()
+
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsUnspecified())
+}
@@ -0,0 +1,338 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/swid"
+)
+
+func sha256Thumbprint(data []byte) *swid.HashEntry {
+	digest := sha256.Sum256(data)
+	return &swid.HashEntry{HashAlgID: swid.Sha256, HashValue: digest[:]}
+}
+
+func rimWithDependents(t *testing.T, id string, deps ...Locator) []byte {
+	rim := unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+	require.NotNil(t, rim.SetID(id))
+
+	for _, d := range deps {
+		require.NotNil(t, rim.AddLocator(d))
+	}
+
+	data, err := rim.ToCBOR()
+	require.NoError(t, err)
+
+	return data
+}
+
+func writeRim(t *testing.T, dir, name string, data []byte) string {
+	p := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(p, data, 0o600))
+	return "file://" + filepath.ToSlash(p)
+}
+
+func rootWithDependents(t *testing.T, deps ...Locator) *UnsignedCorim {
+	return unsignedCorimFromCBOR(t, rimWithDependents(t, "root", deps...))
+}
+
+func TestResolveDependentRims_file(t *testing.T) {
+	dir := t.TempDir()
+
+	leaf := rimWithDependents(t, "leaf")
+	leafHref := writeRim(t, dir, "leaf.cbor", leaf)
+
+	// the middle RIM is signed
+	middle := SignedCorim{
+		UnsignedCorim: *unsignedCorimFromCBOR(t, rimWithDependents(t, "middle",
+			Locator{Href: comid.TaggedURI(leafHref), Thumbprint: sha256Thumbprint(leaf)})),
+		Meta: *metaGood(t),
+	}
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+	middleData, err := middle.Sign(signer)
+	require.NoError(t, err)
+	middleHref := writeRim(t, dir, "middle.cbor", middleData)
+
+	root := rootWithDependents(t,
+		Locator{Href: comid.TaggedURI(middleHref), Thumbprint: sha256Thumbprint(middleData)},
+		// the same RIM referenced twice is resolved only once
+		Locator{Href: comid.TaggedURI(leafHref), Thumbprint: sha256Thumbprint(leaf)},
+	)
+
+	graph, err := ResolveDependentRims(context.Background(), root, FileResolver{}, nil)
+	require.NoError(t, err)
+	require.Len(t, graph, 2)
+
+	assert.Equal(t, KindSigned, graph[0].Corim.Kind)
+	assert.Equal(t, "middle", graph[0].Corim.GetUnsignedCorim().GetID())
+	require.Len(t, graph[0].Dependents, 1)
+	assert.Equal(t, "leaf", graph[0].Dependents[0].Corim.GetUnsignedCorim().GetID())
+	assert.Same(t, graph[0].Dependents[0], graph[1])
+}
+
+func TestResolveDependentRims_dir(t *testing.T) {
+	dir := t.TempDir()
+
+	leaf := rimWithDependents(t, "leaf")
+	writeRim(t, dir, "leaf.cbor", leaf)
+
+	root := rootWithDependents(t, Locator{
+		Href:       "https://rims.example/acme/leaf.cbor",
+		Thumbprint: sha256Thumbprint(leaf),
+	})
+
+	graph, err := ResolveDependentRims(context.Background(), root, DirResolver{Dir: dir}, nil)
+	require.NoError(t, err)
+	require.Len(t, graph, 1)
+	assert.Equal(t, leaf, graph[0].Data)
+
+	root = rootWithDependents(t, Locator{Href: "https://rims.example/acme/missing.cbor"})
+	_, err = ResolveDependentRims(context.Background(), root, DirResolver{Dir: dir}, nil)
+	assert.ErrorContains(t, err, "resolving dependent RIM https://rims.example/acme/missing.cbor")
+
+	_, err = DirResolver{Dir: dir}.Resolve(context.Background(), "https://rims.example/")
+	assert.EqualError(t, err, `no file name in "https://rims.example/"`)
+}
+
+func TestResolveDependentRims_multiple_digests(t *testing.T) {
+	dir := t.TempDir()
+
+	leaf := rimWithDependents(t, "leaf")
+	writeRim(t, dir, "leaf.cbor", leaf)
+
+	l, err := NewLocator("https://rims.example/acme/leaf.cbor", leaf, swid.Sha256, swid.Sha512)
+	require.NoError(t, err)
+
+	graph, err := ResolveDependentRims(context.Background(), rootWithDependents(t, *l), DirResolver{Dir: dir}, nil)
+	require.NoError(t, err)
+	require.Len(t, graph, 1)
+
+	// a single mismatching digest fails the verification
+	l.Thumbprints[1].HashValue = make([]byte, 64)
+
+	_, err = ResolveDependentRims(context.Background(), rootWithDependents(t, *l), DirResolver{Dir: dir}, nil)
+	assert.EqualError(t, err, "dependent RIM https://rims.example/acme/leaf.cbor: thumbprint mismatch")
+}
+
+func TestResolveDependentRims_http(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	leaf := rimWithDependents(t, "leaf")
+	middle := rimWithDependents(t, "middle",
+		Locator{Href: comid.TaggedURI(srv.URL + "/leaf"), Thumbprint: sha256Thumbprint(leaf)})
+
+	mux.HandleFunc("/leaf", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, ContentType, r.Header.Get("Accept"))
+		_, _ = w.Write(leaf)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(middle)
+	})
+
+	root := rootWithDependents(t, Locator{
+		Href:       comid.TaggedURI(srv.URL + "/middle"),
+		Thumbprint: sha256Thumbprint(middle),
+	})
+
+	graph, err := ResolveDependentRims(context.Background(), root, NewDefaultResolver(), nil)
+	require.NoError(t, err)
+	require.Len(t, graph, 1)
+	require.Len(t, graph[0].Dependents, 1)
+	assert.Equal(t, "leaf", graph[0].Dependents[0].Corim.GetUnsignedCorim().GetID())
+
+	root = rootWithDependents(t, Locator{Href: comid.TaggedURI(srv.URL + "/nope")})
+	_, err = ResolveDependentRims(context.Background(), root, NewDefaultResolver(), nil)
+	assert.ErrorContains(t, err, `unexpected HTTP status "404 Not Found"`)
+
+	_, err = HTTPResolver{MaxSize: 10}.Resolve(context.Background(), srv.URL+"/leaf")
+	assert.EqualError(t, err, "response exceeds the maximum size of 10 bytes")
+
+	_, err = NewDefaultResolver().Resolve(context.Background(), "ftp://rims.example/leaf")
+	assert.EqualError(t, err, `no resolver for scheme "ftp"`)
+}
+
+func TestResolveDependentRims_thumbprint(t *testing.T) {
+	dir := t.TempDir()
+
+	leaf := rimWithDependents(t, "leaf")
+	leafHref := writeRim(t, dir, "leaf.cbor", leaf)
+
+	root := rootWithDependents(t, Locator{
+		Href:       comid.TaggedURI(leafHref),
+		Thumbprint: sha256Thumbprint([]byte("something else")),
+	})
+
+	_, err := ResolveDependentRims(context.Background(), root, FileResolver{}, nil)
+	assert.EqualError(t, err, "dependent RIM "+leafHref+": thumbprint mismatch")
+
+	// thumbprints are required unless opted out of
+	root = rootWithDependents(t, Locator{Href: comid.TaggedURI(leafHref)})
+
+	_, err = ResolveDependentRims(context.Background(), root, FileResolver{}, nil)
+	assert.EqualError(t, err, "dependent RIM "+leafHref+": missing thumbprint")
+
+	_, err = ResolveDependentRims(context.Background(), root, FileResolver{},
+		&ResolveOptions{AllowMissingThumbprint: true})
+	assert.NoError(t, err)
+
+	// truncated digests are only accepted if opted into
+	digest := sha256.Sum256(leaf)
+	root = rootWithDependents(t, Locator{
+		Href:       comid.TaggedURI(leafHref),
+		Thumbprint: &swid.HashEntry{HashAlgID: swid.Sha256_32, HashValue: digest[:4]},
+	})
+
+	_, err = ResolveDependentRims(context.Background(), root, FileResolver{}, nil)
+	assert.EqualError(t, err,
+		"dependent RIM "+leafHref+": truncated thumbprint digest (sha-256-32) not accepted")
+
+	_, err = ResolveDependentRims(context.Background(), root, FileResolver{},
+		&ResolveOptions{AllowTruncatedDigests: true})
+	assert.NoError(t, err)
+}
+
+func TestResolveDependentRims_origin(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	secret := rimWithDependents(t, "secret")
+	secretHref := writeRim(t, dir, "secret.cbor", secret)
+
+	// a remote RIM referencing a local file
+	remote := rimWithDependents(t, "remote",
+		Locator{Href: comid.TaggedURI(secretHref), Thumbprint: sha256Thumbprint(secret)})
+
+	mux.HandleFunc("/remote", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(remote)
+	})
+
+	root := rootWithDependents(t, Locator{
+		Href:       comid.TaggedURI(srv.URL + "/remote"),
+		Thumbprint: sha256Thumbprint(remote),
+	})
+
+	_, err := ResolveDependentRims(context.Background(), root, NewDefaultResolver(), nil)
+	assert.EqualError(t, err, "dependent RIM "+secretHref+": file URI referenced by a remote RIM")
+}
+
+func Test_checkOrigin(t *testing.T) {
+	testCases := []struct {
+		origin string
+		target string
+		err    string
+	}{
+		{"", "file:///rims/a.cbor", ""},
+		{"", "http://10.0.0.1/a.cbor", ""},
+		{"file:///rims/a.cbor", "file:///rims/b.cbor", ""},
+		{"file:///rims/a.cbor", "https://rims.example/b.cbor", ""},
+		{"http://rims.example/a.cbor", "https://rims.example/b.cbor", ""},
+		{"http://127.0.0.1:8080/a.cbor", "http://localhost/b.cbor", ""},
+		{"https://rims.example/a.cbor", "file:///etc/passwd", "file URI referenced by a remote RIM"},
+		{"https://rims.example/a.cbor", "http://rims.example/b.cbor", "http URI referenced by a RIM fetched over https"},
+		{"https://rims.example/a.cbor", "https://127.0.0.1/b.cbor", `internal host "127.0.0.1" referenced by a remote RIM`},
+		{"http://rims.example/a.cbor", "http://192.168.1.1/b.cbor", `internal host "192.168.1.1" referenced by a remote RIM`},
+		{"http://rims.example/a.cbor", "http://169.254.169.254/b.cbor", `internal host "169.254.169.254" referenced by a remote RIM`},
+		{"http://rims.example/a.cbor", "http://localhost/b.cbor", `internal host "localhost" referenced by a remote RIM`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.origin+" -> "+tc.target, func(t *testing.T) {
+			var origin *url.URL
+
+			if tc.origin != "" {
+				var err error
+				origin, err = url.Parse(tc.origin)
+				require.NoError(t, err)
+			}
+
+			target, err := url.Parse(tc.target)
+			require.NoError(t, err)
+
+			err = checkOrigin(origin, target)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestResolveDependentRims_cycle(t *testing.T) {
+	dir := t.TempDir()
+
+	aHref := "file://" + filepath.ToSlash(filepath.Join(dir, "a.cbor"))
+	bHref := "file://" + filepath.ToSlash(filepath.Join(dir, "b.cbor"))
+
+	writeRim(t, dir, "a.cbor", rimWithDependents(t, "a", Locator{Href: comid.TaggedURI(bHref)}))
+	writeRim(t, dir, "b.cbor", rimWithDependents(t, "b", Locator{Href: comid.TaggedURI(aHref)}))
+
+	root := rootWithDependents(t, Locator{Href: comid.TaggedURI(aHref)})
+
+	_, err := ResolveDependentRims(context.Background(), root, FileResolver{},
+		&ResolveOptions{AllowMissingThumbprint: true})
+	assert.EqualError(t, err, "dependent RIM cycle detected: "+aHref+" -> "+bHref+" -> "+aHref)
+}
+
+func TestResolveDependentRims_depth(t *testing.T) {
+	dir := t.TempDir()
+
+	href := ""
+	for i := 0; i < 3; i++ {
+		name := string(rune('a'+i)) + ".cbor"
+		var deps []Locator
+		if href != "" {
+			deps = append(deps, Locator{Href: comid.TaggedURI(href)})
+		}
+		href = writeRim(t, dir, name, rimWithDependents(t, name, deps...))
+	}
+
+	root := rootWithDependents(t, Locator{Href: comid.TaggedURI(href)})
+
+	graph, err := ResolveDependentRims(context.Background(), root, FileResolver{}, &ResolveOptions{MaxDepth: 3, AllowMissingThumbprint: true})
+	require.NoError(t, err)
+	require.Len(t, graph, 1)
+
+	_, err = ResolveDependentRims(context.Background(), root, FileResolver{}, &ResolveOptions{MaxDepth: 2, AllowMissingThumbprint: true})
+	assert.ErrorContains(t, err, "maximum dependent RIM depth (2) exceeded at")
+}
+
+func TestResolveDependentRims_fail(t *testing.T) {
+	_, err := ResolveDependentRims(context.Background(), nil, FileResolver{}, nil)
+	assert.EqualError(t, err, "nil unsigned CoRIM")
+
+	_, err = ResolveDependentRims(context.Background(), &UnsignedCorim{}, nil, nil)
+	assert.EqualError(t, err, "nil resolver")
+
+	graph, err := ResolveDependentRims(context.Background(), &UnsignedCorim{}, FileResolver{}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, graph)
+
+	_, err = FileResolver{}.Resolve(context.Background(), "https://rims.example/a")
+	assert.EqualError(t, err, `expecting file URI, got "https://rims.example/a"`)
+
+	dir := t.TempDir()
+	href := writeRim(t, dir, "bad.cbor", []byte{0xf6})
+	root := rootWithDependents(t, Locator{Href: comid.TaggedURI(href), Thumbprint: sha256Thumbprint([]byte{0xf6})})
+
+	_, err = ResolveDependentRims(context.Background(), root, FileResolver{}, nil)
+	assert.ErrorContains(t, err, "decoding dependent RIM "+href)
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
 	cose "github.com/veraison/go-cose"
 )
@@ -27,6 +28,29 @@ type SignedCorim struct {
 	UnsignedCorim UnsignedCorim
 	Meta          Meta
 	message       *cose.Sign1Message
+	detached      bool
+	tagged        bool
+	hashEnvelope  *HashEnvelope
+
+	countersignatures []Countersignature
+}
+
+// SignOptions control the shape of the COSE_Sign1 message produced by
+// SignedCorim.SignWithOptions
+type SignOptions struct {
+	// Detached causes the payload to be omitted from the COSE_Sign1 (i.e.,
+	// it is encoded as nil).  The payload must then be conveyed separately
+	// and supplied to FromCOSEWithPayload.
+	Detached bool
+	// HashEnvelope, if set, causes the COSE_Sign1 payload to be the hash of
+	// the unsigned-corim rather than the unsigned-corim itself.
+	HashEnvelope *HashEnvelope
+	// Tagged causes the COSE_Sign1 to be wrapped in the tagged-signed-corim
+	// (#6.502) tag
+	Tagged bool
+	// TaggedPayload causes the unsigned-corim payload to be wrapped in the
+	// tagged-unsigned-corim-map (#6.501) tag
+	TaggedPayload bool
 }
 
 // NewSignedCorim instantiates an empty SignedCorim
@@ -59,7 +83,23 @@ func (o *SignedCorim) processHdrs() error {
 		return errors.New("missing mandatory protected header")
 	}
 
-	v, ok := hdr.Protected[cose.HeaderLabelContentType]
+	env, err := hashEnvelopeFromHeaders(hdr.Protected)
+	if err != nil {
+		return fmt.Errorf("invalid hash envelope: %w", err)
+	}
+
+	o.hashEnvelope = env
+
+	contentTypeLabel := cose.HeaderLabelContentType
+	if env != nil {
+		if _, ok := hdr.Protected[cose.HeaderLabelContentType]; ok {
+			return errors.New("content type must not be set in a hash envelope")
+		}
+
+		contentTypeLabel = HeaderLabelPayloadPreimageContentType
+	}
+
+	v, ok := hdr.Protected[contentTypeLabel]
 	if !ok {
 		return errors.New("missing mandatory content type")
 	}
@@ -68,11 +108,22 @@ func (o *SignedCorim) processHdrs() error {
 		return fmt.Errorf("expecting content type %q, got %q instead", ContentType, v)
 	}
 
+	o.countersignatures, err = countersignaturesFromHeaders(hdr)
+	if err != nil {
+		return err
+	}
+
 	// TODO(tho) key id is apparently mandatory, which doesn't look right.
 	// TODO(tho) Check with the CoRIM design team.
 	// See https://github.com/veraison/corim/issues/14
 
-	v, ok = hdr.Protected[HeaderLabelCorimMeta]
+	return decodeMetaHeader(hdr.Protected, &o.Meta)
+}
+
+// decodeMetaHeader extracts the mandatory corim-meta from the supplied
+// protected header and decodes it into the target meta
+func decodeMetaHeader(protected cose.ProtectedHeader, meta *Meta) error {
+	v, ok := protected[HeaderLabelCorimMeta]
 	if !ok {
 		return errors.New("missing mandatory corim.meta")
 	}
@@ -82,40 +133,65 @@ func (o *SignedCorim) processHdrs() error {
 		return fmt.Errorf("expecting CBOR-encoded CoRIM Meta, got %T instead", v)
 	}
 
-	var meta Meta
-
-	err := meta.FromCBOR(metaCBOR)
-	if err != nil {
+	if err := meta.FromCBOR(metaCBOR); err != nil {
 		return fmt.Errorf("unable to decode CoRIM Meta: %w", err)
 	}
 
-	o.Meta = meta
-
 	return nil
 }
 
 // FromCOSE decodes and effects syntactic validation on the supplied
 // signed-corim message, including the embedded unsigned-corim and corim-meta.
 // On success, the unsigned-corim-map is made available via the UnsignedCorim
-// field while the corim-meta-map is decoded into the Meta field.
-func (o *SignedCorim) FromCOSE(buf []byte) error {
+// field while the corim-meta-map is decoded into the Meta field. Optional
+// DecodeOptions may be specified, e.g. to decode in strict mode, or with
+// DecodeLimits other than those set via encoding.SetDecodeLimits.
+func (o *SignedCorim) FromCOSE(buf []byte, opts ...encoding.DecodeOptions) error {
+	return o.FromCOSEWithPayload(buf, nil, opts...)
+}
+
+// FromCOSEWithPayload is like FromCOSE, except that the CBOR-encoded
+// unsigned-corim is supplied separately from the signed-corim message.  This
+// is needed to decode signed-corims with a detached payload, or those using a
+// hash envelope.  If the signed-corim carries its own payload, the supplied
+// payload (if not nil) must match it.
+func (o *SignedCorim) FromCOSEWithPayload(
+	buf []byte,
+	payload []byte,
+	opts ...encoding.DecodeOptions,
+) error {
 	o.message = cose.NewSign1Message()
 
-	// If a tagged-corim-type-choice #6.500 of tagged-signed-corim #6.502, strip the prefix.
-	// This is a remnant of an older draft of the specification before
-	// https://github.com/ietf-rats-wg/draft-ietf-rats-corim/pull/337
-	corimTypeChoice := []byte("\xd9\x01\xf4\xd9\x01\xf6")
-	buf, _ = bytes.CutPrefix(buf, corimTypeChoice)
+	limits := encoding.NewDecoder(dm).WithOptions(opts...).Limits()
+
+	if err := encoding.CheckCBORLimits(buf, limits); err != nil {
+		return fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
+	}
+
+	// Strip the optional tagged-corim-type-choice #6.500 and
+	// tagged-signed-corim #6.502 prefixes, and accept untagged COSE_Sign1
+	// messages too.
+	buf, tagged, err := stripSignedCorimTags(buf)
+	if err != nil {
+		return fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
+	}
 
 	if err := o.message.UnmarshalCBOR(buf); err != nil {
 		return fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
 	}
 
+	o.tagged = tagged
+
 	if err := o.processHdrs(); err != nil {
 		return fmt.Errorf("processing COSE headers: %w", err)
 	}
 
-	if err := o.UnsignedCorim.FromCBOR(o.message.Payload); err != nil {
+	unsignedCorim, err := o.resolvePayload(payload)
+	if err != nil {
+		return err
+	}
+
+	if err := o.UnsignedCorim.FromCBOR(unsignedCorim, opts...); err != nil {
 		return fmt.Errorf("failed CBOR decoding of unsigned CoRIM: %w", err)
 	}
 
@@ -126,10 +202,80 @@ func (o *SignedCorim) FromCOSE(buf []byte) error {
 	return nil
 }
 
+// resolvePayload reconciles the payload found in the COSE_Sign1 message with
+// the externally supplied one (if any).  It returns the unsigned-corim, and
+// makes sure that the message payload is populated so that the signature can
+// be verified.
+func (o *SignedCorim) resolvePayload(external []byte) ([]byte, error) {
+	o.detached = o.message.Payload == nil
+
+	if o.hashEnvelope != nil {
+		if external == nil {
+			return nil, errors.New("hash envelope: unsigned CoRIM payload must be supplied")
+		}
+
+		digest, err := o.hashEnvelope.Digest(external)
+		if err != nil {
+			return nil, fmt.Errorf("hash envelope: %w", err)
+		}
+
+		if o.detached {
+			o.message.Payload = digest
+		} else if !bytes.Equal(digest, o.message.Payload) {
+			return nil, errors.New("hash envelope: supplied payload does not match the signed hash")
+		}
+
+		return external, nil
+	}
+
+	if o.detached {
+		if external == nil {
+			return nil, errors.New("detached payload: unsigned CoRIM payload must be supplied")
+		}
+
+		o.message.Payload = external
+
+		return external, nil
+	}
+
+	if external != nil && !bytes.Equal(external, o.message.Payload) {
+		return nil, errors.New("supplied payload does not match the signed-corim payload")
+	}
+
+	return o.message.Payload, nil
+}
+
+// IsDetached returns true if the signed-corim was produced, or decoded,
+// without an embedded payload
+func (o SignedCorim) IsDetached() bool {
+	return o.detached
+}
+
+// GetHashEnvelope returns the hash envelope parameters used by the
+// signed-corim, or nil if the payload is the unsigned-corim itself
+func (o SignedCorim) GetHashEnvelope() *HashEnvelope {
+	return o.hashEnvelope
+}
+
 // Sign returns the serialized signed-corim, signed by the supplied cose Signer.
 // The target SignedCorim must have its UnsignedCorim field correctly
 // populated.
 func (o *SignedCorim) Sign(signer cose.Signer) ([]byte, error) {
+	return o.SignWithOptions(signer, SignOptions{})
+}
+
+// SignDetached is like Sign, except that the payload is omitted from the
+// returned signed-corim.  The CBOR-encoded unsigned-corim (obtained via
+// UnsignedCorim.ToCBOR) must be stored separately and supplied to
+// FromCOSEWithPayload on the receiving side.
+func (o *SignedCorim) SignDetached(signer cose.Signer) ([]byte, error) {
+	return o.SignWithOptions(signer, SignOptions{Detached: true})
+}
+
+// SignWithOptions returns the serialized signed-corim, signed by the supplied
+// cose Signer, and shaped according to the supplied options.  The target
+// SignedCorim must have its UnsignedCorim field correctly populated.
+func (o *SignedCorim) SignWithOptions(signer cose.Signer, opts SignOptions) ([]byte, error) {
 	if signer == nil {
 		return nil, errors.New("nil signer")
 	}
@@ -138,14 +284,32 @@ func (o *SignedCorim) Sign(signer cose.Signer) ([]byte, error) {
 		return nil, fmt.Errorf("failed validation of unsigned CoRIM: %w", err)
 	}
 
+	if opts.HashEnvelope != nil {
+		if err := opts.HashEnvelope.Valid(); err != nil {
+			return nil, fmt.Errorf("invalid hash envelope: %w", err)
+		}
+	}
+
 	o.message = cose.NewSign1Message()
+	o.detached = opts.Detached
+	o.tagged = opts.Tagged
+	o.countersignatures = nil
+	o.hashEnvelope = opts.HashEnvelope
 
+	var unsignedCorim []byte
 	var err error
-	o.message.Payload, err = o.UnsignedCorim.ToCBOR()
+
+	if opts.TaggedPayload {
+		unsignedCorim, err = o.UnsignedCorim.ToTaggedCBOR()
+	} else {
+		unsignedCorim, err = o.UnsignedCorim.ToCBOR()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed CBOR encoding of unsigned CoRIM: %w", err)
 	}
 
+	o.message.Payload = unsignedCorim
+
 	metaCBOR, err := o.Meta.ToCBOR()
 	if err != nil {
 		return nil, fmt.Errorf("failed CBOR encoding of CoRIM Meta: %w", err)
@@ -158,19 +322,45 @@ func (o *SignedCorim) Sign(signer cose.Signer) ([]byte, error) {
 	}
 
 	o.message.Headers.Protected.SetAlgorithm(alg)
-	o.message.Headers.Protected[cose.HeaderLabelContentType] = ContentType
 	o.message.Headers.Protected[HeaderLabelCorimMeta] = metaCBOR
 
+	if env := opts.HashEnvelope; env != nil {
+		env.setHeaders(o.message.Headers.Protected)
+
+		o.message.Payload, err = env.Digest(unsignedCorim)
+		if err != nil {
+			return nil, fmt.Errorf("hash envelope: %w", err)
+		}
+	} else {
+		o.message.Headers.Protected[cose.HeaderLabelContentType] = ContentType
+	}
+
 	err = o.message.Sign(rand.Reader, NoExternalData, signer)
 	if err != nil {
 		return nil, fmt.Errorf("COSE Sign1 signature failed: %w", err)
 	}
 
-	wrap, err := o.message.MarshalCBOR()
+	return o.marshal()
+}
+
+// marshal serializes the COSE_Sign1 message of the target SignedCorim,
+// omitting the payload if detached and adding the tagged-signed-corim tag if
+// requested
+func (o *SignedCorim) marshal() ([]byte, error) {
+	msg := *o.message
+	if o.detached {
+		msg.Payload = nil
+	}
+
+	wrap, err := msg.MarshalCBOR()
 	if err != nil {
 		return nil, fmt.Errorf("signed-corim marshaling failed: %w", err)
 	}
 
+	if o.tagged {
+		wrap = append(append([]byte{}, SignedCorimTag...), wrap...)
+	}
+
 	return wrap, nil
 }
 
@@ -4,12 +4,14 @@
 package corim
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
 )
 
@@ -530,3 +532,116 @@ func TestSignedCorim_extensions(t *testing.T) {
 	err = s.RegisterExtensions(badMap)
 	assert.EqualError(t, err, `unexpected extension point: "test"`)
 }
+
+func TestSignedCorim_SignVerify_detached_ok(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	var signedCorimIn SignedCorim
+	signedCorimIn.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+	signedCorimIn.Meta = *metaGood(t)
+
+	sig, err := signedCorimIn.SignDetached(signer)
+	require.NoError(t, err)
+	assert.True(t, signedCorimIn.IsDetached())
+
+	// the unsigned CoRIM is stored separately
+	payload, err := signedCorimIn.UnsignedCorim.ToCBOR()
+	require.NoError(t, err)
+
+	// the payload is nil in the COSE_Sign1 and therefore much smaller
+	assert.Less(t, len(sig), len(payload))
+
+	var signedCorimOut SignedCorim
+
+	err = signedCorimOut.FromCOSE(sig)
+	assert.EqualError(t, err, "detached payload: unsigned CoRIM payload must be supplied")
+
+	err = signedCorimOut.FromCOSEWithPayload(sig, payload)
+	require.NoError(t, err)
+	assert.True(t, signedCorimOut.IsDetached())
+	assert.Nil(t, signedCorimOut.GetHashEnvelope())
+	assert.Equal(t, signedCorimIn.UnsignedCorim.ID, signedCorimOut.UnsignedCorim.ID)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	assert.NoError(t, signedCorimOut.Verify(pk))
+
+	// a different payload fails verification
+	other := *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+	other.SetID("another corim id")
+	otherPayload, err := other.ToCBOR()
+	require.NoError(t, err)
+
+	err = signedCorimOut.FromCOSEWithPayload(sig, otherPayload)
+	require.NoError(t, err)
+	assert.EqualError(t, signedCorimOut.Verify(pk), "verification error")
+}
+
+func TestSignedCorim_FromCOSEWithPayload_attached_mismatch(t *testing.T) {
+	var signedCorimOut SignedCorim
+
+	err := signedCorimOut.FromCOSEWithPayload(testGoodSignedCorimCBOR, []byte{0xa0})
+	assert.EqualError(t, err, "supplied payload does not match the signed-corim payload")
+}
+
+func TestUnmarshalSignedCorimFromCBORWithPayload(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	var signedCorimIn SignedCorim
+	signedCorimIn.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+	signedCorimIn.Meta = *metaGood(t)
+
+	sig, err := signedCorimIn.SignDetached(signer)
+	require.NoError(t, err)
+
+	payload, err := signedCorimIn.UnsignedCorim.ToCBOR()
+	require.NoError(t, err)
+
+	_, err = UnmarshalSignedCorimFromCBOR(sig)
+	assert.EqualError(t, err, "unsigned CoRIM payload must be supplied")
+
+	out, err := UnmarshalSignedCorimFromCBORWithPayload(sig, payload)
+	require.NoError(t, err)
+	assert.Equal(t, signedCorimIn.UnsignedCorim.ID, out.UnsignedCorim.ID)
+}
+
+func TestUnmarshalSignedCorimFromCBOR_limits(t *testing.T) {
+	defer encoding.SetDecodeLimits(encoding.DefaultDecodeLimits)
+
+	_, err := UnmarshalSignedCorimFromCBOR(testGoodSignedCorimCBOR)
+	require.NoError(t, err)
+
+	// COSE_Sign1 is a 4-element array
+	encoding.SetDecodeLimits(encoding.DecodeLimits{MaxElements: 3})
+
+	_, err = UnmarshalSignedCorimFromCBOR(testGoodSignedCorimCBOR)
+	assert.ErrorIs(t, err, encoding.ErrLimitExceeded)
+
+	var le *encoding.LimitError
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxElements", le.Limit)
+
+	// per-call limits take precedence over the global ones
+	_, err = UnmarshalSignedCorimFromCBOR(testGoodSignedCorimCBOR, encoding.DecodeOptions{
+		Limits: &encoding.DefaultDecodeLimits,
+	})
+	require.NoError(t, err)
+}
+
+func TestSignedCorim_FromCOSE_limits(t *testing.T) {
+	limits := encoding.DecodeLimits{MaxElements: 3}
+	opts := encoding.DecodeOptions{Limits: &limits}
+
+	err := NewSignedCorim().FromCOSE(testGoodSignedCorimCBOR, opts)
+
+	var le *encoding.LimitError
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxElements", le.Limit)
+
+	_, err = UnmarshalSignedCorimFromCBOR(testGoodSignedCorimCBOR, opts)
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxElements", le.Limit)
+}
@@ -8,6 +8,8 @@ import (
 	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"reflect"
@@ -20,8 +22,8 @@ import (
 )
 
 type Signer struct {
-	Name string           `cbor:"0,keyasint" json:"name"`
-	URI  *comid.TaggedURI `cbor:"1,keyasint,omitempty" json:"uri,omitempty"`
+	Name string           `cbor:"0,keyasint" json:"name" cddl:"corim.signer-name"`
+	URI  *comid.TaggedURI `cbor:"1,keyasint,omitempty" json:"uri,omitempty" cddl:"corim.signer-uri"`
 
 	Extensions
 }
@@ -94,7 +96,12 @@ func (o Signer) Valid() error {
 
 // UnmarshalCBOR deserializes from CBOR
 func (o *Signer) UnmarshalCBOR(data []byte) error {
-	return encoding.PopulateStructFromCBOR(dm, data, o)
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *Signer) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
 }
 
 // MarshalCBOR serializes to CBOR
@@ -104,7 +111,12 @@ func (o Signer) MarshalCBOR() ([]byte, error) {
 
 // UnmarshalJSON deserializes from JSON
 func (o *Signer) UnmarshalJSON(data []byte) error {
-	return encoding.PopulateStructFromJSON(data, o)
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *Signer) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
 }
 
 // MarshalJSON serializes to JSON
@@ -118,7 +130,6 @@ func getAlgAndKeyFromJWK(j []byte) (cose.Algorithm, crypto.Signer, error) {
 	var (
 		err error
 		k   jwk.Key
-		crv elliptic.Curve
 		alg cose.Algorithm
 	)
 
@@ -138,7 +149,7 @@ func getAlgAndKeyFromJWK(j []byte) (cose.Algorithm, crypto.Signer, error) {
 	case *ecdsa.PrivateKey:
 		alg = ellipticCurveToAlg(v.Curve)
 		if alg == noAlg {
-			return noAlg, nil, fmt.Errorf("unknown elliptic curve %v", crv)
+			return noAlg, nil, fmt.Errorf("unknown elliptic curve %v", v.Curve)
 		}
 	case ed25519.PrivateKey:
 		alg = cose.AlgorithmEd25519
@@ -197,3 +208,147 @@ func NewPublicKeyFromJWK(j []byte) (crypto.PublicKey, error) {
 
 	return key.Public(), nil
 }
+
+// getAlgAndKeyFromPEM decodes a private key from the supplied PEM or DER data
+// and infers the COSE signing algorithm from the key type: ES256, ES384 or
+// ES512 depending on the curve for EC keys, PS256 for RSA keys and EdDSA for
+// Ed25519 keys.  Supported encodings are PKCS#8 ("PRIVATE KEY"), SEC1 ("EC
+// PRIVATE KEY") and PKCS#1 ("RSA PRIVATE KEY").
+func getAlgAndKeyFromPEM(data []byte) (cose.Algorithm, crypto.Signer, error) {
+	der := data
+
+	block, _ := pem.Decode(data)
+	if block != nil {
+		der = block.Bytes
+	}
+
+	key, err := parsePrivateKeyDER(der)
+	if err != nil {
+		return noAlg, nil, err
+	}
+
+	alg, err := keyToAlg(key.Public())
+	if err != nil {
+		return noAlg, nil, err
+	}
+
+	return alg, key, nil
+}
+
+func parsePrivateKeyDER(der []byte) (crypto.Signer, error) {
+	if k, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		key, ok := k.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unknown private key type %v", reflect.TypeOf(k))
+		}
+		return key, nil
+	}
+
+	if k, err := x509.ParseECPrivateKey(der); err == nil {
+		return k, nil
+	}
+
+	if k, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return k, nil
+	}
+
+	return nil, errors.New("unable to decode private key: expecting PKCS#8, SEC1 or PKCS#1")
+}
+
+func parsePublicKeyDER(der []byte) (crypto.PublicKey, error) {
+	if k, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return k, nil
+	}
+
+	if k, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return k, nil
+	}
+
+	if c, err := x509.ParseCertificate(der); err == nil {
+		return c.PublicKey, nil
+	}
+
+	if k, err := parsePrivateKeyDER(der); err == nil {
+		return k.Public(), nil
+	}
+
+	return nil, errors.New("unable to decode public key: expecting PKIX, PKCS#1, X.509 certificate or private key")
+}
+
+func keyToAlg(pk crypto.PublicKey) (cose.Algorithm, error) {
+	switch v := pk.(type) {
+	case *ecdsa.PublicKey:
+		alg := ellipticCurveToAlg(v.Curve)
+		if alg == noAlg {
+			return noAlg, fmt.Errorf("unknown elliptic curve %v", v.Curve.Params().Name)
+		}
+		return alg, nil
+	case ed25519.PublicKey:
+		return cose.AlgorithmEd25519, nil
+	case *rsa.PublicKey:
+		return cose.AlgorithmPS256, nil
+	default:
+		return noAlg, fmt.Errorf("unknown public key type %v", reflect.TypeOf(pk))
+	}
+}
+
+// NewSignerFromPEM returns a cose.Signer for the private key in the supplied
+// PEM (or raw DER) data.  The key can be encoded as PKCS#8, SEC1 (EC keys) or
+// PKCS#1 (RSA keys).  The signing algorithm is inferred from the key type.
+func NewSignerFromPEM(data []byte) (cose.Signer, error) {
+	alg, key, err := getAlgAndKeyFromPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return cose.NewSigner(alg, key)
+}
+
+// NewPublicKeyFromPEM returns the public key found in the supplied PEM (or
+// raw DER) data.  The data may contain a PKIX or PKCS#1 public key, an X.509
+// certificate, or any of the private key encodings accepted by
+// NewSignerFromPEM.
+func NewPublicKeyFromPEM(data []byte) (crypto.PublicKey, error) {
+	der := data
+
+	block, _ := pem.Decode(data)
+	if block != nil {
+		der = block.Bytes
+	}
+
+	pk, err := parsePublicKeyDER(der)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := keyToAlg(pk); err != nil {
+		return nil, err
+	}
+
+	return pk, nil
+}
+
+// NewPublicKeyFromCert returns the public key of the supplied X.509
+// certificate, which can be PEM or DER encoded.
+func NewPublicKeyFromCert(data []byte) (crypto.PublicKey, error) {
+	der := data
+
+	block, _ := pem.Decode(data)
+	if block != nil {
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("expecting PEM block of type CERTIFICATE, got %q", block.Type)
+		}
+		der = block.Bytes
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode certificate: %w", err)
+	}
+
+	if _, err := keyToAlg(cert.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return cert.PublicKey, nil
+}
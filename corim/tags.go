@@ -0,0 +1,551 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	cbor "github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/cotl"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/eat"
+	"github.com/veraison/swid"
+)
+
+// CBOR tag numbers of the tags that can be carried in an unsigned-corim
+const (
+	CBORTagCoswid uint64 = 505
+	CBORTagComid  uint64 = 506
+	CBORTagCots   uint64 = 507
+	CBORTagCotl   uint64 = 508
+)
+
+// TypedTag is the decoded form of one of the tags carried in the tags array of
+// an unsigned-corim.  It is one of TaggedComid, TaggedCoswid, TaggedCots,
+// TaggedCotl, RegisteredTag (for tag types added via RegisterTagType) or UnknownTag.
+type TypedTag interface {
+	// CBORTag returns the CBOR tag number that identifies the type of the tag
+	CBORTag() uint64
+	// GetTagIdentity returns the identity of the tag, or nil if the tag has
+	// no (known) identity
+	GetTagIdentity() *comid.TagIdentity
+	// ToTag returns the CBOR-encoded (and appropriately tagged) tag
+	ToTag() (Tag, error)
+}
+
+// TaggedComid is a CoMID carried in an unsigned-corim
+type TaggedComid struct {
+	Comid *comid.Comid
+}
+
+func (o TaggedComid) CBORTag() uint64 {
+	return CBORTagComid
+}
+
+func (o TaggedComid) GetTagIdentity() *comid.TagIdentity {
+	if o.Comid == nil {
+		return nil
+	}
+
+	return &o.Comid.TagIdentity
+}
+
+func (o TaggedComid) ToTag() (Tag, error) {
+	if o.Comid == nil {
+		return nil, errors.New("nil CoMID")
+	}
+
+	if err := o.Comid.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid CoMID: %w", err)
+	}
+
+	data, err := o.Comid.ToCBOR()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append(Tag{}, ComidTag...), data...), nil
+}
+
+// TaggedCoswid is a CoSWID carried in an unsigned-corim
+type TaggedCoswid struct {
+	Coswid *swid.SoftwareIdentity
+}
+
+func (o TaggedCoswid) CBORTag() uint64 {
+	return CBORTagCoswid
+}
+
+func (o TaggedCoswid) GetTagIdentity() *comid.TagIdentity {
+	if o.Coswid == nil {
+		return nil
+	}
+
+	return &comid.TagIdentity{
+		TagID:      o.Coswid.TagID,
+		TagVersion: uint(o.Coswid.TagVersion),
+	}
+}
+
+func (o TaggedCoswid) ToTag() (Tag, error) {
+	if o.Coswid == nil {
+		return nil, errors.New("nil CoSWID")
+	}
+
+	data, err := o.Coswid.ToCBOR()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append(Tag{}, CoswidTag...), data...), nil
+}
+
+// TaggedCots is a CoTS carried in an unsigned-corim
+type TaggedCots struct {
+	Cots *cots.ConciseTaStore
+}
+
+func (o TaggedCots) CBORTag() uint64 {
+	return CBORTagCots
+}
+
+func (o TaggedCots) GetTagIdentity() *comid.TagIdentity {
+	if o.Cots == nil {
+		return nil
+	}
+
+	return o.Cots.TagIdentity
+}
+
+func (o TaggedCots) ToTag() (Tag, error) {
+	if o.Cots == nil {
+		return nil, errors.New("nil CoTS")
+	}
+
+	if err := o.Cots.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid CoTS: %w", err)
+	}
+
+	data, err := o.Cots.ToCBOR()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append(Tag{}, cots.CotsTag...), data...), nil
+}
+
+// TaggedCotl is a CoTL carried in an unsigned-corim
+type TaggedCotl struct {
+	Cotl *cotl.ConciseTagList
+}
+
+func (o TaggedCotl) CBORTag() uint64 {
+	return CBORTagCotl
+}
+
+func (o TaggedCotl) GetTagIdentity() *comid.TagIdentity {
+	if o.Cotl == nil {
+		return nil
+	}
+
+	return &o.Cotl.TagIdentity
+}
+
+func (o TaggedCotl) ToTag() (Tag, error) {
+	if o.Cotl == nil {
+		return nil, errors.New("nil CoTL")
+	}
+
+	data, err := o.Cotl.ToCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CoTL: %w", err)
+	}
+
+	return append(append(Tag{}, cotl.CotlTag...), data...), nil
+}
+
+// ITagValue is the interface implemented by the values of the tag types
+// registered via RegisterTagType.  If the value also implements
+// GetTagIdentity() *comid.TagIdentity, the tag can be looked up by identity
+// (see UnsignedCorim.RemoveTag and UnsignedCorim.ReplaceTag).
+type ITagValue interface {
+	FromCBOR(data []byte) error
+	ToCBOR() ([]byte, error)
+	Valid() error
+}
+
+// ITagFactory returns a new, empty, value of a registered tag type
+type ITagFactory func() ITagValue
+
+var (
+	tagTypeRegister   = map[uint64]ITagFactory{}
+	tagTypeRegisterMu sync.RWMutex
+)
+
+// RegisterTagType registers a new kind of tag that can be carried in the tags
+// array of an unsigned-corim under the specified CBOR tag.  Tags of a
+// registered kind are validated by Tag.Valid (and therefore by
+// UnsignedCorim.Valid) and decoded into a RegisteredTag by Tag.Decode.
+func RegisterTagType(cborTag uint64, factory ITagFactory) error {
+	if factory == nil {
+		return errors.New("nil factory")
+	}
+
+	if factory() == nil {
+		return errors.New("factory returned nil value")
+	}
+
+	switch cborTag {
+	case CBORTagComid, CBORTagCoswid, CBORTagCots, CBORTagCotl:
+		return fmt.Errorf("tag %d is a built-in tag type", cborTag)
+	}
+
+	tagTypeRegisterMu.Lock()
+	defer tagTypeRegisterMu.Unlock()
+
+	if _, exists := tagTypeRegister[cborTag]; exists {
+		return fmt.Errorf("tag type %d is already registered", cborTag)
+	}
+
+	tagTypeRegister[cborTag] = factory
+
+	return nil
+}
+
+// UnregisterTagType removes the tag type registered under the specified CBOR
+// tag.  It returns true if the tag type was registered.
+func UnregisterTagType(cborTag uint64) bool {
+	tagTypeRegisterMu.Lock()
+	defer tagTypeRegisterMu.Unlock()
+
+	if _, ok := tagTypeRegister[cborTag]; !ok {
+		return false
+	}
+
+	delete(tagTypeRegister, cborTag)
+
+	return true
+}
+
+// lookupTagType returns the factory of the tag type registered under the
+// specified CBOR tag, if any
+func lookupTagType(cborTag uint64) (ITagFactory, bool) {
+	tagTypeRegisterMu.RLock()
+	defer tagTypeRegisterMu.RUnlock()
+
+	factory, ok := tagTypeRegister[cborTag]
+
+	return factory, ok
+}
+
+// RegisteredTag is a tag of a kind registered via RegisterTagType
+type RegisteredTag struct {
+	// Number is the CBOR tag number
+	Number uint64
+	// Value is the decoded tag content
+	Value ITagValue
+}
+
+func (o RegisteredTag) CBORTag() uint64 {
+	return o.Number
+}
+
+func (o RegisteredTag) GetTagIdentity() *comid.TagIdentity {
+	if v, ok := o.Value.(interface{ GetTagIdentity() *comid.TagIdentity }); ok {
+		return v.GetTagIdentity()
+	}
+
+	return nil
+}
+
+func (o RegisteredTag) ToTag() (Tag, error) {
+	if o.Value == nil {
+		return nil, fmt.Errorf("nil value for tag %d", o.Number)
+	}
+
+	if err := o.Value.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid tag %d: %w", o.Number, err)
+	}
+
+	content, err := o.Value.ToCBOR()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := em.Marshal(cbor.RawTag{Number: o.Number, Content: content})
+	if err != nil {
+		return nil, err
+	}
+
+	return Tag(data), nil
+}
+
+// UnknownTag is a tag of a type that is neither built-in nor registered.  Its
+// content is kept as-is: as long as Number and Content are not modified, the
+// tag is re-encoded byte-for-byte as it was found in the CoRIM.
+type UnknownTag struct {
+	// Number is the CBOR tag number
+	Number uint64
+	// Content is the CBOR-encoded tag content
+	Content []byte
+
+	raw Tag
+}
+
+func (o UnknownTag) CBORTag() uint64 {
+	return o.Number
+}
+
+func (o UnknownTag) GetTagIdentity() *comid.TagIdentity {
+	return nil
+}
+
+func (o UnknownTag) ToTag() (Tag, error) {
+	if o.raw != nil {
+		var orig cbor.RawTag
+		if err := dm.Unmarshal(o.raw, &orig); err == nil &&
+			orig.Number == o.Number && bytes.Equal(orig.Content, o.Content) {
+			return append(Tag{}, o.raw...), nil
+		}
+	}
+
+	data, err := em.Marshal(cbor.RawTag{Number: o.Number, Content: o.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	return Tag(data), nil
+}
+
+// TypedTags decodes the tags carried in the target UnsignedCorim.  CoMIDs are
+// decoded using the extensions of the CoRIM's profile (if registered).  Tags of
+// an unknown type are returned as UnknownTag.  Optional DecodeOptions may be
+// specified, e.g. to decode in strict mode (see Tag.Decode).
+// nolint:gocritic
+func (o UnsignedCorim) TypedTags(opts ...encoding.DecodeOptions) ([]TypedTag, error) {
+	ret := make([]TypedTag, 0, len(o.Tags))
+
+	for i, t := range o.Tags {
+		tt, err := t.Decode(o.Profile, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("decoding tag at pos %d: %w", i, err)
+		}
+
+		ret = append(ret, tt)
+	}
+
+	return ret, nil
+}
+
+// Comids returns the CoMIDs carried in the target UnsignedCorim.  Optional
+// DecodeOptions may be specified, as for TypedTags.
+// nolint:gocritic
+func (o UnsignedCorim) Comids(opts ...encoding.DecodeOptions) ([]*comid.Comid, error) {
+	tags, err := o.TypedTags(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*comid.Comid
+
+	for _, t := range tags {
+		if c, ok := t.(TaggedComid); ok {
+			ret = append(ret, c.Comid)
+		}
+	}
+
+	return ret, nil
+}
+
+// Coswids returns the CoSWIDs carried in the target UnsignedCorim.  Optional
+// DecodeOptions may be specified, as for TypedTags.
+// nolint:gocritic
+func (o UnsignedCorim) Coswids(opts ...encoding.DecodeOptions) ([]*swid.SoftwareIdentity, error) {
+	tags, err := o.TypedTags(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*swid.SoftwareIdentity
+
+	for _, t := range tags {
+		if c, ok := t.(TaggedCoswid); ok {
+			ret = append(ret, c.Coswid)
+		}
+	}
+
+	return ret, nil
+}
+
+// Cots returns the CoTSes carried in the target UnsignedCorim.  Optional
+// DecodeOptions may be specified, as for TypedTags.
+// nolint:gocritic
+func (o UnsignedCorim) Cots(opts ...encoding.DecodeOptions) ([]*cots.ConciseTaStore, error) {
+	tags, err := o.TypedTags(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*cots.ConciseTaStore
+
+	for _, t := range tags {
+		if c, ok := t.(TaggedCots); ok {
+			ret = append(ret, c.Cots)
+		}
+	}
+
+	return ret, nil
+}
+
+// Cotls returns the CoTLs carried in the target UnsignedCorim.  Optional
+// DecodeOptions may be specified, as for TypedTags.
+// nolint:gocritic
+func (o UnsignedCorim) Cotls(opts ...encoding.DecodeOptions) ([]*cotl.ConciseTagList, error) {
+	tags, err := o.TypedTags(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*cotl.ConciseTagList
+
+	for _, t := range tags {
+		if c, ok := t.(TaggedCotl); ok {
+			ret = append(ret, c.Cotl)
+		}
+	}
+
+	return ret, nil
+}
+
+// AddTag appends the CBOR encoding of the supplied TypedTag to the tags array
+// of the unsigned-corim-map
+func (o *UnsignedCorim) AddTag(t TypedTag) *UnsignedCorim {
+	if o != nil {
+		if t == nil {
+			return nil
+		}
+
+		tag, err := t.ToTag()
+		if err != nil {
+			return nil
+		}
+
+		o.Tags = append(o.Tags, tag)
+	}
+	return o
+}
+
+// RemoveTag removes the tag with the supplied identity from the target
+// UnsignedCorim
+func (o *UnsignedCorim) RemoveTag(id comid.TagIdentity) error {
+	i, err := o.findTag(id)
+	if err != nil {
+		return err
+	}
+
+	o.Tags = append(o.Tags[:i], o.Tags[i+1:]...)
+
+	return nil
+}
+
+// ReplaceTag replaces the tag with the supplied identity with the supplied
+// TypedTag, keeping its position in the tags array
+func (o *UnsignedCorim) ReplaceTag(id comid.TagIdentity, t TypedTag) error {
+	if t == nil {
+		return errors.New("nil replacement tag")
+	}
+
+	i, err := o.findTag(id)
+	if err != nil {
+		return err
+	}
+
+	tag, err := t.ToTag()
+	if err != nil {
+		return fmt.Errorf("encoding replacement tag: %w", err)
+	}
+
+	o.Tags[i] = tag
+
+	return nil
+}
+
+func (o UnsignedCorim) findTag(id comid.TagIdentity) (int, error) {
+	tags, err := o.TypedTags()
+	if err != nil {
+		return -1, err
+	}
+
+	for i, t := range tags {
+		if tid := t.GetTagIdentity(); tid != nil && sameTagIdentity(*tid, id) {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("no tag with identity %s (version %d)", id.TagID.String(), id.TagVersion)
+}
+
+func sameTagIdentity(a, b comid.TagIdentity) bool {
+	return a.TagID.String() == b.TagID.String() && a.TagVersion == b.TagVersion
+}
+
+// Decode decodes the target Tag into a TypedTag.  CoMIDs and CoTS are decoded
+// using the extensions of the supplied profile (if registered).  CoMIDs and CoTS are
+// decoded using the type choices of the supplied profile (if any), in addition
+// to the base ones.  Optional DecodeOptions may be specified, e.g. to decode
+// in strict mode; they apply to CoMIDs, CoTSes and CoTLs.
+func (o Tag) Decode(profile *eat.Profile, opts ...encoding.DecodeOptions) (TypedTag, error) {
+	if err := o.Valid(); err != nil {
+		return nil, err
+	}
+
+	dec := encoding.NewDecoder(dm).WithOptions(opts...)
+
+	var raw cbor.RawTag
+	if err := dec.Unmarshal(o, &raw); err != nil {
+		return nil, fmt.Errorf("expecting a CBOR tag: %w", err)
+	}
+
+	switch raw.Number {
+	case CBORTagComid:
+		c, err := UnmarshalComidFromCBOR(raw.Content, profile, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("decoding CoMID: %w", err)
+		}
+		return TaggedComid{Comid: c}, nil
+	case CBORTagCoswid:
+		var c swid.SoftwareIdentity
+		if err := c.FromCBOR(raw.Content); err != nil {
+			return nil, fmt.Errorf("decoding CoSWID: %w", err)
+		}
+		return TaggedCoswid{Coswid: &c}, nil
+	case CBORTagCots:
+		c, err := UnmarshalCotsFromCBOR(raw.Content, profile, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("decoding CoTS: %w", err)
+		}
+		return TaggedCots{Cots: c}, nil
+	case CBORTagCotl:
+		var c cotl.ConciseTagList
+		if err := c.FromCBOR(raw.Content, opts...); err != nil {
+			return nil, fmt.Errorf("decoding CoTL: %w", err)
+		}
+		return TaggedCotl{Cotl: &c}, nil
+	}
+
+	if factory, ok := lookupTagType(raw.Number); ok {
+		v := factory()
+		if err := v.FromCBOR(raw.Content); err != nil {
+			return nil, fmt.Errorf("decoding tag %d: %w", raw.Number, err)
+		}
+		return RegisteredTag{Number: raw.Number, Value: v}, nil
+	}
+
+	return UnknownTag{Number: raw.Number, Content: raw.Content, raw: o}, nil
+}
@@ -0,0 +1,416 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	cbor "github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/cotl"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/swid"
+)
+
+func comidFromTemplate(t *testing.T) *comid.Comid {
+	c := comid.NewComid()
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+	return c
+}
+
+func cotsFromTemplate(t *testing.T) *cots.ConciseTaStore {
+	c := cots.NewConciseTaStore()
+	require.NoError(t, c.FromJSON([]byte(cots.ConciseTaStoreTemplateSingleOrg)))
+	return c
+}
+
+func coswidForTest(t *testing.T) *swid.SoftwareIdentity {
+	c, err := swid.NewTag("com.acme.rrd2013-ce-sp1-v4-1-5-0", "ACME Roadrunner Detector", "4.1.5")
+	require.NoError(t, err)
+	c.TagVersion = 2
+
+	entity, err := swid.NewEntity("ACME Ltd", swid.RoleTagCreator)
+	require.NoError(t, err)
+	require.NoError(t, c.AddEntity(*entity))
+
+	return c
+}
+
+func unsignedCorimWithAllTagTypes(t *testing.T) *UnsignedCorim {
+	tv := NewUnsignedCorim().SetID("test corim id")
+	require.NotNil(t, tv)
+
+	require.NotNil(t, tv.AddComid(comidFromTemplate(t)))
+	require.NotNil(t, tv.AddCoswid(coswidForTest(t)))
+	require.NotNil(t, tv.AddCots(cotsFromTemplate(t)))
+
+	unknown, err := UnknownTag{Number: 1000, Content: []byte{0xa0}}.ToTag()
+	require.NoError(t, err)
+	tv.Tags = append(tv.Tags, unknown)
+
+	return tv
+}
+
+func TestUnsignedCorim_TypedTags(t *testing.T) {
+	tv := unsignedCorimWithAllTagTypes(t)
+
+	tags, err := tv.TypedTags()
+	require.NoError(t, err)
+	require.Len(t, tags, 4)
+
+	m, ok := tags[0].(TaggedComid)
+	require.True(t, ok)
+	assert.Equal(t, CBORTagComid, m.CBORTag())
+	assert.Equal(t, "43bbe37f-2e61-4b33-aed3-53cff1428b16", m.GetTagIdentity().TagID.String())
+
+	s, ok := tags[1].(TaggedCoswid)
+	require.True(t, ok)
+	assert.Equal(t, CBORTagCoswid, s.CBORTag())
+	assert.Equal(t, "com.acme.rrd2013-ce-sp1-v4-1-5-0", s.GetTagIdentity().TagID.String())
+	assert.Equal(t, uint(2), s.GetTagIdentity().TagVersion)
+
+	c, ok := tags[2].(TaggedCots)
+	require.True(t, ok)
+	assert.Equal(t, CBORTagCots, c.CBORTag())
+	assert.Equal(t, "ab0f44b1-bfdc-4604-ab4a-30f80407ebcc", c.GetTagIdentity().TagID.String())
+
+	u, ok := tags[3].(UnknownTag)
+	require.True(t, ok)
+	assert.Equal(t, uint64(1000), u.CBORTag())
+	assert.Nil(t, u.GetTagIdentity())
+	assert.Equal(t, []byte{0xa0}, u.Content)
+
+	// typed tags re-encode to the original bytes
+	for i, tt := range tags {
+		tag, err := tt.ToTag()
+		require.NoError(t, err)
+		assert.Equal(t, tv.Tags[i], tag, "tag at pos %d", i)
+	}
+}
+
+func TestUnsignedCorim_accessors(t *testing.T) {
+	tv := unsignedCorimWithAllTagTypes(t)
+
+	comids, err := tv.Comids()
+	require.NoError(t, err)
+	require.Len(t, comids, 1)
+	assert.Equal(t, "en-GB", *comids[0].Language)
+
+	coswids, err := tv.Coswids()
+	require.NoError(t, err)
+	require.Len(t, coswids, 1)
+	assert.Equal(t, "ACME Roadrunner Detector", coswids[0].SoftwareName)
+
+	ctss, err := tv.Cots()
+	require.NoError(t, err)
+	require.Len(t, ctss, 1)
+	assert.NotNil(t, ctss[0].Keys)
+}
+
+func TestUnsignedCorim_TypedTags_profile(t *testing.T) {
+	buf, err := os.ReadFile("testcases/unsigned-example-corim.cbor")
+	require.NoError(t, err)
+
+	tv, err := UnmarshalUnsignedCorimFromCBOR(buf)
+	require.NoError(t, err)
+
+	comids, err := tv.Comids()
+	require.NoError(t, err)
+	require.Len(t, comids, 1)
+	assert.Equal(t, "123 Fake Street",
+		comids[0].Entities.Values[0].Extensions.MustGetString("Address"))
+}
+
+func TestUnsignedCorim_TypedTags_strict(t *testing.T) {
+	unknown := &encoding.UnknownFields{
+		CBOR: []encoding.UnknownCBORField{{Index: 99, Key: 99, Value: cbor.RawMessage{0xf5}}},
+	}
+
+	keys := comid.NewComid()
+	require.NoError(t, keys.FromJSON([]byte(comid.PSAKeysJSONTemplate)))
+	(*keys.Triples.AttestVerifKeys)[1].Environment.Class.SetUnknownFields(unknown)
+
+	refVals := comidFromTemplate(t)
+	refVals.Triples.ReferenceValues.Values[0].Measurements.Values[1].Val.SetUnknownFields(unknown)
+
+	tv := NewUnsignedCorim().SetID("test corim id").AddComid(keys).AddComid(refVals)
+	require.NotNil(t, tv)
+
+	// lax decoding ignores the unknown entries
+	comids, err := tv.Comids()
+	require.NoError(t, err)
+	require.Len(t, comids, 2)
+
+	opts := encoding.DecodeOptions{Strict: true}
+
+	_, err = tv.Comids(opts)
+	assert.EqualError(t, err, "decoding tag at pos 0: decoding CoMID: unknown field: "+
+		"triples.attester-verification-keys[1].environment.class key 99")
+	assert.ErrorIs(t, err, encoding.ErrUnknownField)
+
+	_, err = tv.Tags[1].Decode(nil, opts)
+	assert.EqualError(t, err, "decoding CoMID: unknown field: "+
+		"triples.reference-values[0].measurements[1].value key 99")
+
+	_, err = tv.TypedTags(opts)
+	assert.ErrorIs(t, err, encoding.ErrUnknownField)
+}
+
+func TestUnsignedCorim_TypedTags_fail(t *testing.T) {
+	tv := UnsignedCorim{Tags: []Tag{{0xa0}}}
+
+	_, err := tv.TypedTags()
+	assert.ErrorContains(t, err, "decoding tag at pos 0: expecting a CBOR tag")
+
+	tv = UnsignedCorim{Tags: []Tag{append(append(Tag{}, ComidTag...), 0xa0)}}
+
+	_, err = tv.Comids()
+	assert.ErrorContains(t, err, "decoding tag at pos 0: decoding CoMID")
+
+	tv = UnsignedCorim{Tags: []Tag{{}}}
+
+	_, err = tv.TypedTags()
+	assert.EqualError(t, err, "decoding tag at pos 0: empty tag")
+}
+
+func TestUnsignedCorim_RemoveTag(t *testing.T) {
+	tv := unsignedCorimWithAllTagTypes(t)
+
+	coswidID := *TaggedCoswid{Coswid: coswidForTest(t)}.GetTagIdentity()
+
+	require.NoError(t, tv.RemoveTag(coswidID))
+	assert.Len(t, tv.Tags, 3)
+
+	coswids, err := tv.Coswids()
+	require.NoError(t, err)
+	assert.Empty(t, coswids)
+
+	err = tv.RemoveTag(coswidID)
+	assert.EqualError(t, err, "no tag with identity com.acme.rrd2013-ce-sp1-v4-1-5-0 (version 2)")
+
+	// the version is part of the identity
+	cotsID := *cotsFromTemplate(t).TagIdentity
+	cotsID.TagVersion++
+	assert.Error(t, tv.RemoveTag(cotsID))
+}
+
+func TestUnsignedCorim_ReplaceTag(t *testing.T) {
+	tv := unsignedCorimWithAllTagTypes(t)
+
+	old := comidFromTemplate(t)
+
+	updated := comidFromTemplate(t)
+	updated.TagIdentity.TagVersion = 1
+	updated.SetLanguage("it-IT")
+
+	require.NoError(t, tv.ReplaceTag(old.TagIdentity, TaggedComid{Comid: updated}))
+	assert.Len(t, tv.Tags, 4)
+
+	comids, err := tv.Comids()
+	require.NoError(t, err)
+	require.Len(t, comids, 1)
+	assert.Equal(t, "it-IT", *comids[0].Language)
+	assert.Equal(t, uint(1), comids[0].TagIdentity.TagVersion)
+
+	err = tv.ReplaceTag(old.TagIdentity, TaggedComid{Comid: updated})
+	assert.EqualError(t, err, "no tag with identity 43bbe37f-2e61-4b33-aed3-53cff1428b16 (version 0)")
+
+	err = tv.ReplaceTag(updated.TagIdentity, TaggedComid{Comid: comid.NewComid()})
+	assert.ErrorContains(t, err, "encoding replacement tag: invalid CoMID")
+
+	err = tv.ReplaceTag(updated.TagIdentity, nil)
+	assert.EqualError(t, err, "nil replacement tag")
+}
+
+type testManifest struct {
+	TagIdentity comid.TagIdentity `cbor:"0,keyasint"`
+	Name        string            `cbor:"1,keyasint"`
+}
+
+func (o *testManifest) FromCBOR(data []byte) error { return dm.Unmarshal(data, o) }
+func (o testManifest) ToCBOR() ([]byte, error)     { return em.Marshal(o) }
+
+func (o testManifest) Valid() error {
+	if o.Name == "" {
+		return errors.New("empty name")
+	}
+	return o.TagIdentity.Valid()
+}
+
+func (o testManifest) GetTagIdentity() *comid.TagIdentity { return &o.TagIdentity }
+
+const testManifestTag uint64 = 60000
+
+func registerTestManifest(t *testing.T) {
+	err := RegisterTagType(testManifestTag, func() ITagValue { return &testManifest{} })
+	require.NoError(t, err)
+	t.Cleanup(func() { UnregisterTagType(testManifestTag) })
+}
+
+func newTestManifest(t *testing.T, name string) *testManifest {
+	id := swid.NewTagID("manifest-" + name)
+	require.NotNil(t, id)
+	return &testManifest{TagIdentity: comid.TagIdentity{TagID: *id}, Name: name}
+}
+
+func TestRegisterTagType(t *testing.T) {
+	registerTestManifest(t)
+
+	err := RegisterTagType(testManifestTag, func() ITagValue { return &testManifest{} })
+	assert.EqualError(t, err, "tag type 60000 is already registered")
+
+	err = RegisterTagType(CBORTagComid, func() ITagValue { return &testManifest{} })
+	assert.EqualError(t, err, "tag 506 is a built-in tag type")
+
+	err = RegisterTagType(60001, nil)
+	assert.EqualError(t, err, "nil factory")
+
+	err = RegisterTagType(60001, func() ITagValue { return nil })
+	assert.EqualError(t, err, "factory returned nil value")
+
+	assert.False(t, UnregisterTagType(60001))
+}
+
+func TestRegisterTagType_concurrent(t *testing.T) {
+	tv := NewUnsignedCorim().SetID("test corim id")
+	require.NotNil(t, tv)
+	require.NotNil(t, tv.AddComid(comidFromTemplate(t)))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		cborTag := uint64(61000 + i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				assert.NoError(t, RegisterTagType(cborTag, func() ITagValue { return &testManifest{} }))
+				assert.True(t, UnregisterTagType(cborTag))
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, tv.Valid())
+
+		_, err := tv.TypedTags()
+		assert.NoError(t, err)
+	}
+
+	wg.Wait()
+}
+
+func TestUnsignedCorim_registered_tag(t *testing.T) {
+	registerTestManifest(t)
+
+	tv := NewUnsignedCorim().SetID("test corim id")
+	require.NotNil(t, tv)
+	require.NotNil(t, tv.AddComid(comidFromTemplate(t)))
+	require.NotNil(t, tv.AddTag(RegisteredTag{Number: testManifestTag, Value: newTestManifest(t, "one")}))
+	require.NoError(t, tv.Valid())
+
+	data, err := tv.ToCBOR()
+	require.NoError(t, err)
+
+	var out UnsignedCorim
+	require.NoError(t, out.FromCBOR(data))
+	require.NoError(t, out.Valid())
+
+	tags, err := out.TypedTags()
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+
+	r, ok := tags[1].(RegisteredTag)
+	require.True(t, ok)
+	assert.Equal(t, testManifestTag, r.CBORTag())
+	assert.Equal(t, "one", r.Value.(*testManifest).Name)
+
+	// registered tags can be looked up by identity
+	id := newTestManifest(t, "one").TagIdentity
+	require.NoError(t, out.ReplaceTag(id, RegisteredTag{Number: testManifestTag, Value: newTestManifest(t, "two")}))
+	require.NoError(t, out.RemoveTag(newTestManifest(t, "two").TagIdentity))
+	assert.Len(t, out.Tags, 1)
+
+	// invalid registered tags are caught by validation
+	assert.Nil(t, tv.AddTag(RegisteredTag{Number: testManifestTag, Value: &testManifest{}}))
+
+	bad, err := em.Marshal(cbor.Tag{Number: testManifestTag, Content: testManifest{TagIdentity: newTestManifest(t, "x").TagIdentity}})
+	require.NoError(t, err)
+	tv.Tags = append(tv.Tags, bad)
+	assert.EqualError(t, tv.Valid(), "tag validation failed at pos 2: invalid tag 60000: empty name")
+
+	tv.Tags[2] = Tag{0xd9, 0xea, 0x60, 0x01}
+	assert.ErrorContains(t, tv.Valid(), "tag validation failed at pos 2: decoding tag 60000")
+}
+
+func TestUnknownTag_opaque(t *testing.T) {
+	// non-preferred encoding of the tag number (1000 encoded on 4 bytes) and
+	// of the content (0 encoded on 2 bytes)
+	orig := Tag{0xda, 0x00, 0x00, 0x03, 0xe8, 0x19, 0x00, 0x00}
+
+	tv := UnsignedCorim{Tags: []Tag{orig}}
+
+	tags, err := tv.TypedTags()
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+
+	u, ok := tags[0].(UnknownTag)
+	require.True(t, ok)
+	assert.Equal(t, uint64(1000), u.Number)
+
+	tag, err := u.ToTag()
+	require.NoError(t, err)
+	assert.Equal(t, orig, tag)
+
+	// once modified, the tag is re-encoded
+	u.Content = []byte{0x01}
+	tag, err = u.ToTag()
+	require.NoError(t, err)
+	assert.Equal(t, Tag{0xd9, 0x03, 0xe8, 0x01}, tag)
+}
+
+func TestUnsignedCorim_AddCotl(t *testing.T) {
+	tl := cotl.NewConciseTagList()
+	require.NoError(t, tl.FromJSON([]byte(cotl.ConciseTagListTemplate)))
+
+	tv := unsignedCorimWithAllTagTypes(t)
+	require.NotNil(t, tv.AddCotl(tl))
+	require.NoError(t, tv.Valid())
+
+	data, err := tv.ToCBOR()
+	require.NoError(t, err)
+
+	var out UnsignedCorim
+	require.NoError(t, out.FromCBOR(data))
+
+	cotls, err := out.Cotls()
+	require.NoError(t, err)
+	require.Len(t, cotls, 1)
+	assert.Equal(t, tl.TagIdentity, cotls[0].TagIdentity)
+
+	tags, err := out.TypedTags()
+	require.NoError(t, err)
+	assert.Equal(t, CBORTagCotl, tags[4].CBORTag())
+
+	// only the CoMIDs listed in the CoTL are active
+	comids, err := out.Comids()
+	require.NoError(t, err)
+	assert.Len(t, cotls[0].FilterComids(comids), 1)
+
+	require.NoError(t, out.RemoveTag(tl.TagIdentity))
+	assert.Len(t, out.Tags, 4)
+
+	assert.Nil(t, tv.AddCotl(cotl.NewConciseTagList()))
+
+	err = RegisterTagType(CBORTagCotl, func() ITagValue { return &testManifest{} })
+	assert.EqualError(t, err, "tag 508 is a built-in tag type")
+}
@@ -8,11 +8,15 @@ import (
 	"fmt"
 	"time"
 
+	cbor "github.com/fxamacker/cbor/v2"
+
+	"github.com/veraison/corim/cotl"
 	"github.com/veraison/corim/cots"
 	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
 
 	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/validation"
 	"github.com/veraison/eat"
 	"github.com/veraison/swid"
 )
@@ -20,7 +24,7 @@ import (
 // UnsignedCorim is the top-level representation of the unsigned-corim-map with
 // CBOR and JSON serialization.
 type UnsignedCorim struct {
-	ID swid.TagID `cbor:"0,keyasint" json:"corim-id"`
+	ID swid.TagID `cbor:"0,keyasint" json:"corim-id" cddl:"corim.id"`
 	// note: even though tags are mandatory for CoRIM, we allow omitting
 	// them in our JSON templates for cocli (the min template just has
 	// corim-id). Since we're never writing JSON (so far), this normally
@@ -29,13 +33,17 @@ type UnsignedCorim struct {
 	// if a field is optional, so we use it during unmarshaling as well as
 	// marshaling. Hence omitempty is present for the json tag, but not
 	// cbor.
-	Tags          []Tag        `cbor:"1,keyasint" json:"tags,omitempty"`
-	DependentRims *[]Locator   `cbor:"2,keyasint,omitempty" json:"dependent-rims,omitempty"`
-	Profile       *eat.Profile `cbor:"3,keyasint,omitempty" json:"profile,omitempty"`
-	RimValidity   *Validity    `cbor:"4,keyasint,omitempty" json:"validity,omitempty"`
-	Entities      *Entities    `cbor:"5,keyasint,omitempty" json:"entities,omitempty"`
+	Tags          []Tag        `cbor:"1,keyasint" json:"tags,omitempty" cddl:"corim.tags"`
+	DependentRims *[]Locator   `cbor:"2,keyasint,omitempty" json:"dependent-rims,omitempty" cddl:"corim.dependent-rims"`
+	Profile       *eat.Profile `cbor:"3,keyasint,omitempty" json:"profile,omitempty" cddl:"corim.profile"`
+	RimValidity   *Validity    `cbor:"4,keyasint,omitempty" json:"validity,omitempty" cddl:"corim.rim-validity"`
+	Entities      *Entities    `cbor:"5,keyasint,omitempty" json:"entities,omitempty" cddl:"corim.entities"`
 
 	Extensions
+
+	// locatorExts holds the extensions registered for the DependentRims
+	// entries (see extensions.WithElementExtensions)
+	locatorExts extensions.Map
 }
 
 type TaggedUnsignedCorim UnsignedCorim
@@ -60,6 +68,11 @@ func (o *UnsignedCorim) RegisterExtensions(exts extensions.Map) error {
 			if err := o.Entities.RegisterExtensions(entMap); err != nil {
 				return err
 			}
+		case ExtLocator:
+			locMap := extensions.NewMap().Add(ExtLocator, v)
+			if err := o.registerLocatorExtensions(locMap); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
 		}
@@ -68,6 +81,27 @@ func (o *UnsignedCorim) RegisterExtensions(exts extensions.Map) error {
 	return nil
 }
 
+func (o *UnsignedCorim) registerLocatorExtensions(exts extensions.Map) error {
+	// validate the extensions by registering them with a new Locator
+	if err := new(Locator).RegisterExtensions(exts); err != nil {
+		return err
+	}
+
+	o.locatorExts = exts
+
+	if o.DependentRims == nil {
+		return nil
+	}
+
+	for i := range *o.DependentRims {
+		if err := extensions.RegisterIfUnset(&(*o.DependentRims)[i], exts); err != nil {
+			return fmt.Errorf("dependent RIM at index %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
 // GetExtensions returns pervisouosly registered extension
 func (o *UnsignedCorim) GetExtensions() extensions.IMapValue {
 	return o.Extensions.IMapValue
@@ -78,15 +112,22 @@ func (o *UnsignedCorim) GetExtensions() extensions.IMapValue {
 // or as a (non-empty) string
 func (o *UnsignedCorim) SetID(v interface{}) *UnsignedCorim {
 	if o != nil {
-		tagID := swid.NewTagID(v)
-		if tagID == nil {
+		if o.setID(v) != nil {
 			return nil
 		}
-		o.ID = *tagID
 	}
 	return o
 }
 
+func (o *UnsignedCorim) setID(v interface{}) error {
+	tagID, err := comid.NewTagID(v)
+	if err != nil {
+		return err
+	}
+	o.ID = *tagID
+	return nil
+}
+
 // GetID retrieves the corim-id from the unsigned-corim-map as a string
 // nolint:gocritic
 func (o UnsignedCorim) GetID() string {
@@ -97,187 +138,247 @@ func (o UnsignedCorim) GetID() string {
 // tags array of the unsigned-corim-map
 func (o *UnsignedCorim) AddComid(c *comid.Comid) *UnsignedCorim {
 	if o != nil {
-		if c.Valid() != nil {
-			return nil
-		}
-
-		comidCBOR, err := c.ToCBOR()
-		if err != nil {
+		if o.addComid(c) != nil {
 			return nil
 		}
+	}
+	return o
+}
 
-		taggedComid := append(ComidTag, comidCBOR...) //nolint:gocritic
+func (o *UnsignedCorim) addComid(c *comid.Comid) error {
+	if err := c.Valid(); err != nil {
+		return fmt.Errorf("invalid CoMID: %w", err)
+	}
 
-		o.Tags = append(o.Tags, taggedComid)
+	comidCBOR, err := c.ToCBOR()
+	if err != nil {
+		return fmt.Errorf("encoding CoMID: %w", err)
 	}
-	return o
+
+	taggedComid := append(ComidTag, comidCBOR...) //nolint:gocritic
+
+	o.Tags = append(o.Tags, taggedComid)
+	return nil
 }
 
 // AddCots appends the CBOR encoded (and appropriately tagged) CoTS to the
 // tags array of the unsigned-corim-map
 func (o *UnsignedCorim) AddCots(c *cots.ConciseTaStore) *UnsignedCorim {
 	if o != nil {
-		if c.Valid() != nil {
+		if o.addCots(c) != nil {
 			return nil
 		}
+	}
+	return o
+}
+
+func (o *UnsignedCorim) addCots(c *cots.ConciseTaStore) error {
+	if err := c.Valid(); err != nil {
+		return fmt.Errorf("invalid CoTS: %w", err)
+	}
+
+	cotsCBOR, err := c.ToCBOR()
+	if err != nil {
+		return fmt.Errorf("encoding CoTS: %w", err)
+	}
 
-		cotsCBOR, err := c.ToCBOR()
-		if err != nil {
+	taggedCots := append(cots.CotsTag, cotsCBOR...) //nolint:gocritic
+
+	o.Tags = append(o.Tags, taggedCots)
+	return nil
+}
+
+// AddCotl appends the CBOR encoded (and appropriately tagged) CoTL to the
+// tags array of the unsigned-corim-map
+func (o *UnsignedCorim) AddCotl(c *cotl.ConciseTagList) *UnsignedCorim {
+	if o != nil {
+		if o.addCotl(c) != nil {
 			return nil
 		}
+	}
+	return o
+}
 
-		taggedCots := append(cots.CotsTag, cotsCBOR...) //nolint:gocritic
+func (o *UnsignedCorim) addCotl(c *cotl.ConciseTagList) error {
+	if err := c.Valid(); err != nil {
+		return fmt.Errorf("invalid CoTL: %w", err)
+	}
 
-		o.Tags = append(o.Tags, taggedCots)
+	cotlCBOR, err := c.ToCBOR()
+	if err != nil {
+		return fmt.Errorf("encoding CoTL: %w", err)
 	}
-	return o
+
+	taggedCotl := append(cotl.CotlTag, cotlCBOR...) //nolint:gocritic
+
+	o.Tags = append(o.Tags, taggedCotl)
+	return nil
 }
 
 // AddCoswid appends the CBOR encoded (and appropriately tagged) CoSWID to the
 // tags array of the unsigned-corim-map
 func (o *UnsignedCorim) AddCoswid(c *swid.SoftwareIdentity) *UnsignedCorim {
 	if o != nil {
-		// Currently the swid package doesn't offer an interface
-		// for validating the supplied CoSWID, so -- for now --
-		// we take any input for granted and pass it to the encoder.
-		// See also https://github.com/veraison/swid/issues/23.
-
-		coswidCBOR, err := c.ToCBOR()
-		if err != nil {
+		if o.addCoswid(c) != nil {
 			return nil
 		}
+	}
+	return o
+}
 
-		taggedCoswid := append(CoswidTag, coswidCBOR...) //nolint:gocritic
+func (o *UnsignedCorim) addCoswid(c *swid.SoftwareIdentity) error {
+	// Currently the swid package doesn't offer an interface
+	// for validating the supplied CoSWID, so -- for now --
+	// we take any input for granted and pass it to the encoder.
+	// See also https://github.com/veraison/swid/issues/23.
 
-		o.Tags = append(o.Tags, taggedCoswid)
+	coswidCBOR, err := c.ToCBOR()
+	if err != nil {
+		return fmt.Errorf("encoding CoSWID: %w", err)
 	}
-	return o
+
+	taggedCoswid := append(CoswidTag, coswidCBOR...) //nolint:gocritic
+
+	o.Tags = append(o.Tags, taggedCoswid)
+	return nil
 }
 
 // AddDependentRim creates a corim-locator-map from the supplied arguments and
 // appends it to the dependent RIMs in the unsigned-corim-map
 func (o *UnsignedCorim) AddDependentRim(href string, thumbprint *swid.HashEntry) *UnsignedCorim {
 	if o != nil {
-		l := Locator{
-			Href:       comid.TaggedURI(href),
-			Thumbprint: thumbprint,
-		}
-
-		if o.DependentRims == nil {
-			o.DependentRims = new([]Locator)
+		if o.addDependentRim(href, thumbprint) != nil {
+			return nil
 		}
-
-		*o.DependentRims = append(*o.DependentRims, l)
-
 	}
 	return o
 }
 
+func (o *UnsignedCorim) addDependentRim(href string, thumbprint *swid.HashEntry) error {
+	return o.addLocator(Locator{
+		Href:       comid.TaggedURI(href),
+		Thumbprint: thumbprint,
+	})
+}
+
 // SetProfile sets the supplied profile identifier (either a URL or OID) as
 // the profile in the unsigned-corim-map
 func (o *UnsignedCorim) SetProfile(urlOrOID string) *UnsignedCorim {
 	if o != nil {
-		p, err := eat.NewProfile(urlOrOID)
-		if err != nil {
+		if o.setProfile(urlOrOID) != nil {
 			return nil
 		}
-
-		o.Profile = p
-
 	}
 	return o
 }
 
+func (o *UnsignedCorim) setProfile(urlOrOID string) error {
+	p, err := eat.NewProfile(urlOrOID)
+	if err != nil {
+		return err
+	}
+
+	o.Profile = p
+	return nil
+}
+
 // SetRimValidity can be used to set the validity period of the CoRIM.
 // The caller must supply a "not-after" timestamp and optionally a "not-before"
 // timestamp.
 func (o *UnsignedCorim) SetRimValidity(notAfter time.Time, notBefore *time.Time) *UnsignedCorim {
 	if o != nil {
-		v := NewValidity().Set(notAfter, notBefore)
-		if v == nil {
+		if o.setRimValidity(notAfter, notBefore) != nil {
 			return nil
 		}
+	}
+	return o
+}
+
+func (o *UnsignedCorim) setRimValidity(notAfter time.Time, notBefore *time.Time) error {
+	v := Validity{
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
+	}
+
+	if err := v.Valid(); err != nil {
+		return err
+	}
+
+	o.RimValidity = &v
+	return nil
+}
 
-		o.RimValidity = v
+// AddLocator appends the supplied corim-locator-map to the dependent RIMs in
+// the unsigned-corim-map.  See NewLocator for creating a locator with the
+// thumbprint(s) of the referenced CoRIM.
+func (o *UnsignedCorim) AddLocator(l Locator) *UnsignedCorim {
+	if o != nil {
+		if o.addLocator(l) != nil {
+			return nil
+		}
 	}
 	return o
 }
 
+func (o *UnsignedCorim) addLocator(l Locator) error {
+	if err := extensions.RegisterIfUnset(&l, o.locatorExts); err != nil {
+		return err
+	}
+
+	if o.DependentRims == nil {
+		o.DependentRims = new([]Locator)
+	}
+
+	*o.DependentRims = append(*o.DependentRims, l)
+
+	return nil
+}
+
 // AddEntity adds an organizational entity, together with the roles this entity
 // claims with regards to the CoRIM, to the target UnsignerCorim.  name is the entity
 // name, regID is a URI that uniquely identifies the entity.  For the moment, roles
 // can only be RoleManifestCreator.
 func (o *UnsignedCorim) AddEntity(name string, regID *string, roles ...Role) *UnsignedCorim {
 	if o != nil {
-		e := NewEntity().
-			SetName(name).
-			SetRoles(roles...)
-
-		if regID != nil {
-			e = e.SetRegID(*regID)
-		}
-
-		if e == nil {
-			return nil
-		}
-
-		if o.Entities == nil {
-			o.Entities = new(Entities)
-		}
-
-		if o.Entities.Add(e) == nil {
+		if o.addEntity(name, regID, roles...) != nil {
 			return nil
 		}
 	}
 	return o
 }
 
-// Valid checks the validity (according to the spec) of the target unsigned CoRIM
-// nolint:gocritic
-func (o UnsignedCorim) Valid() error {
-	if o.ID == (swid.TagID{}) {
-		return fmt.Errorf("empty id")
-	}
+func (o *UnsignedCorim) addEntity(name string, regID *string, roles ...Role) error {
+	e := NewEntity()
 
-	if len(o.Tags) == 0 {
-		return errors.New("tags validation failed: no tags")
+	if err := e.setName(name); err != nil {
+		return err
 	}
 
-	for i, t := range o.Tags {
-		if err := t.Valid(); err != nil {
-			return fmt.Errorf("tag validation failed at pos %d: %w", i, err)
-		}
+	if err := e.setRoles(roles...); err != nil {
+		return err
 	}
 
-	if o.DependentRims != nil {
-		for i, r := range *o.DependentRims {
-			if err := r.Valid(); err != nil {
-				return fmt.Errorf("dependent RIM validation failed at pos %d: %w", i, err)
-			}
+	if regID != nil {
+		if err := e.setRegID(*regID); err != nil {
+			return err
 		}
 	}
 
-	if o.Profile != nil {
-		if err := ValidProfile(*o.Profile); err != nil {
-			return fmt.Errorf("profile validation failed: %w", err)
-		}
+	if o.Entities == nil {
+		o.Entities = new(Entities)
 	}
 
-	if o.RimValidity != nil {
-		if err := o.RimValidity.Valid(); err != nil {
-			return fmt.Errorf("RIM validity validation failed: %w", err)
-		}
+	if o.Entities.Add(e) == nil {
+		return fmt.Errorf("could not add entity")
 	}
 
-	if o.Entities != nil {
-		for i, e := range o.Entities.Values {
-			if err := e.Valid(); err != nil {
-				return fmt.Errorf("entity validation failed at pos %d: %w", i, err)
-			}
-		}
-	}
+	return nil
+}
 
-	return o.Extensions.validCorim(&o)
+// Valid checks the validity (according to the spec) of the target unsigned CoRIM
+// nolint:gocritic
+func (o UnsignedCorim) Valid() error {
+	return validation.FirstError(o.validate)
 }
 
 // ToCBOR serializes the target unsigned CoRIM to CBOR
@@ -296,9 +397,43 @@ func (o UnsignedCorim) ToCBOR() ([]byte, error) {
 	return encoding.SerializeStructToCBOR(em, o)
 }
 
-// FromCBOR deserializes a CBOR-encoded unsigned CoRIM into the target UnsignedCorim
-func (o *UnsignedCorim) FromCBOR(data []byte) error {
-	return encoding.PopulateStructFromCBOR(dm, data, o)
+// ToTaggedCBOR serializes the target unsigned CoRIM to CBOR, wrapped in the
+// tagged-unsigned-corim-map (#6.501) tag
+// nolint:gocritic
+func (o UnsignedCorim) ToTaggedCBOR() ([]byte, error) {
+	data, err := o.ToCBOR()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{}, UnsignedCorimTag...), data...), nil
+}
+
+// FromCBOR deserializes a CBOR-encoded unsigned CoRIM into the target
+// UnsignedCorim.  The tagged-unsigned-corim-map (#6.501) tag is optional.
+// Optional DecodeOptions may be specified, e.g. to decode in strict mode.
+func (o *UnsignedCorim) FromCBOR(data []byte, opts ...encoding.DecodeOptions) error {
+	dec := o.newDecoder().WithOptions(opts...)
+
+	if err := encoding.CheckCBORInput(dec, data, o); err != nil {
+		return err
+	}
+
+	return encoding.PopulateStructFromCBOR(dec, data, o)
+}
+
+// ToEDN serializes the target unsigned CoRIM to CBOR, and renders it in
+// Extended Diagnostic Notation, with map keys annotated with the names of the
+// corresponding fields in the CoRIM specification.  The tags carried in the
+// CoRIM are rendered as embedded data items.
+// nolint:gocritic
+func (o UnsignedCorim) ToEDN() (string, error) {
+	data, err := o.ToCBOR()
+	if err != nil {
+		return "", err
+	}
+
+	return encoding.FormatEDN(data, o)
 }
 
 // ToJSON serializes the target unsigned CoRIM to JSON
@@ -317,42 +452,143 @@ func (o UnsignedCorim) ToJSON() ([]byte, error) {
 	return encoding.SerializeStructToJSON(o)
 }
 
-// FromJSON deserializes a JSON-encoded unsigned CoRIM into the target UnsignedCorim
-func (o *UnsignedCorim) FromJSON(data []byte) error {
-	return encoding.PopulateStructFromJSON(data, o)
+// FromJSON deserializes a JSON-encoded unsigned CoRIM into the target
+// UnsignedCorim. Optional DecodeOptions may be specified, e.g. to decode in
+// strict mode.
+func (o *UnsignedCorim) FromJSON(data []byte, opts ...encoding.DecodeOptions) error {
+	dec := o.newDecoder().WithOptions(opts...)
+
+	if err := encoding.CheckJSONLimits(data, dec.Limits()); err != nil {
+		return err
+	}
+
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
 }
 
-// Tag is either a CBOR-encoded CoMID, CoSWID or CoTS
+// newDecoder returns a Decoder for the target UnsignedCorim, with the
+// extensions of its DependentRims entries in scope
+func (o *UnsignedCorim) newDecoder() *encoding.Decoder {
+	return extensions.WithElementExtensions(encoding.NewDecoder(dm), ExtLocator, o.locatorExts)
+}
+
+// Tag is either a CBOR-encoded CoMID, CoSWID, CoTS or CoTL, or a tag of a kind
+// registered via RegisterTagType
 type Tag []byte
 
 func (o Tag) Valid() error {
-	// there is no much we can check here, except making sure that the tag is
-	// not zero-length
 	if len(o) == 0 {
 		return errors.New("empty tag")
 	}
+
+	// tags of a registered kind are decoded and validated, whereas for the
+	// others there is no much we can check here
+	tags, _, err := peelTags(o)
+	if err != nil || len(tags) == 0 {
+		return nil
+	}
+
+	factory, ok := lookupTagType(tags[0])
+	if !ok {
+		return nil
+	}
+
+	var raw cbor.RawTag
+	if err := dm.Unmarshal(o, &raw); err != nil {
+		return fmt.Errorf("decoding tag %d: %w", tags[0], err)
+	}
+
+	v := factory()
+	if err := v.FromCBOR(raw.Content); err != nil {
+		return fmt.Errorf("decoding tag %d: %w", tags[0], err)
+	}
+
+	if err := v.Valid(); err != nil {
+		return fmt.Errorf("invalid tag %d: %w", tags[0], err)
+	}
+
+	return nil
+}
+
+// EmbeddedCBORType returns a value of the type the tag decodes to, given its
+// CBOR tag number. This is used to name the path segments reported by
+// encoding.CheckDeterministicCBOR.
+func (o Tag) EmbeddedCBORType(cborTag uint64) any {
+	switch cborTag {
+	case CBORTagComid:
+		return comid.Comid{}
+	case CBORTagCoswid:
+		return swid.SoftwareIdentity{}
+	case CBORTagCots:
+		return cots.ConciseTaStore{}
+	case CBORTagCotl:
+		return cotl.ConciseTagList{}
+	}
+
+	if factory, ok := lookupTagType(cborTag); ok {
+		return factory()
+	}
+
 	return nil
 }
 
 // Locator is the internal representation of the corim-locator-map with CBOR and
 // JSON serialization.
 type Locator struct {
-	Href       comid.TaggedURI `cbor:"0,keyasint" json:"href"`
-	Thumbprint *swid.HashEntry `cbor:"1,keyasint,omitempty" json:"thumbprint,omitempty"`
+	Href       comid.TaggedURI `cbor:"0,keyasint" json:"href" cddl:"corim.href"`
+	Thumbprint *swid.HashEntry `cbor:"1,keyasint,omitempty" json:"thumbprint,omitempty" cddl:"corim.thumbprint"`
+	// Thumbprints is the multi-digest form of the thumbprint, allowed by more
+	// recent versions of the CoRIM specification. It is serialized in place of
+	// Thumbprint, and so the two must not be set at the same time. Decoding a
+	// single digest sets Thumbprint, while decoding a list of digests (even
+	// of only one) sets Thumbprints, so that the thumbprint is re-encoded in
+	// the form it was found in.
+	Thumbprints Thumbprint `cbor:"-" json:"-"`
+
+	Extensions
+}
+
+// RegisterExtensions registers a struct as a collections of extensions
+func (o *Locator) RegisterExtensions(exts extensions.Map) error {
+	for p, v := range exts {
+		switch p {
+		case ExtLocator:
+			o.Extensions.Register(v)
+		default:
+			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
+		}
+	}
+
+	return nil
 }
 
+// GetExtensions returns previously registered extension
+func (o *Locator) GetExtensions() extensions.IMapValue {
+	return o.Extensions.IMapValue
+}
+
+// nolint:gocritic
 func (o Locator) Valid() error {
 	if o.Href.Empty() {
 		return errors.New("empty href")
 	}
 
 	if tp := o.Thumbprint; tp != nil {
+		if len(o.Thumbprints) != 0 {
+			return errors.New("both single and multi-digest thumbprints set")
+		}
+
 		if err := swid.ValidHashEntry(tp.HashAlgID, tp.HashValue); err != nil {
 			return fmt.Errorf("invalid locator thumbprint: %w", err)
 		}
 	}
 
-	return nil
+	if len(o.Thumbprints) != 0 {
+		if err := o.Thumbprints.Valid(); err != nil {
+			return fmt.Errorf("invalid locator thumbprint: %w", err)
+		}
+	}
+
+	return o.Extensions.validLocator(&o)
 }
 
 // ValidProfile checks that the supplied profile is in one of the supported
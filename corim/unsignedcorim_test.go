@@ -4,6 +4,7 @@
 package corim
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/veraison/corim/comid"
 	"github.com/veraison/corim/cots"
+	"github.com/veraison/corim/encoding"
 	"github.com/veraison/corim/extensions"
 	"github.com/veraison/swid"
 )
@@ -415,3 +417,98 @@ func TestLocator_Valid(t *testing.T) {
 	assert.EqualError(t, l.Valid(), "invalid locator thumbprint: unknown hash algorithm 0")
 
 }
+
+func TestUnsignedCorim_FromCBOR_strict(t *testing.T) {
+	err := NewUnsignedCorim().FromCBOR(testGoodUnsignedCorimCBOR,
+		encoding.DecodeOptions{Strict: true})
+	assert.NoError(t, err)
+
+	// without the extensions registered, the extension entries are
+	// ignored by default, but are rejected in strict mode
+	err = NewUnsignedCorim().FromCBOR(testUnsignedCorimWithExtensionsCBOR)
+	assert.NoError(t, err)
+
+	err = NewUnsignedCorim().FromCBOR(testUnsignedCorimWithExtensionsCBOR,
+		encoding.DecodeOptions{Strict: true})
+	assert.ErrorIs(t, err, encoding.ErrUnknownField)
+}
+
+func TestUnsignedCorim_FromJSON_strict(t *testing.T) {
+	data := []byte(`{
+		"corim-id": "5c57e8f4-46cd-421b-91c9-08cf93e13cfc",
+		"entities": [ { "name": "ACME Ltd.", "roles": [ "manifestCreator" ], "x-vendor": true } ]
+	}`)
+
+	err := NewUnsignedCorim().FromJSON(data)
+	assert.NoError(t, err)
+
+	err = NewUnsignedCorim().FromJSON(data, encoding.DecodeOptions{Strict: true})
+	assert.EqualError(t, err, `unknown field: entities[0] key "x-vendor"`)
+}
+
+func TestUnsignedCorim_FromCBOR_limits(t *testing.T) {
+	limits := encoding.DecodeLimits{MaxInputSize: len(testGoodUnsignedCorimCBOR)}
+
+	err := NewUnsignedCorim().FromCBOR(testGoodUnsignedCorimCBOR,
+		encoding.DecodeOptions{Limits: &limits})
+	assert.NoError(t, err)
+
+	limits.MaxInputSize--
+
+	err = NewUnsignedCorim().FromCBOR(testGoodUnsignedCorimCBOR,
+		encoding.DecodeOptions{Limits: &limits})
+	assert.ErrorIs(t, err, encoding.ErrLimitExceeded)
+
+	var le *encoding.LimitError
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxInputSize", le.Limit)
+	assert.Equal(t, len(testGoodUnsignedCorimCBOR), le.Value)
+	assert.Equal(t, len(testGoodUnsignedCorimCBOR)-1, le.Max)
+
+	// the tags are embedded in byte strings, and so are not nested within
+	// the unsigned-corim-map as far as the limits are concerned
+	limits = encoding.DecodeLimits{MaxNestingDepth: 3, MaxElements: 3}
+
+	err = NewUnsignedCorim().FromCBOR(testGoodUnsignedCorimCBOR,
+		encoding.DecodeOptions{Limits: &limits})
+	assert.NoError(t, err)
+
+	limits.MaxNestingDepth = 1
+
+	err = NewUnsignedCorim().FromCBOR(testGoodUnsignedCorimCBOR,
+		encoding.DecodeOptions{Limits: &limits})
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxNestingDepth", le.Limit)
+}
+
+func TestUnsignedCorim_FromJSON_limits(t *testing.T) {
+	limits := encoding.DecodeLimits{MaxNestingDepth: 2}
+
+	err := NewUnsignedCorim().FromJSON(testUnsignedCorimJSON,
+		encoding.DecodeOptions{Limits: &limits})
+
+	var le *encoding.LimitError
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxNestingDepth", le.Limit)
+}
+
+func TestUnsignedCorim_ToEDN(t *testing.T) {
+	u := NewUnsignedCorim()
+	require.NoError(t, u.FromCBOR(testGoodUnsignedCorimCBOR))
+
+	edn, err := u.ToEDN()
+	require.NoError(t, err)
+
+	assert.Contains(t, edn, `/ corim.id / 0 : "test corim id"`)
+	// the CoMID is rendered as an embedded data item
+	assert.Contains(t, edn, `/ corim.tags / 1 : [
+    <<506({
+      / comid.language / 0 : "en-GB",`)
+
+	data, err := u.ToCBOR()
+	require.NoError(t, err)
+
+	out, err := encoding.ParseEDN(edn)
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package corim
+
+import (
+	"errors"
+
+	"github.com/veraison/corim/validation"
+	"github.com/veraison/swid"
+)
+
+// ValidateAll performs the same checks as Valid, but rather than stopping at
+// the first problem, it reports all of them, each with its location (as a
+// JSON pointer and as a sequence of CBOR keys), its kind and its severity.
+// The returned list is empty if the UnsignedCorim is valid; use its Err method
+// to obtain an error value.
+// nolint:gocritic
+func (o UnsignedCorim) ValidateAll() validation.Errors {
+	c := validation.NewCollector()
+	o.validate(c)
+
+	return c.Errors()
+}
+
+// nolint:gocritic
+func (o UnsignedCorim) validate(c *validation.Collector) {
+	if o.ID == (swid.TagID{}) {
+		c.Field(o, "ID").Error(validation.ErrMissing, errors.New("empty id"))
+	}
+
+	tags := c.Field(o, "Tags")
+
+	if len(o.Tags) == 0 {
+		tags.Wrap("tags validation failed").Error(validation.ErrEmpty, errors.New("no tags"))
+	}
+
+	for i, t := range o.Tags {
+		tags.Index(i).Wrap("tag validation failed at pos %d", i).Check(t.Valid())
+	}
+
+	if o.DependentRims != nil {
+		rims := c.Field(o, "DependentRims")
+		for i, r := range *o.DependentRims {
+			rims.Index(i).Wrap("dependent RIM validation failed at pos %d", i).Check(r.Valid())
+		}
+	}
+
+	if o.Profile != nil {
+		c.Field(o, "Profile").Wrap("profile validation failed").Check(ValidProfile(*o.Profile))
+	}
+
+	if o.RimValidity != nil {
+		c.Field(o, "RimValidity").Wrap("RIM validity validation failed").Check(o.RimValidity.Valid())
+	}
+
+	if o.Entities != nil {
+		entities := c.Field(o, "Entities")
+		for i, e := range o.Entities.Values {
+			e.validate(entities.Index(i).Wrap("entity validation failed at pos %d", i))
+		}
+	}
+
+	c.CheckExtension(o.Extensions.validCorim(&o))
+}
+
+// nolint:gocritic
+func (o Entity) validate(c *validation.Collector) {
+	invalid := c.Wrap("invalid entity")
+
+	if o.Name == nil {
+		invalid.Field(o, "Name").Error(validation.ErrMissing, errors.New("empty entity-name"))
+	} else {
+		invalid.Field(o, "Name").Check(o.Name.Valid())
+	}
+
+	if o.RegID != nil && o.RegID.Empty() {
+		invalid.Field(o, "RegID").Error(validation.ErrEmpty, errors.New("empty reg-id"))
+	}
+
+	invalid.Field(o, "Roles").Check(o.Roles.Valid())
+
+	c.CheckExtension(o.Extensions.validEntity(&o))
+}
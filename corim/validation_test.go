@@ -0,0 +1,85 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package corim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/validation"
+	"github.com/veraison/swid"
+)
+
+func TestUnsignedCorim_ValidateAll_ok(t *testing.T) {
+	u := NewUnsignedCorim()
+	require.NoError(t, u.FromCBOR(testGoodUnsignedCorimCBOR))
+
+	assert.Empty(t, u.ValidateAll())
+}
+
+func TestUnsignedCorim_ValidateAll(t *testing.T) {
+	u := NewUnsignedCorim()
+	require.NoError(t, u.FromCBOR(testGoodUnsignedCorimCBOR))
+
+	u.ID = swid.TagID{}
+	u.Tags = append(u.Tags, Tag{})
+	u.DependentRims = &[]Locator{{}}
+
+	notBefore := time.Now()
+	u.RimValidity = &Validity{NotBefore: &notBefore, NotAfter: notBefore.Add(-time.Hour)}
+
+	errs := u.ValidateAll()
+	require.Len(t, errs, 4)
+
+	assert.Equal(t, "/corim-id", errs[0].Path.Pointer())
+	assert.Equal(t, []any{0}, errs[0].Path.CBORKeys())
+	assert.Equal(t, "missing", errs[0].Code)
+
+	assert.Equal(t, "/tags/1", errs[1].Path.Pointer())
+	assert.Equal(t, []any{1, 1}, errs[1].Path.CBORKeys())
+	assert.EqualError(t, errs[1].Err, "empty tag")
+
+	assert.Equal(t, "/dependent-rims/0", errs[2].Path.Pointer())
+	assert.EqualError(t, errs[2].Err, "empty href")
+
+	assert.Equal(t, "/validity", errs[3].Path.Pointer())
+	assert.Equal(t, []any{4}, errs[3].Path.CBORKeys())
+	assert.ErrorIs(t, errs[3], validation.ErrInvalidValue)
+}
+
+func TestUnsignedCorim_ValidateAll_expired(t *testing.T) {
+	u := NewUnsignedCorim()
+	require.NoError(t, u.FromCBOR(testGoodUnsignedCorimCBOR))
+
+	u.RimValidity = &Validity{NotAfter: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	// expiry is not a validity problem (see the lint expiring-validity rule)
+	require.NoError(t, u.Valid())
+	assert.Empty(t, u.ValidateAll())
+}
+
+func TestUnsignedCorim_ValidateAll_entity(t *testing.T) {
+	u := NewUnsignedCorim()
+	require.NoError(t, u.FromCBOR(testGoodUnsignedCorimCBOR))
+
+	emptyRegID := comid.TaggedURI("")
+	u.Entities = NewEntities()
+	u.Entities.Add(&Entity{RegID: &emptyRegID})
+
+	// all the problems of the entity are reported...
+	errs := u.ValidateAll()
+	require.Len(t, errs, 3)
+
+	assert.Equal(t, "/entities/0/name", errs[0].Path.Pointer())
+	assert.Equal(t, "missing", errs[0].Code)
+	assert.Equal(t, "/entities/0/regid", errs[1].Path.Pointer())
+	assert.Equal(t, "empty", errs[1].Code)
+	assert.Equal(t, "/entities/0/roles", errs[2].Path.Pointer())
+
+	// ...while Valid reports the first one
+	assert.EqualError(t, u.Valid(),
+		"entity validation failed at pos 0: invalid entity: empty entity-name")
+}
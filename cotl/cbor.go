@@ -0,0 +1,43 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cotl
+
+import (
+	cbor "github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/encoding"
+)
+
+var (
+	em, emError = initCBOREncMode()
+	dm, dmError = initCBORDecMode()
+)
+
+var (
+	CotlTag = []byte{0xd9, 0x01, 0xfc} // 508()
+)
+
+func initCBOREncMode() (en cbor.EncMode, err error) {
+	encOpt := cbor.EncOptions{
+		IndefLength: cbor.IndefLengthForbidden,
+		TimeTag:     cbor.EncTagRequired,
+	}
+	return encOpt.EncMode()
+}
+
+func initCBORDecMode() (dm cbor.DecMode, err error) {
+	decOpt := cbor.DecOptions{
+		IndefLength: cbor.IndefLengthForbidden,
+		TimeTag:     cbor.DecTagRequired,
+	}
+	return encoding.NewDecMode(decOpt, nil)
+}
+
+func init() {
+	if emError != nil {
+		panic(emError)
+	}
+	if dmError != nil {
+		panic(dmError)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cotl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/swid"
+)
+
+// ConciseTagList is the concise-tl-tag, i.e., the list of the tags that are
+// currently active for a device
+type ConciseTagList struct {
+	TagIdentity comid.TagIdentity   `cbor:"0,keyasint" json:"tag-identity"`
+	TagsList    []comid.TagIdentity `cbor:"1,keyasint" json:"tags-list"`
+	TlValidity  comid.Validity      `cbor:"2,keyasint" json:"tl-validity"`
+
+	// unknown holds the map entries that were not known fields, retained
+	// when decoding with encoding.DecodeOptions.PreserveUnknownFields set
+	unknown *encoding.UnknownFields
+}
+
+func NewConciseTagList() *ConciseTagList {
+	return &ConciseTagList{}
+}
+
+// SetTagIdentity sets the identity of the target ConciseTagList
+func (o *ConciseTagList) SetTagIdentity(tagID interface{}, tagIDVersion *uint) *ConciseTagList {
+	if o != nil {
+		id := swid.NewTagID(tagID)
+		if id == nil {
+			return nil
+		}
+		o.TagIdentity = comid.TagIdentity{TagID: *id}
+		if tagIDVersion != nil {
+			o.TagIdentity.TagVersion = *tagIDVersion
+		}
+	}
+	return o
+}
+
+// AddTagIdentity appends the identity of an active tag to the tags list
+func (o *ConciseTagList) AddTagIdentity(tagID interface{}, tagIDVersion *uint) *ConciseTagList {
+	if o != nil {
+		id := swid.NewTagID(tagID)
+		if id == nil {
+			return nil
+		}
+		ti := comid.TagIdentity{TagID: *id}
+		if tagIDVersion != nil {
+			ti.TagVersion = *tagIDVersion
+		}
+		o.TagsList = append(o.TagsList, ti)
+	}
+	return o
+}
+
+// SetValidity sets the validity period of the target ConciseTagList
+func (o *ConciseTagList) SetValidity(notAfter time.Time, notBefore *time.Time) *ConciseTagList {
+	if o != nil {
+		v := comid.Validity{NotBefore: notBefore, NotAfter: notAfter}
+		if v.Valid() != nil {
+			return nil
+		}
+		o.TlValidity = v
+	}
+	return o
+}
+
+// GetTagIdentity returns the identity of the target ConciseTagList
+func (o ConciseTagList) GetTagIdentity() *comid.TagIdentity {
+	return &o.TagIdentity
+}
+
+// Valid checks the tag identity, the tags list and the validity of the target
+// ConciseTagList
+// nolint:gocritic
+func (o ConciseTagList) Valid() error {
+	if err := o.TagIdentity.Valid(); err != nil {
+		return fmt.Errorf("invalid tag-identity: %w", err)
+	}
+
+	if len(o.TagsList) == 0 {
+		return errors.New("empty tags-list")
+	}
+
+	for i, ti := range o.TagsList {
+		if err := ti.Valid(); err != nil {
+			return fmt.Errorf("invalid tags-list entry at index %d: %w", i, err)
+		}
+	}
+
+	if err := o.TlValidity.Valid(); err != nil {
+		return fmt.Errorf("invalid tl-validity: %w", err)
+	}
+
+	return nil
+}
+
+// ToCBOR serializes the target ConciseTagList to CBOR
+// nolint:gocritic
+func (o ConciseTagList) ToCBOR() ([]byte, error) {
+	if err := o.Valid(); err != nil {
+		return nil, err
+	}
+
+	return em.Marshal(o)
+}
+
+// FromCBOR deserializes a CBOR-encoded CoTL into the target ConciseTagList.
+// Optional DecodeOptions may be specified, e.g. to decode in strict mode.
+func (o *ConciseTagList) FromCBOR(data []byte, opts ...encoding.DecodeOptions) error {
+	dec := encoding.NewDecoder(dm).WithOptions(opts...)
+
+	if err := encoding.CheckCBORInput(dec, data, o); err != nil {
+		return err
+	}
+
+	return dec.Unmarshal(data, o)
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *ConciseTagList) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *ConciseTagList) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o ConciseTagList) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o ConciseTagList) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
+}
+
+// ToJSON serializes the target ConciseTagList to JSON
+// nolint:gocritic
+func (o ConciseTagList) ToJSON() ([]byte, error) {
+	if err := o.Valid(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(o)
+}
+
+// FromJSON deserializes a JSON-encoded CoTL into the target ConciseTagList.
+// Optional DecodeOptions may be specified, e.g. to decode in strict mode.
+func (o *ConciseTagList) FromJSON(data []byte, opts ...encoding.DecodeOptions) error {
+	dec := encoding.NewDecoder(dm).WithOptions(opts...)
+
+	if err := encoding.CheckJSONLimits(data, dec.Limits()); err != nil {
+		return err
+	}
+
+	return dec.DecodeJSON(data, o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *ConciseTagList) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *ConciseTagList) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o ConciseTagList) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
+}
+
+// GetUnknownFields returns the unknown map entries retained when the target
+// ConciseTagList was decoded
+// nolint:gocritic
+func (o ConciseTagList) GetUnknownFields() *encoding.UnknownFields {
+	return o.unknown
+}
+
+// SetUnknownFields sets the unknown map entries to be emitted alongside the
+// fields of the target ConciseTagList
+func (o *ConciseTagList) SetUnknownFields(unknown *encoding.UnknownFields) {
+	o.unknown = unknown
+}
+
+// Lists returns true if the supplied tag identity is in the tags list of the
+// target ConciseTagList
+// nolint:gocritic
+func (o ConciseTagList) Lists(id comid.TagIdentity) bool {
+	for _, ti := range o.TagsList {
+		if ti.TagVersion == id.TagVersion && ti.TagID.String() == id.TagID.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsActiveAt returns true if the supplied time falls within the validity
+// period of the target ConciseTagList
+// nolint:gocritic
+func (o ConciseTagList) IsActiveAt(t time.Time) bool {
+	return o.TlValidity.Contains(t)
+}
+
+// FilterComids returns the subset of the supplied CoMIDs whose tag identity is
+// listed in the target ConciseTagList, in the original order
+// nolint:gocritic
+func (o ConciseTagList) FilterComids(comids []*comid.Comid) []*comid.Comid {
+	var ret []*comid.Comid
+
+	for _, c := range comids {
+		if c != nil && o.Lists(c.TagIdentity) {
+			ret = append(ret, c)
+		}
+	}
+
+	return ret
+}
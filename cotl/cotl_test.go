@@ -0,0 +1,156 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cotl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/swid"
+)
+
+func tagIdentity(t *testing.T, id interface{}, version uint) comid.TagIdentity {
+	tagID := swid.NewTagID(id)
+	require.NotNil(t, tagID)
+	return comid.TagIdentity{TagID: *tagID, TagVersion: version}
+}
+
+func TestConciseTagList_JSON_CBOR_roundtrip(t *testing.T) {
+	var tl ConciseTagList
+	require.NoError(t, tl.FromJSON([]byte(ConciseTagListTemplate)))
+	require.NoError(t, tl.Valid())
+
+	assert.Equal(t, "2b6c5ff6-6c8a-4d4d-8d8a-0a9d5e0c8f11", tl.TagIdentity.TagID.String())
+	assert.Equal(t, uint(1), tl.TagIdentity.TagVersion)
+	require.Len(t, tl.TagsList, 2)
+
+	data, err := tl.ToCBOR()
+	require.NoError(t, err)
+
+	var out ConciseTagList
+	require.NoError(t, out.FromCBOR(data))
+	assert.Equal(t, tl.TagsList, out.TagsList)
+	assert.True(t, tl.TlValidity.NotAfter.Equal(out.TlValidity.NotAfter))
+
+	j, err := out.ToJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, ConciseTagListTemplate, string(j))
+}
+
+func TestConciseTagList_builder(t *testing.T) {
+	version := uint(3)
+	notBefore := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tl := NewConciseTagList().
+		SetTagIdentity("my-tag-list", &version).
+		AddTagIdentity("comid-a", nil).
+		AddTagIdentity("comid-b", &version).
+		SetValidity(notBefore.AddDate(1, 0, 0), &notBefore)
+	require.NotNil(t, tl)
+	require.NoError(t, tl.Valid())
+
+	assert.Equal(t, uint(3), tl.GetTagIdentity().TagVersion)
+	assert.True(t, tl.Lists(tagIdentity(t, "comid-b", 3)))
+	assert.False(t, tl.Lists(tagIdentity(t, "comid-b", 0)))
+	assert.True(t, tl.IsActiveAt(notBefore.AddDate(0, 6, 0)))
+	assert.False(t, tl.IsActiveAt(notBefore.AddDate(0, 0, -1)))
+	assert.False(t, tl.IsActiveAt(notBefore.AddDate(2, 0, 0)))
+
+	assert.Nil(t, NewConciseTagList().SetTagIdentity(1.5, nil))
+	assert.Nil(t, NewConciseTagList().AddTagIdentity(1.5, nil))
+	assert.Nil(t, NewConciseTagList().SetValidity(notBefore, &[]time.Time{notBefore.AddDate(1, 0, 0)}[0]))
+}
+
+func TestConciseTagList_Valid(t *testing.T) {
+	tl := ConciseTagList{}
+	assert.EqualError(t, tl.Valid(), "invalid tag-identity: empty tag-id")
+
+	tl.TagIdentity = tagIdentity(t, "my-tag-list", 0)
+	assert.EqualError(t, tl.Valid(), "empty tags-list")
+
+	tl.TagsList = []comid.TagIdentity{tagIdentity(t, "comid-a", 0), {}}
+	assert.EqualError(t, tl.Valid(), "invalid tags-list entry at index 1: empty tag-id")
+
+	tl.TagsList = tl.TagsList[:1]
+	assert.EqualError(t, tl.Valid(), "invalid tl-validity: missing not-after")
+
+	notAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	notBefore := notAfter.AddDate(0, 0, 1)
+	tl.TlValidity = comid.Validity{NotAfter: notAfter, NotBefore: &notBefore}
+	assert.ErrorContains(t, tl.Valid(), "invalid tl-validity: invalid not-before / not-after: negative delta")
+
+	_, err := ConciseTagList{}.ToCBOR()
+	assert.Error(t, err)
+
+	_, err = ConciseTagList{}.ToJSON()
+	assert.Error(t, err)
+}
+
+func TestConciseTagList_FilterComids(t *testing.T) {
+	var tl ConciseTagList
+	require.NoError(t, tl.FromJSON([]byte(ConciseTagListTemplate)))
+
+	listed := comid.NewComid()
+	require.NoError(t, listed.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	notListed := comid.NewComid().SetTagIdentity("some-other-comid", 0)
+	require.NotNil(t, notListed)
+
+	wrongVersion := comid.NewComid().SetTagIdentity("43bbe37f-2e61-4b33-aed3-53cff1428b16", 1)
+	require.NotNil(t, wrongVersion)
+
+	actual := tl.FilterComids([]*comid.Comid{notListed, listed, nil, wrongVersion})
+	require.Len(t, actual, 1)
+	assert.Same(t, listed, actual[0])
+
+	assert.Empty(t, tl.FilterComids(nil))
+}
+
+func TestConciseTagList_decode_options(t *testing.T) {
+	unknownJSON := strings.Replace(ConciseTagListTemplate,
+		`"tags-list"`, `"comment": "unknown", "tags-list"`, 1)
+
+	var tl ConciseTagList
+	require.NoError(t, tl.FromJSON([]byte(unknownJSON)))
+
+	err := tl.FromJSON([]byte(unknownJSON), encoding.DecodeOptions{Strict: true})
+	fields := encoding.UnknownFieldErrors(err)
+	require.Len(t, fields, 1)
+	assert.Equal(t, []any{"comment"}, fields[0].Keys)
+
+	// unknown entries are re-emitted when preserved
+	require.NoError(t, tl.FromJSON([]byte(unknownJSON),
+		encoding.DecodeOptions{PreserveUnknownFields: true}))
+
+	data, err := tl.ToJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, unknownJSON, string(data))
+
+	// per-call limits
+	limits := encoding.DecodeLimits{MaxElements: 2}
+
+	err = tl.FromJSON([]byte(ConciseTagListTemplate), encoding.DecodeOptions{Limits: &limits})
+	var le *encoding.LimitError
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxElements", le.Limit)
+
+	data, err = tl.ToCBOR()
+	require.NoError(t, err)
+
+	err = tl.FromCBOR(data, encoding.DecodeOptions{Limits: &limits})
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxElements", le.Limit)
+
+	require.NoError(t, tl.FromCBOR(data, encoding.DecodeOptions{
+		Strict:        true,
+		Deterministic: true,
+	}))
+	assert.Nil(t, tl.GetUnknownFields())
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cotl
+
+var (
+	ConciseTagListTemplate = `{
+		"tag-identity": {
+			"id": "2b6c5ff6-6c8a-4d4d-8d8a-0a9d5e0c8f11",
+			"version": 1
+		},
+		"tags-list": [
+			{
+				"id": "43bbe37f-2e61-4b33-aed3-53cff1428b16"
+			},
+			{
+				"id": "com.acme.rrd2013-ce-sp1-v4-1-5-0",
+				"version": 2
+			}
+		],
+		"tl-validity": {
+			"not-before": "2024-01-01T00:00:00Z",
+			"not-after": "2030-01-01T00:00:00Z"
+		}
+	}`
+)
@@ -0,0 +1,88 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cots
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ConciseTaStoreBuilder provides the same fluent interface as the
+// ConciseTaStore setters, but instead of returning nil when a step fails, it
+// records the reason and carries on.  The accumulated errors are returned by
+// Err and Build.
+type ConciseTaStoreBuilder struct {
+	cots *ConciseTaStore
+	errs []error
+}
+
+// NewConciseTaStoreBuilder instantiates a builder for an empty ConciseTaStore
+func NewConciseTaStoreBuilder() *ConciseTaStoreBuilder {
+	return &ConciseTaStoreBuilder{cots: NewConciseTaStore()}
+}
+
+func (o *ConciseTaStoreBuilder) check(step string, err error) *ConciseTaStoreBuilder {
+	if err != nil {
+		o.errs = append(o.errs, fmt.Errorf("%s: %w", step, err))
+	}
+	return o
+}
+
+// SetTagIdentity is the builder equivalent of ConciseTaStore.SetTagIdentity
+func (o *ConciseTaStoreBuilder) SetTagIdentity(tagID interface{}, tagIDVersion *uint) *ConciseTaStoreBuilder {
+	return o.check("SetTagIdentity", o.cots.setTagIdentity(tagID, tagIDVersion))
+}
+
+// SetLanguage is the builder equivalent of ConciseTaStore.SetLanguage
+func (o *ConciseTaStoreBuilder) SetLanguage(language string) *ConciseTaStoreBuilder {
+	o.cots.SetLanguage(language)
+	return o
+}
+
+// AddEnvironmentGroup is the builder equivalent of
+// ConciseTaStore.AddEnvironmentGroup
+func (o *ConciseTaStoreBuilder) AddEnvironmentGroup(eg EnvironmentGroup) *ConciseTaStoreBuilder {
+	return o.check("AddEnvironmentGroup", o.cots.addEnvironmentGroup(eg))
+}
+
+// AddPurpose is the builder equivalent of ConciseTaStore.AddPurpose
+func (o *ConciseTaStoreBuilder) AddPurpose(purpose string) *ConciseTaStoreBuilder {
+	o.cots.AddPurpose(purpose)
+	return o
+}
+
+// AddPermClaims is the builder equivalent of ConciseTaStore.AddPermClaims
+func (o *ConciseTaStoreBuilder) AddPermClaims(permclaim *EatCWTClaim) *ConciseTaStoreBuilder {
+	return o.check("AddPermClaims", o.cots.addPermClaims(permclaim))
+}
+
+// AddExclClaims is the builder equivalent of ConciseTaStore.AddExclClaims
+func (o *ConciseTaStoreBuilder) AddExclClaims(exclclaim *EatCWTClaim) *ConciseTaStoreBuilder {
+	return o.check("AddExclClaims", o.cots.addExclClaims(exclclaim))
+}
+
+// SetKeys is the builder equivalent of ConciseTaStore.SetKeys
+func (o *ConciseTaStoreBuilder) SetKeys(keys TasAndCas) *ConciseTaStoreBuilder {
+	o.cots.SetKeys(keys)
+	return o
+}
+
+// Err returns the errors recorded so far, or nil if there are none
+func (o *ConciseTaStoreBuilder) Err() error {
+	return errors.Join(o.errs...)
+}
+
+// Build returns the assembled ConciseTaStore after checking its validity.  If
+// any of the preceding steps failed, the recorded errors are returned instead.
+func (o *ConciseTaStoreBuilder) Build() (*ConciseTaStore, error) {
+	if err := o.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := o.cots.Valid(); err != nil {
+		return nil, err
+	}
+
+	return o.cots, nil
+}
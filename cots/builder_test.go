@@ -0,0 +1,81 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cots
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/extensions"
+)
+
+func TestConciseTaStoreBuilder_ok(t *testing.T) {
+	version := uint(1)
+
+	c, err := NewConciseTaStoreBuilder().
+		SetTagIdentity(comid.TestUUIDString, &version).
+		SetLanguage("en-GB").
+		AddEnvironmentGroup(EnvironmentGroup{
+			Environment: &comid.Environment{
+				Instance: comid.MustNewUUIDInstance(comid.TestUUID),
+			},
+		}).
+		AddPurpose("eat").
+		SetKeys(*NewTasAndCas().AddTaCert(ta)).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, comid.TestUUIDString, c.TagIdentity.TagID.String())
+	assert.Equal(t, uint(1), c.TagIdentity.TagVersion)
+	assert.Equal(t, []string{"eat"}, c.Purposes)
+}
+
+func TestConciseTaStoreBuilder_errors(t *testing.T) {
+	b := NewConciseTaStoreBuilder().
+		SetTagIdentity([]byte{0x01}, nil).
+		AddPermClaims(nil).
+		AddExclClaims(nil).
+		SetKeys(*NewTasAndCas().AddTaCert(ta))
+
+	assert.EqualError(t, b.Err(), `SetTagIdentity: invalid UUID tag-id: invalid UUID (got 1 bytes)
+AddPermClaims: nil claim
+AddExclClaims: nil claim`)
+
+	c, err := b.Build()
+	assert.Nil(t, c)
+	assert.Equal(t, b.Err(), err)
+}
+
+func TestConciseTaStoreBuilder_invalid(t *testing.T) {
+	b := NewConciseTaStoreBuilder().AddPurpose("eat")
+	require.NoError(t, b.Err())
+
+	_, err := b.Build()
+	assert.EqualError(t, err, "environmentGroups must be present")
+}
+
+func TestConciseTaStoreBuilder_environment_group_errors(t *testing.T) {
+	b := NewConciseTaStoreBuilder()
+
+	// extensions that cannot be registered with an EnvironmentGroup
+	b.cots.envGroupExts = extensions.Map{ExtCots: &struct{}{}}
+
+	b.AddEnvironmentGroup(EnvironmentGroup{
+		Environment: &comid.Environment{
+			Instance: comid.MustNewUUIDInstance(comid.TestUUID),
+		},
+	})
+
+	assert.EqualError(t, b.Err(), `AddEnvironmentGroup: unexpected extension point: "Cots"`)
+	assert.Empty(t, b.cots.Environments)
+}
+
+func TestConciseTaStore_AddClaims_nil(t *testing.T) {
+	c := NewConciseTaStore()
+
+	assert.Nil(t, c.AddPermClaims(nil))
+	assert.Nil(t, c.AddExclClaims(nil))
+}
@@ -8,6 +8,7 @@ import (
 
 	cbor "github.com/fxamacker/cbor/v2"
 	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/encoding"
 )
 
 var (
@@ -53,7 +54,7 @@ func initCBORDecMode() (dm cbor.DecMode, err error) {
 		IndefLength: cbor.IndefLengthForbidden,
 		TimeTag:     cbor.DecTagRequired,
 	}
-	return decOpt.DecModeWithTags(cotsTags())
+	return encoding.NewDecMode(decOpt, cotsTags())
 }
 
 func init() {
@@ -9,38 +9,144 @@ import (
 	"fmt"
 
 	"github.com/veraison/corim/comid"
-	"github.com/veraison/swid"
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
 )
 
 type ConciseTaStore struct {
-	Language     *string            `cbor:"0,keyasint,omitempty" json:"language,omitempty"`
-	TagIdentity  *comid.TagIdentity `cbor:"1,keyasint,omitempty" json:"tag-identity,omitempty"`
-	Environments EnvironmentGroups  `cbor:"2,keyasint" json:"environments"`
-	Purposes     []string           `cbor:"3,keyasint,omitempty" json:"purposes,omitempty"`
-	PermClaims   EatCWTClaims       `cbor:"4,keyasint,omitempty" json:"permclaims,omitempty"`
-	ExclClaims   EatCWTClaims       `cbor:"5,keyasint,omitempty" json:"exclclaims,omitempty"`
-	Keys         *TasAndCas         `cbor:"6,keyasint" json:"keys"`
+	Language     *string            `cbor:"0,keyasint,omitempty" json:"language,omitempty" cddl:"tastore.language"`
+	TagIdentity  *comid.TagIdentity `cbor:"1,keyasint,omitempty" json:"tag-identity,omitempty" cddl:"tastore.store-identity"`
+	Environments EnvironmentGroups  `cbor:"2,keyasint" json:"environments" cddl:"tastore.environments"`
+	Purposes     []string           `cbor:"3,keyasint,omitempty" json:"purposes,omitempty" cddl:"tastore.purposes"`
+	PermClaims   EatCWTClaims       `cbor:"4,keyasint,omitempty" json:"permclaims,omitempty" cddl:"tastore.perm_claims"`
+	ExclClaims   EatCWTClaims       `cbor:"5,keyasint,omitempty" json:"exclclaims,omitempty" cddl:"tastore.excl_claims"`
+	Keys         *TasAndCas         `cbor:"6,keyasint" json:"keys" cddl:"tastore.keys"`
+
+	Extensions
+
+	types *comid.TypeRegistry
+
+	// envGroupExts, permClaimExts and exclClaimExts hold the extensions
+	// registered for the Environments, PermClaims and ExclClaims entries
+	// respectively
+	envGroupExts  extensions.Map
+	permClaimExts extensions.Map
+	exclClaimExts extensions.Map
 }
 
 func NewConciseTaStore() *ConciseTaStore {
 	return &ConciseTaStore{}
 }
 
+// SetTypeRegistry sets the comid.TypeRegistry whose type choices are used, in
+// addition to the base ones, when serializing and deserializing the target
+// ConciseTaStore
+func (o *ConciseTaStore) SetTypeRegistry(r *comid.TypeRegistry) *ConciseTaStore {
+	if o != nil {
+		o.types = r
+	}
+	return o
+}
+
+// GetTypeRegistry returns the comid.TypeRegistry set on the target
+// ConciseTaStore, if any
+// nolint:gocritic
+func (o ConciseTaStore) GetTypeRegistry() *comid.TypeRegistry {
+	return o.types
+}
+
+// RegisterExtensions registers a struct as a collections of extensions
+func (o *ConciseTaStore) RegisterExtensions(exts extensions.Map) error {
+	for p, v := range exts {
+		switch p {
+		case ExtCots:
+			o.Extensions.Register(v)
+		case ExtEnvironmentGroup:
+			egMap := extensions.NewMap().Add(ExtEnvironmentGroup, v)
+			if err := o.registerEnvironmentGroupExtensions(egMap); err != nil {
+				return err
+			}
+		case ExtPermClaims:
+			claimMap := extensions.NewMap().Add(ExtEatCWTClaim, v)
+			if err := registerClaimExtensions(o.PermClaims, claimMap); err != nil {
+				return fmt.Errorf("permclaims: %w", err)
+			}
+			o.permClaimExts = claimMap
+		case ExtExclClaims:
+			claimMap := extensions.NewMap().Add(ExtEatCWTClaim, v)
+			if err := registerClaimExtensions(o.ExclClaims, claimMap); err != nil {
+				return fmt.Errorf("exclclaims: %w", err)
+			}
+			o.exclClaimExts = claimMap
+		default:
+			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
+		}
+	}
+
+	return nil
+}
+
+func (o *ConciseTaStore) registerEnvironmentGroupExtensions(exts extensions.Map) error {
+	// validate the extensions by registering them with a new
+	// EnvironmentGroup
+	if err := new(EnvironmentGroup).RegisterExtensions(exts); err != nil {
+		return err
+	}
+
+	o.envGroupExts = exts
+
+	for i := range o.Environments {
+		if err := extensions.RegisterIfUnset(&o.Environments[i], exts); err != nil {
+			return fmt.Errorf("environment group at index %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// registerClaimExtensions validates exts and registers them with those of the
+// supplied claims that do not have extensions of their own
+func registerClaimExtensions(claims EatCWTClaims, exts extensions.Map) error {
+	if err := new(EatCWTClaim).RegisterExtensions(exts); err != nil {
+		return err
+	}
+
+	for i := range claims {
+		if err := extensions.RegisterIfUnset(&claims[i], exts); err != nil {
+			return fmt.Errorf("claim at index %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// GetExtensions returns previously registered extension
+func (o *ConciseTaStore) GetExtensions() extensions.IMapValue {
+	return o.Extensions.IMapValue
+}
+
 func (o *ConciseTaStore) SetTagIdentity(tagID interface{}, tagIDVersion *uint) *ConciseTaStore {
 	if o != nil {
-		id := swid.NewTagID(tagID)
-		if id == nil {
+		if o.setTagIdentity(tagID, tagIDVersion) != nil {
 			return nil
 		}
-		o.TagIdentity = &comid.TagIdentity{}
-		o.TagIdentity.TagID = *id
-		if tagIDVersion != nil {
-			o.TagIdentity.TagVersion = *tagIDVersion
-		}
 	}
 	return o
 }
 
+func (o *ConciseTaStore) setTagIdentity(tagID interface{}, tagIDVersion *uint) error {
+	id, err := comid.NewTagID(tagID)
+	if err != nil {
+		return err
+	}
+	o.TagIdentity = &comid.TagIdentity{}
+	o.TagIdentity.TagID = *id
+	if tagIDVersion != nil {
+		o.TagIdentity.TagVersion = *tagIDVersion
+	}
+	return nil
+}
+
 func (o *ConciseTaStore) SetLanguage(language string) *ConciseTaStore {
 	if o != nil {
 		o.Language = &language
@@ -50,11 +156,23 @@ func (o *ConciseTaStore) SetLanguage(language string) *ConciseTaStore {
 
 func (o *ConciseTaStore) AddEnvironmentGroup(eg EnvironmentGroup) *ConciseTaStore {
 	if o != nil {
-		o.Environments = append(o.Environments, eg)
+		if o.addEnvironmentGroup(eg) != nil {
+			return nil
+		}
 	}
 	return o
 }
 
+func (o *ConciseTaStore) addEnvironmentGroup(eg EnvironmentGroup) error {
+	if err := extensions.RegisterIfUnset(&eg, o.envGroupExts); err != nil {
+		return err
+	}
+
+	o.Environments = append(o.Environments, eg)
+
+	return nil
+}
+
 func (o *ConciseTaStore) AddPurpose(purpose string) *ConciseTaStore {
 	if o != nil {
 		o.Purposes = append(o.Purposes, purpose)
@@ -64,18 +182,52 @@ func (o *ConciseTaStore) AddPurpose(purpose string) *ConciseTaStore {
 
 func (o *ConciseTaStore) AddPermClaims(permclaim *EatCWTClaim) *ConciseTaStore {
 	if o != nil {
-		o.PermClaims = append(o.PermClaims, *permclaim)
+		if o.addPermClaims(permclaim) != nil {
+			return nil
+		}
 	}
 	return o
 }
 
+func (o *ConciseTaStore) addPermClaims(permclaim *EatCWTClaim) error {
+	if permclaim == nil {
+		return errors.New("nil claim")
+	}
+
+	claim := *permclaim
+	if err := extensions.RegisterIfUnset(&claim, o.permClaimExts); err != nil {
+		return err
+	}
+
+	o.PermClaims = append(o.PermClaims, claim)
+
+	return nil
+}
+
 func (o *ConciseTaStore) AddExclClaims(exclclaim *EatCWTClaim) *ConciseTaStore {
 	if o != nil {
-		o.ExclClaims = append(o.ExclClaims, *exclclaim)
+		if o.addExclClaims(exclclaim) != nil {
+			return nil
+		}
 	}
 	return o
 }
 
+func (o *ConciseTaStore) addExclClaims(exclclaim *EatCWTClaim) error {
+	if exclclaim == nil {
+		return errors.New("nil claim")
+	}
+
+	claim := *exclclaim
+	if err := extensions.RegisterIfUnset(&claim, o.exclClaimExts); err != nil {
+		return err
+	}
+
+	o.ExclClaims = append(o.ExclClaims, claim)
+
+	return nil
+}
+
 func (o *ConciseTaStore) SetKeys(keys TasAndCas) *ConciseTaStore {
 	if o != nil {
 		o.Keys = &keys
@@ -90,12 +242,79 @@ func (o ConciseTaStore) ToCBOR() ([]byte, error) {
 		return nil, err
 	}
 
-	return em.Marshal(o)
+	return o.types.ScopeEncoder(encoding.NewEncoder(em)).Marshal(o)
+}
+
+// ToEDN serializes the target ConciseTaStore to CBOR, and renders it in
+// Extended Diagnostic Notation, with map keys annotated with the names of the
+// corresponding fields in the CoTS specification.
+// nolint:gocritic
+func (o ConciseTaStore) ToEDN() (string, error) {
+	data, err := o.ToCBOR()
+	if err != nil {
+		return "", err
+	}
+
+	return encoding.FormatEDN(data, o)
 }
 
 // FromCBOR deserializes a CBOR-encoded CoTS into the target ConciseTaStore.
-func (o *ConciseTaStore) FromCBOR(data []byte) error {
-	return dm.Unmarshal(data, o)
+// Optional DecodeOptions may be specified, e.g. to decode in strict mode.
+func (o *ConciseTaStore) FromCBOR(data []byte, opts ...encoding.DecodeOptions) error {
+	dec := o.types.ScopeDecoder(encoding.NewDecoder(dm)).WithOptions(opts...)
+
+	if err := encoding.CheckCBORInput(dec, data, o); err != nil {
+		return err
+	}
+
+	return dec.Unmarshal(data, o)
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *ConciseTaStore) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *ConciseTaStore) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromCBOR(o.scopeDecoder(dec).WithDecMode(dm), data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o ConciseTaStore) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o ConciseTaStore) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *ConciseTaStore) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *ConciseTaStore) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return encoding.PopulateStructFromJSONWith(o.scopeDecoder(dec), data, o)
+}
+
+// scopeDecoder returns a copy of dec with the extensions of the
+// Environments, PermClaims and ExclClaims entries in scope
+func (o *ConciseTaStore) scopeDecoder(dec *encoding.Decoder) *encoding.Decoder {
+	dec = extensions.WithElementExtensions(dec, ExtEnvironmentGroup, o.envGroupExts)
+	dec = withClaimsExtensions(dec, &o.PermClaims, o.permClaimExts)
+
+	return withClaimsExtensions(dec, &o.ExclClaims, o.exclClaimExts)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o ConciseTaStore) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
 }
 
 // Valid iterates over the range of individual entities to check for validity.
@@ -120,12 +339,31 @@ func (o ConciseTaStore) Valid() error {
 		return fmt.Errorf("empty Keys")
 	}
 
-	return nil
+	if len(o.PermClaims) != 0 {
+		if err := o.PermClaims.Valid(); err != nil {
+			return fmt.Errorf("invalid permclaims: %w", err)
+		}
+	}
+
+	if len(o.ExclClaims) != 0 {
+		if err := o.ExclClaims.Valid(); err != nil {
+			return fmt.Errorf("invalid exclclaims: %w", err)
+		}
+	}
+
+	return o.Extensions.validCots(&o)
 }
 
 // FromJSON deserializes a JSON-encoded CoTS into the target ConciseTaStore.
-func (o *ConciseTaStore) FromJSON(data []byte) error {
-	return json.Unmarshal(data, o)
+// Optional DecodeOptions may be specified, e.g. to decode in strict mode.
+func (o *ConciseTaStore) FromJSON(data []byte, opts ...encoding.DecodeOptions) error {
+	dec := o.types.ScopeDecoder(encoding.NewDecoder(dm)).WithOptions(opts...)
+
+	if err := encoding.CheckJSONLimits(data, dec.Limits()); err != nil {
+		return err
+	}
+
+	return dec.DecodeJSON(data, o)
 }
 
 // FromJSON deserializes a JSON-encoded CoTS into the target ConsiseTaStore.
@@ -4,10 +4,13 @@
 package cots
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/encoding"
 )
 
 func TestConciseTaStore_Valid_no_environment_groups(t *testing.T) {
@@ -53,3 +56,45 @@ func TestConciseTaStores_Valid_bad_cots(t *testing.T) {
 	cotsList := ConciseTaStores{*NewConciseTaStore().AddPurpose("cots")}
 	assert.EqualError(t, cotsList.Valid(), "bad ConciseTaStore group at index 0: environmentGroups must be present")
 }
+
+func TestConciseTaStore_decode_limits(t *testing.T) {
+	require.NoError(t, NewConciseTaStore().FromCBOR(cotsCBOR))
+
+	limits := encoding.DecodeLimits{MaxInputSize: len(cotsCBOR) - 1}
+
+	err := NewConciseTaStore().FromCBOR(cotsCBOR, encoding.DecodeOptions{Limits: &limits})
+	assert.ErrorIs(t, err, encoding.ErrLimitExceeded)
+
+	var le *encoding.LimitError
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxInputSize", le.Limit)
+
+	store := NewConciseTaStore()
+	require.NoError(t, store.FromCBOR(cotsCBOR))
+
+	data, err := store.ToJSON()
+	require.NoError(t, err)
+
+	limits = encoding.DecodeLimits{MaxNestingDepth: 1}
+
+	err = NewConciseTaStore().FromJSON(data, encoding.DecodeOptions{Limits: &limits})
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, "MaxNestingDepth", le.Limit)
+}
+
+func TestConciseTaStore_ToEDN(t *testing.T) {
+	store := NewConciseTaStore()
+	require.NoError(t, store.FromCBOR(cotsCBOR))
+
+	edn, err := store.ToEDN()
+	require.NoError(t, err)
+
+	assert.Contains(t, edn, "/ tastore.keys / 6 : {")
+
+	data, err := store.ToCBOR()
+	require.NoError(t, err)
+
+	out, err := encoding.ParseEDN(edn)
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
 	"github.com/veraison/eat"
 )
 
@@ -18,7 +20,7 @@ type EatCWTClaim struct {
 	SecureBoot    *bool              `cbor:"15,keyasint,omitempty" json:"secure-boot,omitempty"`
 	Debug         *eat.Debug         `cbor:"16,keyasint,omitempty" json:"debug-disable,omitempty"`
 	Location      *eat.Location      `cbor:"17,keyasint,omitempty" json:"location,omitempty"`
-	Profile       *eat.Profile       `cbor:"18,keyasint,omitempty" json:"eat-profile,omitempty"`
+	Profile       *eat.Profile       `cbor:"18,keyasint,omitempty" json:"eat-profile,omitempty" cddl:"eat_profile"`
 	Uptime        *uint              `cbor:"19,keyasint,omitempty" json:"uptime,omitempty"`
 	Submods       *eat.Submods       `cbor:"20,keyasint,omitempty" json:"submods,omitempty"`
 
@@ -26,11 +28,85 @@ type EatCWTClaim struct {
 
 	// Partial list of claims defined by draft-ietf-rats-eat-12
 	HardwareModelLabel    *[]byte              `cbor:"259,keyasint,omitempty" json:"hwmodel,omitempty"`
-	HardwareVersionScheme *HardwareVersionType `cbor:"260,keyasint,omitempty" json:"hwvers,omitempty"`
+	HardwareVersionScheme *HardwareVersionType `cbor:"260,keyasint,omitempty" json:"hwvers,omitempty" cddl:"hwversion"`
 
 	// numbers for the next two have not yet been assigned
 	SoftwareNameLabel     *string              `cbor:"998,keyasint,omitempty" json:"swname,omitempty"`
 	SoftwareVersionScheme *HardwareVersionType `cbor:"999,keyasint,omitempty" json:"swversion,omitempty"`
+
+	Extensions
+}
+
+// RegisterExtensions registers a struct as a collections of extensions
+func (o *EatCWTClaim) RegisterExtensions(exts extensions.Map) error {
+	for p, v := range exts {
+		switch p {
+		case ExtEatCWTClaim:
+			o.Extensions.Register(v)
+		default:
+			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
+		}
+	}
+
+	return nil
+}
+
+// GetExtensions returns previously registered extension
+func (o *EatCWTClaim) GetExtensions() extensions.IMapValue {
+	return o.Extensions.IMapValue
+}
+
+// Valid checks the target EatCWTClaim against the constraints of its
+// extensions (if any). The base claims are not constrained.
+// nolint:gocritic
+func (o EatCWTClaim) Valid() error {
+	return o.Extensions.validEatCWTClaim(&o)
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *EatCWTClaim) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *EatCWTClaim) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtEatCWTClaim, o); err != nil {
+		return err
+	}
+
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o EatCWTClaim) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+// nolint:gocritic
+func (o EatCWTClaim) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *EatCWTClaim) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *EatCWTClaim) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtEatCWTClaim, o); err != nil {
+		return err
+	}
+
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o EatCWTClaim) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
 }
 
 // ToCBOR serializes the target EatCWTClaim to CBOR.
@@ -57,11 +133,55 @@ func (o *EatCWTClaim) FromJSON(data []byte) error {
 
 type EatCWTClaims []EatCWTClaim
 
+// claimsExtensionsKey is the key of the extensions in scope of an
+// encoding.Decoder for the claims of the EatCWTClaims at the given address.
+// The permitted and excluded claims of a ConciseTaStore share the same type,
+// but may have different extensions, and so cannot be told apart by extension
+// point alone.
+type claimsExtensionsKey struct {
+	claims *EatCWTClaims
+}
+
+// withClaimsExtensions returns a copy of dec with the supplied extensions in
+// scope for the claims decoded into the EatCWTClaims at claims. If exts is
+// empty, dec is returned as is.
+func withClaimsExtensions(dec *encoding.Decoder, claims *EatCWTClaims, exts extensions.Map) *encoding.Decoder {
+	if len(exts) == 0 {
+		return dec
+	}
+
+	return dec.WithValue(claimsExtensionsKey{claims}, exts)
+}
+
+// decoderFor returns dec with the extensions in scope for the target
+// EatCWTClaims (if any) made available to the individual claims
+func (o *EatCWTClaims) decoderFor(dec *encoding.Decoder) *encoding.Decoder {
+	exts, _ := dec.Value(claimsExtensionsKey{o}).(extensions.Map)
+
+	return extensions.WithElementExtensions(dec, ExtEatCWTClaim, exts)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *EatCWTClaims) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	return o.decoderFor(dec).Unmarshal(data, (*[]EatCWTClaim)(o))
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *EatCWTClaims) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	return o.decoderFor(dec).DecodeJSON(data, (*[]EatCWTClaim)(o))
+}
+
 func (o EatCWTClaims) Valid() error {
 	if len(o) == 0 {
 		return fmt.Errorf("empty EatCWTClaims")
 	}
 
+	for i, c := range o {
+		if err := c.Valid(); err != nil {
+			return fmt.Errorf("bad claim at index %d: %w", i, err)
+		}
+	}
+
 	return nil
 }
 
@@ -8,14 +8,18 @@ import (
 	"fmt"
 
 	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
 )
 
 // EnvironmentGroup is the top-level representation of the unsigned-corim-map with
 // CBOR and JSON serialization.
 type EnvironmentGroup struct {
-	Environment  *comid.Environment  `cbor:"1,keyasint,omitempty" json:"environment,omitempty"`
-	SwidTag      *AbbreviatedSwidTag `cbor:"2,keyasint,omitempty" json:"swidtag,omitempty"`
-	NamedTaStore *string             `cbor:"3,keyasint,omitempty" json:"namedtastore,omitempty"`
+	Environment  *comid.Environment  `cbor:"1,keyasint,omitempty" json:"environment,omitempty" cddl:"environment"`
+	SwidTag      *AbbreviatedSwidTag `cbor:"2,keyasint,omitempty" json:"swidtag,omitempty" cddl:"abbreviated_swid_tag"`
+	NamedTaStore *string             `cbor:"3,keyasint,omitempty" json:"namedtastore,omitempty" cddl:"named_ta_store"`
+
+	Extensions
 }
 
 // NewEnvironmentGroup instantiates an empty EnvironmentGroup
@@ -23,6 +27,25 @@ func NewEnvironmentGroup() *EnvironmentGroup {
 	return &EnvironmentGroup{}
 }
 
+// RegisterExtensions registers a struct as a collections of extensions
+func (o *EnvironmentGroup) RegisterExtensions(exts extensions.Map) error {
+	for p, v := range exts {
+		switch p {
+		case ExtEnvironmentGroup:
+			o.Extensions.Register(v)
+		default:
+			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
+		}
+	}
+
+	return nil
+}
+
+// GetExtensions returns previously registered extension
+func (o *EnvironmentGroup) GetExtensions() extensions.IMapValue {
+	return o.Extensions.IMapValue
+}
+
 func (o *EnvironmentGroup) SetEnvironment(environment comid.Environment) *EnvironmentGroup {
 	if o != nil {
 		o.Environment = &environment
@@ -58,7 +81,50 @@ func (o EnvironmentGroup) Valid() error {
 		}
 	}
 
-	return nil
+	return o.Extensions.validEnvironmentGroup(&o)
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *EnvironmentGroup) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith deserializes from CBOR using the supplied Decoder
+func (o *EnvironmentGroup) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtEnvironmentGroup, o); err != nil {
+		return err
+	}
+
+	return encoding.PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+func (o EnvironmentGroup) MarshalCBOR() ([]byte, error) {
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith serializes to CBOR using the supplied Encoder
+func (o EnvironmentGroup) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return encoding.SerializeStructToCBOR(enc.WithEncMode(em), o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *EnvironmentGroup) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith deserializes from JSON using the supplied Decoder
+func (o *EnvironmentGroup) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
+	if err := extensions.RegisterElementExtensions(dec, ExtEnvironmentGroup, o); err != nil {
+		return err
+	}
+
+	return encoding.PopulateStructFromJSONWith(dec, data, o)
+}
+
+// MarshalJSON serializes to JSON
+func (o EnvironmentGroup) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
 }
 
 // ToCBOR serializes the target EnvironmentGroup to CBOR
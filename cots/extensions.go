@@ -0,0 +1,82 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package cots
+
+import (
+	"github.com/veraison/corim/extensions"
+)
+
+const (
+	ExtCots             extensions.Point = "Cots"
+	ExtEnvironmentGroup extensions.Point = "CotsEnvironmentGroup"
+	ExtPermClaims       extensions.Point = "CotsPermClaims"
+	ExtExclClaims       extensions.Point = "CotsExclClaims"
+	ExtEatCWTClaim      extensions.Point = "CotsEatCWTClaim"
+)
+
+type ICotsConstrainer interface {
+	ConstrainCots(*ConciseTaStore) error
+}
+
+type IEnvironmentGroupConstrainer interface {
+	ConstrainEnvironmentGroup(*EnvironmentGroup) error
+}
+
+type IEatCWTClaimConstrainer interface {
+	ConstrainEatCWTClaim(*EatCWTClaim) error
+}
+
+type Extensions struct {
+	extensions.Extensions
+}
+
+func (o *Extensions) validCots(cots *ConciseTaStore) error {
+	if !o.HaveExtensions() {
+		return nil
+	}
+
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(ICotsConstrainer)
+		if ok {
+			if err := ev.ConstrainCots(cots); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *Extensions) validEnvironmentGroup(eg *EnvironmentGroup) error {
+	if !o.HaveExtensions() {
+		return nil
+	}
+
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IEnvironmentGroupConstrainer)
+		if ok {
+			if err := ev.ConstrainEnvironmentGroup(eg); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *Extensions) validEatCWTClaim(claim *EatCWTClaim) error {
+	if !o.HaveExtensions() {
+		return nil
+	}
+
+	for _, v := range o.ExtensionValues() {
+		ev, ok := v.(IEatCWTClaimConstrainer)
+		if ok {
+			if err := ev.ConstrainEatCWTClaim(claim); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
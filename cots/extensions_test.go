@@ -0,0 +1,174 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package cots
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/extensions"
+)
+
+type TestExtension struct{}
+
+func (o *TestExtension) ConstrainCots(_ *ConciseTaStore) error {
+	return errors.New("invalid")
+}
+
+func (o *TestExtension) ConstrainEnvironmentGroup(_ *EnvironmentGroup) error {
+	return errors.New("invalid")
+}
+
+func (o *TestExtension) ConstrainEatCWTClaim(_ *EatCWTClaim) error {
+	return errors.New("invalid")
+}
+
+func Test_Extensions(t *testing.T) {
+	exts := Extensions{}
+	exts.Register(&TestExtension{})
+
+	err := exts.validCots(nil)
+	assert.EqualError(t, err, "invalid")
+
+	err = exts.validEnvironmentGroup(nil)
+	assert.EqualError(t, err, "invalid")
+
+	err = exts.validEatCWTClaim(nil)
+	assert.EqualError(t, err, "invalid")
+}
+
+type testCotsExtensions struct {
+	Expiry *int64 `cbor:"-1,keyasint,omitempty" json:"expiry,omitempty"`
+}
+
+func (o *testCotsExtensions) ConstrainCots(_ *ConciseTaStore) error {
+	if o.Expiry == nil {
+		return errors.New("missing expiry")
+	}
+	return nil
+}
+
+type testEnvironmentGroupExtensions struct {
+	Site *string `cbor:"-1,keyasint,omitempty" json:"site,omitempty"`
+}
+
+type testClaimExtensions struct {
+	KeyUsage *string `cbor:"-1,keyasint,omitempty" json:"key-usage,omitempty"`
+}
+
+func (o *testClaimExtensions) ConstrainEatCWTClaim(_ *EatCWTClaim) error {
+	if o.KeyUsage == nil {
+		return errors.New("missing key usage")
+	}
+	return nil
+}
+
+func newTestCotsExtensions() extensions.Map {
+	return extensions.NewMap().
+		Add(ExtCots, &testCotsExtensions{}).
+		Add(ExtEnvironmentGroup, &testEnvironmentGroupExtensions{}).
+		Add(ExtPermClaims, &testClaimExtensions{})
+}
+
+func TestConciseTaStore_extensions(t *testing.T) {
+	store := NewConciseTaStore()
+	require.NoError(t, store.RegisterExtensions(newTestCotsExtensions()))
+
+	store.AddEnvironmentGroup(
+		*NewEnvironmentGroup().SetEnvironment(comid.Environment{
+			Class: comid.NewClassOID("1.2.3.4.5"),
+		}),
+	).
+		AddPermClaims(&EatCWTClaim{}).
+		SetKeys(*NewTasAndCas().AddTaCert(ta))
+	require.NotNil(t, store)
+
+	assert.EqualError(t, store.Valid(),
+		"invalid permclaims: bad claim at index 0: missing key usage")
+	keyUsage := "firmware-signing"
+	require.NoError(t, store.PermClaims[0].Set("key-usage", &keyUsage))
+
+	assert.EqualError(t, store.Valid(), "missing expiry")
+	expiry := int64(1735689600)
+	require.NoError(t, store.Set("expiry", &expiry))
+
+	site := "lab 2"
+	require.NoError(t, store.Environments[0].Set("site", &site))
+
+	require.NoError(t, store.Valid())
+
+	data, err := store.ToCBOR()
+	require.NoError(t, err)
+
+	out := NewConciseTaStore()
+	require.NoError(t, out.RegisterExtensions(newTestCotsExtensions()))
+	require.NoError(t, out.FromCBOR(data))
+	require.NoError(t, out.Valid())
+
+	assert.Equal(t, expiry, out.MustGetInt64("expiry"))
+	assert.Equal(t, site, out.Environments[0].MustGetString("site"))
+	assert.Equal(t, keyUsage, out.PermClaims[0].MustGetString("key-usage"))
+	assert.Empty(t, out.ExclClaims)
+
+	jsonData, err := store.ToJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(jsonData), `"expiry":1735689600`)
+	assert.NotContains(t, string(jsonData), `exclclaims`)
+
+	out = NewConciseTaStore()
+	require.NoError(t, out.RegisterExtensions(newTestCotsExtensions()))
+	require.NoError(t, out.FromJSON(jsonData))
+	assert.Equal(t, keyUsage, out.PermClaims[0].MustGetString("key-usage"))
+
+	// without registered extensions, the extension fields are ignored
+	out = NewConciseTaStore()
+	require.NoError(t, out.FromCBOR(data))
+	assert.NoError(t, out.Valid())
+	assert.Nil(t, out.GetExtensions())
+}
+
+type testExclClaimExtensions struct {
+	Reason *string `cbor:"-2,keyasint,omitempty" json:"reason,omitempty"`
+}
+
+func TestConciseTaStore_extensions_perm_and_excl_claims(t *testing.T) {
+	exts := extensions.NewMap().
+		Add(ExtPermClaims, &testClaimExtensions{}).
+		Add(ExtExclClaims, &testExclClaimExtensions{})
+
+	keyUsage := "firmware-signing"
+	reason := "revoked"
+
+	store := NewConciseTaStore()
+	require.NoError(t, store.RegisterExtensions(exts))
+	store.AddEnvironmentGroup(EnvironmentGroup{}).
+		AddPermClaims(&EatCWTClaim{}).
+		AddExclClaims(&EatCWTClaim{}).
+		SetKeys(*NewTasAndCas().AddTaCert(ta))
+	require.NoError(t, store.PermClaims[0].Set("key-usage", &keyUsage))
+	require.NoError(t, store.ExclClaims[0].Set("reason", &reason))
+
+	data, err := store.ToCBOR()
+	require.NoError(t, err)
+
+	out := NewConciseTaStore()
+	require.NoError(t, out.RegisterExtensions(exts))
+	require.NoError(t, out.FromCBOR(data))
+
+	require.Len(t, out.PermClaims, 1)
+	require.Len(t, out.ExclClaims, 1)
+	assert.Equal(t, keyUsage, out.PermClaims[0].MustGetString("key-usage"))
+	assert.Equal(t, reason, out.ExclClaims[0].MustGetString("reason"))
+	assert.IsType(t, &testClaimExtensions{}, out.PermClaims[0].GetExtensions())
+	assert.IsType(t, &testExclClaimExtensions{}, out.ExclClaims[0].GetExtensions())
+}
+
+func TestConciseTaStore_RegisterExtensions_bad_point(t *testing.T) {
+	store := NewConciseTaStore()
+	err := store.RegisterExtensions(
+		extensions.NewMap().Add(ExtEatCWTClaim, &testClaimExtensions{}))
+	assert.ErrorIs(t, err, extensions.ErrUnexpectedPoint)
+}
@@ -10,7 +10,6 @@ import (
 	"math"
 	"reflect"
 	"strconv"
-	"strings"
 
 	cbor "github.com/fxamacker/cbor/v2"
 )
@@ -21,7 +20,11 @@ func SerializeStructToCBOR(em cbor.EncMode, source any) ([]byte, error) {
 	structType := reflect.TypeOf(source)
 	structVal := reflect.ValueOf(source)
 
-	if err := doSerializeStructToCBOR(em, rawMap, structType, structVal); err != nil {
+	if err := doSerializeStructToCBOR(AsEncoder(em), rawMap, structType, structVal); err != nil {
+		return nil, err
+	}
+
+	if err := emitUnknownCBOR(rawMap, source); err != nil {
 		return nil, err
 	}
 
@@ -29,7 +32,7 @@ func SerializeStructToCBOR(em cbor.EncMode, source any) ([]byte, error) {
 }
 
 func doSerializeStructToCBOR(
-	em cbor.EncMode,
+	enc *Encoder,
 	rawMap *structFieldsCBOR,
 	structType reflect.Type,
 	structVal reflect.Value,
@@ -39,65 +42,38 @@ func doSerializeStructToCBOR(
 		structVal = structVal.Elem()
 	}
 
-	var embeds []embedded
-
-	for i := 0; i < structVal.NumField(); i++ {
-		typeField := structType.Field(i)
-		valField := structVal.Field(i)
-
-		if collectEmbedded(&typeField, valField, &embeds) {
-			continue
-		}
-
-		tag, ok := typeField.Tag.Lookup("cbor")
-		if !ok {
-			continue
-		}
-
-		parts := strings.Split(tag, ",")
-		keyString := parts[0]
-
-		if keyString == "-" {
-			continue // field is not marshaled
-		}
+	plan := getStructPlan(structType)
+	if plan.CBORErr != nil {
+		return plan.CBORErr
+	}
 
-		isOmitEmpty := false
-		if len(parts) > 1 {
-			for _, option := range parts[1:] {
-				if option == omitempty {
-					isOmitEmpty = true
-					break
-				}
-			}
-		}
+	for _, field := range plan.CBOR {
+		valField := structVal.Field(field.Index)
 
 		// do not serialize zero values if the corresponding field is
 		// omitempty
-		if isOmitEmpty && valField.IsZero() {
+		if field.OmitEmpty && valField.IsZero() {
 			continue
 		}
 
-		keyInt, err := strconv.Atoi(keyString)
-		if err != nil {
-			return fmt.Errorf("non-integer cbor key: %s", keyString)
-		}
-
-		data, err := em.Marshal(valField.Interface())
+		data, err := enc.Marshal(valField.Interface())
 		if err != nil {
 			return fmt.Errorf("error marshaling field %q: %w",
-				typeField.Name,
+				field.Name,
 				err,
 			)
 		}
 
-		if err := rawMap.Add(keyInt, cbor.RawMessage(data)); err != nil {
+		if err := rawMap.Add(field.Key, cbor.RawMessage(data)); err != nil {
 			return err
 		}
 	}
 
-	for _, emb := range embeds {
-		if err := doSerializeStructToCBOR(em, rawMap, emb.Type, emb.Value); err != nil {
-			return err
+	for _, index := range plan.Embeds {
+		for _, emb := range plan.embeddedValues(structType, structVal, index) {
+			if err := doSerializeStructToCBOR(enc, rawMap, emb.Type, emb.Value); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -111,84 +87,85 @@ func PopulateStructFromCBOR(dm cbor.DecMode, data []byte, dest any) error {
 		return err
 	}
 
+	origKeys := append([]any(nil), rawMap.Keys...)
+
 	structType := reflect.TypeOf(dest)
 	structVal := reflect.ValueOf(dest)
 
-	return doPopulateStructFromCBOR(dm, rawMap, structType, structVal)
+	dec := AsDecoder(dm)
+
+	var unknown UnknownFieldCollector
+
+	if err := doPopulateStructFromCBOR(dec, rawMap, structType, structVal, &unknown); err != nil {
+		return err
+	}
+
+	if dec.Options().Strict && len(rawMap.Keys) != 0 {
+		ufe := &UnknownFieldError{}
+		for _, key := range rawMap.Keys {
+			ufe.Keys = append(ufe.Keys, key)
+		}
+
+		// the entries of the map itself are reported ahead of those of
+		// its members
+		unknown.errs = append([]*UnknownFieldError{ufe}, unknown.errs...)
+	}
+
+	if err := unknown.Err(); err != nil {
+		return err
+	}
+
+	captureUnknownCBOR(dec, rawMap, origKeys, dest)
+
+	return nil
 }
 
 func doPopulateStructFromCBOR(
-	dm cbor.DecMode,
+	dec *Decoder,
 	rawMap *structFieldsCBOR,
 	structType reflect.Type,
 	structVal reflect.Value,
+	unknown *UnknownFieldCollector,
 ) error {
 	if structType.Kind() == reflect.Pointer {
 		structType = structType.Elem()
 		structVal = structVal.Elem()
 	}
 
-	var embeds []embedded
-
-	for i := 0; i < structVal.NumField(); i++ {
-		typeField := structType.Field(i)
-		valField := structVal.Field(i)
-
-		if collectEmbedded(&typeField, valField, &embeds) {
-			continue
-		}
-
-		tag, ok := typeField.Tag.Lookup("cbor")
-		if !ok {
-			continue
-		}
-
-		parts := strings.Split(tag, ",")
-		keyString := parts[0]
-
-		if keyString == "-" { // field is not marshaled
-			continue
-		}
-
-		isOmitEmpty := false
-		if len(parts) > 1 {
-			for _, option := range parts[1:] {
-				if option == omitempty {
-					isOmitEmpty = true
-					break
-				}
-			}
-		}
-
-		keyInt, err := strconv.Atoi(keyString)
-		if err != nil {
-			return fmt.Errorf("non-integer cbor key %s", keyString)
-		}
+	plan := getStructPlan(structType)
+	if plan.CBORErr != nil {
+		return plan.CBORErr
+	}
 
-		rawVal, ok := rawMap.Get(keyInt)
+	for _, field := range plan.CBOR {
+		rawVal, ok := rawMap.Get(field.Key)
 		if !ok {
-			if isOmitEmpty {
+			if field.OmitEmpty {
 				continue
 			}
 
-			return fmt.Errorf("missing mandatory field %q (%d)",
-				typeField.Name, keyInt)
+			return fmt.Errorf("missing mandatory field %q (%s)",
+				field.Name, formatCBORKey(field.Key))
 		}
 
-		fieldPtr := valField.Addr().Interface()
-		if err := dm.Unmarshal(rawVal, fieldPtr); err != nil {
-			return fmt.Errorf("error unmarshalling field %q: %w",
-				typeField.Name,
-				err,
-			)
+		fieldPtr := structVal.Field(field.Index).Addr().Interface()
+		if err := dec.Unmarshal(rawVal, fieldPtr); err != nil {
+			if !unknown.Collect(err, field.Segment) {
+				return fmt.Errorf("error unmarshalling field %q: %w",
+					field.Name,
+					err,
+				)
+			}
 		}
 
-		rawMap.Delete(keyInt)
+		rawMap.Delete(field.Key)
 	}
 
-	for _, emb := range embeds {
-		if err := doPopulateStructFromCBOR(dm, rawMap, emb.Type, emb.Value); err != nil {
-			return err
+	for _, index := range plan.Embeds {
+		for _, emb := range plan.embeddedValues(structType, structVal, index) {
+			if err := doPopulateStructFromCBOR(dec, rawMap, emb.Type, emb.Value, unknown); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -201,26 +178,27 @@ func doPopulateStructFromCBOR(
 // isn't strictly necessary, it is useful to have a _stable_ serialization
 // order for map keys to be compatible with regular Go struct serialization
 // behavior. This is also useful for tests/examples that compare encoded
-// []byte's.
+// []byte's. Keys are either ints (covering both unsigned and negative CBOR
+// integers) or strings (CBOR text strings).
 type structFieldsCBOR struct {
-	Fields map[int]cbor.RawMessage
-	Keys   []int
+	Fields map[any]cbor.RawMessage
+	Keys   []any
 }
 
 func newStructFieldsCBOR() *structFieldsCBOR {
 	return &structFieldsCBOR{
-		Fields: make(map[int]cbor.RawMessage),
+		Fields: make(map[any]cbor.RawMessage),
 	}
 }
 
-func (o structFieldsCBOR) Has(key int) bool {
+func (o structFieldsCBOR) Has(key any) bool {
 	_, ok := o.Fields[key]
 	return ok
 }
 
-func (o *structFieldsCBOR) Add(key int, val cbor.RawMessage) error {
+func (o *structFieldsCBOR) Add(key any, val cbor.RawMessage) error {
 	if o.Has(key) {
-		return fmt.Errorf("duplicate cbor key: %d", key)
+		return fmt.Errorf("duplicate cbor key: %s", formatCBORKey(key))
 	}
 
 	o.Fields[key] = val
@@ -229,12 +207,29 @@ func (o *structFieldsCBOR) Add(key int, val cbor.RawMessage) error {
 	return nil
 }
 
-func (o *structFieldsCBOR) Get(key int) (cbor.RawMessage, bool) {
+// Insert adds the specified key and value at position pos within the key
+// order. If pos is past the end, the key is appended.
+func (o *structFieldsCBOR) Insert(pos int, key any, val cbor.RawMessage) error {
+	if o.Has(key) {
+		return fmt.Errorf("duplicate cbor key: %s", formatCBORKey(key))
+	}
+
+	if pos < 0 || pos > len(o.Keys) {
+		pos = len(o.Keys)
+	}
+
+	o.Fields[key] = val
+	o.Keys = append(o.Keys[:pos], append([]any{key}, o.Keys[pos:]...)...)
+
+	return nil
+}
+
+func (o *structFieldsCBOR) Get(key any) (cbor.RawMessage, bool) {
 	val, ok := o.Fields[key]
 	return val, ok
 }
 
-func (o *structFieldsCBOR) Delete(key int) {
+func (o *structFieldsCBOR) Delete(key any) {
 	delete(o.Fields, key)
 
 	for i, existing := range o.Keys {
@@ -272,7 +267,7 @@ func (o *structFieldsCBOR) ToCBOR(em cbor.EncMode) ([]byte, error) {
 	for _, key := range o.Keys {
 		marshalledKey, err := em.Marshal(key)
 		if err != nil {
-			return nil, fmt.Errorf("problem marshaling key %d: %w", key, err)
+			return nil, fmt.Errorf("problem marshaling key %s: %w", formatCBORKey(key), err)
 		}
 
 		out = append(out, marshalledKey...)
@@ -317,8 +312,22 @@ func (o *structFieldsCBOR) FromCBOR(dm cbor.DecMode, data []byte) error {
 		return err
 	}
 
-	if mapLen != 0 {
-		o.Fields = make(map[int]cbor.RawMessage, mapLen)
+	maxElements := AsDecoder(dm).Limits().MaxElements
+
+	if additionalInfo != 31 {
+		if err := checkLimit("MaxElements", mapLen, maxElements); err != nil {
+			return err
+		}
+
+		// each entry takes at least two bytes, so the length from the
+		// header is only trusted as far as the input can back it
+		sizeHint := mapLen
+		if sizeHint > len(rest)/2 {
+			sizeHint = len(rest) / 2
+		}
+
+		o.Fields = make(map[any]cbor.RawMessage, sizeHint)
+		o.Keys = make([]any, 0, sizeHint)
 
 		for i := 0; i < mapLen; i++ {
 			rest, err = o.unmarshalKeyValue(dm, rest)
@@ -326,8 +335,8 @@ func (o *structFieldsCBOR) FromCBOR(dm cbor.DecMode, data []byte) error {
 				return fmt.Errorf("map item %d: %w", i, err)
 			}
 		}
-	} else { // mapLen == 0 --> indefinite encoding
-		o.Fields = make(map[int]cbor.RawMessage)
+	} else { // indefinite encoding
+		o.Fields = make(map[any]cbor.RawMessage)
 
 		i := 0
 		done := false
@@ -343,6 +352,10 @@ func (o *structFieldsCBOR) FromCBOR(dm cbor.DecMode, data []byte) error {
 			}
 
 			i++
+
+			if err := checkLimit("MaxElements", i, maxElements); err != nil {
+				return err
+			}
 		}
 
 		if !done {
@@ -354,15 +367,20 @@ func (o *structFieldsCBOR) FromCBOR(dm cbor.DecMode, data []byte) error {
 }
 
 func (o *structFieldsCBOR) unmarshalKeyValue(dm cbor.DecMode, rest []byte) ([]byte, error) {
-	var key int
+	var rawKey any
 	var val cbor.RawMessage
 	var err error
 
-	rest, err = dm.UnmarshalFirst(rest, &key)
+	rest, err = dm.UnmarshalFirst(rest, &rawKey)
 	if err != nil {
 		return rest, fmt.Errorf("could not unmarshal key: %w", err)
 	}
 
+	key, err := normalizeCBORKey(rawKey)
+	if err != nil {
+		return rest, err
+	}
+
 	rest, err = dm.UnmarshalFirst(rest, &val)
 	if err != nil {
 		return rest, fmt.Errorf("could not unmarshal value: %w", err)
@@ -414,3 +432,50 @@ func processAdditionalInfo(
 
 	return mapLen, rest, nil
 }
+
+// parseCBORKey returns the map key specified by a cbor struct tag. The key is
+// an int if the keyasint option is set, or if the name is an integer (for
+// compatibility with tags that omit the option); otherwise it is the name
+// itself, encoded as a text string.
+func parseCBORKey(name string, options []string) (any, error) {
+	keyAsInt := hasOption(options, "keyasint")
+
+	if i, err := strconv.Atoi(name); err == nil {
+		return i, nil
+	}
+
+	if keyAsInt || name == "" {
+		return nil, fmt.Errorf("non-integer cbor key: %q", name)
+	}
+
+	return name, nil
+}
+
+// normalizeCBORKey converts a decoded map key into the representation used
+// by structFieldsCBOR.
+func normalizeCBORKey(key any) (any, error) {
+	switch t := key.(type) {
+	case uint64:
+		if t > math.MaxInt {
+			return nil, fmt.Errorf("cbor key out of range: %d", t)
+		}
+		return int(t), nil
+	case int64:
+		if t < math.MinInt {
+			return nil, fmt.Errorf("cbor key out of range: %d", t)
+		}
+		return int(t), nil
+	case string:
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unsupported cbor key type: %T", key)
+	}
+}
+
+func formatCBORKey(key any) string {
+	if s, ok := key.(string); ok {
+		return strconv.Quote(s)
+	}
+
+	return fmt.Sprint(key)
+}
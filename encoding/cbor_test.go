@@ -119,7 +119,7 @@ func Test_structFieldsCBOR_CRUD(t *testing.T) {
 	err = sf.Add(3, cbor.RawMessage{0x03})
 	assert.NoError(t, err)
 
-	assert.Equal(t, []int{2, 1, 3}, sf.Keys)
+	assert.Equal(t, []any{2, 1, 3}, sf.Keys)
 	assert.True(t, sf.Has(3))
 	assert.False(t, sf.Has(4))
 
@@ -221,7 +221,7 @@ func Test_structFieldsCBOR_CBOR_decode_tagged(t *testing.T) {
 	sfOut := newStructFieldsCBOR()
 	err = sfOut.FromCBOR(dm, data)
 	require.NoError(t, err)
-	assert.Equal(t, []int{0, 1, 2, 3, 4}, sfOut.Keys)
+	assert.Equal(t, []any{0, 1, 2, 3, 4}, sfOut.Keys)
 }
 
 func Test_structFieldsCBOR_CBOR_decode_indefinite(t *testing.T) {
@@ -241,7 +241,17 @@ func Test_structFieldsCBOR_CBOR_decode_indefinite(t *testing.T) {
 	sfOut := newStructFieldsCBOR()
 	err = sfOut.FromCBOR(dm, data)
 	require.NoError(t, err)
-	assert.Equal(t, []int{0, 1, 2, 3, 4}, sfOut.Keys)
+	assert.Equal(t, []any{0, 1, 2, 3, 4}, sfOut.Keys)
+}
+
+func Test_structFieldsCBOR_CBOR_decode_empty(t *testing.T) {
+	dm, err := cbor.DecOptions{}.DecMode()
+	require.NoError(t, err)
+
+	sfOut := newStructFieldsCBOR()
+	err = sfOut.FromCBOR(dm, []byte{0xa0})
+	require.NoError(t, err)
+	assert.Empty(t, sfOut.Keys)
 }
 
 func Test_structFieldsCBOR_CBOR_decode_negative(t *testing.T) {
@@ -278,3 +288,67 @@ func Test_processAdditionalInfo(t *testing.T) {
 	_, _, err = processAdditionalInfo(addInfo, []byte{})
 	assert.EqualError(t, err, "unexpected EOF")
 }
+
+func Test_PopulateStructFromCBOR_key_types(t *testing.T) {
+	type KeyTypes struct {
+		Unsigned string `cbor:"1,keyasint" json:"unsigned"`
+		Negative string `cbor:"-65536,keyasint,omitempty" json:"negative,omitempty"`
+		Text     string `cbor:"vendor-name,omitempty" json:"vendor-name,omitempty"`
+	}
+
+	data := []byte{
+		0xa3, // map(3)
+
+		0x01,       // key 1
+		0x61, 0x61, // val "a"
+
+		0x39, 0xff, 0xff, // key -65536
+		0x61, 0x62, // val "b"
+
+		0x6b,                                                             // key tstr(11)
+		0x76, 0x65, 0x6e, 0x64, 0x6f, 0x72, 0x2d, 0x6e, 0x61, 0x6d, 0x65, // "vendor-name"
+		0x61, 0x63, // val "c"
+	}
+
+	dm, err := cbor.DecOptions{}.DecMode()
+	require.NoError(t, err)
+
+	em, err := cbor.EncOptions{}.EncMode()
+	require.NoError(t, err)
+
+	var v KeyTypes
+
+	err = PopulateStructFromCBOR(dm, data, &v)
+	require.NoError(t, err)
+	assert.Equal(t, KeyTypes{Unsigned: "a", Negative: "b", Text: "c"}, v)
+
+	out, err := SerializeStructToCBOR(em, v)
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+
+	// the native encoder agrees on the keys
+	var native map[any]string
+	require.NoError(t, dm.Unmarshal(out, &native))
+	assert.Equal(t, map[any]string{uint64(1): "a", int64(-65536): "b", "vendor-name": "c"}, native)
+
+	jsonOut, err := SerializeStructToJSON(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"unsigned":"a","negative":"b","vendor-name":"c"}`, string(jsonOut))
+
+	err = PopulateStructFromCBOR(dm, []byte{0xa2, 0x01, 0x61, 0x61, 0x41, 0x00, 0x00}, &v)
+	assert.EqualError(t, err, "map item 1: unsupported cbor key type: []uint8")
+
+	type BadKey struct {
+		Field string `cbor:"foo,keyasint"`
+	}
+
+	_, err = SerializeStructToCBOR(em, BadKey{})
+	assert.EqualError(t, err, `non-integer cbor key: "foo"`)
+
+	type Missing struct {
+		Field string `cbor:"foo"`
+	}
+
+	err = PopulateStructFromCBOR(dm, []byte{0xa0}, &Missing{})
+	assert.EqualError(t, err, `missing mandatory field "Field" ("foo")`)
+}
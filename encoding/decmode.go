@@ -0,0 +1,152 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"io"
+	"sync"
+
+	cbor "github.com/fxamacker/cbor/v2"
+)
+
+// The minima accepted by the underlying CBOR library for the nesting depth
+// and number of elements. Lower DecodeLimits are enforced by CheckCBORLimits
+// only.
+const (
+	minCBORNestingDepth = 4
+	minCBORElements     = 16
+)
+
+// limitedDecMode is a cbor.DecMode whose nesting depth and element limits are
+// set from DecodeLimits (see NewDecMode)
+type limitedDecMode struct {
+	opts cbor.DecOptions
+	tags cbor.TagSet
+
+	// modes holds the decoding modes built so far, by decModeCaps
+	modes sync.Map
+}
+
+// decModeCaps are the nesting depth and element limits of a decoding mode
+type decModeCaps struct {
+	NestingDepth int
+	Elements     int
+}
+
+// NewDecMode returns a decoding mode using the supplied options and tags
+// (which may be nil), whose nesting depth and element limits are set from the
+// DecodeLimits in effect: those of the Decoder using it (see Decoder.Limits),
+// or those set via SetDecodeLimits when it is used directly. Any such limits
+// set in opts are ignored.
+//
+// This keeps the limits of the underlying CBOR library in line with the
+// DecodeLimits checked by CheckCBORLimits: values decoded directly (e.g. via
+// their UnmarshalCBOR methods) are subject to the same bounds, while higher
+// limits set for a given decode operation are not overridden by the library
+// defaults. The limits are capped at the range supported by the library (see
+// MaxCBORNestingDepth and MaxCBORElements).
+func NewDecMode(opts cbor.DecOptions, tags cbor.TagSet) (cbor.DecMode, error) {
+	ret := &limitedDecMode{opts: opts, tags: tags}
+
+	// build a mode straight away, so that invalid options are reported here
+	if _, err := ret.forLimits(GetDecodeLimits()); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// forLimits returns the decoding mode for the supplied limits
+func (o *limitedDecMode) forLimits(limits DecodeLimits) (cbor.DecMode, error) {
+	caps := decModeCaps{
+		NestingDepth: max(capLimit(limits.MaxNestingDepth, MaxCBORNestingDepth), minCBORNestingDepth),
+		Elements:     max(capLimit(limits.MaxElements, MaxCBORElements), minCBORElements),
+	}
+
+	if dm, ok := o.modes.Load(caps); ok {
+		return dm.(cbor.DecMode), nil
+	}
+
+	opts := o.opts
+	opts.MaxNestedLevels = caps.NestingDepth
+	opts.MaxArrayElements = caps.Elements
+	opts.MaxMapPairs = caps.Elements
+
+	var (
+		dm  cbor.DecMode
+		err error
+	)
+
+	if o.tags != nil {
+		dm, err = opts.DecModeWithTags(o.tags)
+	} else {
+		dm, err = opts.DecMode()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := o.modes.LoadOrStore(caps, dm)
+
+	return actual.(cbor.DecMode), nil
+}
+
+// current returns the decoding mode for the limits set via SetDecodeLimits
+func (o *limitedDecMode) current() (cbor.DecMode, error) {
+	return o.forLimits(GetDecodeLimits())
+}
+
+func (o *limitedDecMode) Unmarshal(data []byte, v any) error {
+	dm, err := o.current()
+	if err != nil {
+		return err
+	}
+
+	return dm.Unmarshal(data, v)
+}
+
+func (o *limitedDecMode) UnmarshalFirst(data []byte, v any) ([]byte, error) {
+	dm, err := o.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return dm.UnmarshalFirst(data, v)
+}
+
+// Valid is the deprecated equivalent of Wellformed
+func (o *limitedDecMode) Valid(data []byte) error {
+	return o.Wellformed(data)
+}
+
+func (o *limitedDecMode) Wellformed(data []byte) error {
+	dm, err := o.current()
+	if err != nil {
+		return err
+	}
+
+	return dm.Wellformed(data)
+}
+
+func (o *limitedDecMode) NewDecoder(r io.Reader) *cbor.Decoder {
+	dm, err := o.current()
+	if err != nil {
+		// the options were checked by NewDecMode, and the limits are
+		// always within the supported range
+		panic(err)
+	}
+
+	return dm.NewDecoder(r)
+}
+
+// DecOptions returns the options of the decoding mode for the limits set via
+// SetDecodeLimits
+func (o *limitedDecMode) DecOptions() cbor.DecOptions {
+	dm, err := o.current()
+	if err != nil {
+		panic(err) // see NewDecoder
+	}
+
+	return dm.DecOptions()
+}
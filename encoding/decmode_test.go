@@ -0,0 +1,65 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDecMode_global_limits(t *testing.T) {
+	defer SetDecodeLimits(DefaultDecodeLimits)
+
+	dm, err := NewDecMode(cbor.DecOptions{}, nil)
+	require.NoError(t, err)
+
+	var v any
+
+	// the library caps follow the default limits
+	require.NoError(t, dm.Unmarshal(nestedArraysCBOR(32), &v))
+	assert.ErrorContains(t, dm.Unmarshal(nestedArraysCBOR(33), &v),
+		"exceeded max nested level 32")
+
+	SetDecodeLimits(DecodeLimits{MaxNestingDepth: 40})
+	require.NoError(t, dm.Unmarshal(nestedArraysCBOR(40), &v))
+	assert.ErrorContains(t, dm.Unmarshal(nestedArraysCBOR(41), &v),
+		"exceeded max nested level 40")
+
+	// unset limits are capped at the maxima of the library
+	SetDecodeLimits(DecodeLimits{})
+	require.NoError(t, dm.Unmarshal(nestedArraysCBOR(100), &v))
+	assert.Equal(t, MaxCBORNestingDepth, dm.DecOptions().MaxNestedLevels)
+	assert.Equal(t, MaxCBORElements, dm.DecOptions().MaxArrayElements)
+
+	// limits below the minima of the library are left to CheckCBORLimits
+	SetDecodeLimits(DecodeLimits{MaxNestingDepth: 1, MaxElements: 1})
+	assert.Equal(t, 4, dm.DecOptions().MaxNestedLevels)
+	assert.Equal(t, 16, dm.DecOptions().MaxMapPairs)
+}
+
+func TestNewDecMode_decoder_limits(t *testing.T) {
+	dm, err := NewDecMode(cbor.DecOptions{}, nil)
+	require.NoError(t, err)
+
+	var v any
+
+	dec := decoderWithLimits(dm, DecodeLimits{MaxNestingDepth: 40})
+	require.NoError(t, dec.Unmarshal(nestedArraysCBOR(40), &v))
+	assert.ErrorContains(t, dec.Unmarshal(nestedArraysCBOR(41), &v),
+		"exceeded max nested level 40")
+
+	rest, err := dec.UnmarshalFirst(append(nestedArraysCBOR(40), 0x01), &v)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, rest)
+
+	// the mode itself still applies the global limits
+	assert.Error(t, dm.Unmarshal(nestedArraysCBOR(40), &v))
+}
+
+func TestNewDecMode_invalid_options(t *testing.T) {
+	_, err := NewDecMode(cbor.DecOptions{DupMapKey: cbor.DupMapKeyMode(99)}, nil)
+	assert.ErrorContains(t, err, "invalid DupMapKey")
+}
@@ -0,0 +1,311 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	cbor "github.com/fxamacker/cbor/v2"
+)
+
+// IDecoderCBORUnmarshaler is implemented by types that need the state carried
+// by a Decoder, either for themselves (e.g. type choices whose CBOR tags
+// depend on a profile) or for the values nested in them.
+type IDecoderCBORUnmarshaler interface {
+	UnmarshalCBORWith(dec *Decoder, data []byte) error
+}
+
+// IDecoderJSONUnmarshaler is the JSON counterpart of IDecoderCBORUnmarshaler
+type IDecoderJSONUnmarshaler interface {
+	UnmarshalJSONWith(dec *Decoder, data []byte) error
+}
+
+// Decoder carries the state of a single decode operation down to the nested
+// values being decoded, i.e. its DecodeOptions (see WithOptions) and the
+// values attached to it via WithValue (such as a profile's type registry).
+//
+// A Decoder implements cbor.DecMode, so that it can be passed to
+// PopulateStructFromCBOR in place of a package's decoding mode. The values it
+// decodes are handed the Decoder if they implement IDecoderCBORUnmarshaler
+// (see Unmarshal); JSON values are decoded via DecodeJSON. A Decoder is never
+// modified once created, and so is safe for concurrent use.
+type Decoder struct {
+	cbor.DecMode
+
+	opts   DecodeOptions
+	values map[any]any
+}
+
+// NewDecoder instantiates a Decoder that uses the supplied decoding mode for
+// the values that do not implement IDecoderCBORUnmarshaler. dm may be nil if
+// the Decoder is only used for JSON. If dm was created using NewDecMode, its
+// nesting depth and element limits follow the DecodeLimits of the Decoder.
+func NewDecoder(dm cbor.DecMode) *Decoder {
+	return &Decoder{DecMode: dm}
+}
+
+// AsDecoder returns dm if it is a *Decoder, or a new Decoder using it
+// otherwise
+func AsDecoder(dm cbor.DecMode) *Decoder {
+	if dec, ok := dm.(*Decoder); ok {
+		return dec
+	}
+
+	return NewDecoder(dm)
+}
+
+// WithDecMode returns a copy of the target Decoder using the supplied decoding
+// mode. Packages use this to decode their own values with their own mode (and
+// CBOR tags) when handed a Decoder by another package.
+func (o *Decoder) WithDecMode(dm cbor.DecMode) *Decoder {
+	ret := *o
+	ret.DecMode = dm
+
+	return &ret
+}
+
+// WithValue returns a copy of the target Decoder in which key is associated
+// with val. As for context.Context, keys should be of an unexported type
+// defined by the package using them.
+func (o *Decoder) WithValue(key, val any) *Decoder {
+	ret := *o
+	ret.values = make(map[any]any, len(o.values)+1)
+
+	for k, v := range o.values {
+		ret.values[k] = v
+	}
+
+	ret.values[key] = val
+
+	return &ret
+}
+
+// Value returns the value associated with key, or nil if there is none
+func (o *Decoder) Value(key any) any {
+	return o.values[key]
+}
+
+// WithOptions returns a copy of the target Decoder applying the supplied
+// DecodeOptions. If more than one set of options is specified, they are
+// combined: the boolean options are set if they are set in any of them, and
+// the last Limits set takes precedence. If none are, the target Decoder is
+// returned as is. This is meant for entry points (such as FromCBOR and
+// FromJSON methods) that take optional DecodeOptions.
+func (o *Decoder) WithOptions(opts ...DecodeOptions) *Decoder {
+	if len(opts) == 0 {
+		return o
+	}
+
+	ret := *o
+	ret.opts = combineDecodeOptions(opts)
+
+	return &ret
+}
+
+// Options returns the DecodeOptions applied by the target Decoder
+func (o *Decoder) Options() DecodeOptions {
+	return o.opts
+}
+
+// Limits returns the DecodeLimits applied by the target Decoder: those set in
+// its DecodeOptions if any, or those set via SetDecodeLimits otherwise
+func (o *Decoder) Limits() DecodeLimits {
+	if o.opts.Limits != nil {
+		return *o.opts.Limits
+	}
+
+	return GetDecodeLimits()
+}
+
+// decMode returns the decoding mode of the target Decoder. If it was created
+// using NewDecMode, its nesting depth and element limits are set from the
+// DecodeLimits of the Decoder (see Limits).
+func (o *Decoder) decMode() (cbor.DecMode, error) {
+	if dm, ok := o.DecMode.(*limitedDecMode); ok {
+		return dm.forLimits(o.Limits())
+	}
+
+	return o.DecMode, nil
+}
+
+// Unmarshal decodes the CBOR-encoded data into v. If v implements
+// IDecoderCBORUnmarshaler, it is handed the target Decoder; the same applies to
+// pointers to, and slices of, such values. Any other value is decoded using
+// the Decoder's decoding mode.
+func (o *Decoder) Unmarshal(data []byte, v any) error {
+	if u, ok := v.(IDecoderCBORUnmarshaler); ok {
+		return u.UnmarshalCBORWith(o, data)
+	}
+
+	dm, err := o.decMode()
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() ||
+		!needsWith(rv.Type().Elem(), cborUnmarshalerWithType) {
+		return dm.Unmarshal(data, v)
+	}
+
+	elem := rv.Elem()
+
+	switch elem.Kind() {
+	case reflect.Pointer:
+		if len(data) == 1 && (data[0] == 0xf6 || data[0] == 0xf7) { // null, undefined
+			elem.SetZero()
+			return nil
+		}
+
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+
+		return o.Unmarshal(data, elem.Interface())
+	case reflect.Slice:
+		var items []cbor.RawMessage
+
+		if err := dm.Unmarshal(data, &items); err != nil {
+			return err
+		}
+
+		return decodeItems(elem, len(items), items == nil, func(i int, dest any) error {
+			return o.Unmarshal(items[i], dest)
+		})
+	default:
+		return dm.Unmarshal(data, v)
+	}
+}
+
+// UnmarshalFirst decodes the first CBOR data item in data into v, returning
+// the remaining bytes. Unlike Unmarshal, v is not handed the Decoder.
+func (o *Decoder) UnmarshalFirst(data []byte, v any) ([]byte, error) {
+	dm, err := o.decMode()
+	if err != nil {
+		return nil, err
+	}
+
+	return dm.UnmarshalFirst(data, v)
+}
+
+// Wellformed checks that data is a single well-formed CBOR data item, within
+// the DecodeLimits of the target Decoder
+func (o *Decoder) Wellformed(data []byte) error {
+	dm, err := o.decMode()
+	if err != nil {
+		return err
+	}
+
+	return dm.Wellformed(data)
+}
+
+// DecodeJSON decodes the JSON-encoded data into v. If v implements
+// IDecoderJSONUnmarshaler, it is handed the target Decoder; the same applies to
+// pointers to, and slices of, such values. Any other value is decoded using
+// json.Unmarshal.
+func (o *Decoder) DecodeJSON(data []byte, v any) error {
+	if u, ok := v.(IDecoderJSONUnmarshaler); ok {
+		return u.UnmarshalJSONWith(o, data)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() ||
+		!needsWith(rv.Type().Elem(), jsonUnmarshalerWithType) {
+		return json.Unmarshal(data, v)
+	}
+
+	elem := rv.Elem()
+
+	switch elem.Kind() {
+	case reflect.Pointer:
+		if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+			elem.SetZero()
+			return nil
+		}
+
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+
+		return o.DecodeJSON(data, elem.Interface())
+	case reflect.Slice:
+		var items []json.RawMessage
+
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+
+		return decodeItems(elem, len(items), items == nil, func(i int, dest any) error {
+			return o.DecodeJSON(items[i], dest)
+		})
+	default:
+		return json.Unmarshal(data, v)
+	}
+}
+
+// decodeItems sets the slice s to a new slice of n elements decoded by
+// decodeItem, or to nil if isNil is true
+func decodeItems(s reflect.Value, n int, isNil bool, decodeItem func(int, any) error) error {
+	if isNil {
+		s.SetZero()
+		return nil
+	}
+
+	items := reflect.MakeSlice(s.Type(), n, n)
+
+	var unknown UnknownFieldCollector
+
+	for i := 0; i < n; i++ {
+		if err := decodeItem(i, items.Index(i).Addr().Interface()); err != nil {
+			if !unknown.Collect(err, IndexSegment(i)) {
+				return err
+			}
+		}
+	}
+
+	if err := unknown.Err(); err != nil {
+		return err
+	}
+
+	s.Set(items)
+
+	return nil
+}
+
+var (
+	cborUnmarshalerWithType = reflect.TypeOf((*IDecoderCBORUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerWithType = reflect.TypeOf((*IDecoderJSONUnmarshaler)(nil)).Elem()
+	cborMarshalerWithType   = reflect.TypeOf((*IEncoderCBORMarshaler)(nil)).Elem()
+
+	// needsWithCache holds the results of needsWith
+	needsWithCache sync.Map
+)
+
+type withType struct {
+	Type  reflect.Type
+	Iface reflect.Type
+}
+
+// needsWith returns true if values of type t implement iface (as pointers),
+// or are pointers to, or slices of, such values. Other kinds of values are
+// (de)serialized as is, without being handed the Decoder or Encoder.
+func needsWith(t reflect.Type, iface reflect.Type) bool {
+	key := withType{t, iface}
+
+	if ret, ok := needsWithCache.Load(key); ok {
+		return ret.(bool)
+	}
+
+	ret := reflect.PointerTo(t).Implements(iface)
+
+	if !ret && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		ret = needsWith(t.Elem(), iface)
+	}
+
+	needsWithCache.Store(key, ret)
+
+	return ret
+}
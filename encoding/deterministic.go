@@ -0,0 +1,579 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	cbor "github.com/fxamacker/cbor/v2"
+)
+
+// ErrNonCanonical is wrapped by the errors returned when CBOR data is found
+// not to be deterministically encoded.
+var ErrNonCanonical = errors.New("non-canonical encoding")
+
+// NonCanonicalError reports the first departure from the core deterministic
+// encoding requirements of RFC 8949 (section 4.2.1) found in CBOR data, along
+// with its location.
+type NonCanonicalError struct {
+	// Path is the sequence of field names (as per their JSON tags), map
+	// keys and array indexes (e.g. "[2]") leading to the offending data
+	// item. Map keys that do not correspond to a known field are rendered
+	// as per UnknownFieldError.
+	Path []string
+	// Reason describes the problem, e.g. "indefinite-length encoding"
+	Reason string
+}
+
+// PathString returns the Path as a single string, e.g.
+// "triples.reference-values[2].measurements[0].value"
+func (o NonCanonicalError) PathString() string {
+	return joinPath(o.Path)
+}
+
+func (o NonCanonicalError) Error() string {
+	if len(o.Path) == 0 {
+		return fmt.Sprintf("%s: %s", ErrNonCanonical, o.Reason)
+	}
+
+	return fmt.Sprintf("%s at %s: %s", ErrNonCanonical, o.PathString(), o.Reason)
+}
+
+func (o NonCanonicalError) Unwrap() error {
+	return ErrNonCanonical
+}
+
+// IEmbeddedCBOR is implemented by byte string types whose content is itself a
+// CBOR data item, such as the tags carried in a CoRIM. The content of such
+// byte strings is checked (and canonicalized) along with the enclosing data.
+type IEmbeddedCBOR interface {
+	// EmbeddedCBORType returns a value of the type the embedded data item
+	// decodes to, given the number of the CBOR tag it is wrapped in (or
+	// nil if it is not known). This is only used to name path segments.
+	EmbeddedCBORType(tag uint64) any
+}
+
+// CheckDeterministicCBOR checks that data is encoded as per the core
+// deterministic encoding requirements of RFC 8949: integers, lengths and tag
+// numbers in their shortest form, floats in their shortest value-preserving
+// form, no indefinite-length items, and map keys that are unique and sorted in
+// bytewise lexicographic order of their encodings. The first violation is
+// reported as a *NonCanonicalError. v is optional: if specified, it is a value
+// of the type data decodes to, and is used to name the path segments.
+func CheckDeterministicCBOR(data []byte, v any) error {
+	c, err := newCanonicalizer(data)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := c.itemAt(0, typeOf(v), nil); err != nil {
+		return err
+	}
+
+	if c.issue != nil {
+		return c.issue
+	}
+
+	return nil
+}
+
+// CanonicalizeCBOR re-encodes data as per the core deterministic encoding
+// requirements of RFC 8949 (see CheckDeterministicCBOR). The data is not
+// decoded into Go values, so all its content (including any unknown to this
+// module) is preserved. Maps with duplicate keys cannot be canonicalized, and
+// are reported as a *NonCanonicalError. v is optional, as per
+// CheckDeterministicCBOR.
+func CanonicalizeCBOR(data []byte, v any) ([]byte, error) {
+	c, err := newCanonicalizer(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out, _, err := c.itemAt(0, typeOf(v), nil)
+
+	return out, err
+}
+
+// CheckCBORInput performs the checks that apply to CBOR input before it is
+// decoded by a FromCBOR entry point using dec: the decode limits of dec are
+// enforced and, if requested via its DecodeOptions, the input must be
+// deterministically encoded. v is the decode destination, used to name path
+// segments.
+func CheckCBORInput(dec *Decoder, data []byte, v any) error {
+	if err := CheckCBORLimits(data, dec.Limits()); err != nil {
+		return err
+	}
+
+	if dec.Options().Deterministic {
+		return CheckDeterministicCBOR(data, v)
+	}
+
+	return nil
+}
+
+var (
+	// wellformedDM only checks well-formedness; limits are applied
+	// separately, via DecodeLimits
+	wellformedDM, _ = cbor.DecOptions{
+		MaxNestedLevels:  MaxCBORNestingDepth,
+		MaxArrayElements: MaxCBORElements,
+		MaxMapPairs:      MaxCBORElements,
+	}.DecMode()
+
+	shortestFloatEM, _ = cbor.EncOptions{
+		ShortestFloat: cbor.ShortestFloat16,
+	}.EncMode()
+)
+
+func typeOf(v any) reflect.Type {
+	if v == nil {
+		return nil
+	}
+
+	return reflect.TypeOf(v)
+}
+
+type canonicalizer struct {
+	data  []byte
+	issue *NonCanonicalError // first issue found, if any
+}
+
+func newCanonicalizer(data []byte) (*canonicalizer, error) {
+	if err := wellformedDM.Wellformed(data); err != nil {
+		return nil, fmt.Errorf("malformed CBOR: %w", err)
+	}
+
+	return &canonicalizer{data: data}, nil
+}
+
+func (o *canonicalizer) report(path []string, format string, args ...any) {
+	if o.issue == nil {
+		o.issue = &NonCanonicalError{
+			Path:   append([]string(nil), path...),
+			Reason: fmt.Sprintf(format, args...),
+		}
+	}
+}
+
+// head decodes the head of the data item at pos, reporting an argument that
+// is not in its shortest form.
+func (o *canonicalizer) head(pos int, path []string, what string) (major byte, info byte, arg uint64, next int) {
+	major, info, arg, next = readHead(o.data, pos)
+
+	if !isPreferredHead(o.data[pos:next], major, info, arg) {
+		o.report(path, "%s not in shortest form", what)
+	}
+
+	return major, info, arg, next
+}
+
+// readHead decodes the head of the (well-formed) data item at pos of data.
+// For indefinite-length items, and simple values and floats encoded in the
+// head itself, arg is the additional information.
+func readHead(data []byte, pos int) (major byte, info byte, arg uint64, next int) {
+	initial := data[pos]
+	major, info = initial>>5, initial&0x1f
+	next = pos + 1
+
+	switch info {
+	case 24:
+		arg = uint64(data[next])
+		next++
+	case 25:
+		arg = uint64(binary.BigEndian.Uint16(data[next:]))
+		next += 2
+	case 26:
+		arg = uint64(binary.BigEndian.Uint32(data[next:]))
+		next += 4
+	case 27:
+		arg = binary.BigEndian.Uint64(data[next:])
+		next += 8
+	default:
+		arg = uint64(info)
+	}
+
+	return major, info, arg, next
+}
+
+// isPreferredHead reports whether the argument of the specified head is
+// encoded in its shortest form. Floats are not covered.
+func isPreferredHead(head []byte, major byte, info byte, arg uint64) bool {
+	if major == 7 || info < 24 || info > 27 {
+		return true
+	}
+
+	return bytes.Equal(appendHead(nil, major, arg), head)
+}
+
+// itemAt returns the canonical encoding of the data item at pos, which is
+// expected to decode to a value of type t (if not nil), and the position
+// following it in the input.
+// nolint:gocyclo
+func (o *canonicalizer) itemAt(pos int, t reflect.Type, path []string) ([]byte, int, error) {
+	t = derefType(t)
+
+	switch o.data[pos] >> 5 {
+	case 0, 1: // unsigned and negative integers
+		major, _, arg, next := o.head(pos, path, "integer")
+		return appendHead(nil, major, arg), next, nil
+	case 2, 3: // byte and text strings
+		content, next := o.stringContent(pos, path)
+
+		if o.data[pos]>>5 == 2 && isEmbeddedCBOR(t) && wellformedDM.Wellformed(content) == nil {
+			embedded := &canonicalizer{data: content, issue: o.issue}
+
+			canonical, _, err := embedded.itemAt(0, embeddedType(t, content), path)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			o.issue = embedded.issue
+			content = canonical
+		}
+
+		return append(appendHead(nil, o.data[pos]>>5, uint64(len(content))), content...), next, nil
+	case 4: // arrays
+		_, info, count, next := o.head(pos, path, "array length")
+		if info == 31 {
+			o.report(path, "indefinite-length array")
+		}
+
+		var elements [][]byte
+
+		for i := 0; info == 31 && o.data[next] != 0xff || info != 31 && uint64(i) < count; i++ {
+			segment, elemType := arrayElementHint(t, i)
+
+			elem, n, err := o.itemAt(next, elemType, append(path, segment))
+			if err != nil {
+				return nil, 0, err
+			}
+
+			elements = append(elements, elem)
+			next = n
+		}
+
+		if info == 31 {
+			next++ // break
+		}
+
+		out := appendHead(nil, 4, uint64(len(elements)))
+		for _, e := range elements {
+			out = append(out, e...)
+		}
+
+		return out, next, nil
+	case 5: // maps
+		return o.mapAt(pos, t, path)
+	case 6: // tags
+		_, _, number, next := o.head(pos, path, "tag number")
+
+		content, n, err := o.itemAt(next, t, path)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return append(appendHead(nil, 6, number), content...), n, nil
+	default: // simple values and floats
+		_, info, _, next := o.head(pos, path, "simple value")
+		if info < 25 {
+			return o.data[pos:next], next, nil
+		}
+
+		var f float64
+		if err := cbor.Unmarshal(o.data[pos:next], &f); err != nil {
+			return nil, 0, err
+		}
+
+		out, err := shortestFloatEM.Marshal(f)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if !bytes.Equal(out, o.data[pos:next]) {
+			o.report(path, "float not in shortest form")
+		}
+
+		return out, next, nil
+	}
+}
+
+// stringContent returns the content of the (possibly indefinite-length)
+// string at pos, and the position following it.
+func (o *canonicalizer) stringContent(pos int, path []string) ([]byte, int) {
+	_, info, length, next := o.head(pos, path, "string length")
+	if info != 31 {
+		return o.data[next : next+int(length)], next + int(length)
+	}
+
+	o.report(path, "indefinite-length string")
+
+	var content []byte
+
+	for o.data[next] != 0xff {
+		chunk, n := o.stringContent(next, path)
+		content = append(content, chunk...)
+		next = n
+	}
+
+	return content, next + 1
+}
+
+type canonicalEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+func (o *canonicalizer) mapAt(pos int, t reflect.Type, path []string) ([]byte, int, error) {
+	_, info, count, next := o.head(pos, path, "map length")
+	if info == 31 {
+		o.report(path, "indefinite-length map")
+	}
+
+	var entries []canonicalEntry
+
+	for i := 0; info == 31 && o.data[next] != 0xff || info != 31 && uint64(i) < count; i++ {
+		key, n, err := o.itemAt(next, nil, path)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		segment, valueType := mapValueHint(t, key)
+
+		value, n, err := o.itemAt(n, valueType, append(path, segment))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// duplicates are detected once the entries are sorted
+		if len(entries) != 0 && bytes.Compare(entries[len(entries)-1].Key, key) > 0 {
+			o.report(path, "map key %s out of order", describeKey(key))
+		}
+
+		entries = append(entries, canonicalEntry{Key: key, Value: value})
+		next = n
+	}
+
+	if info == 31 {
+		next++ // break
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Key, entries[j].Key) < 0
+	})
+
+	for i := 1; i < len(entries); i++ {
+		if bytes.Equal(entries[i-1].Key, entries[i].Key) {
+			return nil, 0, &NonCanonicalError{
+				Path:   append([]string(nil), path...),
+				Reason: "duplicate map key " + describeKey(entries[i].Key),
+			}
+		}
+	}
+
+	out := appendHead(nil, 5, uint64(len(entries)))
+	for _, e := range entries {
+		out = append(out, e.Key...)
+		out = append(out, e.Value...)
+	}
+
+	return out, next, nil
+}
+
+// appendHead appends the shortest encoding of the head of a data item of the
+// specified major type and argument.
+func appendHead(out []byte, major byte, arg uint64) []byte {
+	initial := major << 5
+
+	switch {
+	case arg < 24:
+		return append(out, initial|byte(arg))
+	case arg <= 0xff:
+		return append(out, initial|24, byte(arg))
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(out, initial|25), uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(out, initial|26), uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64(append(out, initial|27), arg)
+	}
+}
+
+// describeKey returns a human-readable rendition of the (canonically encoded)
+// map key
+func describeKey(key []byte) string {
+	if k := decodeKey(key); k != nil {
+		return formatCBORKey(k)
+	}
+
+	return "0x" + hex.EncodeToString(key)
+}
+
+// decodeKey returns the encoded map key in the representation used by
+// structFieldsCBOR, or nil if it is not an integer or a text string.
+func decodeKey(key []byte) any {
+	var v any
+	if err := cbor.Unmarshal(key, &v); err != nil {
+		return nil
+	}
+
+	k, err := normalizeCBORKey(v)
+	if err != nil {
+		return nil
+	}
+
+	return k
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+func isEmbeddedCBOR(t reflect.Type) bool {
+	return t != nil && reflect.PointerTo(t).Implements(reflect.TypeOf((*IEmbeddedCBOR)(nil)).Elem())
+}
+
+// embeddedType returns the type of the data item embedded in a byte string of
+// type t, if it is known.
+func embeddedType(t reflect.Type, content []byte) reflect.Type {
+	if content[0]>>5 != 6 {
+		return nil
+	}
+
+	c := &canonicalizer{data: content}
+	_, _, number, _ := c.head(0, nil, "")
+
+	ec := reflect.New(t).Interface().(IEmbeddedCBOR)
+
+	return typeOf(ec.EmbeddedCBORType(number))
+}
+
+// mapValueHint returns the path segment and the expected type of the value of
+// the entry with the specified (canonically encoded) key, in a map that is
+// expected to decode to a value of type t.
+func mapValueHint(t reflect.Type, key []byte) (string, reflect.Type) {
+	segment := describeKey(key)
+	k := decodeKey(key)
+
+	if t == nil {
+		return segment, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		return segment, t.Elem()
+	case reflect.Struct:
+		if field, ok := findCBORField(t, k); ok {
+			return field.Segment, t.FieldByIndex(field.path).Type
+		}
+	}
+
+	return segment, nil
+}
+
+type cborFieldHint struct {
+	fieldPlan
+	path []int
+}
+
+// findCBORField looks up the field of struct type t (or of the structs it
+// statically embeds) that is mapped onto the specified CBOR key.
+func findCBORField(t reflect.Type, key any) (cborFieldHint, bool) {
+	if key == nil {
+		return cborFieldHint{}, false
+	}
+
+	plan := getStructPlan(t)
+
+	for _, f := range plan.CBOR {
+		if f.Key == key {
+			return cborFieldHint{fieldPlan: f, path: []int{f.Index}}, true
+		}
+	}
+
+	for _, i := range plan.Embeds {
+		embeddedType := t.Field(i).Type
+		if embeddedType.Kind() != reflect.Struct {
+			continue
+		}
+
+		if f, ok := findCBORField(embeddedType, key); ok {
+			f.path = append([]int{i}, f.path...)
+			return f, true
+		}
+	}
+
+	return cborFieldHint{}, false
+}
+
+// arrayElementHint returns the path segment and the expected type of the
+// element at index i of an array that is expected to decode to a value of
+// type t.
+func arrayElementHint(t reflect.Type, i int) (string, reflect.Type) {
+	segment := IndexSegment(i)
+
+	if t == nil {
+		return segment, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return segment, nil
+		}
+
+		return segment, t.Elem()
+	case reflect.Struct:
+		if isToArray(t) {
+			// elements correspond to the exported fields, in order
+			n := 0
+			for j := 0; j < t.NumField(); j++ {
+				field := t.Field(j)
+				if !field.IsExported() {
+					continue
+				}
+
+				if n == i {
+					return fieldSegment(field.Name, field.Tag.Get("json")), field.Type
+				}
+
+				n++
+			}
+
+			return segment, nil
+		}
+
+		// collections (e.g. extensions.Collection) hold their elements
+		// in a Values slice
+		if field, ok := t.FieldByName("Values"); ok && field.Type.Kind() == reflect.Slice {
+			return segment, field.Type.Elem()
+		}
+	}
+
+	return segment, nil
+}
+
+func isToArray(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name != "_" {
+			continue
+		}
+
+		_, options := splitTag(field.Tag.Get("cbor"))
+		if hasOption(options, "toarray") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,263 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDeterministicCBOR(t *testing.T) {
+	testCases := []struct {
+		name      string
+		data      []byte
+		canonical []byte
+		err       string
+	}{
+		{
+			name:      "canonical",
+			data:      []byte{0xa2, 0x01, 0x82, 0x18, 0x18, 0xf9, 0x3c, 0x00, 0x20, 0xc1, 0x40},
+			canonical: []byte{0xa2, 0x01, 0x82, 0x18, 0x18, 0xf9, 0x3c, 0x00, 0x20, 0xc1, 0x40},
+		},
+		{
+			name:      "non-shortest integer",
+			data:      []byte{0x81, 0x19, 0x00, 0x01},
+			canonical: []byte{0x81, 0x01},
+			err:       "non-canonical encoding at [0]: integer not in shortest form",
+		},
+		{
+			name:      "non-shortest negative integer",
+			data:      []byte{0x38, 0x00},
+			canonical: []byte{0x20},
+			err:       "non-canonical encoding: integer not in shortest form",
+		},
+		{
+			name:      "non-shortest length",
+			data:      []byte{0x58, 0x01, 0xaa},
+			canonical: []byte{0x41, 0xaa},
+			err:       "non-canonical encoding: string length not in shortest form",
+		},
+		{
+			name:      "non-shortest tag number",
+			data:      []byte{0xd8, 0x01, 0x00},
+			canonical: []byte{0xc1, 0x00},
+			err:       "non-canonical encoding: tag number not in shortest form",
+		},
+		{
+			name:      "non-shortest float",
+			data:      []byte{0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			canonical: []byte{0xf9, 0x3c, 0x00},
+			err:       "non-canonical encoding: float not in shortest form",
+		},
+		{
+			name:      "indefinite-length array",
+			data:      []byte{0x9f, 0x01, 0x02, 0xff},
+			canonical: []byte{0x82, 0x01, 0x02},
+			err:       "non-canonical encoding: indefinite-length array",
+		},
+		{
+			name:      "indefinite-length map",
+			data:      []byte{0xa1, 0x01, 0xbf, 0x02, 0x03, 0xff},
+			canonical: []byte{0xa1, 0x01, 0xa1, 0x02, 0x03},
+			err:       "non-canonical encoding at 1: indefinite-length map",
+		},
+		{
+			name:      "indefinite-length string",
+			data:      []byte{0x7f, 0x61, 0x61, 0x61, 0x62, 0xff},
+			canonical: []byte{0x62, 0x61, 0x62},
+			err:       "non-canonical encoding: indefinite-length string",
+		},
+		{
+			name:      "unsorted keys",
+			data:      []byte{0xa3, 0x20, 0x00, 0x01, 0x00, 0x61, 0x61, 0x00},
+			canonical: []byte{0xa3, 0x01, 0x00, 0x20, 0x00, 0x61, 0x61, 0x00},
+			err:       "non-canonical encoding: map key 1 out of order",
+		},
+		{
+			// bytewise order, unlike the length-first order of the
+			// "canonical CBOR" of RFC 7049
+			name:      "bytewise key order",
+			data:      []byte{0xa2, 0x18, 0x18, 0x00, 0x20, 0x00},
+			canonical: []byte{0xa2, 0x18, 0x18, 0x00, 0x20, 0x00},
+		},
+		{
+			name: "duplicate keys",
+			data: []byte{0x81, 0xa3, 0x01, 0x00, 0x02, 0x00, 0x01, 0x01},
+			err:  "non-canonical encoding at [0]: duplicate map key 1",
+		},
+		{
+			name: "duplicate non-shortest keys",
+			data: []byte{0xa2, 0x01, 0x00, 0x18, 0x01, 0x00},
+			err:  "non-canonical encoding: duplicate map key 1",
+		},
+		{
+			name: "duplicate text keys",
+			data: []byte{0xa2, 0x61, 0x61, 0x00, 0x61, 0x61, 0x00},
+			err:  `non-canonical encoding: duplicate map key "a"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckDeterministicCBOR(tc.data, nil)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+				assert.ErrorIs(t, err, ErrNonCanonical)
+			}
+
+			out, err := CanonicalizeCBOR(tc.data, nil)
+			if tc.canonical == nil {
+				var nce *NonCanonicalError
+				assert.True(t, errors.As(err, &nce))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.canonical, out)
+			assert.NoError(t, CheckDeterministicCBOR(out, nil))
+		})
+	}
+}
+
+func TestCheckDeterministicCBOR_malformed(t *testing.T) {
+	err := CheckDeterministicCBOR([]byte{0x82, 0x01}, nil)
+	assert.ErrorContains(t, err, "malformed CBOR")
+	assert.NotErrorIs(t, err, ErrNonCanonical)
+
+	_, err = CanonicalizeCBOR([]byte{0x01, 0x02}, nil)
+	assert.ErrorContains(t, err, "malformed CBOR")
+}
+
+type testCanonicalInner struct {
+	_     struct{} `cbor:",toarray"`
+	Label string   `json:"label"`
+	Value []int    `json:"value"`
+}
+
+type testCanonicalEmbedded struct {
+	Extra int `cbor:"-1,keyasint" json:"extra"`
+}
+
+type testCanonicalTag []byte
+
+func (o testCanonicalTag) EmbeddedCBORType(tag uint64) any {
+	if tag == 1 {
+		return testCanonicalOuter{}
+	}
+
+	return nil
+}
+
+type testCanonicalOuter struct {
+	Inner    *testCanonicalInner `cbor:"0,keyasint" json:"inner"`
+	Named    map[string]int      `cbor:"1,keyasint" json:"named"`
+	Embedded []testCanonicalTag  `cbor:"2,keyasint" json:"embedded"`
+
+	testCanonicalEmbedded
+}
+
+func TestCheckDeterministicCBOR_path(t *testing.T) {
+	testCases := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{
+			name: "toarray struct field",
+			data: []byte{
+				0xa1, 0x00, // {0:
+				0x82, 0x61, 0x61, // ["a",
+				0x82, 0x01, 0x18, 0x02, // [1, 2]]}
+			},
+			err: "non-canonical encoding at inner.value[1]: integer not in shortest form",
+		},
+		{
+			name: "map value",
+			data: []byte{
+				0xa1, 0x01, // {1:
+				0xa1, 0x61, 0x78, 0x18, 0x01, // {"x": 1}}
+			},
+			err: `non-canonical encoding at named."x": integer not in shortest form`,
+		},
+		{
+			name: "embedded struct field",
+			data: []byte{0xa1, 0x20, 0x9f, 0xff},
+			err:  "non-canonical encoding at extra: indefinite-length array",
+		},
+		{
+			name: "unknown key",
+			data: []byte{0xa1, 0x07, 0x9f, 0xff},
+			err:  "non-canonical encoding at 7: indefinite-length array",
+		},
+		{
+			name: "embedded CBOR",
+			data: []byte{
+				0xa1, 0x02, 0x81, // {2: [
+				0x46,                               // bstr(6)
+				0xc1, 0xa1, 0x00, 0x82, 0x60, 0x9f, // 1({0: ["", [_
+				// the content of the bstr is not a well-formed
+				// data item, so it is left untouched
+			},
+		},
+		{
+			name: "embedded CBOR with issues",
+			data: []byte{
+				0xa1, 0x02, 0x81, // {2: [
+				0x47,                                     // bstr(7)
+				0xc1, 0xa1, 0x00, 0x82, 0x60, 0x9f, 0xff, // 1({0: ["", [_ ]]})
+			},
+			err: "non-canonical encoding at embedded[0].inner.value: indefinite-length array",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckDeterministicCBOR(tc.data, &testCanonicalOuter{})
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeCBOR_embedded(t *testing.T) {
+	data := []byte{
+		0xa2,       // map(2)
+		0x20, 0x00, // -1: 0
+		0x02, 0x81, // 2: [
+		0x48,                                           // bstr(8)
+		0xc1, 0xa1, 0x00, 0x82, 0x60, 0x9f, 0x01, 0xff, // 1({0: ["", [_ 1]]})
+	}
+
+	out, err := CanonicalizeCBOR(data, testCanonicalOuter{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		0xa2,
+		0x02, 0x81,
+		0x47,
+		0xc1, 0xa1, 0x00, 0x82, 0x60, 0x81, 0x01,
+		0x20, 0x00,
+	}, out)
+
+	// without the type, the byte string is not known to contain CBOR
+	out, err = CanonicalizeCBOR(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte{0xa2, 0x02, 0x81}, append(data[5:], 0x20, 0x00)...), out)
+}
+
+func TestCheckCBORInput(t *testing.T) {
+	data := []byte{0x9f, 0xff}
+
+	dec := NewDecoder(wellformedDM)
+	assert.NoError(t, CheckCBORInput(dec, data, nil))
+
+	err := CheckCBORInput(dec.WithOptions(DecodeOptions{Deterministic: true}), data, nil)
+	assert.ErrorIs(t, err, ErrNonCanonical)
+}
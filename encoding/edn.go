@@ -0,0 +1,1057 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/x448/float16"
+)
+
+// FormatEDN renders CBOR data in the Extended Diagnostic Notation (EDN) of
+// RFC 8949 (section 8) and RFC 8610 (appendix G). v is optional: if specified,
+// it is a value of the type data decodes to, and is used to annotate the keys
+// of maps decoding to structs with the names of the corresponding fields in
+// the CDDL of the relevant specification (e.g. "/ comid.tag-identity / 1"),
+// and to render byte strings containing CBOR (e.g. the tags carried in a
+// CoRIM) as embedded data items ("<< ... >>").
+//
+// Non-preferred encodings are rendered with encoding indicators, and
+// indefinite-length items as such, so that ParseEDN reproduces data exactly
+// (with the exception of NaN payloads).
+func FormatEDN(data []byte, v any) (string, error) {
+	if err := wellformedDM.Wellformed(data); err != nil {
+		return "", fmt.Errorf("malformed CBOR: %w", err)
+	}
+
+	p := &ednPrinter{data: data}
+	p.item(0, typeOf(v), 0)
+
+	return p.b.String(), nil
+}
+
+const ednIndent = "  "
+
+type ednPrinter struct {
+	data []byte
+	b    strings.Builder
+}
+
+// sub renders the data item at pos of the input using a separate printer, and
+// returns its rendition along with the position following the item.
+func (o *ednPrinter) sub(pos int, t reflect.Type, depth int) (string, int) {
+	p := &ednPrinter{data: o.data}
+	next := p.item(pos, t, depth)
+
+	return p.b.String(), next
+}
+
+// indicator returns the encoding indicator for the head spanning pos to next,
+// or an empty string if it is in its preferred form.
+func (o *ednPrinter) indicator(pos int, next int, major byte, info byte, arg uint64) string {
+	if isPreferredHead(o.data[pos:next], major, info, arg) {
+		return ""
+	}
+
+	return "_" + strconv.Itoa(int(info-24))
+}
+
+// item renders the data item at pos, which is expected to decode to a value
+// of type t (if not nil), and returns the position following it. depth is the
+// indentation level of the line the item starts on.
+func (o *ednPrinter) item(pos int, t reflect.Type, depth int) int {
+	t = derefType(t)
+
+	major, info, arg, next := readHead(o.data, pos)
+	indicator := o.indicator(pos, next, major, info, arg)
+
+	switch major {
+	case 0: // unsigned integers
+		fmt.Fprintf(&o.b, "%d%s", arg, indicator)
+	case 1: // negative integers
+		n := new(big.Int).SetUint64(arg)
+		n.Add(n, big.NewInt(1))
+		fmt.Fprintf(&o.b, "-%s%s", n, indicator)
+	case 2, 3: // byte and text strings
+		if info == 31 {
+			return o.indefiniteString(next, major, depth)
+		}
+
+		end := next + int(arg)
+		content := o.data[next:end]
+
+		switch {
+		case major == 3:
+			o.b.WriteString(quoteEDN(string(content)))
+		case len(content) != 0 && isEmbeddedCBOR(t) && wellformedDM.Wellformed(content) == nil:
+			p := &ednPrinter{data: content}
+			p.item(0, embeddedType(t, content), depth)
+			fmt.Fprintf(&o.b, "<<%s>>", p.b.String())
+		default:
+			fmt.Fprintf(&o.b, "h'%x'", content)
+		}
+
+		o.b.WriteString(indicator)
+
+		return end
+	case 4: // arrays
+		return o.array(next, t, depth, containerLength(info, arg), indicator)
+	case 5: // maps
+		return o.mapAt(next, t, depth, containerLength(info, arg), indicator)
+	case 6: // tags
+		fmt.Fprintf(&o.b, "%d%s(", arg, indicator)
+		next = o.item(next, t, depth)
+		o.b.WriteString(")")
+	default: // simple values and floats
+		o.simpleOrFloat(pos, next, info, arg)
+	}
+
+	return next
+}
+
+// indefiniteString renders the chunks of an indefinite-length string starting
+// at pos, and returns the position following the "break".
+func (o *ednPrinter) indefiniteString(pos int, major byte, depth int) int {
+	if o.data[pos] == 0xff {
+		// there is no chunk to tell the type of the string from
+		if major == 2 {
+			o.b.WriteString("''_")
+		} else {
+			o.b.WriteString(`""_`)
+		}
+
+		return pos + 1
+	}
+
+	o.b.WriteString("(_ ")
+
+	for i := 0; o.data[pos] != 0xff; i++ {
+		if i > 0 {
+			o.b.WriteString(", ")
+		}
+
+		pos = o.item(pos, nil, depth)
+	}
+
+	o.b.WriteString(")")
+
+	return pos + 1
+}
+
+func (o *ednPrinter) array(pos int, t reflect.Type, depth int, count int, indicator string) int {
+	indefinite := count < 0
+
+	var elements []string
+
+	multiline := false
+
+	for i := 0; indefinite && o.data[pos] != 0xff || !indefinite && i < count; i++ {
+		_, elemType := arrayElementHint(t, i)
+
+		elem, next := o.sub(pos, elemType, depth+1)
+		if strings.Contains(elem, "\n") {
+			multiline = true
+		}
+
+		elements = append(elements, elem)
+		pos = next
+	}
+
+	if indefinite {
+		pos++ // break
+	}
+
+	opening := openingIndicator(indefinite, indicator)
+
+	o.b.WriteString("[" + opening)
+
+	if multiline {
+		o.lines(elements, depth)
+	} else {
+		if opening != "" && (indefinite || len(elements) != 0) {
+			o.b.WriteString(" ")
+		}
+		o.b.WriteString(strings.Join(elements, ", "))
+	}
+
+	o.b.WriteString("]")
+
+	return pos
+}
+
+func (o *ednPrinter) mapAt(pos int, t reflect.Type, depth int, count int, indicator string) int {
+	indefinite := count < 0
+
+	var entries []string
+
+	for i := 0; indefinite && o.data[pos] != 0xff || !indefinite && i < count; i++ {
+		key, next := o.sub(pos, nil, depth+1)
+
+		spec, valueType := mapEntryHint(t, o.data[pos:next])
+		if spec != "" {
+			key = fmt.Sprintf("/ %s / %s", spec, key)
+		}
+
+		value, next := o.sub(next, valueType, depth+1)
+
+		entries = append(entries, key+" : "+value)
+		pos = next
+	}
+
+	if indefinite {
+		pos++ // break
+	}
+
+	opening := openingIndicator(indefinite, indicator)
+
+	o.b.WriteString("{" + opening)
+
+	if indefinite && len(entries) == 0 {
+		o.b.WriteString(" ")
+	}
+
+	o.lines(entries, depth)
+	o.b.WriteString("}")
+
+	return pos
+}
+
+// containerLength returns the number of elements (or entries) of an array (or
+// map), or -1 if it is indefinite-length.
+func containerLength(info byte, arg uint64) int {
+	if info == 31 {
+		return -1
+	}
+
+	return int(arg)
+}
+
+// openingIndicator returns what follows the opening bracket or brace of an
+// array or map: the indefinite-length or encoding indicator, if any.
+func openingIndicator(indefinite bool, indicator string) string {
+	if indefinite {
+		return "_"
+	}
+
+	return indicator
+}
+
+// lines writes the elements of a container one per line, indented one level
+// further than depth.
+func (o *ednPrinter) lines(elements []string, depth int) {
+	if len(elements) == 0 {
+		return
+	}
+
+	for i, e := range elements {
+		if i > 0 {
+			o.b.WriteString(",")
+		}
+
+		o.b.WriteString("\n")
+		o.b.WriteString(strings.Repeat(ednIndent, depth+1))
+		o.b.WriteString(e)
+	}
+
+	o.b.WriteString("\n")
+	o.b.WriteString(strings.Repeat(ednIndent, depth))
+}
+
+func (o *ednPrinter) simpleOrFloat(pos int, next int, info byte, arg uint64) {
+	switch {
+	case info == 20:
+		o.b.WriteString("false")
+	case info == 21:
+		o.b.WriteString("true")
+	case info == 22:
+		o.b.WriteString("null")
+	case info == 23:
+		o.b.WriteString("undefined")
+	case info <= 24:
+		fmt.Fprintf(&o.b, "simple(%d)", arg)
+	default:
+		var f float64
+		// the data is well-formed, so this cannot fail
+		_ = wellformedDM.Unmarshal(o.data[pos:next], &f)
+
+		o.b.WriteString(formatFloat(f))
+
+		if shortest, _ := shortestFloatEM.Marshal(f); !bytes.Equal(shortest, o.data[pos:next]) {
+			fmt.Fprintf(&o.b, "_%d", info-24)
+		}
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		// distinguish it from an integer
+		s += ".0"
+	}
+
+	return s
+}
+
+// quoteEDN returns s as an EDN text string literal, escaping it as per JSON.
+func quoteEDN(s string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// mapEntryHint returns the specification name of the field corresponding to
+// the specified (encoded) key, if any, and the expected type of the entry's
+// value, in a map that is expected to decode to a value of type t.
+func mapEntryHint(t reflect.Type, key []byte) (string, reflect.Type) {
+	_, valueType := mapValueHint(t, key)
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", valueType
+	}
+
+	if field, ok := findCBORField(t, decodeKey(key)); ok {
+		return field.Spec, valueType
+	}
+
+	return "", valueType
+}
+
+// ParseEDN returns the CBOR encoding of the single data item described in
+// Extended Diagnostic Notation (RFC 8949, section 8, and RFC 8610, appendix
+// G) by edn. The following are supported: comments ("/ ... /" and "#" to the
+// end of the line); integers (including the "0x", "0o" and "0b" forms);
+// floats (including NaN and Infinity); text strings; byte strings (h'...',
+// b64'...' and '...'); embedded data items (<< ... >>); arrays; maps; tags;
+// simple values; indefinite-length items ("[_ ...]", "{_ ...}",
+// "(_ chunk, ...)") and encoding indicators (e.g. "1_0"). Items without an
+// encoding indicator get their preferred (shortest) encoding.
+func ParseEDN(edn string) ([]byte, error) {
+	p := &ednParser{src: edn}
+
+	out, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.src) {
+		return nil, p.errorf("unexpected %q after data item", p.peekRune())
+	}
+
+	return out, nil
+}
+
+const (
+	noIndicator         = -1
+	indefiniteIndicator = -2
+)
+
+type ednParser struct {
+	src string
+	pos int
+}
+
+func (o *ednParser) errorf(format string, args ...any) error {
+	line := 1 + strings.Count(o.src[:o.pos], "\n")
+	column := 1 + utf8.RuneCountInString(o.src[strings.LastIndex(o.src[:o.pos], "\n")+1:o.pos])
+
+	return fmt.Errorf("EDN syntax error at line %d, column %d: %s",
+		line, column, fmt.Sprintf(format, args...))
+}
+
+func (o *ednParser) peekRune() rune {
+	r, _ := utf8.DecodeRuneInString(o.src[o.pos:])
+	return r
+}
+
+func (o *ednParser) rest() string {
+	return o.src[o.pos:]
+}
+
+// skipSpace skips white space and comments.
+func (o *ednParser) skipSpace() error {
+	for o.pos < len(o.src) {
+		switch o.src[o.pos] {
+		case ' ', '\t', '\n', '\r':
+			o.pos++
+		case '/':
+			end := strings.IndexByte(o.src[o.pos+1:], '/')
+			if end < 0 {
+				return o.errorf("unterminated comment")
+			}
+			o.pos += end + 2
+		case '#':
+			end := strings.IndexByte(o.src[o.pos:], '\n')
+			if end < 0 {
+				o.pos = len(o.src)
+			} else {
+				o.pos += end + 1
+			}
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// expect skips white space and comments, then consumes s.
+func (o *ednParser) expect(s string) error {
+	if err := o.skipSpace(); err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(o.rest(), s) {
+		if o.pos == len(o.src) {
+			return o.errorf("expected %q, found end of input", s)
+		}
+		return o.errorf("expected %q, found %q", s, o.peekRune())
+	}
+
+	o.pos += len(s)
+
+	return nil
+}
+
+// indicator consumes the encoding indicator at the current position, if any.
+func (o *ednParser) indicator() int {
+	rest := o.rest()
+
+	if !strings.HasPrefix(rest, "_") {
+		return noIndicator
+	}
+
+	o.pos++
+
+	if len(rest) > 1 && rest[1] >= '0' && rest[1] <= '3' {
+		o.pos++
+		return int(rest[1] - '0')
+	}
+
+	return indefiniteIndicator
+}
+
+// head returns the encoding of a head with the specified major type and
+// argument, as per the encoding indicator.
+func (o *ednParser) head(major byte, arg uint64, indicator int) ([]byte, error) {
+	switch indicator {
+	case noIndicator:
+		return appendHead(nil, major, arg), nil
+	case indefiniteIndicator:
+		return nil, o.errorf("unexpected indefinite-length indicator")
+	}
+
+	size := 1 << indicator
+	if size < 8 && arg >= 1<<(8*size) {
+		return nil, o.errorf("%d does not fit encoding indicator _%d", arg, indicator)
+	}
+
+	out := []byte{major<<5 | byte(24+indicator)}
+	for i := size - 1; i >= 0; i-- {
+		out = append(out, byte(arg>>(8*i)))
+	}
+
+	return out, nil
+}
+
+// nolint:gocyclo
+func (o *ednParser) value() ([]byte, error) {
+	if err := o.skipSpace(); err != nil {
+		return nil, err
+	}
+
+	if o.pos == len(o.src) {
+		return nil, o.errorf("unexpected end of input")
+	}
+
+	rest := o.rest()
+
+	switch c := rest[0]; {
+	case c == '[':
+		return o.container(']', 4)
+	case c == '{':
+		return o.container('}', 5)
+	case c == '(':
+		return o.indefiniteString()
+	case strings.HasPrefix(rest, "<<"):
+		return o.embedded()
+	case c == '"':
+		o.pos++
+		content, err := o.quoted('"')
+		if err != nil {
+			return nil, err
+		}
+		return o.stringItem(3, content)
+	case c == '\'':
+		o.pos++
+		content, err := o.quoted('\'')
+		if err != nil {
+			return nil, err
+		}
+		return o.stringItem(2, content)
+	case strings.HasPrefix(rest, "h'"):
+		return o.encodedBytes(2, func(s string) ([]byte, error) {
+			return hex.DecodeString(s)
+		})
+	case strings.HasPrefix(rest, "b64'"):
+		return o.encodedBytes(4, decodeBase64)
+	case c == '-' || c >= '0' && c <= '9' || strings.HasPrefix(rest, "Infinity") || strings.HasPrefix(rest, "NaN"):
+		return o.number()
+	case c >= 'a' && c <= 'z':
+		return o.simple()
+	default:
+		return nil, o.errorf("unexpected %q", o.peekRune())
+	}
+}
+
+// container parses an array or a map, depending on major.
+func (o *ednParser) container(closing byte, major byte) ([]byte, error) {
+	o.pos++ // open
+
+	indicator := o.indicator()
+
+	var (
+		content []byte
+		count   uint64
+	)
+
+	for {
+		if err := o.skipSpace(); err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(o.rest(), string(closing)) {
+			o.pos++
+			break
+		}
+
+		if count > 0 {
+			if err := o.expect(","); err != nil {
+				return nil, err
+			}
+
+			// allow a trailing comma
+			if err := o.skipSpace(); err != nil {
+				return nil, err
+			}
+
+			if strings.HasPrefix(o.rest(), string(closing)) {
+				o.pos++
+				break
+			}
+		}
+
+		item, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, item...)
+
+		if major == 5 {
+			if err := o.expect(":"); err != nil {
+				return nil, err
+			}
+
+			value, err := o.value()
+			if err != nil {
+				return nil, err
+			}
+			content = append(content, value...)
+		}
+
+		count++
+	}
+
+	if indicator == indefiniteIndicator {
+		out := append([]byte{major<<5 | 31}, content...)
+		return append(out, 0xff), nil
+	}
+
+	head, err := o.head(major, count, indicator)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(head, content...), nil
+}
+
+// indefiniteString parses "(_ chunk, ...)".
+func (o *ednParser) indefiniteString() ([]byte, error) {
+	o.pos++ // (
+
+	if o.indicator() != indefiniteIndicator {
+		return nil, o.errorf(`expected "_" after "("`)
+	}
+
+	var (
+		out   []byte
+		major byte
+	)
+
+	for i := 0; ; i++ {
+		if err := o.skipSpace(); err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(o.rest(), ")") {
+			o.pos++
+			break
+		}
+
+		if i > 0 {
+			if err := o.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := o.skipSpace(); err != nil {
+			return nil, err
+		}
+
+		start := o.pos
+
+		chunk, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+
+		chunkMajor := chunk[0] >> 5
+		if chunkMajor != 2 && chunkMajor != 3 || chunk[0]&0x1f == 31 || i > 0 && chunkMajor != major {
+			o.pos = start
+			return nil, o.errorf("chunks must be definite-length strings of the same type")
+		}
+
+		if i == 0 {
+			major = chunkMajor
+			out = []byte{major<<5 | 31}
+		}
+
+		out = append(out, chunk...)
+	}
+
+	if out == nil {
+		return nil, o.errorf(`empty indefinite-length string: use ''_ or ""_`)
+	}
+
+	return append(out, 0xff), nil
+}
+
+// embedded parses "<< item, ... >>" into a byte string holding the encoded
+// items.
+func (o *ednParser) embedded() ([]byte, error) {
+	o.pos += 2 // <<
+
+	var content []byte
+
+	for i := 0; ; i++ {
+		if err := o.skipSpace(); err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(o.rest(), ">>") {
+			o.pos += 2
+			break
+		}
+
+		if i > 0 {
+			if err := o.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		item, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+
+		content = append(content, item...)
+	}
+
+	head, err := o.head(2, uint64(len(content)), o.indicator())
+	if err != nil {
+		return nil, err
+	}
+
+	return append(head, content...), nil
+}
+
+// stringItem encodes a string of the specified major type, as per the
+// encoding indicator following its literal.
+func (o *ednParser) stringItem(major byte, content []byte) ([]byte, error) {
+	indicator := o.indicator()
+
+	if indicator == indefiniteIndicator {
+		if len(content) != 0 {
+			return nil, o.errorf("only empty strings can be marked as indefinite-length")
+		}
+
+		return []byte{major<<5 | 31, 0xff}, nil
+	}
+
+	head, err := o.head(major, uint64(len(content)), indicator)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(head, content...), nil
+}
+
+// encodedBytes parses a byte string whose content is encoded as per decode,
+// and whose literal has a prefix of the specified length (e.g. "h'").
+func (o *ednParser) encodedBytes(prefixLen int, decode func(string) ([]byte, error)) ([]byte, error) {
+	o.pos += prefixLen
+
+	end := strings.IndexByte(o.rest(), '\'')
+	if end < 0 {
+		return nil, o.errorf("unterminated byte string")
+	}
+
+	encoded := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" \t\n\r", r) {
+			return -1
+		}
+		return r
+	}, o.rest()[:end])
+
+	content, err := decode(encoded)
+	if err != nil {
+		return nil, o.errorf("invalid byte string: %v", err)
+	}
+
+	o.pos += end + 1
+
+	return o.stringItem(2, content)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	encoding := base64.StdEncoding
+	if strings.ContainsAny(s, "-_") {
+		encoding = base64.URLEncoding
+	}
+
+	if !strings.HasSuffix(s, "=") {
+		encoding = encoding.WithPadding(base64.NoPadding)
+	}
+
+	return encoding.DecodeString(s)
+}
+
+// quoted parses the content of a string literal up to the (unescaped) quote
+// character, using JSON escapes.
+func (o *ednParser) quoted(quote byte) ([]byte, error) {
+	var out []byte
+
+	for {
+		if o.pos == len(o.src) {
+			return nil, o.errorf("unterminated string")
+		}
+
+		c := o.src[o.pos]
+		o.pos++
+
+		switch c {
+		case quote:
+			return out, nil
+		case '\\':
+			r, err := o.escape()
+			if err != nil {
+				return nil, err
+			}
+			out = utf8.AppendRune(out, r)
+		default:
+			out = append(out, c)
+		}
+	}
+}
+
+func (o *ednParser) escape() (rune, error) {
+	if o.pos == len(o.src) {
+		return 0, o.errorf("unterminated string")
+	}
+
+	c := o.src[o.pos]
+	o.pos++
+
+	switch c {
+	case '"', '\'', '\\', '/':
+		return rune(c), nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'u':
+		r, err := o.hex4()
+		if err != nil {
+			return 0, err
+		}
+
+		if utf16.IsSurrogate(r) && strings.HasPrefix(o.rest(), `\u`) {
+			o.pos += 2
+
+			r2, err := o.hex4()
+			if err != nil {
+				return 0, err
+			}
+
+			r = utf16.DecodeRune(r, r2)
+		}
+
+		return r, nil
+	default:
+		o.pos--
+		return 0, o.errorf("invalid escape %q", "\\"+string(c))
+	}
+}
+
+func (o *ednParser) hex4() (rune, error) {
+	if len(o.rest()) < 4 {
+		return 0, o.errorf("invalid \\u escape")
+	}
+
+	v, err := strconv.ParseUint(o.rest()[:4], 16, 16)
+	if err != nil {
+		return 0, o.errorf("invalid \\u escape")
+	}
+
+	o.pos += 4
+
+	return rune(v), nil
+}
+
+// number parses an integer, a float, or a tag.
+func (o *ednParser) number() ([]byte, error) {
+	start := o.pos
+
+	token := o.numberToken()
+
+	digits := strings.TrimPrefix(token, "-")
+	base := 10
+
+	if len(digits) > 1 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+
+		if base != 10 {
+			digits = digits[2:]
+		}
+	}
+
+	if base == 10 && (strings.ContainsAny(digits, ".eE") || digits == "Infinity" || digits == "NaN") {
+		f, err := strconv.ParseFloat(strings.Replace(token, "Infinity", "Inf", 1), 64)
+		if err != nil {
+			o.pos = start
+			return nil, o.errorf("invalid number %q", token)
+		}
+
+		return o.float(f)
+	}
+
+	n, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		o.pos = start
+		return nil, o.errorf("invalid number %q", token)
+	}
+
+	negative := strings.HasPrefix(token, "-") && n.Sign() != 0
+	if negative {
+		// -1 - n
+		n.Add(n, big.NewInt(-1))
+	}
+
+	if !n.IsUint64() {
+		o.pos = start
+		return nil, o.errorf("integer out of range: %s", token)
+	}
+
+	indicator := o.indicator()
+
+	if strings.HasPrefix(o.rest(), "(") {
+		if negative {
+			return nil, o.errorf("tag number must be non-negative")
+		}
+
+		return o.tag(n.Uint64(), indicator)
+	}
+
+	major := byte(0)
+	if negative {
+		major = 1
+	}
+
+	return o.head(major, n.Uint64(), indicator)
+}
+
+func (o *ednParser) numberToken() string {
+	start := o.pos
+
+	if strings.HasPrefix(o.rest(), "-") {
+		o.pos++
+	}
+
+	for o.pos < len(o.src) {
+		c := o.src[o.pos]
+
+		isExponentSign := (c == '+' || c == '-') &&
+			strings.ContainsRune("eE", rune(o.src[o.pos-1])) &&
+			!strings.Contains(strings.ToLower(o.src[start:o.pos]), "0x")
+
+		if c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '.' || isExponentSign {
+			o.pos++
+			continue
+		}
+
+		break
+	}
+
+	return o.src[start:o.pos]
+}
+
+func (o *ednParser) float(f float64) ([]byte, error) {
+	switch indicator := o.indicator(); indicator {
+	case noIndicator:
+		return shortestFloatEM.Marshal(f)
+	case 1:
+		return binary.BigEndian.AppendUint16([]byte{0xf9}, float16.Fromfloat32(float32(f)).Bits()), nil
+	case 2:
+		return binary.BigEndian.AppendUint32([]byte{0xfa}, math.Float32bits(float32(f))), nil
+	case 3:
+		return binary.BigEndian.AppendUint64([]byte{0xfb}, math.Float64bits(f)), nil
+	default:
+		return nil, o.errorf("invalid encoding indicator for a float")
+	}
+}
+
+// tag parses the content of a tag with the specified number.
+func (o *ednParser) tag(number uint64, indicator int) ([]byte, error) {
+	o.pos++ // (
+
+	head, err := o.head(6, number, indicator)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := o.value()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return append(head, content...), nil
+}
+
+// simple parses the named simple values, and simple(N).
+func (o *ednParser) simple() ([]byte, error) {
+	start := o.pos
+
+	for o.pos < len(o.src) && o.src[o.pos] >= 'a' && o.src[o.pos] <= 'z' {
+		o.pos++
+	}
+
+	switch word := o.src[start:o.pos]; word {
+	case "false":
+		return []byte{0xf4}, nil
+	case "true":
+		return []byte{0xf5}, nil
+	case "null":
+		return []byte{0xf6}, nil
+	case "undefined":
+		return []byte{0xf7}, nil
+	case "simple":
+		if err := o.expect("("); err != nil {
+			return nil, err
+		}
+
+		if err := o.skipSpace(); err != nil {
+			return nil, err
+		}
+
+		numberStart := o.pos
+		token := o.numberToken()
+
+		n, err := strconv.ParseUint(token, 10, 8)
+		if err != nil || n >= 24 && n < 32 {
+			o.pos = numberStart
+			return nil, o.errorf("invalid simple value %q", token)
+		}
+
+		if err := o.expect(")"); err != nil {
+			return nil, err
+		}
+
+		if n < 24 {
+			return []byte{0xe0 | byte(n)}, nil
+		}
+
+		return []byte{0xf8, byte(n)}, nil
+	default:
+		o.pos = start
+		return nil, o.errorf("unexpected %q", word)
+	}
+}
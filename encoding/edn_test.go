@@ -0,0 +1,255 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEDN(t *testing.T) {
+	testCases := []struct {
+		name     string
+		edn      string
+		expected []byte
+	}{
+		{
+			name:     "integers",
+			edn:      "[0, 23, 24, -1, -25, 18446744073709551615, -18446744073709551616]",
+			expected: []byte{0x87, 0x00, 0x17, 0x18, 0x18, 0x20, 0x38, 0x18, 0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			name:     "integer bases",
+			edn:      "[0x10, 0o10, 0b10, -0x10]",
+			expected: []byte{0x84, 0x10, 0x08, 0x02, 0x2f},
+		},
+		{
+			name:     "encoding indicators",
+			edn:      "[1_0, 1_1, h'01'_0, 1_0(0), [_0 ], {_1 }]",
+			expected: []byte{0x86, 0x18, 0x01, 0x19, 0x00, 0x01, 0x58, 0x01, 0x01, 0xd8, 0x01, 0x00, 0x98, 0x00, 0xb9, 0x00, 0x00},
+		},
+		{
+			name:     "floats",
+			edn:      "[1.0, 1.5_2, 1e300, -0.0, Infinity, -Infinity, NaN, 1.0_3]",
+			expected: []byte{0x88, 0xf9, 0x3c, 0x00, 0xfa, 0x3f, 0xc0, 0x00, 0x00, 0xfb, 0x7e, 0x37, 0xe4, 0x3c, 0x88, 0x00, 0x75, 0x9c, 0xf9, 0x80, 0x00, 0xf9, 0x7c, 0x00, 0xf9, 0xfc, 0x00, 0xf9, 0x7e, 0x00, 0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name:     "simple values",
+			edn:      "[false, true, null, undefined, simple(16), simple(255)]",
+			expected: []byte{0x86, 0xf4, 0xf5, 0xf6, 0xf7, 0xf0, 0xf8, 0xff},
+		},
+		{
+			name:     "text strings",
+			edn:      `["a", "\"\\\né😀", "é"]`,
+			expected: []byte{0x83, 0x61, 0x61, 0x69, 0x22, 0x5c, 0x0a, 0xc3, 0xa9, 0xf0, 0x9f, 0x98, 0x80, 0x62, 0xc3, 0xa9},
+		},
+		{
+			name:     "byte strings",
+			edn:      `[h'01 02', b64'AQI', b64'AQI=', 'a\'', h'']`,
+			expected: []byte{0x85, 0x42, 0x01, 0x02, 0x42, 0x01, 0x02, 0x42, 0x01, 0x02, 0x42, 0x61, 0x27, 0x40},
+		},
+		{
+			name:     "embedded",
+			edn:      "<<1, [2]>>",
+			expected: []byte{0x43, 0x01, 0x81, 0x02},
+		},
+		{
+			name:     "indefinite-length items",
+			edn:      `[[_ 1], {_ 1: 2}, (_ h'01', h'02'), (_ "a"), ''_, ""_]`,
+			expected: []byte{0x86, 0x9f, 0x01, 0xff, 0xbf, 0x01, 0x02, 0xff, 0x5f, 0x41, 0x01, 0x41, 0x02, 0xff, 0x7f, 0x61, 0x61, 0xff, 0x5f, 0xff, 0x7f, 0xff},
+		},
+		{
+			name: "map with comments",
+			edn: `/ concise-mid-tag / {
+  / comid.tag-identity / 1 : {
+    / comid.tag-id / 0 : 37(h'00') # trailing comment
+  },
+  "x": -1,
+}`,
+			expected: []byte{0xa2, 0x01, 0xa1, 0x00, 0xd8, 0x25, 0x41, 0x00, 0x61, 0x78, 0x20},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := ParseEDN(tc.edn)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, out)
+		})
+	}
+}
+
+func TestParseEDN_errors(t *testing.T) {
+	testCases := []struct {
+		edn string
+		err string
+	}{
+		{
+			edn: "",
+			err: "EDN syntax error at line 1, column 1: unexpected end of input",
+		},
+		{
+			edn: "[1,\n  2 3]",
+			err: `EDN syntax error at line 2, column 5: expected ",", found '3'`,
+		},
+		{
+			edn: "{1 2}",
+			err: `EDN syntax error at line 1, column 4: expected ":", found '2'`,
+		},
+		{
+			edn: "1 2",
+			err: "EDN syntax error at line 1, column 3: unexpected '2' after data item",
+		},
+		{
+			edn: "18446744073709551616",
+			err: "EDN syntax error at line 1, column 1: integer out of range: 18446744073709551616",
+		},
+		{
+			edn: "256_0",
+			err: "EDN syntax error at line 1, column 6: 256 does not fit encoding indicator _0",
+		},
+		{
+			edn: "-1(0)",
+			err: "EDN syntax error at line 1, column 3: tag number must be non-negative",
+		},
+		{
+			edn: "h'0'",
+			err: "EDN syntax error at line 1, column 3: invalid byte string: encoding/hex: odd length hex string",
+		},
+		{
+			edn: `"abc`,
+			err: "EDN syntax error at line 1, column 5: unterminated string",
+		},
+		{
+			edn: `"a"_`,
+			err: "EDN syntax error at line 1, column 5: only empty strings can be marked as indefinite-length",
+		},
+		{
+			edn: `(_ h'01', "a")`,
+			err: "EDN syntax error at line 1, column 11: chunks must be definite-length strings of the same type",
+		},
+		{
+			edn: `(_ )`,
+			err: `EDN syntax error at line 1, column 5: empty indefinite-length string: use ''_ or ""_`,
+		},
+		{
+			edn: "simple(24)",
+			err: `EDN syntax error at line 1, column 8: invalid simple value "24"`,
+		},
+		{
+			edn: "/ unterminated",
+			err: "EDN syntax error at line 1, column 1: unterminated comment",
+		},
+		{
+			edn: "nope",
+			err: `EDN syntax error at line 1, column 1: unexpected "nope"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.edn, func(t *testing.T) {
+			_, err := ParseEDN(tc.edn)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+type testEDNInner struct {
+	_     struct{} `cbor:",toarray"`
+	Label string   `json:"label"`
+	Value []int    `json:"value"`
+}
+
+type testEDNTag []byte
+
+func (o testEDNTag) EmbeddedCBORType(tag uint64) any {
+	return testEDNOuter{}
+}
+
+type testEDNOuter struct {
+	Inner    *testEDNInner  `cbor:"0,keyasint" json:"inner" cddl:"test.inner"`
+	Named    map[string]int `cbor:"1,keyasint" json:"named"`
+	Embedded []testEDNTag   `cbor:"2,keyasint,omitempty" json:"embedded,omitempty"`
+}
+
+func TestFormatEDN(t *testing.T) {
+	data, err := ParseEDN(`{
+		0: ["a", [1, 2]],
+		1: {"x": 1},
+		2: [<<1({0: ["b", []]})>>],
+		3: h'00ff'
+	}`)
+	require.NoError(t, err)
+
+	out, err := FormatEDN(data, testEDNOuter{})
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  / test.inner / 0 : ["a", [1, 2]],
+  / named / 1 : {
+    "x" : 1
+  },
+  / embedded / 2 : [
+    <<1({
+      / test.inner / 0 : ["b", []]
+    })>>
+  ],
+  3 : h'00ff'
+}`, out)
+
+	// without a type, neither annotations nor embedded items
+	out, err = FormatEDN(data, nil)
+	require.NoError(t, err)
+	assert.Contains(t, out, "\n  0 : [")
+	assert.Contains(t, out, "2 : [h'c1a10082616280']")
+}
+
+func TestFormatEDN_roundtrip(t *testing.T) {
+	testCases := [][]byte{
+		{0x00},
+		{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0x18, 0x01},
+		{0x1a, 0x00, 0x00, 0x00, 0x01},
+		{0x58, 0x00},
+		{0x79, 0x00, 0x01, 0x61},
+		{0x5f, 0x41, 0x01, 0x42, 0x02, 0x03, 0xff},
+		{0x7f, 0x61, 0x61, 0xff},
+		{0x5f, 0xff},
+		{0x7f, 0xff},
+		{0x9f, 0xff},
+		{0x9f, 0x01, 0x81, 0x02, 0xff},
+		{0xbf, 0xff},
+		{0xbf, 0x01, 0x9f, 0xff, 0xff},
+		{0x98, 0x01, 0x00},
+		{0x98, 0x00},
+		{0xb8, 0x00},
+		{0xd8, 0x01, 0x00},
+		{0xd9, 0xd9, 0xf7, 0xa1, 0x61, 0x61, 0xf6},
+		{0xf9, 0x3c, 0x00},
+		{0xfa, 0x3f, 0x80, 0x00, 0x00},
+		{0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		{0xfb, 0x3f, 0xb9, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a},
+		{0xf9, 0x80, 0x00},
+		{0xf9, 0x7c, 0x00},
+		{0xfa, 0x7f, 0xc0, 0x00, 0x00},
+		{0xf8, 0x20},
+		{0xe0},
+		{0x62, 0x0a, 0x7f},
+		{0x82, 0x43, 0x01, 0x02, 0x03, 0x63, 0x22, 0x5c, 0x01},
+	}
+
+	for _, data := range testCases {
+		edn, err := FormatEDN(data, nil)
+		require.NoError(t, err)
+
+		out, err := ParseEDN(edn)
+		require.NoError(t, err, edn)
+		assert.Equal(t, data, out, edn)
+	}
+}
+
+func TestFormatEDN_malformed(t *testing.T) {
+	_, err := FormatEDN([]byte{0x82, 0x01}, nil)
+	assert.ErrorContains(t, err, "malformed CBOR")
+}
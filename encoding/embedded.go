@@ -6,46 +6,28 @@ import "reflect"
 
 const omitempty = "omitempty"
 
+// IEmbeddedValues is implemented by values that stand for several embedded
+// structs (e.g., extensions.Composite). Each of the returned values (pointers
+// to structs) is treated as if it was embedded in the containing struct.
+type IEmbeddedValues interface {
+	EmbeddedValues() []any
+}
+
 type embedded struct {
 	Type  reflect.Type
 	Value reflect.Value
 }
 
-// collectEmbedded returns true if the Field is embedded (regardless of
-// whether or not it was collected).
-func collectEmbedded(
-	typeField *reflect.StructField,
-	valField reflect.Value,
-	embeds *[]embedded,
-) bool {
-	// embedded fields are alway anonymous:w
+// isEmbedded returns true if the field is an embedded struct or interface,
+// whose fields (or those of its underlying value) are treated as fields of the
+// containing struct.
+func isEmbedded(typeField *reflect.StructField) bool {
+	// embedded fields are always anonymous
 	if !typeField.Anonymous {
 		return false
 	}
 
-	if typeField.Name == typeField.Type.Name() &&
+	return typeField.Name == typeField.Type.Name() &&
 		(typeField.Type.Kind() == reflect.Struct ||
-			typeField.Type.Kind() == reflect.Interface) {
-
-		var fieldType reflect.Type
-		var fieldValue reflect.Value
-
-		if typeField.Type.Kind() == reflect.Interface {
-			fieldValue = valField.Elem()
-			if fieldValue.Kind() == reflect.Invalid {
-				// no value underlying the interface
-				return true
-			}
-			// use the interface's underlying value's real type
-			fieldType = valField.Elem().Type()
-		} else {
-			fieldType = typeField.Type
-			fieldValue = valField
-		}
-
-		*embeds = append(*embeds, embedded{Type: fieldType, Value: fieldValue})
-		return true
-	}
-
-	return false
+			typeField.Type.Kind() == reflect.Interface)
 }
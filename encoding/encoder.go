@@ -0,0 +1,121 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"reflect"
+
+	cbor "github.com/fxamacker/cbor/v2"
+)
+
+// IEncoderCBORMarshaler is the encoding counterpart of IDecoderCBORUnmarshaler
+type IEncoderCBORMarshaler interface {
+	MarshalCBORWith(enc *Encoder) ([]byte, error)
+}
+
+// Encoder is the encoding counterpart of Decoder: it carries the state of a
+// single encode operation down to the nested values being encoded. An Encoder
+// implements cbor.EncMode, so that it can be passed to SerializeStructToCBOR
+// in place of a package's encoding mode.
+type Encoder struct {
+	cbor.EncMode
+
+	values map[any]any
+}
+
+// NewEncoder instantiates an Encoder that uses the supplied encoding mode for
+// the values that do not implement IEncoderCBORMarshaler
+func NewEncoder(em cbor.EncMode) *Encoder {
+	return &Encoder{EncMode: em}
+}
+
+// AsEncoder returns em if it is an *Encoder, or a new Encoder using it
+// otherwise
+func AsEncoder(em cbor.EncMode) *Encoder {
+	if enc, ok := em.(*Encoder); ok {
+		return enc
+	}
+
+	return NewEncoder(em)
+}
+
+// WithEncMode returns a copy of the target Encoder using the supplied encoding
+// mode (see Decoder.WithDecMode)
+func (o *Encoder) WithEncMode(em cbor.EncMode) *Encoder {
+	ret := *o
+	ret.EncMode = em
+
+	return &ret
+}
+
+// WithValue returns a copy of the target Encoder in which key is associated
+// with val (see Decoder.WithValue)
+func (o *Encoder) WithValue(key, val any) *Encoder {
+	ret := *o
+	ret.values = make(map[any]any, len(o.values)+1)
+
+	for k, v := range o.values {
+		ret.values[k] = v
+	}
+
+	ret.values[key] = val
+
+	return &ret
+}
+
+// Value returns the value associated with key, or nil if there is none
+func (o *Encoder) Value(key any) any {
+	return o.values[key]
+}
+
+// Marshal encodes v to CBOR. If v implements IEncoderCBORMarshaler, it is
+// handed the target Encoder; the same applies to pointers to, and slices of,
+// such values. Any other value is encoded using the Encoder's encoding mode.
+func (o *Encoder) Marshal(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() == reflect.Pointer && rv.IsNil()) {
+		return o.EncMode.Marshal(v)
+	}
+
+	if m, ok := v.(IEncoderCBORMarshaler); ok {
+		return m.MarshalCBORWith(o)
+	}
+
+	if !needsEncoder(rv.Type()) {
+		return o.EncMode.Marshal(v)
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		return o.Marshal(rv.Elem().Interface())
+	case reflect.Slice:
+		if rv.IsNil() {
+			return o.EncMode.Marshal(v)
+		}
+
+		items := make([]cbor.RawMessage, rv.Len())
+
+		for i := range items {
+			data, err := o.Marshal(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			items[i] = data
+		}
+
+		return o.EncMode.Marshal(items)
+	default:
+		return o.EncMode.Marshal(v)
+	}
+}
+
+// needsEncoder returns true if values of type t are pointers to, or slices
+// of, values implementing IEncoderCBORMarshaler
+func needsEncoder(t reflect.Type) bool {
+	if t.Kind() != reflect.Pointer && t.Kind() != reflect.Slice {
+		return false
+	}
+
+	return needsWith(t.Elem(), cborMarshalerWithType)
+}
@@ -8,7 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"strings"
 )
 
 func SerializeStructToJSON(source any) ([]byte, error) {
@@ -21,6 +20,10 @@ func SerializeStructToJSON(source any) ([]byte, error) {
 		return nil, err
 	}
 
+	if err := emitUnknownJSON(rawMap, source); err != nil {
+		return nil, err
+	}
+
 	return rawMap.ToJSON()
 }
 
@@ -34,60 +37,35 @@ func doSerializeStructToJSON(
 		structVal = structVal.Elem()
 	}
 
-	var embeds []embedded
-
-	for i := 0; i < structVal.NumField(); i++ {
-		typeField := structType.Field(i)
-		valField := structVal.Field(i)
-
-		if collectEmbedded(&typeField, valField, &embeds) {
-			continue
-		}
-
-		tag, ok := typeField.Tag.Lookup("json")
-		if !ok {
-			continue
-		}
-
-		parts := strings.Split(tag, ",")
-		key := parts[0]
+	plan := getStructPlan(structType)
 
-		if key == "-" {
-			continue // field is not marshaled
-		}
-
-		isOmitEmpty := false
-		if len(parts) > 1 {
-			for _, option := range parts[1:] {
-				if option == omitempty {
-					isOmitEmpty = true
-					break
-				}
-			}
-		}
+	for _, field := range plan.JSON {
+		valField := structVal.Field(field.Index)
 
 		// do not serialize zero values if the corresponding field is
 		// omitempty
-		if isOmitEmpty && valField.IsZero() {
+		if field.OmitEmpty && valField.IsZero() {
 			continue
 		}
 
 		data, err := json.Marshal(valField.Interface())
 		if err != nil {
 			return fmt.Errorf("error marshaling field %q: %w",
-				typeField.Name,
+				field.Name,
 				err,
 			)
 		}
 
-		if err := rawMap.Add(key, json.RawMessage(data)); err != nil {
+		if err := rawMap.Add(field.Key.(string), json.RawMessage(data)); err != nil {
 			return err
 		}
 	}
 
-	for _, emb := range embeds {
-		if err := doSerializeStructToJSON(rawMap, emb.Type, emb.Value); err != nil {
-			return err
+	for _, index := range plan.Embeds {
+		for _, emb := range plan.embeddedValues(structType, structVal, index) {
+			if err := doSerializeStructToJSON(rawMap, emb.Type, emb.Value); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -95,90 +73,132 @@ func doSerializeStructToJSON(
 }
 
 func PopulateStructFromJSON(data []byte, dest any) error {
+	return populateStructFromJSON(NewDecoder(nil), data, dest)
+}
+
+// PopulateStructFromJSONWith is like PopulateStructFromJSON, except that the
+// fields are decoded via the supplied Decoder (see Decoder.DecodeJSON). It is
+// meant for implementing UnmarshalJSON, and so, as json.Unmarshal, reports
+// data that is not a JSON object as a *json.UnmarshalTypeError for the type of
+// dest.
+func PopulateStructFromJSONWith(dec *Decoder, data []byte, dest any) error {
+	err := populateStructFromJSON(dec, data, dest)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Type == structFieldsJSONType {
+		return &json.UnmarshalTypeError{
+			Value:  typeErr.Value,
+			Type:   reflect.Indirect(reflect.ValueOf(dest)).Type(),
+			Offset: typeErr.Offset,
+		}
+	}
+
+	return err
+}
+
+// structFieldsJSONType is the type JSON objects are first decoded into
+var structFieldsJSONType = reflect.TypeOf(map[string]json.RawMessage{})
+
+func populateStructFromJSON(dec *Decoder, data []byte, dest any) error {
 	rawMap := newStructFieldsJSON()
 
 	if err := rawMap.FromJSON(data); err != nil {
 		return err
 	}
 
+	origKeys := append([]string(nil), rawMap.Keys...)
+
 	structType := reflect.TypeOf(dest)
 	structVal := reflect.ValueOf(dest)
 
-	return doPopulateStructFromJSON(rawMap, structType, structVal)
+	var unknown UnknownFieldCollector
+
+	if err := doPopulateStructFromJSON(dec, rawMap, structType, structVal, &unknown); err != nil {
+		return err
+	}
+
+	if dec.Options().Strict && len(rawMap.Keys) != 0 {
+		ufe := &UnknownFieldError{}
+		for _, key := range rawMap.Keys {
+			ufe.Keys = append(ufe.Keys, key)
+		}
+
+		// the entries of the map itself are reported ahead of those of
+		// its members
+		unknown.errs = append([]*UnknownFieldError{ufe}, unknown.errs...)
+	}
+
+	if err := unknown.Err(); err != nil {
+		return err
+	}
+
+	captureUnknownJSON(dec, rawMap, origKeys, dest)
+
+	return nil
 }
 
 func doPopulateStructFromJSON(
+	dec *Decoder,
 	rawMap *structFieldsJSON,
 	structType reflect.Type,
 	structVal reflect.Value,
+	unknown *UnknownFieldCollector,
 ) error {
 	if structType.Kind() == reflect.Pointer {
 		structType = structType.Elem()
 		structVal = structVal.Elem()
 	}
 
-	var embeds []embedded
-
-	for i := 0; i < structVal.NumField(); i++ {
-		typeField := structType.Field(i)
-		valField := structVal.Field(i)
-
-		if collectEmbedded(&typeField, valField, &embeds) {
-			continue
-		}
-
-		tag, ok := typeField.Tag.Lookup("json")
-		if !ok {
-			continue
-		}
-
-		parts := strings.Split(tag, ",")
-		key := parts[0]
+	plan := getStructPlan(structType)
 
-		if key == "-" {
-			continue // field is not marshaled
-		}
-
-		isOmitEmpty := false
-		if len(parts) > 1 {
-			for _, option := range parts[1:] {
-				if option == omitempty {
-					isOmitEmpty = true
-					break
-				}
-			}
-		}
+	for _, field := range plan.JSON {
+		key := field.Key.(string)
 
 		rawVal, ok := rawMap.Get(key)
 		if !ok {
-			if isOmitEmpty {
+			if field.OmitEmpty {
 				continue
 			}
 
 			return fmt.Errorf("missing mandatory field %q (%q)",
-				typeField.Name, key)
+				field.Name, key)
 		}
 
-		fieldPtr := valField.Addr().Interface()
-		if err := json.Unmarshal(rawVal, fieldPtr); err != nil {
-			return fmt.Errorf("error unmarshalling field %q: %w",
-				typeField.Name,
-				err,
-			)
+		fieldPtr := structVal.Field(field.Index).Addr().Interface()
+		if err := dec.DecodeJSON(rawVal, fieldPtr); err != nil {
+			if !unknown.Collect(err, key) {
+				return fieldJSONError(structType, field.Name, key, err)
+			}
 		}
 
 		rawMap.Delete(key)
 	}
 
-	for _, emb := range embeds {
-		if err := doPopulateStructFromJSON(rawMap, emb.Type, emb.Value); err != nil {
-			return err
+	for _, index := range plan.Embeds {
+		for _, emb := range plan.embeddedValues(structType, structVal, index) {
+			if err := doPopulateStructFromJSON(dec, rawMap, emb.Type, emb.Value, unknown); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// fieldJSONError returns the error to report when the value of the field with
+// the specified name and key could not be decoded
+func fieldJSONError(structType reflect.Type, name, key string, err error) error {
+	// as json.Unmarshal, locate type mismatches within the struct
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok && typeErr.Field == "" {
+		typeErr.Struct = structType.Name()
+		typeErr.Field = key
+
+		return typeErr
+	}
+
+	return fmt.Errorf("error unmarshalling field %q: %w", name, err)
+}
+
 // structFieldsJSON is a specialized implementation of "OrderedMap", where the
 // order of the keys is kept track of, and used when serializing the map to
 // JSON. While JSON maps do not mandate any particular ordering, and so this
@@ -213,6 +233,23 @@ func (o *structFieldsJSON) Add(key string, val json.RawMessage) error {
 	return nil
 }
 
+// Insert adds the specified key and value at position pos within the key
+// order. If pos is past the end, the key is appended.
+func (o *structFieldsJSON) Insert(pos int, key string, val json.RawMessage) error {
+	if o.Has(key) {
+		return fmt.Errorf("duplicate JSON key: %q", key)
+	}
+
+	if pos < 0 || pos > len(o.Keys) {
+		pos = len(o.Keys)
+	}
+
+	o.Fields[key] = val
+	o.Keys = append(o.Keys[:pos], append([]string{key}, o.Keys[pos:]...)...)
+
+	return nil
+}
+
 func (o *structFieldsJSON) Get(key string) (json.RawMessage, bool) {
 	val, ok := o.Fields[key]
 	return val, ok
@@ -0,0 +1,414 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrLimitExceeded is wrapped by the errors returned when the input being
+// decoded exceeds one of the DecodeLimits.
+var ErrLimitExceeded = errors.New("decode limit exceeded")
+
+// DecodeLimits bound the resources used when decoding (potentially untrusted)
+// input. A limit of zero (or less) means that the corresponding quantity is
+// not limited.
+type DecodeLimits struct {
+	// MaxInputSize is the maximum size (in bytes) of the input
+	MaxInputSize int
+	// MaxNestingDepth is the maximum depth of nested arrays, maps and tags
+	MaxNestingDepth int
+	// MaxElements is the maximum number of elements of an array, or of
+	// key/value pairs of a map
+	MaxElements int
+	// MaxTags is the maximum total number of CBOR tags in the input
+	MaxTags int
+	// MaxMeasurements is the maximum number of measurements in a
+	// reference or endorsed value triple
+	MaxMeasurements int
+}
+
+// DefaultDecodeLimits are the limits in effect unless changed using
+// SetDecodeLimits (or overridden via DecodeOptions). The nesting depth and
+// element limits match the defaults of the underlying CBOR library, which the
+// decoding modes of this module set from the DecodeLimits in effect (see
+// NewDecMode).
+var DefaultDecodeLimits = DecodeLimits{
+	MaxInputSize:    64 * 1024 * 1024,
+	MaxNestingDepth: 32,
+	MaxElements:     131072,
+	MaxTags:         131072,
+	MaxMeasurements: 131072,
+}
+
+// The maxima supported by the underlying CBOR library for the nesting depth
+// and number of elements. CBOR DecodeLimits above these (or unset) are capped
+// at them.
+const (
+	MaxCBORNestingDepth = 65535
+	MaxCBORElements     = 2147483647
+)
+
+var (
+	decodeLimitsMu sync.RWMutex
+	decodeLimits   = DefaultDecodeLimits
+)
+
+// SetDecodeLimits sets the limits applied when decoding CoRIMs, CoMIDs and
+// CoTSes, unless overridden via DecodeOptions (see Decoder.Limits).
+func SetDecodeLimits(limits DecodeLimits) {
+	decodeLimitsMu.Lock()
+	defer decodeLimitsMu.Unlock()
+
+	decodeLimits = limits
+}
+
+// GetDecodeLimits returns the limits set via SetDecodeLimits. Those applied by
+// a given decode operation may be overridden via DecodeOptions (see
+// Decoder.Limits).
+func GetDecodeLimits() DecodeLimits {
+	decodeLimitsMu.RLock()
+	defer decodeLimitsMu.RUnlock()
+
+	return decodeLimits
+}
+
+// LimitError is returned when the input being decoded exceeds one of the
+// DecodeLimits. It wraps ErrLimitExceeded.
+type LimitError struct {
+	// Limit is the name of the DecodeLimits field that was exceeded
+	Limit string
+	// Value is the offending value (or, for MaxNestingDepth and MaxTags,
+	// the value at which the limit was found to be exceeded)
+	Value int
+	// Max is the value of the limit
+	Max int
+}
+
+func (o LimitError) Error() string {
+	return fmt.Sprintf("%s: %s: %d > %d", ErrLimitExceeded, o.Limit, o.Value, o.Max)
+}
+
+func (o LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+func checkLimit(name string, value int, max int) error {
+	if max > 0 && value > max {
+		return &LimitError{Limit: name, Value: value, Max: max}
+	}
+
+	return nil
+}
+
+// CheckCBORLimits checks that the CBOR-encoded data does not exceed the
+// supplied decode limits. The data is scanned without being decoded, so the
+// limits are enforced before any memory is allocated based on its content.
+// Malformed data is not reported here, but left to the decoder to reject.
+// Byte strings are not scanned, so CBOR embedded in them (e.g. the tags of a
+// CoRIM) is checked only when it is itself decoded. The nesting depth and
+// element limits are capped at MaxCBORNestingDepth and MaxCBORElements.
+func CheckCBORLimits(data []byte, limits DecodeLimits) error {
+	if err := checkLimit("MaxInputSize", len(data), limits.MaxInputSize); err != nil {
+		return err
+	}
+
+	limits.MaxNestingDepth = capLimit(limits.MaxNestingDepth, MaxCBORNestingDepth)
+	limits.MaxElements = capLimit(limits.MaxElements, MaxCBORElements)
+
+	s := cborScanner{limits: limits, data: data}
+
+	err := s.scanItem(1)
+
+	var le *LimitError
+	if errors.As(err, &le) {
+		return le
+	}
+
+	return nil
+}
+
+// capLimit returns limit if it is set and does not exceed ceiling, or ceiling
+// otherwise
+func capLimit(limit int, ceiling int) int {
+	if limit <= 0 || limit > ceiling {
+		return ceiling
+	}
+
+	return limit
+}
+
+// CheckCBORMeasurements checks that the CBOR-encoded array of measurements
+// does not have more elements than allowed by the supplied decode limits.
+// Only the array header is inspected.
+func CheckCBORMeasurements(data []byte, limits DecodeLimits) error {
+	s := cborScanner{data: data}
+
+	major, arg, _, err := s.head()
+	if err != nil || major != 4 {
+		return nil // left to the decoder to report
+	}
+
+	return checkLimit("MaxMeasurements", int(min(arg, uint64(maxInt))), limits.MaxMeasurements)
+}
+
+// CheckJSONMeasurements checks that the JSON-encoded array of measurements
+// does not have more elements than allowed by the supplied decode limits.
+func CheckJSONMeasurements(data []byte, limits DecodeLimits) error {
+	max := limits.MaxMeasurements
+	if max <= 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil // left to the decoder to report
+	}
+
+	count := 0
+
+	for decoder.More() {
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return nil
+		}
+
+		count++
+
+		if err := checkLimit("MaxMeasurements", count, max); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckJSONLimits checks that the JSON-encoded data does not exceed the
+// supplied decode limits (MaxTags does not apply to JSON). Malformed data is
+// not reported here, but left to the decoder to reject.
+func CheckJSONLimits(data []byte, limits DecodeLimits) error {
+	if err := checkLimit("MaxInputSize", len(data), limits.MaxInputSize); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	// enclosing arrays and objects, innermost last
+	var stack []jsonContainer
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			// io.EOF, or malformed data left to the decoder
+			return nil
+		}
+
+		delim, isDelim := token.(json.Delim)
+
+		if isDelim && (delim == ']' || delim == '}') {
+			if len(stack) != 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		if len(stack) != 0 {
+			top := &stack[len(stack)-1]
+			top.Tokens++
+
+			if err := checkLimit("MaxElements", top.Elements(), limits.MaxElements); err != nil {
+				return err
+			}
+		}
+
+		if isDelim {
+			stack = append(stack, jsonContainer{IsObject: delim == '{'})
+
+			if err := checkLimit("MaxNestingDepth", len(stack), limits.MaxNestingDepth); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+type jsonContainer struct {
+	IsObject bool
+	Tokens   int
+}
+
+// Elements returns the number of elements of an array, or of members of an
+// object (whose names and values are returned as separate tokens).
+func (o jsonContainer) Elements() int {
+	if o.IsObject {
+		return (o.Tokens + 1) / 2
+	}
+
+	return o.Tokens
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+type cborScanner struct {
+	limits DecodeLimits
+	data   []byte
+	pos    int
+	tags   int
+}
+
+var errMalformed = errors.New("malformed")
+
+// head parses the head of the next data item, returning its major type,
+// argument, and whether it has an indefinite length.
+func (o *cborScanner) head() (major byte, arg uint64, indef bool, err error) {
+	if o.pos >= len(o.data) {
+		return 0, 0, false, errMalformed
+	}
+
+	initial := o.data[o.pos]
+	o.pos++
+
+	major = initial >> 5
+	info := initial & 0x1f
+
+	switch {
+	case info < 24:
+		return major, uint64(info), false, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if o.pos+size > len(o.data) {
+			return 0, 0, false, errMalformed
+		}
+
+		buf := o.data[o.pos : o.pos+size]
+		o.pos += size
+
+		switch size {
+		case 1:
+			arg = uint64(buf[0])
+		case 2:
+			arg = uint64(binary.BigEndian.Uint16(buf))
+		case 4:
+			arg = uint64(binary.BigEndian.Uint32(buf))
+		default:
+			arg = binary.BigEndian.Uint64(buf)
+		}
+
+		return major, arg, false, nil
+	case info == 31:
+		return major, 0, true, nil
+	default:
+		return 0, 0, false, errMalformed
+	}
+}
+
+func (o *cborScanner) scanItem(depth int) error {
+	major, arg, indef, err := o.head()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case 0, 1: // unsigned and negative integers
+		return nil
+	case 2, 3: // byte and text strings
+		if indef {
+			return o.scanIndefinite(depth, false)
+		}
+
+		if arg > uint64(len(o.data)-o.pos) {
+			return errMalformed
+		}
+
+		o.pos += int(arg)
+
+		return nil
+	case 4, 5: // arrays and maps
+		if err := checkLimit("MaxNestingDepth", depth, o.limits.MaxNestingDepth); err != nil {
+			return err
+		}
+
+		if indef {
+			return o.scanIndefinite(depth, major == 5)
+		}
+
+		// each element takes at least one byte, so a count beyond the
+		// remaining input is reported as exceeding the limit (if it
+		// does), and as malformed otherwise
+		count := int(min(arg, uint64(maxInt)))
+		if err := checkLimit("MaxElements", count, o.limits.MaxElements); err != nil {
+			return err
+		}
+
+		items := count
+		if major == 5 {
+			items *= 2
+		}
+
+		if items > len(o.data)-o.pos {
+			return errMalformed
+		}
+
+		for i := 0; i < items; i++ {
+			if err := o.scanItem(depth + 1); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case 6: // tags
+		o.tags++
+		if err := checkLimit("MaxTags", o.tags, o.limits.MaxTags); err != nil {
+			return err
+		}
+
+		if err := checkLimit("MaxNestingDepth", depth, o.limits.MaxNestingDepth); err != nil {
+			return err
+		}
+
+		return o.scanItem(depth + 1)
+	default: // simple values and floats
+		if indef {
+			return errMalformed // unexpected "break"
+		}
+
+		return nil
+	}
+}
+
+// scanIndefinite scans the items of an indefinite-length string, array or
+// map, up to and including the terminating "break".
+func (o *cborScanner) scanIndefinite(depth int, isMap bool) error {
+	count := 0
+
+	for {
+		if o.pos >= len(o.data) {
+			return errMalformed
+		}
+
+		if o.data[o.pos] == 0xff {
+			o.pos++
+			return nil
+		}
+
+		if err := o.scanItem(depth + 1); err != nil {
+			return err
+		}
+
+		count++
+
+		elements := count
+		if isMap {
+			elements = (count + 1) / 2
+		}
+
+		if err := checkLimit("MaxElements", elements, o.limits.MaxElements); err != nil {
+			return err
+		}
+	}
+}
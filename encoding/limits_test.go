@@ -0,0 +1,266 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decoderWithLimits(dm cbor.DecMode, limits DecodeLimits) *Decoder {
+	return NewDecoder(dm).WithOptions(DecodeOptions{Limits: &limits})
+}
+
+func requireLimitError(t *testing.T, err error, limit string) *LimitError {
+	t.Helper()
+
+	require.ErrorIs(t, err, ErrLimitExceeded)
+
+	var le *LimitError
+	require.True(t, errors.As(err, &le))
+	assert.Equal(t, limit, le.Limit)
+
+	return le
+}
+
+func nestedArraysCBOR(depth int) []byte {
+	data := make([]byte, depth+1)
+	for i := 0; i < depth; i++ {
+		data[i] = 0x81 // array(1)
+	}
+	data[depth] = 0x00
+
+	return data
+}
+
+func TestCheckCBORLimits_defaults(t *testing.T) {
+	assert.NoError(t, CheckCBORLimits(nestedArraysCBOR(32), DefaultDecodeLimits))
+
+	err := CheckCBORLimits(nestedArraysCBOR(33), DefaultDecodeLimits)
+	le := requireLimitError(t, err, "MaxNestingDepth")
+	assert.Equal(t, 33, le.Value)
+	assert.Equal(t, 32, le.Max)
+	assert.EqualError(t, err, "decode limit exceeded: MaxNestingDepth: 33 > 32")
+}
+
+func TestCheckCBORLimits(t *testing.T) {
+	limits := DecodeLimits{
+		MaxInputSize:    16,
+		MaxNestingDepth: 3,
+		MaxElements:     4,
+		MaxTags:         2,
+	}
+
+	testCases := []struct {
+		name  string
+		data  []byte
+		limit string
+	}{
+		{
+			name: "ok",
+			data: []byte{0xc1, 0x83, 0x01, 0x81, 0x02, 0xa1, 0x00, 0x40},
+		},
+		{
+			name:  "input size",
+			data:  append([]byte{0x51}, make([]byte, 17)...), // bstr(17)
+			limit: "MaxInputSize",
+		},
+		{
+			name:  "nesting depth",
+			data:  []byte{0x81, 0xa1, 0x00, 0x81, 0x81, 0x00},
+			limit: "MaxNestingDepth",
+		},
+		{
+			name:  "tags count towards nesting depth",
+			data:  []byte{0x81, 0x81, 0x81, 0xc1, 0x00},
+			limit: "MaxNestingDepth",
+		},
+		{
+			name:  "array elements",
+			data:  []byte{0x85, 0x00, 0x00, 0x00, 0x00, 0x00},
+			limit: "MaxElements",
+		},
+		{
+			// the header alone is enough to trip the limit
+			name:  "declared map pairs",
+			data:  []byte{0xba, 0xff, 0xff, 0xff, 0xff},
+			limit: "MaxElements",
+		},
+		{
+			name:  "indefinite-length array elements",
+			data:  []byte{0x9f, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff},
+			limit: "MaxElements",
+		},
+		{
+			name:  "indefinite-length map pairs",
+			data:  []byte{0xbf, 0x00, 0x00, 0x01, 0x00, 0x02, 0x00, 0x03, 0x00, 0x04, 0x00, 0xff},
+			limit: "MaxElements",
+		},
+		{
+			name:  "tags",
+			data:  []byte{0x82, 0xc1, 0x00, 0xc1, 0xc1, 0x00},
+			limit: "MaxTags",
+		},
+		{
+			// malformed input is left to the decoder
+			name: "truncated",
+			data: []byte{0x83, 0x00},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckCBORLimits(tc.data, limits)
+
+			if tc.limit == "" {
+				assert.NoError(t, err)
+			} else {
+				requireLimitError(t, err, tc.limit)
+			}
+		})
+	}
+}
+
+func TestCheckCBORLimits_unlimited(t *testing.T) {
+	assert.NoError(t, CheckCBORLimits(nestedArraysCBOR(100), DecodeLimits{}))
+
+	// beyond the maxima of the CBOR library
+	le := requireLimitError(t,
+		CheckCBORLimits(nestedArraysCBOR(MaxCBORNestingDepth+1), DecodeLimits{}),
+		"MaxNestingDepth")
+	assert.Equal(t, MaxCBORNestingDepth, le.Max)
+
+	limits := DecodeLimits{MaxNestingDepth: 2 * MaxCBORNestingDepth}
+
+	le = requireLimitError(t,
+		CheckCBORLimits(nestedArraysCBOR(MaxCBORNestingDepth+1), limits),
+		"MaxNestingDepth")
+	assert.Equal(t, MaxCBORNestingDepth, le.Max)
+}
+
+func TestSetDecodeLimits(t *testing.T) {
+	defer SetDecodeLimits(DefaultDecodeLimits)
+
+	SetDecodeLimits(DecodeLimits{MaxNestingDepth: 2})
+	assert.Equal(t, DecodeLimits{MaxNestingDepth: 2}, GetDecodeLimits())
+
+	dec := NewDecoder(nil)
+	assert.Equal(t, DecodeLimits{MaxNestingDepth: 2}, dec.Limits())
+
+	// per-call limits take precedence
+	dec = decoderWithLimits(nil, DecodeLimits{MaxNestingDepth: 3})
+	assert.Equal(t, DecodeLimits{MaxNestingDepth: 3}, dec.Limits())
+}
+
+func TestDecoder_WithOptions_limits(t *testing.T) {
+	first := DecodeLimits{MaxElements: 1}
+	second := DecodeLimits{MaxElements: 2}
+
+	dec := NewDecoder(nil).WithOptions(
+		DecodeOptions{Limits: &first},
+		DecodeOptions{Strict: true},
+		DecodeOptions{Limits: &second},
+	)
+	assert.Equal(t, second, dec.Limits())
+	assert.True(t, dec.Options().Strict)
+
+	// options are not shared with the Decoder they derive from
+	assert.Equal(t, first, NewDecoder(nil).WithOptions(DecodeOptions{Limits: &first}).Limits())
+	assert.Equal(t, GetDecodeLimits(), NewDecoder(nil).Limits())
+}
+
+func TestCheckCBORMeasurements(t *testing.T) {
+	limits := DecodeLimits{MaxMeasurements: 2}
+
+	assert.NoError(t, CheckCBORMeasurements([]byte{0x82, 0xa0, 0xa0}, limits))
+
+	err := CheckCBORMeasurements([]byte{0x83, 0xa0, 0xa0, 0xa0}, limits)
+	le := requireLimitError(t, err, "MaxMeasurements")
+	assert.Equal(t, 3, le.Value)
+}
+
+func TestCheckJSONLimits(t *testing.T) {
+	limits := DecodeLimits{
+		MaxInputSize:    64,
+		MaxNestingDepth: 3,
+		MaxElements:     3,
+	}
+
+	testCases := []struct {
+		name  string
+		data  string
+		limit string
+	}{
+		{
+			name: "ok",
+			data: `{"a":[1,2,3],"b":{"c":[]},"d":null}`,
+		},
+		{
+			name:  "input size",
+			data:  `"` + strings.Repeat("x", 64) + `"`,
+			limit: "MaxInputSize",
+		},
+		{
+			name:  "nesting depth",
+			data:  `[{"a":[[1]]}]`,
+			limit: "MaxNestingDepth",
+		},
+		{
+			name:  "array elements",
+			data:  `[1,[],{},"x"]`,
+			limit: "MaxElements",
+		},
+		{
+			name:  "object members",
+			data:  `{"a":1,"b":[1,2,3],"c":{},"d":4}`,
+			limit: "MaxElements",
+		},
+		{
+			name: "malformed",
+			data: `[1,2`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckJSONLimits([]byte(tc.data), limits)
+
+			if tc.limit == "" {
+				assert.NoError(t, err)
+			} else {
+				requireLimitError(t, err, tc.limit)
+			}
+		})
+	}
+}
+
+func TestCheckJSONMeasurements(t *testing.T) {
+	limits := DecodeLimits{MaxMeasurements: 2}
+
+	assert.NoError(t, CheckJSONMeasurements([]byte(`[{"a":[1,2,3]},{}]`), limits))
+
+	err := CheckJSONMeasurements([]byte(`[{},{},{}]`), limits)
+	requireLimitError(t, err, "MaxMeasurements")
+}
+
+func Test_structFieldsCBOR_FromCBOR_limits(t *testing.T) {
+	dm, err := cbor.DecOptions{}.DecMode()
+	require.NoError(t, err)
+
+	// a map header declaring far more entries than the input holds
+	data := []byte{0xba, 0x00, 0x01, 0x00, 0x00, 0x00}
+
+	sf := newStructFieldsCBOR()
+	err = sf.FromCBOR(decoderWithLimits(dm, DecodeLimits{MaxElements: 16}), data)
+	requireLimitError(t, err, "MaxElements")
+
+	sf = newStructFieldsCBOR()
+	err = sf.FromCBOR(decoderWithLimits(dm, DecodeLimits{MaxElements: 2}),
+		[]byte{0xbf, 0x00, 0x00, 0x01, 0x00, 0x02, 0x00, 0xff})
+	requireLimitError(t, err, "MaxElements")
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+// DecodeOptions control optional behaviours of PopulateStructFromCBOR and
+// PopulateStructFromJSON. They are carried by the Decoder (see
+// Decoder.WithOptions), and so apply to a single decode operation.
+type DecodeOptions struct {
+	// PreserveUnknownFields causes map entries that do not correspond to
+	// any field of the destination struct (or of its registered
+	// extensions) to be retained in the struct's UnknownFields holder (if
+	// it has one), so that they are emitted again when the struct is
+	// serialized.
+	PreserveUnknownFields bool
+
+	// Strict causes map entries that do not correspond to any field of the
+	// destination struct (or of its registered extensions) to be rejected
+	// with an UnknownFieldError. Strict takes precedence over
+	// PreserveUnknownFields.
+	Strict bool
+
+	// Deterministic causes CBOR input that is not deterministically
+	// encoded (see CheckDeterministicCBOR) to be rejected with a
+	// NonCanonicalError. It has no effect on JSON input.
+	Deterministic bool
+
+	// Limits, if set, override the DecodeLimits set via SetDecodeLimits
+	Limits *DecodeLimits
+}
+
+// combineDecodeOptions merges the supplied options: the boolean options are
+// set if they are set in any of them, and the last Limits set takes
+// precedence.
+func combineDecodeOptions(opts []DecodeOptions) DecodeOptions {
+	var ret DecodeOptions
+
+	for _, o := range opts {
+		ret.PreserveUnknownFields = ret.PreserveUnknownFields || o.PreserveUnknownFields
+		ret.Strict = ret.Strict || o.Strict
+		ret.Deterministic = ret.Deterministic || o.Deterministic
+
+		if o.Limits != nil {
+			ret.Limits = o.Limits
+		}
+	}
+
+	return ret
+}
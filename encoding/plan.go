@@ -0,0 +1,201 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldPlan describes how a (non-embedded) struct field is mapped onto a CBOR
+// map entry or a JSON object member.
+type fieldPlan struct {
+	Index     int
+	Name      string
+	Key       any // int or string for CBOR, string for JSON
+	OmitEmpty bool
+	Segment   string // path segment used in UnknownFieldError
+	Spec      string // name in the specification's CDDL, used in EDN
+}
+
+// structPlan is the serialization "plan" for a struct type. It is derived
+// from the type's fields and their tags once, and cached for subsequent
+// (de)serializations of values of that type.
+type structPlan struct {
+	CBOR    []fieldPlan
+	CBORErr error // set if the cbor tags of the type are invalid
+	JSON    []fieldPlan
+	Embeds  []int // indexes of the embedded struct and interface fields
+}
+
+var structPlans sync.Map // reflect.Type -> *structPlan
+
+// getStructPlan returns the plan for the specified struct type, building and
+// caching it on first use.
+func getStructPlan(structType reflect.Type) *structPlan {
+	if plan, ok := structPlans.Load(structType); ok {
+		return plan.(*structPlan)
+	}
+
+	plan, _ := structPlans.LoadOrStore(structType, buildStructPlan(structType))
+
+	return plan.(*structPlan)
+}
+
+func buildStructPlan(structType reflect.Type) *structPlan {
+	plan := &structPlan{}
+
+	for i := 0; i < structType.NumField(); i++ {
+		typeField := structType.Field(i)
+
+		if isEmbedded(&typeField) {
+			plan.Embeds = append(plan.Embeds, i)
+			continue
+		}
+
+		jsonTag, hasJSON := typeField.Tag.Lookup("json")
+		segment := fieldSegment(typeField.Name, jsonTag)
+
+		if tag, ok := typeField.Tag.Lookup("cbor"); ok {
+			name, options := splitTag(tag)
+
+			if name != "-" {
+				key, err := parseCBORKey(name, options)
+				if err != nil {
+					if plan.CBORErr == nil {
+						plan.CBORErr = err
+					}
+				} else {
+					plan.CBOR = append(plan.CBOR, fieldPlan{
+						Index:     i,
+						Name:      typeField.Name,
+						Key:       key,
+						OmitEmpty: hasOption(options, omitempty),
+						Segment:   segment,
+						Spec:      specName(&typeField, segment),
+					})
+				}
+			}
+		}
+
+		if hasJSON {
+			name, options := splitTag(jsonTag)
+
+			if name != "-" {
+				plan.JSON = append(plan.JSON, fieldPlan{
+					Index:     i,
+					Name:      typeField.Name,
+					Key:       name,
+					OmitEmpty: hasOption(options, omitempty),
+					Segment:   name,
+				})
+			}
+		}
+	}
+
+	return plan
+}
+
+// embeddedValues returns the types and values of the structs embedded via the
+// field at the specified index of structVal. For an embedded interface, these
+// are the types and values of the underlying value(s), if any.
+func (o *structPlan) embeddedValues(structType reflect.Type, structVal reflect.Value, index int) []embedded {
+	valField := structVal.Field(index)
+	fieldType := structType.Field(index).Type
+
+	if fieldType.Kind() != reflect.Interface {
+		return []embedded{{Type: fieldType, Value: valField}}
+	}
+
+	fieldValue := valField.Elem()
+	if fieldValue.Kind() == reflect.Invalid {
+		// no value underlying the interface
+		return nil
+	}
+
+	if ev, ok := fieldValue.Interface().(IEmbeddedValues); ok {
+		var ret []embedded
+		for _, v := range ev.EmbeddedValues() {
+			rv := reflect.ValueOf(v)
+			ret = append(ret, embedded{Type: rv.Type(), Value: rv})
+		}
+		return ret
+	}
+
+	// use the interface's underlying value's real type
+	return []embedded{{Type: fieldValue.Type(), Value: fieldValue}}
+}
+
+// specName returns the name of the field in the CDDL of the specification
+// defining it, as per its cddl tag, falling back to its path segment.
+func specName(typeField *reflect.StructField, segment string) string {
+	if name := typeField.Tag.Get("cddl"); name != "" {
+		return name
+	}
+
+	return segment
+}
+
+func splitTag(tag string) (string, []string) {
+	name, rest, found := strings.Cut(tag, ",")
+	if !found {
+		return name, nil
+	}
+
+	return name, strings.Split(rest, ",")
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FieldKeys returns the JSON name and the CBOR key of the named field of
+// struct type t (or of a struct type t points to), as per the field's tags.
+// For structs serialized as CBOR arrays, the CBOR key is the position of the
+// field within the array. Fields without a JSON name are named after their Go
+// name. ok is false if t has no such field.
+func FieldKeys(t reflect.Type, name string) (jsonName string, cborKey any, ok bool) {
+	t = derefType(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", nil, false
+	}
+
+	field, ok := t.FieldByName(name)
+	if !ok {
+		return "", nil, false
+	}
+
+	jsonName = fieldSegment(field.Name, field.Tag.Get("json"))
+
+	// the (possibly embedded) struct directly containing the field
+	parent := t
+	if len(field.Index) > 1 {
+		parent = derefType(t.FieldByIndex(field.Index[:len(field.Index)-1]).Type)
+	}
+
+	if isToArray(parent) {
+		pos := 0
+		for i := 0; i < field.Index[len(field.Index)-1]; i++ {
+			if parent.Field(i).IsExported() {
+				pos++
+			}
+		}
+
+		return jsonName, pos, true
+	}
+
+	if key, options := splitTag(field.Tag.Get("cbor")); key != "" && key != "-" {
+		if k, err := parseCBORKey(key, options); err == nil {
+			cborKey = k
+		}
+	}
+
+	return jsonName, cborKey, true
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPlanEmbedded struct {
+	Inner int `cbor:"5,keyasint" json:"inner"`
+}
+
+type testPlanStruct struct {
+	_          struct{} `cbor:",toarray"`
+	FieldOne   string   `cbor:"0,keyasint,omitempty" json:"field-one,omitempty"`
+	FieldTwo   int      `cbor:"-1,keyasint" json:"field-two" cddl:"test.field-2"`
+	Text       string   `cbor:"text" json:"text"`
+	CBOROnly   int      `cbor:"2,keyasint"`
+	Ignored    int      `cbor:"-" json:"-"`
+	Unexported int
+
+	testPlanEmbedded
+	Iface any
+}
+
+func Test_buildStructPlan(t *testing.T) {
+	plan := buildStructPlan(reflect.TypeOf(testPlanStruct{}))
+
+	// the "_" field has a cbor tag without a key, which is only an error
+	// if the struct is (de)serialized via the CBOR map codec
+	assert.EqualError(t, plan.CBORErr, `non-integer cbor key: ""`)
+
+	assert.Equal(t, []fieldPlan{
+		{Index: 1, Name: "FieldOne", Key: 0, OmitEmpty: true, Segment: "field-one", Spec: "field-one"},
+		{Index: 2, Name: "FieldTwo", Key: -1, Segment: "field-two", Spec: "test.field-2"},
+		{Index: 3, Name: "Text", Key: "text", Segment: "text", Spec: "text"},
+		{Index: 4, Name: "CBOROnly", Key: 2, Segment: "CBOROnly", Spec: "CBOROnly"},
+	}, plan.CBOR)
+
+	assert.Equal(t, []fieldPlan{
+		{Index: 1, Name: "FieldOne", Key: "field-one", OmitEmpty: true, Segment: "field-one"},
+		{Index: 2, Name: "FieldTwo", Key: "field-two", Segment: "field-two"},
+		{Index: 3, Name: "Text", Key: "text", Segment: "text"},
+	}, plan.JSON)
+
+	// Iface is a named field of interface type, not an embedded one
+	assert.Equal(t, []int{7}, plan.Embeds)
+}
+
+func Test_getStructPlan_cached(t *testing.T) {
+	typ := reflect.TypeOf(testPlanEmbedded{})
+
+	plan := getStructPlan(typ)
+	require.NotNil(t, plan)
+	assert.Same(t, plan, getStructPlan(typ))
+}
+
+type TestPlanIface interface{}
+
+type testPlanWithIface struct {
+	Outer int `json:"outer"`
+	TestPlanIface
+}
+
+func Test_structPlan_embeddedValues(t *testing.T) {
+	typ := reflect.TypeOf(testPlanWithIface{})
+	plan := getStructPlan(typ)
+	require.Equal(t, []int{1}, plan.Embeds)
+
+	// nothing underlying the interface
+	v := testPlanWithIface{}
+	assert.Empty(t, plan.embeddedValues(typ, reflect.ValueOf(v), 1))
+
+	v.TestPlanIface = &testPlanEmbedded{Inner: 3}
+	embeds := plan.embeddedValues(typ, reflect.ValueOf(v), 1)
+	require.Len(t, embeds, 1)
+	assert.Equal(t, reflect.TypeOf(&testPlanEmbedded{}), embeds[0].Type)
+
+	out, err := SerializeStructToJSON(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"outer":0,"inner":3}`, string(out))
+}
+
+func TestFieldKeys(t *testing.T) {
+	testCases := []struct {
+		typ      any
+		name     string
+		jsonName string
+		cborKey  any
+		ok       bool
+	}{
+		{testPlanStruct{}, "FieldTwo", "field-two", 1, true},
+		{testPlanStruct{}, "CBOROnly", "CBOROnly", 3, true},
+		{&testPlanStruct{}, "Inner", "inner", 5, true},
+		{testPlanStruct{}, "Missing", "", nil, false},
+		{testPlanEmbedded{}, "Inner", "inner", 5, true},
+		{testCanonicalOuter{}, "Named", "named", 1, true},
+		{testCanonicalOuter{}, "Extra", "extra", -1, true},
+		{testCanonicalInner{}, "Value", "value", 1, true},
+		{42, "Value", "", nil, false},
+	}
+
+	for _, tc := range testCases {
+		jsonName, cborKey, ok := FieldKeys(reflect.TypeOf(tc.typ), tc.name)
+		assert.Equal(t, tc.ok, ok, tc.name)
+		assert.Equal(t, tc.jsonName, jsonName, tc.name)
+		assert.Equal(t, tc.cborKey, cborKey, tc.name)
+	}
+}
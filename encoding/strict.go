@@ -0,0 +1,200 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownField is wrapped by the errors returned when decoding in strict
+// mode encounters map entries that do not correspond to any known field.
+var ErrUnknownField = errors.New("unknown field")
+
+// UnknownFieldError reports the unexpected entries of a map decoded in strict
+// mode, along with the location of the map within the decoded document.
+type UnknownFieldError struct {
+	// Path is the sequence of field names (as per their JSON tags) and
+	// array indexes (e.g. "[2]") leading to the map containing the
+	// unexpected entries.
+	Path []string
+	// Keys are the unexpected keys: ints for CBOR, strings for JSON.
+	Keys []any
+}
+
+// PathString returns the Path as a single string, e.g.
+// "triples.reference-values[2].measurements[0].value"
+func (o UnknownFieldError) PathString() string {
+	return joinPath(o.Path)
+}
+
+func (o UnknownFieldError) Error() string {
+	path := o.PathString()
+
+	entries := make([]string, 0, len(o.Keys))
+	for _, k := range o.Keys {
+		var key string
+		if s, ok := k.(string); ok {
+			key = fmt.Sprintf("key %q", s)
+		} else {
+			key = fmt.Sprintf("key %v", k)
+		}
+
+		if path != "" {
+			key = path + " " + key
+		}
+
+		entries = append(entries, key)
+	}
+
+	label := ErrUnknownField.Error()
+	if len(o.Keys) > 1 {
+		label += "s"
+	}
+
+	return fmt.Sprintf("%s: %s", label, strings.Join(entries, ", "))
+}
+
+func (o UnknownFieldError) Unwrap() error {
+	return ErrUnknownField
+}
+
+// PrefixPath prepends segment to the paths of the UnknownFieldErrors that err
+// is made of (see UnknownFieldErrors), and returns an error reporting all of
+// them. ok is false if err is not made of UnknownFieldErrors only. This is
+// used by decoders of nested values to record their location.
+func PrefixPath(err error, segment string) (ret error, ok bool) {
+	var unknown UnknownFieldCollector
+
+	if !unknown.Collect(err, segment) {
+		return nil, false
+	}
+
+	return unknown.Err(), true
+}
+
+// UnknownFieldErrors returns all the UnknownFieldErrors reported by err, i.e.
+// one per map with unexpected entries that was encountered when decoding in
+// strict mode.
+func UnknownFieldErrors(err error) []*UnknownFieldError {
+	var ret []*UnknownFieldError
+
+	switch t := err.(type) {
+	case nil:
+	case *UnknownFieldError:
+		ret = append(ret, t)
+	case interface{ Unwrap() []error }:
+		for _, e := range t.Unwrap() {
+			ret = append(ret, UnknownFieldErrors(e)...)
+		}
+	default:
+		ret = UnknownFieldErrors(errors.Unwrap(err))
+	}
+
+	return ret
+}
+
+// UnknownFieldCollector collects the UnknownFieldErrors reported when decoding the
+// members of a map or array, so that decoding carries on past them and all
+// the unexpected entries of the document are reported.
+type UnknownFieldCollector struct {
+	errs []*UnknownFieldError
+}
+
+// Collect records the UnknownFieldErrors that err is made of, prefixing their
+// paths with segment (see PrefixPath). It returns false, without recording
+// anything, if err is not made of UnknownFieldErrors only, in which case
+// decoding should stop with err.
+func (o *UnknownFieldCollector) Collect(err error, segment string) bool {
+	ufes, ok := unknownFieldsOnly(err)
+	if !ok {
+		return false
+	}
+
+	for _, ufe := range ufes {
+		ufe.Path = append([]string{segment}, ufe.Path...)
+	}
+
+	o.errs = append(o.errs, ufes...)
+
+	return true
+}
+
+// Err returns an error reporting all of the collected UnknownFieldErrors, or
+// nil if there are none
+func (o *UnknownFieldCollector) Err() error {
+	return joinUnknownFields(o.errs)
+}
+
+// unknownFieldsOnly returns the UnknownFieldErrors that err is made of: err
+// itself, the error it wraps, or the members of a joined error. ok is false if
+// err does not lead to UnknownFieldErrors only.
+func unknownFieldsOnly(err error) (ret []*UnknownFieldError, ok bool) {
+	switch t := err.(type) {
+	case nil:
+		return nil, false
+	case *UnknownFieldError:
+		return []*UnknownFieldError{t}, true
+	case interface{ Unwrap() []error }:
+		for _, e := range t.Unwrap() {
+			ufes, ok := unknownFieldsOnly(e)
+			if !ok {
+				return nil, false
+			}
+
+			ret = append(ret, ufes...)
+		}
+
+		return ret, len(ret) != 0
+	default:
+		return unknownFieldsOnly(errors.Unwrap(err))
+	}
+}
+
+// joinUnknownFields returns nil if ufes is empty, its only element if it has
+// one, or the errors.Join of its elements otherwise
+func joinUnknownFields(ufes []*UnknownFieldError) error {
+	switch len(ufes) {
+	case 0:
+		return nil
+	case 1:
+		return ufes[0]
+	}
+
+	errs := make([]error, len(ufes))
+	for i, ufe := range ufes {
+		errs[i] = ufe
+	}
+
+	return errors.Join(errs...)
+}
+
+func joinPath(path []string) string {
+	var b strings.Builder
+
+	for i, seg := range path {
+		if i > 0 && !strings.HasPrefix(seg, "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg)
+	}
+
+	return b.String()
+}
+
+// IndexSegment returns the path segment for the element at the specified
+// index of an array
+func IndexSegment(i int) string {
+	return fmt.Sprintf("[%d]", i)
+}
+
+// fieldSegment returns the path segment for a struct field, using its JSON
+// name if it has one.
+func fieldSegment(name string, jsonTag string) string {
+	if jsonName := strings.Split(jsonTag, ",")[0]; jsonName != "" && jsonName != "-" {
+		return jsonName
+	}
+
+	return name
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStrictInner struct {
+	FieldOne string `cbor:"0,keyasint" json:"field-one"`
+}
+
+func (o *testStrictInner) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(NewDecoder(nil), data)
+}
+
+func (o *testStrictInner) UnmarshalCBORWith(dec *Decoder, data []byte) error {
+	dm, _ := cbor.DecOptions{}.DecMode()
+	return PopulateStructFromCBOR(dec.WithDecMode(dm), data, o)
+}
+
+func (o *testStrictInner) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(NewDecoder(nil), data)
+}
+
+func (o *testStrictInner) UnmarshalJSONWith(dec *Decoder, data []byte) error {
+	return PopulateStructFromJSONWith(dec, data, o)
+}
+
+type testStrictOuter struct {
+	Inner testStrictInner `cbor:"0,keyasint" json:"inner"`
+	Other int             `cbor:"1,keyasint,omitempty"`
+}
+
+func Test_PopulateStructFromCBOR_strict(t *testing.T) {
+	data := []byte{
+		0xa1, // map(1)
+
+		0x00, // key 0
+		0xa3, // val map(3)
+
+		0x00,                   // key 0
+		0x64,                   // val tstr(4)
+		0x61, 0x63, 0x6d, 0x65, // "acme"
+
+		0x18, 0x63, // key 99 (unknown)
+		0xf5, // val true
+
+		0x20, // key -1 (unknown)
+		0xf4, // val false
+	}
+
+	dm, err := cbor.DecOptions{}.DecMode()
+	require.NoError(t, err)
+
+	var v testStrictOuter
+
+	err = PopulateStructFromCBOR(dm, data, &v)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", v.Inner.FieldOne)
+
+	strict := NewDecoder(dm).WithOptions(DecodeOptions{Strict: true})
+
+	err = PopulateStructFromCBOR(strict, data, &v)
+	assert.EqualError(t, err, "unknown fields: inner key 99, inner key -1")
+	assert.True(t, errors.Is(err, ErrUnknownField))
+
+	var ufe *UnknownFieldError
+	require.True(t, errors.As(err, &ufe))
+	assert.Equal(t, []string{"inner"}, ufe.Path)
+	assert.Equal(t, []any{99, -1}, ufe.Keys)
+
+	data = []byte{
+		0xa2, // map(2)
+
+		0x00, // key 0
+		0xa1, // val map(1)
+
+		0x00,                   // key 0
+		0x64,                   // val tstr(4)
+		0x61, 0x63, 0x6d, 0x65, // "acme"
+
+		0x02, // key 2 (unknown)
+		0x02, // val 2
+	}
+
+	err = PopulateStructFromCBOR(strict, data, &v)
+	assert.EqualError(t, err, "unknown field: key 2")
+}
+
+func Test_PopulateStructFromJSON_strict(t *testing.T) {
+	data := []byte(`{"inner":{"field-one":"acme","x-vendor":1}}`)
+
+	var v testStrictOuter
+
+	err := PopulateStructFromJSON(data, &v)
+	require.NoError(t, err)
+
+	strict := NewDecoder(nil).WithOptions(DecodeOptions{Strict: true})
+
+	err = PopulateStructFromJSONWith(strict, data, &v)
+	assert.EqualError(t, err, `unknown field: inner key "x-vendor"`)
+	assert.True(t, errors.Is(err, ErrUnknownField))
+
+	// strict takes precedence over preserving unknown fields
+	dec := NewDecoder(nil).WithOptions(
+		DecodeOptions{PreserveUnknownFields: true},
+		DecodeOptions{Strict: true},
+	)
+
+	err = PopulateStructFromJSONWith(dec, data, &v)
+	assert.ErrorIs(t, err, ErrUnknownField)
+}
+
+type testStrictMulti struct {
+	Inner testStrictInner   `cbor:"0,keyasint" json:"inner"`
+	Items []testStrictInner `cbor:"1,keyasint,omitempty" json:"items,omitempty"`
+}
+
+func Test_PopulateStructFromJSON_strict_all_paths(t *testing.T) {
+	data := []byte(`{
+		"inner": {"field-one": "acme", "x-inner": 1},
+		"items": [
+			{"field-one": "a"},
+			{"field-one": "b", "x-item": 2},
+			{"field-one": "c", "x-item": 3}
+		],
+		"x-outer": true
+	}`)
+
+	var v testStrictMulti
+
+	strict := NewDecoder(nil).WithOptions(DecodeOptions{Strict: true})
+
+	err := PopulateStructFromJSONWith(strict, data, &v)
+	assert.EqualError(t, err, `unknown field: key "x-outer"
+unknown field: inner key "x-inner"
+unknown field: items[1] key "x-item"
+unknown field: items[2] key "x-item"`)
+	assert.ErrorIs(t, err, ErrUnknownField)
+
+	ufes := UnknownFieldErrors(err)
+	require.Len(t, ufes, 4)
+
+	var paths []string
+	for _, ufe := range ufes {
+		paths = append(paths, ufe.PathString())
+	}
+
+	assert.Equal(t, []string{"", "inner", "items[1]", "items[2]"}, paths)
+
+	// errors other than unknown fields still stop decoding
+	data = []byte(`{"inner": {"field-one": "acme", "x-inner": 1}, "items": [{"field-one": 1}]}`)
+
+	err = PopulateStructFromJSONWith(strict, data, &v)
+	assert.NotErrorIs(t, err, ErrUnknownField)
+	assert.Empty(t, UnknownFieldErrors(err))
+}
+
+func Test_UnknownFieldErrors(t *testing.T) {
+	first := &UnknownFieldError{Keys: []any{1}}
+	second := &UnknownFieldError{Path: []string{"foo"}, Keys: []any{2}}
+
+	assert.Nil(t, UnknownFieldErrors(nil))
+	assert.Nil(t, UnknownFieldErrors(errors.New("some error")))
+	assert.Equal(t, []*UnknownFieldError{first}, UnknownFieldErrors(first))
+
+	err := fmt.Errorf("wrapped: %w", errors.Join(first, second))
+	assert.Equal(t, []*UnknownFieldError{first, second}, UnknownFieldErrors(err))
+
+	prefixed, ok := PrefixPath(err, "bar")
+	require.True(t, ok)
+	assert.EqualError(t, prefixed, "unknown field: bar key 1\nunknown field: bar.foo key 2")
+
+	_, ok = PrefixPath(errors.Join(first, errors.New("some error")), "bar")
+	assert.False(t, ok)
+}
+
+func Test_UnknownFieldError_PathString(t *testing.T) {
+	ufe := UnknownFieldError{
+		Path: []string{"triples", "reference-values", "[2]", "measurements", "[0]", "value"},
+		Keys: []any{99},
+	}
+
+	assert.Equal(t, "triples.reference-values[2].measurements[0].value", ufe.PathString())
+	assert.EqualError(t, ufe, "unknown field: triples.reference-values[2].measurements[0].value key 99")
+
+	_, ok := PrefixPath(errors.New("some error"), "foo")
+	assert.False(t, ok)
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"encoding/json"
+
+	cbor "github.com/fxamacker/cbor/v2"
+)
+
+// IUnknownFieldsSource is implemented by values that may carry unknown fields
+// captured when they were decoded. The returned fields (if any) are emitted by
+// SerializeStructToCBOR and SerializeStructToJSON alongside the known ones.
+type IUnknownFieldsSource interface {
+	GetUnknownFields() *UnknownFields
+}
+
+// IUnknownFieldsSink is implemented by values that can store the unknown
+// fields encountered when decoding them with PreserveUnknownFields set.
+type IUnknownFieldsSink interface {
+	SetUnknownFields(*UnknownFields)
+}
+
+// UnknownCBORField is a CBOR map entry that did not correspond to a known
+// field. Index is the position of the entry within the original map.
+type UnknownCBORField struct {
+	Index int
+	Key   any // int or string
+	Value cbor.RawMessage
+}
+
+// UnknownJSONField is a JSON object member that did not correspond to a known
+// field. Index is the position of the member within the original object.
+type UnknownJSONField struct {
+	Index int
+	Key   string
+	Value json.RawMessage
+}
+
+// UnknownFields holds the unknown entries of a decoded map. Only the entries
+// from the format the struct was last decoded from are kept, as CBOR keys and
+// JSON names cannot be mapped onto each other without knowing the fields.
+type UnknownFields struct {
+	CBOR []UnknownCBORField
+	JSON []UnknownJSONField
+}
+
+// IsEmpty returns true if no unknown entries are held
+func (o *UnknownFields) IsEmpty() bool {
+	return o == nil || (len(o.CBOR) == 0 && len(o.JSON) == 0)
+}
+
+func captureUnknownCBOR(dec *Decoder, rawMap *structFieldsCBOR, origKeys []any, dest any) {
+	sink, ok := dest.(IUnknownFieldsSink)
+	if !ok {
+		return
+	}
+
+	if !dec.Options().PreserveUnknownFields || len(rawMap.Keys) == 0 {
+		sink.SetUnknownFields(nil)
+		return
+	}
+
+	unknown := &UnknownFields{}
+
+	for i, key := range origKeys {
+		if val, ok := rawMap.Get(key); ok {
+			unknown.CBOR = append(unknown.CBOR,
+				UnknownCBORField{Index: i, Key: key, Value: val})
+		}
+	}
+
+	sink.SetUnknownFields(unknown)
+}
+
+func captureUnknownJSON(dec *Decoder, rawMap *structFieldsJSON, origKeys []string, dest any) {
+	sink, ok := dest.(IUnknownFieldsSink)
+	if !ok {
+		return
+	}
+
+	if !dec.Options().PreserveUnknownFields || len(rawMap.Keys) == 0 {
+		sink.SetUnknownFields(nil)
+		return
+	}
+
+	unknown := &UnknownFields{}
+
+	for i, key := range origKeys {
+		if val, ok := rawMap.Get(key); ok {
+			unknown.JSON = append(unknown.JSON,
+				UnknownJSONField{Index: i, Key: key, Value: val})
+		}
+	}
+
+	sink.SetUnknownFields(unknown)
+}
+
+func emitUnknownCBOR(rawMap *structFieldsCBOR, source any) error {
+	src, ok := source.(IUnknownFieldsSource)
+	if !ok {
+		return nil
+	}
+
+	unknown := src.GetUnknownFields()
+	if unknown.IsEmpty() {
+		return nil
+	}
+
+	// entries are held in ascending Index order, so inserting them in turn
+	// restores their original positions relative to the known fields
+	for _, u := range unknown.CBOR {
+		if err := rawMap.Insert(u.Index, u.Key, u.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func emitUnknownJSON(rawMap *structFieldsJSON, source any) error {
+	src, ok := source.(IUnknownFieldsSource)
+	if !ok {
+		return nil
+	}
+
+	unknown := src.GetUnknownFields()
+	if unknown.IsEmpty() {
+		return nil
+	}
+
+	for _, u := range unknown.JSON {
+		if err := rawMap.Insert(u.Index, u.Key, u.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
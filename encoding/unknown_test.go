@@ -0,0 +1,137 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package encoding
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUnknownHolder struct {
+	Unknown *UnknownFields `cbor:"-" json:"-"`
+}
+
+func (o testUnknownHolder) GetUnknownFields() *UnknownFields {
+	return o.Unknown
+}
+
+func (o *testUnknownHolder) SetUnknownFields(unknown *UnknownFields) {
+	o.Unknown = unknown
+}
+
+type testPreservingStruct struct {
+	FieldOne string `cbor:"0,keyasint" json:"field-one"`
+	FieldTwo int    `cbor:"2,keyasint,omitempty" json:"field-two,omitempty"`
+
+	testUnknownHolder
+}
+
+func Test_PopulateStructFromCBOR_unknown_fields(t *testing.T) {
+	data := []byte{
+		0xa4, // map(4)
+
+		0x00,                   // key 0
+		0x64,                   // val tstr(4)
+		0x61, 0x63, 0x6d, 0x65, // "acme"
+
+		0x01,       // key 1 (unknown)
+		0x82,       // val array(2)
+		0x01, 0x02, // 1, 2
+
+		0x02, // key 2
+		0x06, // val 6
+
+		0x20, // key -1 (unknown)
+		0xf5, // val true
+	}
+
+	dm, err := cbor.DecOptions{}.DecMode()
+	require.NoError(t, err)
+
+	em, err := cbor.EncOptions{}.EncMode()
+	require.NoError(t, err)
+
+	// by default, unknown entries are dropped
+	var v testPreservingStruct
+
+	err = PopulateStructFromCBOR(dm, data, &v)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", v.FieldOne)
+	assert.Equal(t, 6, v.FieldTwo)
+	assert.Nil(t, v.Unknown)
+
+	out, err := SerializeStructToCBOR(em, v)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xa2, 0x00, 0x64, 0x61, 0x63, 0x6d, 0x65, 0x02, 0x06}, out)
+
+	preserving := NewDecoder(dm).WithOptions(DecodeOptions{PreserveUnknownFields: true})
+
+	err = PopulateStructFromCBOR(preserving, data, &v)
+	require.NoError(t, err)
+	require.NotNil(t, v.Unknown)
+	assert.Equal(t, []UnknownCBORField{
+		{Index: 1, Key: 1, Value: cbor.RawMessage{0x82, 0x01, 0x02}},
+		{Index: 3, Key: -1, Value: cbor.RawMessage{0xf5}},
+	}, v.Unknown.CBOR)
+	assert.Empty(t, v.Unknown.JSON)
+
+	// unknown entries are emitted in their original positions
+	out, err = SerializeStructToCBOR(em, v)
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+
+	// re-decoding without the option clears previously captured entries
+	err = PopulateStructFromCBOR(dm, data, &v)
+	require.NoError(t, err)
+	assert.Nil(t, v.Unknown)
+}
+
+func Test_PopulateStructFromJSON_unknown_fields(t *testing.T) {
+	data := []byte(`{"vendor":{"a":1},"field-one":"acme","field-two":6,"other":[1,2]}`)
+
+	var v testPreservingStruct
+
+	err := PopulateStructFromJSON(data, &v)
+	require.NoError(t, err)
+	assert.Nil(t, v.Unknown)
+
+	out, err := SerializeStructToJSON(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"field-one":"acme","field-two":6}`, string(out))
+
+	preserving := NewDecoder(nil).WithOptions(DecodeOptions{PreserveUnknownFields: true})
+
+	err = PopulateStructFromJSONWith(preserving, data, &v)
+	require.NoError(t, err)
+	require.NotNil(t, v.Unknown)
+	assert.Len(t, v.Unknown.JSON, 2)
+
+	out, err = SerializeStructToJSON(v)
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(out))
+
+	// JSON entries are not emitted to CBOR
+	em, err := cbor.EncOptions{}.EncMode()
+	require.NoError(t, err)
+
+	out, err = SerializeStructToCBOR(em, v)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xa2, 0x00, 0x64, 0x61, 0x63, 0x6d, 0x65, 0x02, 0x06}, out)
+}
+
+func Test_structFieldsCBOR_Insert(t *testing.T) {
+	sf := newStructFieldsCBOR()
+	require.NoError(t, sf.Add(1, cbor.RawMessage{0x01}))
+	require.NoError(t, sf.Add(3, cbor.RawMessage{0x03}))
+
+	require.NoError(t, sf.Insert(1, 2, cbor.RawMessage{0x02}))
+	require.NoError(t, sf.Insert(10, 4, cbor.RawMessage{0x04}))
+	require.NoError(t, sf.Insert(0, 0, cbor.RawMessage{0x00}))
+	assert.Equal(t, []any{0, 1, 2, 3, 4}, sf.Keys)
+
+	err := sf.Insert(0, 3, cbor.RawMessage{0x03})
+	assert.EqualError(t, err, "duplicate cbor key: 3")
+}
@@ -5,6 +5,7 @@ package extensions
 
 import (
 	cbor "github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/encoding"
 )
 
 var (
@@ -25,7 +26,7 @@ func initCBORDecMode() (dm cbor.DecMode, err error) {
 	decOpt := cbor.DecOptions{
 		IndefLength: cbor.IndefLengthForbidden,
 	}
-	return decOpt.DecMode()
+	return encoding.NewDecMode(decOpt, nil)
 }
 
 func init() {
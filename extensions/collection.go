@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	cbor "github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/encoding"
 )
 
 var ErrUnexpectedPoint = errors.New("unexpected extension point")
@@ -34,6 +35,57 @@ func (o Map) Add(p Point, v IMapValue) Map {
 	return o
 }
 
+// Layer associates the supplied IMapValue with the Point alongside the
+// IMapValue that is already associated with it (if any), composing the two
+// (see Compose). An error is returned if the two define the same field.
+func (o Map) Layer(p Point, v IMapValue) error {
+	existing, ok := o[p]
+	if !ok {
+		if _, err := Compose(v); err != nil {
+			return fmt.Errorf("%q: %w", p, err)
+		}
+
+		o[p] = v
+
+		return nil
+	}
+
+	c, err := Compose(existing, v)
+	if err != nil {
+		return fmt.Errorf("%q: %w", p, err)
+	}
+
+	o[p] = c
+
+	return nil
+}
+
+// Fresh returns a Map associating each Point of the target Map with a new,
+// zero-valued, instance of its IMapValue. This allows registering the same
+// extensions with several objects without them sharing their values.
+func (o Map) Fresh() Map {
+	res := make(Map, len(o))
+
+	for p, v := range o {
+		res[p] = newIMapValue(v)
+	}
+
+	return res
+}
+
+// Merge layers the IMapValue's of the supplied Map onto those of the target
+// Map (see Layer). This allows, for example, combining the extensions of a
+// base profile with those of a sub-profile.
+func (o Map) Merge(other Map) error {
+	for p, v := range other {
+		if err := o.Layer(p, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // IExtensible defines an interface for extensible objects (those that can
 // register extensions.
 type IExtensible[P any] interface {
@@ -147,10 +199,22 @@ func (o *Collection[P, I]) RegisterExtensions(exts Map) error {
 }
 
 func (o Collection[P, I]) MarshalCBOR() ([]byte, error) {
-	return em.Marshal(o.Values)
+	return o.MarshalCBORWith(encoding.NewEncoder(em))
+}
+
+// MarshalCBORWith is like MarshalCBOR, except that the values are encoded via
+// the supplied Encoder
+func (o Collection[P, I]) MarshalCBORWith(enc *encoding.Encoder) ([]byte, error) {
+	return enc.Marshal(o.Values)
 }
 
 func (o *Collection[P, I]) UnmarshalCBOR(data []byte) error {
+	return o.UnmarshalCBORWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalCBORWith is like UnmarshalCBOR, except that the values are decoded
+// via the supplied Decoder
+func (o *Collection[P, I]) UnmarshalCBORWith(dec *encoding.Decoder, data []byte) error {
 	var rawVals []cbor.RawMessage
 
 	if err := cbor.Unmarshal(data, &rawVals); err != nil {
@@ -159,6 +223,8 @@ func (o *Collection[P, I]) UnmarshalCBOR(data []byte) error {
 
 	vals := make([]P, len(rawVals))
 
+	var unknown encoding.UnknownFieldCollector
+
 	for i, rv := range rawVals {
 		var m I = new(P)
 
@@ -168,13 +234,19 @@ func (o *Collection[P, I]) UnmarshalCBOR(data []byte) error {
 			}
 		}
 
-		if err := cbor.Unmarshal(rv, m); err != nil {
-			return fmt.Errorf("error at index %d: %w", i, err)
+		if err := dec.Unmarshal(rv, m); err != nil {
+			if !unknown.Collect(err, encoding.IndexSegment(i)) {
+				return fmt.Errorf("error at index %d: %w", i, err)
+			}
 		}
 
 		vals[i] = *m
 	}
 
+	if err := unknown.Err(); err != nil {
+		return err
+	}
+
 	o.Values = vals
 
 	return nil
@@ -186,6 +258,12 @@ func (o Collection[P, I]) MarshalJSON() ([]byte, error) {
 }
 
 func (o *Collection[P, I]) UnmarshalJSON(data []byte) error {
+	return o.UnmarshalJSONWith(encoding.NewDecoder(dm), data)
+}
+
+// UnmarshalJSONWith is like UnmarshalJSON, except that the values are decoded
+// via the supplied Decoder
+func (o *Collection[P, I]) UnmarshalJSONWith(dec *encoding.Decoder, data []byte) error {
 	var rawVals []json.RawMessage
 
 	if err := json.Unmarshal(data, &rawVals); err != nil {
@@ -194,6 +272,8 @@ func (o *Collection[P, I]) UnmarshalJSON(data []byte) error {
 
 	vals := make([]P, len(rawVals))
 
+	var unknown encoding.UnknownFieldCollector
+
 	for i, rv := range rawVals {
 		var m I = new(P)
 
@@ -203,13 +283,19 @@ func (o *Collection[P, I]) UnmarshalJSON(data []byte) error {
 			}
 		}
 
-		if err := json.Unmarshal(rv, m); err != nil {
-			return fmt.Errorf("error at index %d: %w", i, err)
+		if err := dec.DecodeJSON(rv, m); err != nil {
+			if !unknown.Collect(err, encoding.IndexSegment(i)) {
+				return fmt.Errorf("error at index %d: %w", i, err)
+			}
 		}
 
 		vals[i] = *m
 	}
 
+	if err := unknown.Err(); err != nil {
+		return err
+	}
+
 	o.Values = vals
 
 	return nil
@@ -224,13 +310,7 @@ func (o *cache) Set(exts Map) {
 }
 
 func (o cache) Get() Map {
-	res := make(Map)
-
-	for p, v := range o.extensions {
-		res[p] = newIMapValue(v)
-	}
-
-	return res
+	return o.extensions.Fresh()
 }
 
 func (o cache) IsEmpty() bool {
@@ -0,0 +1,221 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package extensions
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrFieldCollision is returned when extension structs registered at the same
+// Point define the same CBOR key or JSON name.
+var ErrFieldCollision = errors.New("extension field collision")
+
+// Composite is an IMapValue combining several extension structs that are
+// registered at the same Point (e.g., those of a base profile and those of a
+// vendor sub-profile). The fields of all structs are merged when serializing
+// to, and deserializing from, CBOR and JSON; all their constrainers are
+// invoked on validation.
+type Composite struct {
+	values []IMapValue
+}
+
+// Compose creates a Composite from the supplied extension structs (which
+// must be pointers to structs). Composites among the supplied values are
+// flattened. An error wrapping ErrFieldCollision is returned if two of the
+// structs define the same CBOR key or JSON name.
+func Compose(values ...IMapValue) (*Composite, error) {
+	ret := &Composite{}
+
+	for _, v := range values {
+		if err := ret.add(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// MustCompose is like Compose, but panics on error
+func MustCompose(values ...IMapValue) *Composite {
+	ret, err := Compose(values...)
+	if err != nil {
+		panic(err)
+	}
+
+	return ret
+}
+
+// Values returns the extension structs combined in the target Composite, in
+// the order in which they have been added
+func (o *Composite) Values() []IMapValue {
+	return o.values
+}
+
+// EmbeddedValues returns the extension structs combined in the target
+// Composite. It allows the encoding package to treat each of them as an
+// embedded struct.
+func (o *Composite) EmbeddedValues() []any {
+	ret := make([]any, 0, len(o.values))
+	for _, v := range o.values {
+		ret = append(ret, v)
+	}
+
+	return ret
+}
+
+// MarshalJSON merges the JSON serializations of the combined extension
+// structs into a single JSON object
+func (o *Composite) MarshalJSON() ([]byte, error) {
+	merged := make(map[string]json.RawMessage)
+
+	for _, v := range o.values {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("%T: %w", v, err)
+		}
+
+		for k, f := range fields {
+			merged[k] = f
+		}
+	}
+
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON populates each of the combined extension structs from the
+// supplied JSON object
+func (o *Composite) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	for _, v := range o.values {
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("%T: %w", v, err)
+		}
+	}
+
+	return nil
+}
+
+func (o *Composite) add(v IMapValue) error {
+	if v == nil {
+		return errors.New("nil extension value")
+	}
+
+	if c, ok := v.(*Composite); ok {
+		for _, cv := range c.values {
+			if err := o.add(cv); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	typ := reflect.TypeOf(v)
+	if typ.Kind() != reflect.Pointer || typ.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("extension value must be a pointer to a struct, got %T", v)
+	}
+
+	for _, existing := range o.values {
+		if reflect.TypeOf(existing) == typ {
+			return fmt.Errorf("extension type %T registered more than once", v)
+		}
+
+		if err := checkCollisions(existing, v); err != nil {
+			return err
+		}
+	}
+
+	o.values = append(o.values, v)
+
+	return nil
+}
+
+// checkCollisions returns an error if the two supplied extension structs
+// define the same CBOR key or JSON name
+func checkCollisions(a, b IMapValue) error {
+	aCBOR, aJSON := fieldKeys(reflect.TypeOf(a).Elem())
+	bCBOR, bJSON := fieldKeys(reflect.TypeOf(b).Elem())
+
+	for k := range bCBOR {
+		if _, ok := aCBOR[k]; ok {
+			return fmt.Errorf("%w: CBOR key %s defined by both %T and %T",
+				ErrFieldCollision, k, a, b)
+		}
+	}
+
+	for k := range bJSON {
+		if _, ok := aJSON[k]; ok {
+			return fmt.Errorf("%w: JSON name %q defined by both %T and %T",
+				ErrFieldCollision, k, a, b)
+		}
+	}
+
+	return nil
+}
+
+func fieldKeys(typ reflect.Type) (cborKeys, jsonKeys map[string]bool) {
+	cborKeys = make(map[string]bool)
+	jsonKeys = make(map[string]bool)
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		if tag, ok := field.Tag.Lookup("cbor"); ok {
+			if k := strings.Split(tag, ",")[0]; k != "" && k != "-" {
+				cborKeys[k] = true
+			}
+		}
+
+		jsonName := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if k := strings.Split(tag, ",")[0]; k != "" {
+				jsonName = k
+			}
+		}
+
+		if jsonName != "-" {
+			jsonKeys[jsonName] = true
+		}
+	}
+
+	return cborKeys, jsonKeys
+}
+
+// Find returns the extension struct of type T found in the supplied
+// IMapValue, which may be either a single extension struct or a Composite.
+// The second return value indicates whether such a struct has been found.
+func Find[T any](v IMapValue) (T, bool) {
+	for _, ev := range flatten(v) {
+		if t, ok := ev.(T); ok {
+			return t, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+func flatten(v IMapValue) []IMapValue {
+	if v == nil {
+		return nil
+	}
+
+	if c, ok := v.(*Composite); ok {
+		return c.values
+	}
+
+	return []IMapValue{v}
+}
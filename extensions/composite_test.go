@@ -0,0 +1,126 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package extensions
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type VendorExtensions struct {
+	Vendor string `cbor:"-10,keyasint,omitempty" json:"vendor,omitempty"`
+}
+
+type CollidingCBORExtensions struct {
+	Other string `cbor:"-1,keyasint,omitempty" json:"other,omitempty"`
+}
+
+type CollidingJSONExtensions struct {
+	Other string `cbor:"-11,keyasint,omitempty" json:"address,omitempty"`
+}
+
+func TestCompose(t *testing.T) {
+	base := &TestExtensions{}
+	vendor := &VendorExtensions{}
+
+	c, err := Compose(base, vendor)
+	require.NoError(t, err)
+	assert.Equal(t, []IMapValue{base, vendor}, c.Values())
+
+	// nested composites are flattened
+	other := &CollidingCBORExtensions{}
+	_, err = Compose(c, other)
+	assert.ErrorIs(t, err, ErrFieldCollision)
+	assert.EqualError(t, err,
+		"extension field collision: CBOR key -1 defined by both *extensions.TestExtensions and *extensions.CollidingCBORExtensions")
+
+	_, err = Compose(base, &CollidingJSONExtensions{})
+	assert.EqualError(t, err,
+		`extension field collision: JSON name "address" defined by both *extensions.TestExtensions and *extensions.CollidingJSONExtensions`)
+
+	_, err = Compose(base, &TestExtensions{})
+	assert.EqualError(t, err, "extension type *extensions.TestExtensions registered more than once")
+
+	_, err = Compose(TestExtensions{})
+	assert.EqualError(t, err, "extension value must be a pointer to a struct, got extensions.TestExtensions")
+
+	_, err = Compose(nil)
+	assert.EqualError(t, err, "nil extension value")
+
+	assert.Panics(t, func() { MustCompose(base, base) })
+}
+
+func TestExtensions_Add(t *testing.T) {
+	exts := Extensions{}
+
+	require.NoError(t, exts.Add(&TestExtensions{}))
+	_, isComposite := exts.IMapValue.(*Composite)
+	assert.False(t, isComposite)
+
+	require.NoError(t, exts.Add(&VendorExtensions{}))
+	assert.Len(t, exts.ExtensionValues(), 2)
+
+	err := exts.Add(&CollidingCBORExtensions{})
+	assert.ErrorIs(t, err, ErrFieldCollision)
+	assert.Len(t, exts.ExtensionValues(), 2)
+
+	// fields are accessible by name regardless of the struct defining them
+	require.NoError(t, exts.Set("address", "123 Fake Street"))
+	require.NoError(t, exts.Set("vendor", "ACME"))
+	assert.Equal(t, "123 Fake Street", exts.MustGetString("Address"))
+	assert.Equal(t, "ACME", exts.MustGetString("-10"))
+	assert.False(t, exts.IsEmpty())
+
+	_, err = exts.Get("missing")
+	assert.ErrorIs(t, err, ErrExtensionNotFound)
+
+	// typed retrieval
+	vendor, ok := Find[*VendorExtensions](exts.IMapValue)
+	require.True(t, ok)
+	assert.Equal(t, "ACME", vendor.Vendor)
+
+	base, ok := Find[*TestExtensions](exts.IMapValue)
+	require.True(t, ok)
+	assert.Equal(t, "123 Fake Street", base.Address)
+
+	_, ok = Find[*CollidingCBORExtensions](exts.IMapValue)
+	assert.False(t, ok)
+
+	// New() returns fresh copies of all the structs
+	fresh, ok := exts.New().(*Composite)
+	require.True(t, ok)
+	require.Len(t, fresh.Values(), 2)
+	assert.Equal(t, &TestExtensions{}, fresh.Values()[0])
+	assert.Equal(t, &VendorExtensions{}, fresh.Values()[1])
+}
+
+func TestComposite_JSON(t *testing.T) {
+	c := MustCompose(&TestExtensions{Address: "here"}, &VendorExtensions{Vendor: "ACME"})
+
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"address":"here","vendor":"ACME"}`, string(data))
+
+	out := MustCompose(&TestExtensions{}, &VendorExtensions{})
+	require.NoError(t, json.Unmarshal(data, out))
+	assert.Equal(t, c, out)
+}
+
+func TestMap_Layer(t *testing.T) {
+	base := NewMap().Add(Point("Test"), &TestExtensions{})
+	sub := NewMap().Add(Point("Test"), &VendorExtensions{}).Add(Point("Other"), &TestExtensions{})
+
+	require.NoError(t, base.Merge(sub))
+
+	c, ok := base[Point("Test")].(*Composite)
+	require.True(t, ok)
+	assert.Len(t, c.Values(), 2)
+	assert.Equal(t, &TestExtensions{}, base[Point("Other")])
+
+	err := base.Layer(Point("Test"), &CollidingCBORExtensions{})
+	assert.ErrorIs(t, err, ErrFieldCollision)
+	assert.ErrorContains(t, err, `"Test": `)
+}
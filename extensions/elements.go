@@ -0,0 +1,53 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package extensions
+
+import "github.com/veraison/corim/encoding"
+
+// IRegisterer is implemented by the objects extensions can be registered with
+type IRegisterer interface {
+	RegisterExtensions(exts Map) error
+	GetExtensions() IMapValue
+}
+
+// elementExtensionsKey is the key of the extensions in scope of an
+// encoding.Decoder for the objects at a Point
+type elementExtensionsKey struct {
+	point Point
+}
+
+// WithElementExtensions returns a copy of dec with the supplied extensions in
+// scope for the objects at Point p that it decodes. This is used for the
+// elements of plain slices, which, unlike those of a Collection, have no
+// container to hold their extensions: the struct holding the slice keeps them
+// instead, and puts them in scope when it is decoded, so that the elements
+// can register them (see RegisterElementExtensions) before being populated.
+// If exts is empty, dec is returned as is.
+func WithElementExtensions(dec *encoding.Decoder, p Point, exts Map) *encoding.Decoder {
+	if len(exts) == 0 {
+		return dec
+	}
+
+	return dec.WithValue(elementExtensionsKey{p}, exts)
+}
+
+// RegisterElementExtensions registers a fresh instance of the extensions in
+// scope of dec for the objects at Point p (see WithElementExtensions) with v,
+// unless there are none or v already has extensions of its own.
+func RegisterElementExtensions(dec *encoding.Decoder, p Point, v IRegisterer) error {
+	exts, _ := dec.Value(elementExtensionsKey{p}).(Map)
+
+	return RegisterIfUnset(v, exts)
+}
+
+// RegisterIfUnset registers a fresh instance of exts with v, unless exts is
+// empty or v already has extensions of its own. This mirrors what a
+// Collection does for the values added to it.
+func RegisterIfUnset(v IRegisterer, exts Map) error {
+	if len(exts) == 0 || v.GetExtensions() != nil {
+		return nil
+	}
+
+	return v.RegisterExtensions(exts.Fresh())
+}
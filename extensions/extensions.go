@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cast"
+	"github.com/veraison/corim/encoding"
 )
 
 var ErrExtensionNotFound = errors.New("extension not found")
@@ -17,6 +18,11 @@ type IMapValue any
 
 type Extensions struct {
 	IMapValue `json:"extensions,omitempty"`
+
+	// Unknown holds the map entries that were neither known fields of the
+	// containing struct nor registered extensions. It is only populated when
+	// decoding with encoding.DecodeOptions.PreserveUnknownFields set.
+	Unknown *encoding.UnknownFields `cbor:"-" json:"-"`
 }
 
 func (o *Extensions) Register(exts IMapValue) {
@@ -27,6 +33,49 @@ func (o *Extensions) Register(exts IMapValue) {
 	o.IMapValue = exts
 }
 
+// Add registers an additional extensions struct alongside the ones already
+// registered (if any). The fields of all the registered structs are merged
+// when serializing, and all their constrainers are invoked on validation. An
+// error wrapping ErrFieldCollision is returned if the new struct defines a
+// CBOR key or a JSON name that is already defined by a registered struct.
+func (o *Extensions) Add(exts IMapValue) error {
+	if o.IMapValue == nil {
+		if _, err := Compose(exts); err != nil {
+			return err
+		}
+
+		o.IMapValue = exts
+
+		return nil
+	}
+
+	c, err := Compose(o.IMapValue, exts)
+	if err != nil {
+		return err
+	}
+
+	o.IMapValue = c
+
+	return nil
+}
+
+// ExtensionValues returns the registered extensions structs
+func (o *Extensions) ExtensionValues() []IMapValue {
+	return flatten(o.IMapValue)
+}
+
+// GetUnknownFields returns the unknown map entries retained when the
+// containing struct was decoded (if any)
+func (o Extensions) GetUnknownFields() *encoding.UnknownFields {
+	return o.Unknown
+}
+
+// SetUnknownFields sets the unknown map entries to be emitted alongside the
+// known fields when the containing struct is serialized
+func (o *Extensions) SetUnknownFields(unknown *encoding.UnknownFields) {
+	o.Unknown = unknown
+}
+
 func (o *Extensions) HaveExtensions() bool {
 	return o.IMapValue != nil
 }
@@ -36,12 +85,18 @@ func (o Extensions) New() IMapValue {
 }
 
 func (o *Extensions) Get(name string) (any, error) {
-	if o.IMapValue == nil {
-		return nil, fmt.Errorf("%w: %s", ErrExtensionNotFound, name)
+	for _, v := range o.ExtensionValues() {
+		if val, ok := getField(v, name); ok {
+			return val, nil
+		}
 	}
 
-	extType := reflect.TypeOf(o.IMapValue)
-	extVal := reflect.ValueOf(o.IMapValue)
+	return nil, fmt.Errorf("%w: %s", ErrExtensionNotFound, name)
+}
+
+func getField(v IMapValue, name string) (any, bool) {
+	extType := reflect.TypeOf(v)
+	extVal := reflect.ValueOf(v)
 	if extType.Kind() == reflect.Pointer {
 		extType = extType.Elem()
 		extVal = extVal.Elem()
@@ -63,26 +118,28 @@ func (o *Extensions) Get(name string) (any, error) {
 		}
 
 		if fieldName == name || fieldJSONTag == name || fieldCBORTag == name {
-			return extVal.Field(i).Interface(), nil
+			return extVal.Field(i).Interface(), true
 		}
 	}
 
-	return nil, fmt.Errorf("%w: %s", ErrExtensionNotFound, name)
+	return nil, false
 }
 
 func (o *Extensions) IsEmpty() bool {
-	if o.IMapValue == nil {
-		return true
+	if !o.Unknown.IsEmpty() {
+		return false
 	}
 
-	extVal := reflect.ValueOf(o.IMapValue)
-	if reflect.TypeOf(o.IMapValue).Kind() == reflect.Pointer {
-		extVal = extVal.Elem()
-	}
+	for _, v := range o.ExtensionValues() {
+		extVal := reflect.ValueOf(v)
+		if reflect.TypeOf(v).Kind() == reflect.Pointer {
+			extVal = extVal.Elem()
+		}
 
-	for i := 0; i < extVal.NumField(); i++ {
-		if !extVal.Field(i).IsZero() {
-			return false
+		for i := 0; i < extVal.NumField(); i++ {
+			if !extVal.Field(i).IsZero() {
+				return false
+			}
 		}
 	}
 
@@ -356,12 +413,18 @@ func (o *Extensions) GetStringMapString(name string) (map[string]string, error)
 }
 
 func (o *Extensions) Set(name string, value any) error {
-	if o.IMapValue == nil {
-		return fmt.Errorf("%w: %s", ErrExtensionNotFound, name)
+	for _, v := range o.ExtensionValues() {
+		if ok, err := setField(v, name, value); ok {
+			return err
+		}
 	}
 
-	extType := reflect.TypeOf(o.IMapValue)
-	extVal := reflect.ValueOf(o.IMapValue)
+	return fmt.Errorf("%w: %s", ErrExtensionNotFound, name)
+}
+
+func setField(v IMapValue, name string, value any) (bool, error) {
+	extType := reflect.TypeOf(v)
+	extVal := reflect.ValueOf(v)
 	if extType.Kind() == reflect.Pointer {
 		extType = extType.Elem()
 		extVal = extVal.Elem()
@@ -387,10 +450,10 @@ func (o *Extensions) Set(name string, value any) error {
 			newVal := reflect.ValueOf(value)
 			if newVal.CanConvert(valField.Type()) {
 				valField.Set(newVal.Convert(valField.Type()))
-				return nil
+				return true, nil
 			}
 
-			return fmt.Errorf(
+			return true, fmt.Errorf(
 				"cannot set field %q (of type %s) to %v (%T)",
 				name, typeField.Type.Name(),
 				value, value,
@@ -398,7 +461,7 @@ func (o *Extensions) Set(name string, value any) error {
 		}
 	}
 
-	return fmt.Errorf("%w: %s", ErrExtensionNotFound, name)
+	return false, nil
 }
 
 func newIMapValue(v IMapValue) IMapValue {
@@ -406,6 +469,14 @@ func newIMapValue(v IMapValue) IMapValue {
 		return nil
 	}
 
+	if c, ok := v.(*Composite); ok {
+		ret := &Composite{values: make([]IMapValue, 0, len(c.values))}
+		for _, cv := range c.values {
+			ret.values = append(ret.values, newIMapValue(cv))
+		}
+		return ret
+	}
+
 	valType := reflect.Indirect(reflect.ValueOf(v)).Type()
 
 	return reflect.New(valType).Interface()
@@ -0,0 +1,366 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package lint reports likely mistakes and questionable practices in CoRIM and
+// CoMID content that Valid does not treat as errors, such as the use of weak
+// digests or deprecated fields.
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/validation"
+	"github.com/veraison/eat"
+)
+
+// Severity is the level of a lint finding. Levels are ordered, from the least
+// to the most severe.
+type Severity int
+
+const (
+	// SeverityInfo is used for findings that are worth knowing about, but
+	// do not normally require any action
+	SeverityInfo Severity = iota
+	// SeverityWarning is used for findings that should normally be fixed
+	SeverityWarning
+	// SeverityError is used for findings that should block publication of
+	// the content
+	SeverityError
+)
+
+func (o Severity) String() string {
+	switch o {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(o))
+	}
+}
+
+// Finding is a single problem reported by a lint rule.
+type Finding struct {
+	// RuleID is the ID of the rule that reported the finding
+	RuleID string
+	// Severity of the finding
+	Severity Severity
+	// Path locates the offending value (see validation.Path)
+	Path validation.Path
+	// Message describes the finding
+	Message string
+}
+
+func (o Finding) String() string {
+	if len(o.Path) == 0 {
+		return fmt.Sprintf("%s [%s] %s", o.Severity, o.RuleID, o.Message)
+	}
+
+	return fmt.Sprintf("%s [%s] %s: %s", o.Severity, o.RuleID, o.Path.Pointer(), o.Message)
+}
+
+// Report is the list of findings produced by a Linter, in the order they were
+// reported.
+type Report []Finding
+
+// AtLeast returns the findings whose severity is at least min.
+func (o Report) AtLeast(min Severity) Report {
+	var ret Report
+
+	for _, f := range o {
+		if f.Severity >= min {
+			ret = append(ret, f)
+		}
+	}
+
+	return ret
+}
+
+// ByRule returns the findings reported by the specified rule.
+func (o Report) ByRule(id string) Report {
+	var ret Report
+
+	for _, f := range o {
+		if f.RuleID == id {
+			ret = append(ret, f)
+		}
+	}
+
+	return ret
+}
+
+// HasErrors returns true if the report contains any findings of
+// SeverityError.
+func (o Report) HasErrors() bool {
+	return len(o.AtLeast(SeverityError)) != 0
+}
+
+// String returns the findings, one per line.
+func (o Report) String() string {
+	lines := make([]string, 0, len(o))
+
+	for _, f := range o {
+		lines = append(lines, f.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Rule is a lint check. A rule may inspect CoMIDs, unsigned CoRIMs, or both.
+type Rule struct {
+	// ID uniquely identifies the rule, and is used to suppress it or to
+	// change its severity
+	ID string
+	// Description briefly describes what the rule checks
+	Description string
+	// Severity is the default severity of the rule's findings
+	Severity Severity
+	// Comid, if set, is invoked for each linted CoMID, including those
+	// carried in a linted CoRIM
+	Comid func(r *Reporter, c *comid.Comid)
+	// Corim, if set, is invoked for each linted unsigned CoRIM. tags
+	// holds its decoded tags, in the same order as u.Tags; the entries for
+	// tags that could not be decoded are nil.
+	Corim func(r *Reporter, u *corim.UnsignedCorim, tags []corim.TypedTag)
+}
+
+// Reporter is used by rules to report findings at a given location. The
+// reporters for nested values are derived via Field and Index.
+type Reporter struct {
+	loc    *validation.Collector
+	rule   string
+	sev    Severity
+	now    time.Time
+	report *Report
+}
+
+// Field returns the Reporter for the named field of v, which must be a struct
+// (or a pointer to one). See validation.Collector.Field.
+func (o *Reporter) Field(v any, name string) *Reporter {
+	ret := *o
+	ret.loc = o.loc.Field(v, name)
+	return &ret
+}
+
+// Index returns the Reporter for the element at the specified index of an
+// array.
+func (o *Reporter) Index(i int) *Reporter {
+	ret := *o
+	ret.loc = o.loc.Index(i)
+	return &ret
+}
+
+// Report records a finding of the rule's severity at the location of the
+// Reporter. The message is formatted as with fmt.Sprintf.
+func (o *Reporter) Report(format string, args ...any) {
+	*o.report = append(*o.report, Finding{
+		RuleID:   o.rule,
+		Severity: o.sev,
+		Path:     o.loc.Path(),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Now returns the time at which linting started (see Linter.SetClock).
+func (o *Reporter) Now() time.Time {
+	return o.now
+}
+
+// Linter applies a set of rules to CoRIM and CoMID content. Rules can be
+// suppressed, either always or only for content associated with a given
+// profile, and their severity can be changed.
+type Linter struct {
+	rules      []Rule
+	severity   map[string]Severity
+	suppressed map[string]bool
+	profiles   map[string]map[string]bool
+	clock      func() time.Time
+}
+
+// NewLinter instantiates a Linter with the supplied rules, or with the ones
+// returned by DefaultRules if none are supplied.
+func NewLinter(rules ...Rule) *Linter {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+
+	return &Linter{
+		rules:      rules,
+		severity:   make(map[string]Severity),
+		suppressed: make(map[string]bool),
+		profiles:   make(map[string]map[string]bool),
+		clock:      time.Now,
+	}
+}
+
+// Rules returns the rules of the target Linter
+func (o *Linter) Rules() []Rule {
+	return o.rules
+}
+
+func (o *Linter) checkIDs(ids []string) error {
+	for _, id := range ids {
+		if !o.hasRule(id) {
+			return fmt.Errorf("unknown rule %q", id)
+		}
+	}
+
+	return nil
+}
+
+func (o *Linter) hasRule(id string) bool {
+	for _, r := range o.rules {
+		if r.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Suppress disables the specified rules.
+func (o *Linter) Suppress(ids ...string) error {
+	if err := o.checkIDs(ids); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		o.suppressed[id] = true
+	}
+
+	return nil
+}
+
+// SuppressForProfile disables the specified rules for content associated with
+// the specified profile, i.e. CoRIMs declaring that profile, the CoMIDs they
+// carry, and CoMIDs linted with that profile.
+func (o *Linter) SuppressForProfile(profile *eat.Profile, ids ...string) error {
+	strID, err := profile.Get()
+	if err != nil {
+		return err
+	}
+
+	if err := o.checkIDs(ids); err != nil {
+		return err
+	}
+
+	if o.profiles[strID] == nil {
+		o.profiles[strID] = make(map[string]bool)
+	}
+
+	for _, id := range ids {
+		o.profiles[strID][id] = true
+	}
+
+	return nil
+}
+
+// SetSeverity overrides the default severity of the specified rule.
+func (o *Linter) SetSeverity(id string, s Severity) error {
+	if err := o.checkIDs([]string{id}); err != nil {
+		return err
+	}
+
+	o.severity[id] = s
+
+	return nil
+}
+
+// SetClock sets the function used to obtain the current time, which rules
+// such as the one about RIM validity compare against. It defaults to
+// time.Now.
+func (o *Linter) SetClock(clock func() time.Time) *Linter {
+	o.clock = clock
+	return o
+}
+
+// active returns the rules that are not suppressed for the specified profile
+// (which may be nil).
+func (o *Linter) active(profile *eat.Profile) []Rule {
+	var forProfile map[string]bool
+
+	if profile != nil {
+		if strID, err := profile.Get(); err == nil {
+			forProfile = o.profiles[strID]
+		}
+	}
+
+	var ret []Rule
+
+	for _, r := range o.rules {
+		if o.suppressed[r.ID] || forProfile[r.ID] {
+			continue
+		}
+
+		if s, ok := o.severity[r.ID]; ok {
+			r.Severity = s
+		}
+
+		ret = append(ret, r)
+	}
+
+	return ret
+}
+
+func (o *Linter) reporter(r Rule, loc *validation.Collector, now time.Time, report *Report) *Reporter {
+	return &Reporter{loc: loc, rule: r.ID, sev: r.Severity, now: now, report: report}
+}
+
+// LintComid applies the rules to the supplied CoMID. Profile is optional, and
+// is only used to determine which rules are suppressed.
+func (o *Linter) LintComid(c *comid.Comid, profile *eat.Profile) Report {
+	var report Report
+
+	o.lintComid(c, o.active(profile), validation.NewCollector(), o.clock(), &report)
+
+	return report
+}
+
+func (o *Linter) lintComid(
+	c *comid.Comid, rules []Rule, loc *validation.Collector, now time.Time, report *Report,
+) {
+	for _, r := range rules {
+		if r.Comid != nil {
+			r.Comid(o.reporter(r, loc, now, report), c)
+		}
+	}
+}
+
+// LintUnsignedCorim applies the rules to the supplied unsigned CoRIM and to
+// the CoMIDs it carries. The rules suppressed for the CoRIM's profile (if
+// any) are not applied. Tags that cannot be decoded are skipped (Valid
+// reports them).
+func (o *Linter) LintUnsignedCorim(u *corim.UnsignedCorim) Report {
+	var report Report
+
+	rules := o.active(u.Profile)
+	now := o.clock()
+	root := validation.NewCollector()
+
+	tags := make([]corim.TypedTag, len(u.Tags))
+	for i, t := range u.Tags {
+		if tt, err := t.Decode(u.Profile); err == nil {
+			tags[i] = tt
+		}
+	}
+
+	for _, r := range rules {
+		if r.Corim != nil {
+			r.Corim(o.reporter(r, root, now, &report), u, tags)
+		}
+	}
+
+	for i, tt := range tags {
+		if tc, ok := tt.(corim.TaggedComid); ok {
+			o.lintComid(tc.Comid, rules, root.Field(u, "Tags").Index(i), now, &report)
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/corim/validation"
+	"github.com/veraison/eat"
+)
+
+func TestSeverity_String(t *testing.T) {
+	assert.Equal(t, "info", SeverityInfo.String())
+	assert.Equal(t, "warning", SeverityWarning.String())
+	assert.Equal(t, "error", SeverityError.String())
+	assert.Equal(t, "Severity(7)", Severity(7).String())
+}
+
+func TestReport(t *testing.T) {
+	report := Report{
+		{RuleID: "a", Severity: SeverityInfo, Message: "first"},
+		{
+			RuleID:   "b",
+			Severity: SeverityError,
+			Path:     validation.Path{{Name: "entities", Key: 2}},
+			Message:  "second",
+		},
+		{RuleID: "a", Severity: SeverityWarning, Message: "third"},
+	}
+
+	assert.Equal(t, "info [a] first\nerror [b] /entities: second\nwarning [a] third", report.String())
+	assert.Len(t, report.AtLeast(SeverityWarning), 2)
+	assert.Len(t, report.ByRule("a"), 2)
+	assert.True(t, report.HasErrors())
+	assert.False(t, report.ByRule("a").HasErrors())
+	assert.Empty(t, Report{}.String())
+}
+
+func TestLinter_custom_rule(t *testing.T) {
+	rule := Rule{
+		ID:       "no-language",
+		Severity: SeverityError,
+		Comid: func(r *Reporter, c *comid.Comid) {
+			if c.Language == nil {
+				r.Field(c, "Language").Report("language not set for tag %s", c.TagIdentity.TagID.String())
+			}
+		},
+	}
+
+	l := NewLinter(rule)
+	require.Len(t, l.Rules(), 1)
+
+	report := l.LintComid(comid.NewComid().SetTagIdentity("test", 0), nil)
+	require.Len(t, report, 1)
+	assert.Equal(t, "error [no-language] /lang: language not set for tag test", report[0].String())
+	assert.Equal(t, []any{0}, report[0].Path.CBORKeys())
+}
+
+func TestLinter_Suppress(t *testing.T) {
+	c := testComid(t)
+
+	l := NewLinter()
+	assert.Len(t, l.LintComid(c, nil).ByRule(RuleWeakDigest), 2)
+
+	require.NoError(t, l.Suppress(RuleWeakDigest, RuleDuplicateMeasurement))
+
+	report := l.LintComid(c, nil)
+	assert.Empty(t, report.ByRule(RuleWeakDigest))
+	assert.Empty(t, report.ByRule(RuleDuplicateMeasurement))
+	assert.NotEmpty(t, report.ByRule(RuleMissingTagCreator))
+
+	assert.EqualError(t, l.Suppress("no-such-rule"), `unknown rule "no-such-rule"`)
+}
+
+func TestLinter_SuppressForProfile(t *testing.T) {
+	profile, err := eat.NewProfile("http://example.com/lint-profile")
+	require.NoError(t, err)
+
+	other, err := eat.NewProfile("http://example.com/other-profile")
+	require.NoError(t, err)
+
+	l := NewLinter()
+	require.NoError(t, l.SuppressForProfile(profile, RuleMissingTagCreator))
+
+	c := testComid(t)
+	assert.Empty(t, l.LintComid(c, profile).ByRule(RuleMissingTagCreator))
+	assert.Len(t, l.LintComid(c, other).ByRule(RuleMissingTagCreator), 1)
+	assert.Len(t, l.LintComid(c, nil).ByRule(RuleMissingTagCreator), 1)
+
+	// the profile declared by a CoRIM applies to the CoMIDs it carries
+	c.Triples.ReferenceValues.Values[0].Measurements.Values[1].Val.Digests = nil
+
+	u := corim.NewUnsignedCorim().SetID("test.corim").AddComid(c)
+	require.NotNil(t, u)
+	assert.Len(t, NewLinter().LintUnsignedCorim(u).ByRule(RuleMissingTagCreator), 1)
+
+	u.Profile = profile
+	assert.Empty(t, l.LintUnsignedCorim(u).ByRule(RuleMissingTagCreator))
+
+	assert.EqualError(t, l.SuppressForProfile(profile, "no-such-rule"), `unknown rule "no-such-rule"`)
+	assert.Error(t, l.SuppressForProfile(&eat.Profile{}, RuleWeakDigest))
+}
+
+func TestLinter_SetSeverity(t *testing.T) {
+	l := NewLinter()
+	require.NoError(t, l.SetSeverity(RuleMissingTagCreator, SeverityError))
+
+	report := l.LintComid(testComid(t), nil)
+	assert.True(t, report.HasErrors())
+	assert.Equal(t, SeverityError, report.ByRule(RuleMissingTagCreator)[0].Severity)
+
+	// the rules themselves are left untouched
+	for _, r := range l.Rules() {
+		if r.ID == RuleMissingTagCreator {
+			assert.Equal(t, SeverityWarning, r.Severity)
+		}
+	}
+
+	assert.EqualError(t, l.SetSeverity("no-such-rule", SeverityInfo), `unknown rule "no-such-rule"`)
+}
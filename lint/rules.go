@@ -0,0 +1,293 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"time"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/swid"
+)
+
+// IDs of the built-in rules
+const (
+	RuleWeakDigest              = "weak-digest"
+	RuleInstanceOnlyEnvironment = "instance-only-environment"
+	RuleDuplicateMeasurement    = "duplicate-measurement"
+	RuleMissingTagCreator       = "missing-tag-creator"
+	RuleExpiringValidity        = "expiring-validity"
+	RuleUnusedLinkedTag         = "unused-linked-tag"
+	RuleDeprecatedRawValueMask  = "deprecated-raw-value-mask"
+)
+
+// DefaultExpiryWindow is the period before the end of a RIM validity within
+// which the default expiring-validity rule reports it.
+const DefaultExpiryWindow = 30 * 24 * time.Hour
+
+// DefaultRules returns the built-in rules.
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			ID:          RuleWeakDigest,
+			Description: "digests using a truncated or SHA-1 sized hash",
+			Severity:    SeverityWarning,
+			Comid:       checkWeakDigests,
+		},
+		{
+			ID:          RuleInstanceOnlyEnvironment,
+			Description: "environments with an instance, but no (or an empty) class",
+			Severity:    SeverityWarning,
+			Comid:       checkInstanceOnlyEnvironments,
+		},
+		{
+			ID:          RuleDuplicateMeasurement,
+			Description: "measurements with the same key within a triple",
+			Severity:    SeverityWarning,
+			Comid:       checkDuplicateMeasurements,
+		},
+		{
+			ID:          RuleMissingTagCreator,
+			Description: "CoMIDs without an entity claiming the tag-creator role",
+			Severity:    SeverityWarning,
+			Comid:       checkTagCreator,
+		},
+		ExpiringValidityRule(DefaultExpiryWindow),
+		{
+			ID: RuleUnusedLinkedTag,
+			Description: "empty or self-referencing linked tags, and linked tags " +
+				"referring to tags not carried in the CoRIM",
+			Severity: SeverityInfo,
+			Comid:    checkLinkedTags,
+			Corim:    checkCorimLinkedTags,
+		},
+		{
+			ID:          RuleDeprecatedRawValueMask,
+			Description: "use of the deprecated raw-value-mask",
+			Severity:    SeverityWarning,
+			Comid:       checkRawValueMask,
+		},
+	}
+}
+
+// ExpiringValidityRule returns the expiring-validity rule, which reports a RIM
+// validity period that has ended, or that ends within the specified window.
+func ExpiringValidityRule(window time.Duration) Rule {
+	return Rule{
+		ID:          RuleExpiringValidity,
+		Description: "RIM validity periods that have ended or are about to end",
+		Severity:    SeverityWarning,
+		Corim: func(r *Reporter, u *corim.UnsignedCorim, _ []corim.TypedTag) {
+			if u.RimValidity == nil {
+				return
+			}
+
+			notAfter := u.RimValidity.NotAfter
+			loc := r.Field(u, "RimValidity").Field(u.RimValidity, "NotAfter")
+
+			switch {
+			case notAfter.Before(r.Now()):
+				loc.Report("validity period ended on %s", notAfter.Format(time.RFC3339))
+			case notAfter.Before(r.Now().Add(window)):
+				loc.Report("validity period ends on %s, in less than %s",
+					notAfter.Format(time.RFC3339), window)
+			}
+		},
+	}
+}
+
+// forEachValueTriple invokes fn for the reference and endorsed values of c
+func forEachValueTriple(r *Reporter, c *comid.Comid, fn func(r *Reporter, vt *comid.ValueTriple)) {
+	triples := r.Field(c, "Triples")
+
+	for _, t := range []struct {
+		name string
+		vts  *comid.ValueTriples
+	}{
+		{"ReferenceValues", c.Triples.ReferenceValues},
+		{"EndorsedValues", c.Triples.EndorsedValues},
+	} {
+		if t.vts == nil {
+			continue
+		}
+
+		loc := triples.Field(c.Triples, t.name)
+		for i := range t.vts.Values {
+			fn(loc.Index(i), &t.vts.Values[i])
+		}
+	}
+}
+
+// forEachMeasurement invokes fn for the measurements of the reference and
+// endorsed values of c
+func forEachMeasurement(r *Reporter, c *comid.Comid, fn func(r *Reporter, m *comid.Measurement)) {
+	forEachValueTriple(r, c, func(r *Reporter, vt *comid.ValueTriple) {
+		loc := r.Field(vt, "Measurements")
+		for i := range vt.Measurements.Values {
+			fn(loc.Index(i), &vt.Measurements.Values[i])
+		}
+	})
+}
+
+// forEachEnvironment invokes fn for the environments of all the triples of c
+func forEachEnvironment(r *Reporter, c *comid.Comid, fn func(r *Reporter, env *comid.Environment)) {
+	forEachValueTriple(r, c, func(r *Reporter, vt *comid.ValueTriple) {
+		fn(r.Field(vt, "Environment"), &vt.Environment)
+	})
+
+	triples := r.Field(c, "Triples")
+
+	for _, t := range []struct {
+		name string
+		kts  *comid.KeyTriples
+	}{
+		{"AttestVerifKeys", c.Triples.AttestVerifKeys},
+		{"DevIdentityKeys", c.Triples.DevIdentityKeys},
+	} {
+		if t.kts == nil {
+			continue
+		}
+
+		loc := triples.Field(c.Triples, t.name)
+		for i := range *t.kts {
+			kt := &(*t.kts)[i]
+			fn(loc.Index(i).Field(kt, "Environment"), &kt.Environment)
+		}
+	}
+}
+
+// sha1Size is the size of a SHA-1 digest, which has no hash algorithm ID in
+// the named information registry, but may still appear with a bogus one
+const sha1Size = 20
+
+func checkWeakDigests(r *Reporter, c *comid.Comid) {
+	forEachMeasurement(r, c, func(r *Reporter, m *comid.Measurement) {
+		if m.Val.Digests == nil {
+			return
+		}
+
+		loc := r.Field(m, "Val").Field(m.Val, "Digests")
+
+		for i, d := range *m.Val.Digests {
+			switch {
+			case d.HashAlgID >= swid.Sha256_128 && d.HashAlgID <= swid.Sha256_32:
+				loc.Index(i).Report("truncated digest (%s)", d.AlgIDToString())
+			case len(d.HashValue) == sha1Size:
+				loc.Index(i).Report("SHA-1 sized digest (%d bytes)", sha1Size)
+			}
+		}
+	})
+}
+
+func checkInstanceOnlyEnvironments(r *Reporter, c *comid.Comid) {
+	forEachEnvironment(r, c, func(r *Reporter, env *comid.Environment) {
+		if env.Instance == nil {
+			return
+		}
+
+		if env.Class == nil {
+			r.Report("environment has an instance, but no class")
+		} else if env.Class.IsEmpty() {
+			r.Field(env, "Class").Report("environment has an instance, but an empty class")
+		}
+	})
+}
+
+func checkDuplicateMeasurements(r *Reporter, c *comid.Comid) {
+	forEachValueTriple(r, c, func(r *Reporter, vt *comid.ValueTriple) {
+		seen := make(map[string]int)
+		loc := r.Field(vt, "Measurements")
+
+		for i, m := range vt.Measurements.Values {
+			if m.Key == nil || !m.Key.IsSet() {
+				continue
+			}
+
+			key, err := m.Key.MarshalCBOR()
+			if err != nil {
+				continue
+			}
+
+			if first, ok := seen[string(key)]; ok {
+				loc.Index(i).Report("measurement key already used at index %d", first)
+				continue
+			}
+
+			seen[string(key)] = i
+		}
+	})
+}
+
+func checkTagCreator(r *Reporter, c *comid.Comid) {
+	if c.Entities != nil {
+		for _, e := range c.Entities.Values {
+			for _, role := range e.Roles {
+				if role == comid.RoleTagCreator {
+					return
+				}
+			}
+		}
+	}
+
+	r.Field(c, "Entities").Report("no entity claims the tag-creator role")
+}
+
+func checkLinkedTags(r *Reporter, c *comid.Comid) {
+	if c.LinkedTags == nil {
+		return
+	}
+
+	loc := r.Field(c, "LinkedTags")
+
+	if len(*c.LinkedTags) == 0 {
+		loc.Report("empty linked-tags")
+		return
+	}
+
+	for i, lt := range *c.LinkedTags {
+		if lt.LinkedTagID == c.TagIdentity.TagID {
+			loc.Index(i).Report("linked tag refers to the CoMID itself")
+		}
+	}
+}
+
+func checkCorimLinkedTags(r *Reporter, u *corim.UnsignedCorim, tags []corim.TypedTag) {
+	carried := make(map[swid.TagID]bool)
+
+	for _, tt := range tags {
+		if tt == nil {
+			continue
+		}
+
+		if id := tt.GetTagIdentity(); id != nil {
+			carried[id.TagID] = true
+		}
+	}
+
+	for i, tt := range tags {
+		tc, ok := tt.(corim.TaggedComid)
+		if !ok || tc.Comid.LinkedTags == nil {
+			continue
+		}
+
+		loc := r.Field(u, "Tags").Index(i).Field(tc.Comid, "LinkedTags")
+
+		for j, lt := range *tc.Comid.LinkedTags {
+			if lt.LinkedTagID != tc.Comid.TagIdentity.TagID && !carried[lt.LinkedTagID] {
+				loc.Index(j).Report("linked tag %q is not carried in the CoRIM", lt.LinkedTagID.String())
+			}
+		}
+	}
+}
+
+func checkRawValueMask(r *Reporter, c *comid.Comid) {
+	forEachMeasurement(r, c, func(r *Reporter, m *comid.Measurement) {
+		if m.Val.RawValueMask == nil {
+			return
+		}
+
+		r.Field(m, "Val").Field(m.Val, "RawValueMask").
+			Report("raw-value-mask is deprecated, use a masked raw-value instead")
+	})
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	"github.com/veraison/swid"
+)
+
+const testOtherTagID = "urn:example:other"
+
+var testNow = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func testComid(t *testing.T) *comid.Comid {
+	m0 := comid.MustNewUintMeasurement(uint64(1)).
+		AddDigest(swid.Sha256_32, []byte{0xde, 0xad, 0xbe, 0xef})
+	require.NotNil(t, m0)
+
+	// a SHA-1 digest mislabelled as SHA-256, and the same key as m0
+	m1 := comid.MustNewUintMeasurement(uint64(1))
+	m1.Val.Digests = &comid.Digests{{HashAlgID: swid.Sha256, HashValue: make([]byte, 20)}}
+	m1.SetRawValueBytes([]byte{0x01, 0x02}, []byte{0xff, 0x00})
+
+	m2 := comid.MustNewUintMeasurement(uint64(2)).SetSVN(1)
+
+	c := comid.NewComid().
+		SetTagIdentity(comid.TestTagID, 0).
+		AddEntity("ACME Ltd.", nil, comid.RoleCreator).
+		AddLinkedTag(comid.TestTagID, comid.RelSupplements).
+		AddLinkedTag(testOtherTagID, comid.RelSupplements).
+		AddReferenceValue(comid.ValueTriple{
+			Environment: comid.Environment{
+				Instance: comid.MustNewUUIDInstance(comid.TestUUID),
+			},
+			Measurements: *comid.NewMeasurements().Add(m0).Add(m1).Add(m2),
+		})
+	require.NotNil(t, c)
+
+	return c
+}
+
+func TestDefaultRules_comid(t *testing.T) {
+	report := NewLinter().LintComid(testComid(t), nil)
+
+	expected := []struct {
+		rule    string
+		pointer string
+		msg     string
+	}{
+		{
+			RuleWeakDigest, "/triples/reference-values/0/measurements/0/value/digests/0",
+			"truncated digest (sha-256-32)",
+		},
+		{
+			RuleWeakDigest, "/triples/reference-values/0/measurements/1/value/digests/0",
+			"SHA-1 sized digest (20 bytes)",
+		},
+		{
+			RuleInstanceOnlyEnvironment, "/triples/reference-values/0/environment",
+			"environment has an instance, but no class",
+		},
+		{
+			RuleDuplicateMeasurement, "/triples/reference-values/0/measurements/1",
+			"measurement key already used at index 0",
+		},
+		{RuleMissingTagCreator, "/entities", "no entity claims the tag-creator role"},
+		{RuleUnusedLinkedTag, "/linked-tags/0", "linked tag refers to the CoMID itself"},
+		{
+			RuleDeprecatedRawValueMask, "/triples/reference-values/0/measurements/1/value/raw-value-mask",
+			"raw-value-mask is deprecated, use a masked raw-value instead",
+		},
+	}
+
+	require.Len(t, report, len(expected))
+
+	for i, e := range expected {
+		assert.Equal(t, e.rule, report[i].RuleID)
+		assert.Equal(t, e.pointer, report[i].Path.Pointer())
+		assert.Equal(t, e.msg, report[i].Message)
+	}
+
+	assert.Equal(t, []any{4, 0, 0, 1, 1, 1, 5}, report[6].Path.CBORKeys())
+	assert.Equal(t, SeverityInfo, report[5].Severity)
+	assert.Equal(t, SeverityWarning, report[0].Severity)
+}
+
+func TestDefaultRules_comid_clean(t *testing.T) {
+	c := comid.NewComid()
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	report := NewLinter().LintComid(c, nil)
+	assert.Empty(t, report, report.String())
+}
+
+func TestDefaultRules_corim(t *testing.T) {
+	c := testComid(t)
+
+	// drop the invalid digest, so that the CoMID can be added
+	c.Triples.ReferenceValues.Values[0].Measurements.Values[1].Val.Digests = nil
+
+	u := corim.NewUnsignedCorim().
+		SetID("test.corim").
+		AddComid(c).
+		SetRimValidity(testNow.Add(7*24*time.Hour), nil)
+	require.NotNil(t, u)
+
+	report := NewLinter().
+		SetClock(func() time.Time { return testNow }).
+		LintUnsignedCorim(u)
+
+	validity := report.ByRule(RuleExpiringValidity)
+	require.Len(t, validity, 1)
+	assert.Equal(t, "/validity/not-after", validity[0].Path.Pointer())
+	assert.Equal(t,
+		"validity period ends on 2024-06-08T00:00:00Z, in less than 720h0m0s",
+		validity[0].Message)
+
+	linked := report.ByRule(RuleUnusedLinkedTag)
+	require.Len(t, linked, 2)
+	assert.Equal(t, "/tags/0/linked-tags/1", linked[0].Path.Pointer())
+	assert.Equal(t, `linked tag "urn:example:other" is not carried in the CoRIM`, linked[0].Message)
+	assert.Equal(t, "/tags/0/linked-tags/0", linked[1].Path.Pointer())
+
+	// CoMID findings are located within the CoRIM
+	weak := report.ByRule(RuleWeakDigest)
+	require.Len(t, weak, 1)
+	assert.Equal(t, "/tags/0/triples/reference-values/0/measurements/0/value/digests/0",
+		weak[0].Path.Pointer())
+	assert.Equal(t, []any{1, 0, 4, 0, 0, 1, 0, 1, 2, 0}, weak[0].Path.CBORKeys())
+}
+
+func TestExpiringValidityRule(t *testing.T) {
+	u := corim.NewUnsignedCorim().SetRimValidity(testNow.Add(-time.Hour), nil)
+	require.NotNil(t, u)
+
+	l := NewLinter(ExpiringValidityRule(time.Hour)).
+		SetClock(func() time.Time { return testNow })
+
+	report := l.LintUnsignedCorim(u)
+	require.Len(t, report, 1)
+	assert.Equal(t, "validity period ended on 2024-05-31T23:00:00Z", report[0].Message)
+
+	u.SetRimValidity(testNow.Add(2*time.Hour), nil)
+	assert.Empty(t, l.LintUnsignedCorim(u))
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/veraison/corim/encoding"
+)
+
+// Collector accumulates the problems found while validating a structure,
+// rather than stopping at the first one. A Collector is associated with the
+// location of the value being validated; the collectors for nested values are
+// derived via Field and Index, and share the same list of problems.
+type Collector struct {
+	path    Path
+	context []string
+	errs    *Errors
+}
+
+// NewCollector returns a Collector for the root of a structure.
+func NewCollector() *Collector {
+	return &Collector{errs: new(Errors)}
+}
+
+// Field returns the Collector for the named field of v, which must be a
+// struct (or a pointer to one). The JSON name and CBOR key of the field are
+// taken from its tags.
+func (o *Collector) Field(v any, name string) *Collector {
+	jsonName, cborKey, ok := encoding.FieldKeys(reflect.TypeOf(v), name)
+	if !ok {
+		jsonName = name
+	}
+
+	return o.at(Segment{Name: jsonName, Key: cborKey})
+}
+
+// Index returns the Collector for the element at the specified index of an
+// array.
+func (o *Collector) Index(i int) *Collector {
+	return o.at(Segment{Name: strconv.Itoa(i), Key: i})
+}
+
+func (o *Collector) at(seg Segment) *Collector {
+	path := make(Path, len(o.path), len(o.path)+1)
+	copy(path, o.path)
+
+	return &Collector{path: append(path, seg), context: o.context, errs: o.errs}
+}
+
+// Wrap returns a Collector for the same location, whose problems are
+// prefixed with the formatted message (e.g. "class validation failed") in the
+// error returned by Errors.First. This allows the Valid methods to be
+// implemented on top of a Collector, while retaining their error messages.
+func (o *Collector) Wrap(format string, args ...any) *Collector {
+	context := make([]string, len(o.context), len(o.context)+1)
+	copy(context, o.context)
+
+	return &Collector{
+		path:    o.path,
+		context: append(context, fmt.Sprintf(format, args...)),
+		errs:    o.errs,
+	}
+}
+
+// Path returns the location associated with the Collector.
+func (o *Collector) Path() Path {
+	return o.path
+}
+
+// Error records a problem of the specified kind (one of the sentinel errors)
+// at the location of the Collector.
+func (o *Collector) Error(kind error, err error) {
+	o.add(SeverityError, kind, err)
+}
+
+// Warning records a problem of the specified kind that does not make the
+// value invalid.
+func (o *Collector) Warning(kind error, err error) {
+	o.add(SeverityWarning, kind, err)
+}
+
+func (o *Collector) add(severity Severity, kind error, err error) {
+	*o.errs = append(*o.errs, &Error{
+		Path:     o.path,
+		Code:     Code(kind),
+		Severity: severity,
+		Kind:     kind,
+		Err:      err,
+		context:  o.context,
+	})
+}
+
+// Check records err, as returned by the Valid method of the value at the
+// location of the Collector, if it is not nil, and reports whether it was.
+// The kind of the problem is taken from the sentinel error wrapped by err, if
+// any, and is ErrInvalidValue otherwise. If err is itself an Errors, its
+// entries are recorded relative to the location of the Collector.
+func (o *Collector) Check(err error) bool {
+	return o.check(err, ErrInvalidValue)
+}
+
+// CheckExtension is like Check, but for errors returned by the validation
+// methods of registered extensions, for which the default kind is
+// ErrExtension.
+func (o *Collector) CheckExtension(err error) bool {
+	return o.check(err, ErrExtension)
+}
+
+func (o *Collector) check(err error, defaultKind error) bool {
+	if err == nil {
+		return true
+	}
+
+	var errs Errors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			nested := *e
+			nested.Path = append(append(Path{}, o.path...), e.Path...)
+			nested.context = append(append([]string{}, o.context...), e.context...)
+			*o.errs = append(*o.errs, &nested)
+		}
+
+		return len(errs) == 0
+	}
+
+	o.Error(kindOf(err, defaultKind), err)
+
+	return false
+}
+
+func kindOf(err error, defaultKind error) error {
+	for _, kind := range []error{ErrEmpty, ErrMissing, ErrExtension, ErrInvalidValue} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+
+	return defaultKind
+}
+
+// Errors returns the problems recorded so far (via any Collector derived from
+// the same root).
+func (o *Collector) Errors() Errors {
+	return *o.errs
+}
+
+// FirstError runs the supplied validation function with a new Collector, and
+// returns the first problem it records of SeverityError (see Errors.First).
+// It is used to implement the Valid methods on top of the functions reporting
+// all problems.
+func FirstError(validate func(*Collector)) error {
+	c := NewCollector()
+	validate(c)
+
+	return c.Errors().First()
+}
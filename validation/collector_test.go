@@ -0,0 +1,119 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testInner struct {
+	_     struct{} `cbor:",toarray"`
+	Label string   `json:"label"`
+	Value int      `json:"value"`
+}
+
+type testOuter struct {
+	Inner  []testInner `cbor:"1,keyasint" json:"inner"`
+	Named  string      `cbor:"name" json:"named"`
+	NoTags int
+}
+
+func TestCollector(t *testing.T) {
+	var o testOuter
+
+	c := NewCollector()
+	assert.Empty(t, c.Errors())
+
+	inner := c.Field(o, "Inner").Index(2)
+	assert.True(t, inner.Check(nil))
+	assert.False(t, inner.Field(testInner{}, "Value").Check(errors.New("bad value")))
+
+	c.Field(&o, "Named").Error(ErrMissing, errors.New("no name"))
+	c.Field(o, "NoTags").Warning(ErrInvalidValue, errors.New("old"))
+	c.Field(o, "Unknown").Check(fmt.Errorf("wrapped: %w", ErrEmpty))
+	c.CheckExtension(errors.New("profile says no"))
+
+	errs := c.Errors()
+	require.Len(t, errs, 5)
+
+	assert.Equal(t, "/inner/2/value", errs[0].Path.Pointer())
+	assert.Equal(t, []any{1, 2, 1}, errs[0].Path.CBORKeys())
+	assert.Equal(t, "invalid-value", errs[0].Code)
+	assert.Equal(t, SeverityError, errs[0].Severity)
+
+	assert.Equal(t, "/named", errs[1].Path.Pointer())
+	assert.Equal(t, []any{"name"}, errs[1].Path.CBORKeys())
+	assert.Equal(t, "missing", errs[1].Code)
+
+	assert.Equal(t, "/NoTags", errs[2].Path.Pointer())
+	assert.Equal(t, []any{nil}, errs[2].Path.CBORKeys())
+	assert.Equal(t, SeverityWarning, errs[2].Severity)
+
+	assert.Equal(t, "/Unknown", errs[3].Path.Pointer())
+	assert.Equal(t, "empty", errs[3].Code)
+
+	assert.Empty(t, errs[4].Path)
+	assert.Equal(t, "extension", errs[4].Code)
+	assert.ErrorIs(t, errs[4], ErrExtension)
+
+	assert.EqualError(t, errs,
+		"/inner/2/value: bad value; /named: no name; warning: /NoTags: old; "+
+			"/Unknown: wrapped: empty; profile says no")
+}
+
+func TestCollector_Check_nested(t *testing.T) {
+	nested := NewCollector()
+	nested.Field(testInner{}, "Label").Error(ErrEmpty, errors.New("no label"))
+
+	c := NewCollector()
+	child := c.Field(testOuter{}, "Inner").Index(0)
+	assert.False(t, child.CheckExtension(nested.Errors()))
+
+	// the nested entries are relocated, and keep their kind
+	errs := c.Errors()
+	require.Len(t, errs, 1)
+	assert.Equal(t, "/inner/0/label", errs[0].Path.Pointer())
+	assert.Equal(t, []any{1, 0, 0}, errs[0].Path.CBORKeys())
+	assert.Equal(t, "empty", errs[0].Code)
+
+	// the original entries are left untouched
+	assert.Equal(t, "/label", nested.Errors()[0].Path.Pointer())
+
+	assert.Equal(t, Path{{Name: "inner", Key: 1}, {Name: "0", Key: 0}}, child.Path())
+}
+
+func TestCollector_Wrap(t *testing.T) {
+	c := NewCollector()
+	assert.NoError(t, c.Errors().First())
+
+	inner := c.Field(testOuter{}, "Inner").Wrap("inner validation failed")
+	inner.Index(0).Warning(ErrInvalidValue, errors.New("old"))
+
+	entry := inner.Index(1).Wrap("entry at index %d", 1)
+	entry.Field(testInner{}, "Label").Error(ErrEmpty, errors.New("no label"))
+
+	nested := NewCollector()
+	nested.Wrap("value").Error(ErrInvalidValue, errors.New("too big"))
+	entry.Check(nested.Errors())
+
+	errs := c.Errors()
+	require.Len(t, errs, 3)
+
+	// the location is not affected by the context
+	assert.Equal(t, "/inner/1/label", errs[1].Path.Pointer())
+	assert.EqualError(t, errs[1], "/inner/1/label: no label")
+
+	// the first error (not warning) is reported with its context
+	first := errs.First()
+	assert.EqualError(t, first, "inner validation failed: entry at index 1: no label")
+	assert.ErrorIs(t, first, errs[1].Err)
+
+	assert.EqualError(t, errs[2:].First(),
+		"inner validation failed: entry at index 1: value: too big")
+	assert.NoError(t, errs[:1].First())
+}
@@ -0,0 +1,238 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors identifying the kind of a validation problem. Every Error
+// wraps one of them, so that they can be matched with errors.Is.
+var (
+	// ErrEmpty indicates a structure or collection that must not be
+	// empty (e.g. a non-empty<> map in the CDDL)
+	ErrEmpty = errors.New("empty")
+	// ErrMissing indicates that a mandatory field is not set
+	ErrMissing = errors.New("missing mandatory field")
+	// ErrInvalidValue indicates a field whose value is not valid
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrExtension indicates a violation of the constraints imposed by
+	// registered extensions (e.g. by a profile)
+	ErrExtension = errors.New("extension constraint violated")
+)
+
+// codes maps the sentinel errors onto their machine-readable codes
+var codes = map[error]string{
+	ErrEmpty:        "empty",
+	ErrMissing:      "missing",
+	ErrInvalidValue: "invalid-value",
+	ErrExtension:    "extension",
+}
+
+// Code returns the machine-readable code associated with the specified
+// sentinel error (e.g. "missing" for ErrMissing), or "invalid-value" if it is
+// not one of the sentinels defined by this package.
+func Code(sentinel error) string {
+	if code, ok := codes[sentinel]; ok {
+		return code
+	}
+
+	return codes[ErrInvalidValue]
+}
+
+// Severity indicates whether a problem makes the validated value invalid.
+type Severity int
+
+const (
+	// SeverityError is used for problems that make the value invalid
+	SeverityError Severity = iota
+	// SeverityWarning is used for problems that do not make the value
+	// invalid, but are likely to be unintended
+	SeverityWarning
+)
+
+func (o Severity) String() string {
+	switch o {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(o))
+	}
+}
+
+// Segment is an element of the Path to a validated value: either a field or
+// an array element.
+type Segment struct {
+	// Name is the JSON name of the field, or the index of the array
+	// element in decimal
+	Name string
+	// Key is the CBOR map key of the field (an int or a string), or the
+	// index of the array element (or of the field, within structures
+	// serialized as CBOR arrays). It is nil if the field has no CBOR
+	// serialization.
+	Key any
+}
+
+// Path locates a value within the validated structure.
+type Path []Segment
+
+// Pointer returns the path as a JSON pointer (RFC 6901) into the JSON
+// serialization of the validated structure, e.g.
+// "/triples/reference-values/0/environment"
+func (o Path) Pointer() string {
+	var b strings.Builder
+
+	for _, seg := range o {
+		b.WriteByte('/')
+		b.WriteString(escapePointer(seg.Name))
+	}
+
+	return b.String()
+}
+
+// CBORKeys returns the sequence of CBOR map keys and array indexes leading to
+// the value within the CBOR serialization of the validated structure.
+func (o Path) CBORKeys() []any {
+	ret := make([]any, 0, len(o))
+
+	for _, seg := range o {
+		ret = append(ret, seg.Key)
+	}
+
+	return ret
+}
+
+// CBORPointer returns the CBOR keys of the path in the form of a JSON pointer,
+// e.g. "/4/0/0/0". Text string keys are quoted.
+func (o Path) CBORPointer() string {
+	var b strings.Builder
+
+	for _, seg := range o {
+		b.WriteByte('/')
+
+		switch k := seg.Key.(type) {
+		case string:
+			b.WriteString(escapePointer(strconv.Quote(k)))
+		case nil:
+			b.WriteString("?")
+		default:
+			fmt.Fprint(&b, k)
+		}
+	}
+
+	return b.String()
+}
+
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// Error is a single problem found while validating a structure.
+type Error struct {
+	// Path locates the offending value
+	Path Path
+	// Code is the machine-readable code of the kind of problem (see
+	// Code)
+	Code string
+	// Severity of the problem
+	Severity Severity
+	// Kind is the sentinel error identifying the kind of problem
+	Kind error
+	// Err describes the problem
+	Err error
+
+	// context is the sequence of messages set via Collector.Wrap
+	context []string
+}
+
+func (o Error) Error() string {
+	var prefix string
+
+	if o.Severity == SeverityWarning {
+		prefix = "warning: "
+	}
+
+	if len(o.Path) == 0 {
+		return prefix + o.Err.Error()
+	}
+
+	return fmt.Sprintf("%s%s: %s", prefix, o.Path.Pointer(), o.Err)
+}
+
+// Unwrap returns both the sentinel error and the underlying one
+func (o Error) Unwrap() []error {
+	return []error{o.Kind, o.Err}
+}
+
+// Errors is the list of problems found while validating a structure, in the
+// order they were found.
+type Errors []*Error
+
+func (o Errors) Error() string {
+	msgs := make([]string, 0, len(o))
+
+	for _, e := range o {
+		msgs = append(msgs, e.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual errors, so that errors.Is and errors.As
+// consider all of them
+func (o Errors) Unwrap() []error {
+	ret := make([]error, 0, len(o))
+
+	for _, e := range o {
+		ret = append(ret, e)
+	}
+
+	return ret
+}
+
+// HasErrors returns true if the list contains any problems of SeverityError.
+func (o Errors) HasErrors() bool {
+	for _, e := range o {
+		if e.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// First returns the first problem of SeverityError in the list, or nil if
+// there is none. The problem is returned as reported by the Valid methods,
+// i.e. its Err prefixed with the messages set via Collector.Wrap, rather than
+// with its location.
+func (o Errors) First() error {
+	for _, e := range o {
+		if e.Severity != SeverityError {
+			continue
+		}
+
+		err := e.Err
+		for i := len(e.context) - 1; i >= 0; i-- {
+			err = fmt.Errorf("%s: %w", e.context[i], err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Err returns the list as an error if it contains any problems of
+// SeverityError, and nil otherwise.
+func (o Errors) Err() error {
+	if !o.HasErrors() {
+		return nil
+	}
+
+	return o
+}
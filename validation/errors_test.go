@@ -0,0 +1,76 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath(t *testing.T) {
+	path := Path{
+		{Name: "triples", Key: 4},
+		{Name: "a/b~c", Key: "x/y"},
+		{Name: "0", Key: 0},
+		{Name: "json-only"},
+	}
+
+	assert.Equal(t, "/triples/a~1b~0c/0/json-only", path.Pointer())
+	assert.Equal(t, []any{4, "x/y", 0, nil}, path.CBORKeys())
+	assert.Equal(t, `/4/"x~1y"/0/?`, path.CBORPointer())
+
+	assert.Equal(t, "", Path{}.Pointer())
+	assert.Equal(t, []any{}, Path{}.CBORKeys())
+}
+
+func TestCode(t *testing.T) {
+	assert.Equal(t, "empty", Code(ErrEmpty))
+	assert.Equal(t, "missing", Code(ErrMissing))
+	assert.Equal(t, "invalid-value", Code(ErrInvalidValue))
+	assert.Equal(t, "extension", Code(ErrExtension))
+	assert.Equal(t, "invalid-value", Code(errors.New("other")))
+}
+
+func TestSeverity_String(t *testing.T) {
+	assert.Equal(t, "error", SeverityError.String())
+	assert.Equal(t, "warning", SeverityWarning.String())
+	assert.Equal(t, "Severity(7)", Severity(7).String())
+}
+
+func TestErrors(t *testing.T) {
+	errs := Errors{
+		{
+			Path:     Path{{Name: "tag-identity", Key: 1}},
+			Code:     "invalid-value",
+			Severity: SeverityError,
+			Kind:     ErrInvalidValue,
+			Err:      errors.New("empty tag-id"),
+		},
+		{
+			Code:     "empty",
+			Severity: SeverityWarning,
+			Kind:     ErrEmpty,
+			Err:      errors.New("too late"),
+		},
+	}
+
+	assert.EqualError(t, errs, "/tag-identity: empty tag-id; warning: too late")
+	assert.ErrorIs(t, errs, ErrInvalidValue)
+	assert.ErrorIs(t, errs, ErrEmpty)
+	assert.NotErrorIs(t, errs, ErrMissing)
+
+	var e *Error
+	assert.True(t, errors.As(errs, &e))
+	assert.Equal(t, "/tag-identity", e.Path.Pointer())
+
+	assert.True(t, errs.HasErrors())
+	assert.Equal(t, errs, errs.Err())
+
+	warnings := errs[1:]
+	assert.False(t, warnings.HasErrors())
+	assert.NoError(t, warnings.Err())
+
+	assert.NoError(t, Errors{}.Err())
+}